@@ -5,11 +5,14 @@ import (
 	"github.com/jst-Frenzy/ControlSystem/AuthService/internal/AuthService"
 	"github.com/jst-Frenzy/ControlSystem/AuthService/internal/dataBase"
 	"github.com/jst-Frenzy/ControlSystem/AuthService/internal/gRPC"
+	"github.com/jst-Frenzy/ControlSystem/AuthService/internal/gRPC/client"
 	"github.com/jst-Frenzy/ControlSystem/AuthService/internal/rest/handlers"
+	"github.com/jst-Frenzy/ControlSystem/protobuf/permissions"
 	"github.com/sirupsen/logrus"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 )
@@ -20,9 +23,42 @@ func main() {
 	dataBase.InitPostgres()
 
 	authPostgresRepo := AuthService.NewAuthPostgresRepo(dataBase.PostgresDB)
-	authRedisRepo := AuthService.NewAuthRedisRepo(dataBase.RedisDB)
-	tokenManager := AuthService.NewManager(os.Getenv("SIGNING_KEY"))
-	authService := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, tokenManager)
+	userCacheTTL, err := AuthService.UserCacheTTLFromEnv()
+	if err != nil {
+		logger.WithError(err).Fatal("can't configure user cache")
+	}
+	authRedisRepo := AuthService.NewAuthRedisRepo(dataBase.RedisDB, userCacheTTL)
+	keyRotationInterval, err := time.ParseDuration(os.Getenv("JWT_KEY_ROTATION_INTERVAL"))
+	if err != nil {
+		logger.WithError(err).Fatal("can't get key rotation interval from env")
+	}
+	tokenManager, err := AuthService.NewManager(os.Getenv("JWT_KEYS_DIR"), os.Getenv("JWT_SIGNING_ALGORITHM"), keyRotationInterval)
+	if err != nil {
+		logger.WithError(err).Fatal("can't load signing keys")
+	}
+	var mailer AuthService.Mailer
+	if os.Getenv("MAILER") == "file" {
+		mailer = AuthService.NewFileMailer(os.Getenv("MAILER_DIR"), os.Getenv("PUBLIC_URL"))
+	} else {
+		mailer = AuthService.NewLogMailer(os.Getenv("PUBLIC_URL"))
+	}
+	goodsClient, err := client.NewGoodsClient(os.Getenv("ADDRESS_GRPC_GOODS"))
+	if err != nil {
+		logger.WithError(err).Fatal("can't start goods client")
+	}
+	orderClient, err := client.NewOrderClient(os.Getenv("ADDRESS_GRPC_ORDER"))
+	if err != nil {
+		logger.WithError(err).Fatal("can't start order client")
+	}
+	passwordHasher, err := AuthService.NewPasswordHasherFromEnv()
+	if err != nil {
+		logger.WithError(err).Fatal("can't configure password hashing")
+	}
+	passwordPolicy, err := AuthService.NewPasswordPolicyFromEnv()
+	if err != nil {
+		logger.WithError(err).Fatal("can't load password policy")
+	}
+	authService := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, tokenManager, mailer, passwordHasher, passwordPolicy, orderClient, goodsClient)
 
 	grpcServer := gRPC.NewGRPCServer(gRPC.Deps{
 		Logger:      logger,
@@ -40,9 +76,43 @@ func main() {
 		}
 	}()
 
+	oauthService := AuthService.NewOAuthService(authService, os.Getenv("PUBLIC_URL"))
+
+	// every provider in EXTERNAL_PROVIDERS is configured by EXTERNAL_PROVIDER_<NAME>_ISSUER, _CLIENT_ID and _CLIENT_SECRET
+	var externalProviders []AuthService.ExternalProvider
+	for _, name := range strings.Split(os.Getenv("EXTERNAL_PROVIDERS"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		prefix := "EXTERNAL_PROVIDER_" + strings.ToUpper(name) + "_"
+		externalProviders = append(externalProviders, AuthService.ExternalProvider{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+		})
+	}
+	externalLoginService := AuthService.NewExternalLoginService(authService, os.Getenv("PUBLIC_URL"), externalProviders...)
+
 	authHandler := handlers.NewAuthHandler(authService)
+	oauthHandler := handlers.NewOAuthHandler(oauthService)
+	externalLoginHandler := handlers.NewExternalLoginHandler(externalLoginService)
 
 	router := gin.Default()
+	// sign in limits are counted per client IP, so only the reverse proxy may set X-Forwarded-For
+	var trustedProxies []string
+	if proxies := os.Getenv("TRUSTED_PROXIES"); proxies != "" {
+		trustedProxies = strings.Split(proxies, ",")
+	}
+	if errProxies := router.SetTrustedProxies(trustedProxies); errProxies != nil {
+		logger.WithError(errProxies).Fatal("can't set trusted proxies")
+	}
+
+	router.Use(authHandler.CorrelationID)
+
+	router.GET("/.well-known/jwks.json", authHandler.JWKS)
+	router.GET("/.well-known/openid-configuration", oauthHandler.Discovery)
 
 	api := router.Group("/api")
 	{
@@ -50,8 +120,57 @@ func main() {
 		{
 			auth.POST("/signup", authHandler.SignUp)
 			auth.POST("/signin", authHandler.SignIn)
+			auth.POST("/signin/2fa", authHandler.SignInMFA)
+			auth.POST("/2fa/enroll", authHandler.UserIdentity, authHandler.DenyImpersonation, authHandler.EnrollTOTP)
+			auth.POST("/2fa/confirm", authHandler.UserIdentity, authHandler.DenyImpersonation, authHandler.ConfirmTOTP)
+			auth.GET("/verify", authHandler.VerifyEmail)
+			auth.POST("/verify/resend", authHandler.ResendEmailVerification)
 			auth.POST("/refresh", authHandler.Refresh)
-			auth.POST("/changeRole", authHandler.ChangeRole)
+			auth.POST("/password/forgot", authHandler.ForgotPassword)
+			auth.POST("/password/reset", authHandler.ResetPassword)
+			auth.POST("/password/change", authHandler.UserIdentity, authHandler.DenyImpersonation, authHandler.ChangePassword)
+			auth.POST("/logout", authHandler.Logout)
+			auth.POST("/logout/all", authHandler.UserIdentity, authHandler.LogoutAll)
+			auth.GET("/sessions", authHandler.UserIdentity, authHandler.ListSessions)
+			auth.DELETE("/sessions/:id", authHandler.UserIdentity, authHandler.RevokeSession)
+			auth.GET("/api-keys", authHandler.UserIdentity, authHandler.ListAPIKeys)
+			auth.POST("/api-keys", authHandler.UserIdentity, authHandler.DenyImpersonation, authHandler.CreateAPIKey)
+			auth.DELETE("/api-keys/:id", authHandler.UserIdentity, authHandler.RevokeAPIKey)
+			auth.POST("/unlock", authHandler.UserIdentity, authHandler.RequirePermission(permissions.UsersManage), authHandler.UnlockAccount)
+			auth.POST("/changeRole", authHandler.UserIdentity, authHandler.DenyImpersonation, authHandler.RequirePermission(permissions.UsersManage), authHandler.ChangeRole)
+			auth.GET("/me", authHandler.UserIdentity, authHandler.GetProfile)
+			auth.PATCH("/me", authHandler.UserIdentity, authHandler.DenyImpersonation, authHandler.UpdateProfile)
+			auth.DELETE("/me", authHandler.UserIdentity, authHandler.DenyImpersonation, authHandler.DeleteAccount)
+			auth.GET("/me/export", authHandler.UserIdentity, authHandler.ExportAccount)
+			auth.GET("/me/identities", authHandler.UserIdentity, externalLoginHandler.ListIdentities)
+			auth.DELETE("/me/identities/:provider", authHandler.UserIdentity, authHandler.DenyImpersonation, externalLoginHandler.UnlinkIdentity)
+			auth.GET("/email/confirm", authHandler.ConfirmEmailChange)
+			auth.GET("/users", authHandler.UserIdentity, authHandler.RequirePermission(permissions.UsersManage), authHandler.ListUsers)
+			auth.POST("/users/:id/deactivate", authHandler.UserIdentity, authHandler.RequirePermission(permissions.UsersManage), authHandler.DeactivateUser)
+			auth.POST("/users/:id/reactivate", authHandler.UserIdentity, authHandler.RequirePermission(permissions.UsersManage), authHandler.ReactivateUser)
+			auth.POST("/users/:id/impersonate", authHandler.UserIdentity, authHandler.DenyImpersonation, authHandler.RequirePermission(permissions.UsersManage), authHandler.Impersonate)
+			auth.GET("/audit", authHandler.UserIdentity, authHandler.RequirePermission(permissions.UsersManage), authHandler.ListAuditEvents)
+			auth.GET("/audit/export", authHandler.UserIdentity, authHandler.RequirePermission(permissions.UsersManage), authHandler.ExportAuditEvents)
+
+			oauth := auth.Group("/oauth")
+			{
+				oauth.GET("/authorize", authHandler.UserIdentity, authHandler.DenyImpersonation, oauthHandler.Authorize)
+				oauth.POST("/authorize", authHandler.UserIdentity, authHandler.DenyImpersonation, oauthHandler.Authorize)
+				oauth.POST("/token", oauthHandler.Token)
+				oauth.GET("/userinfo", oauthHandler.UserInfo)
+				oauth.POST("/userinfo", oauthHandler.UserInfo)
+				oauth.GET("/clients", authHandler.UserIdentity, authHandler.RequirePermission(permissions.UsersManage), oauthHandler.ListClients)
+				oauth.POST("/clients", authHandler.UserIdentity, authHandler.RequirePermission(permissions.UsersManage), oauthHandler.RegisterClient)
+				oauth.DELETE("/clients/:id", authHandler.UserIdentity, authHandler.RequirePermission(permissions.UsersManage), oauthHandler.DeleteClient)
+			}
+
+			external := auth.Group("/external")
+			{
+				external.GET("", externalLoginHandler.Providers)
+				external.GET("/:provider/login", externalLoginHandler.Login)
+				external.GET("/:provider/callback", externalLoginHandler.Callback)
+				external.POST("/:provider/reauthenticate", authHandler.UserIdentity, authHandler.DenyImpersonation, externalLoginHandler.Reauthenticate)
+			}
 		}
 	}
 
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"github.com/jst-Frenzy/ControlSystem/AuthService/internal/AuthService"
+	"github.com/jst-Frenzy/ControlSystem/AuthService/internal/dataBase"
+	"github.com/sirupsen/logrus"
+	"os"
+)
+
+// createadmin creates the first admin account or promotes an existing one:
+//
+//	ADMIN_PASSWORD=... ./create-admin -email admin@example.com -username admin
+func main() {
+	email := flag.String("email", "", "admin email")
+	userName := flag.String("username", "admin", "admin user name")
+	flag.Parse()
+
+	password := os.Getenv("ADMIN_PASSWORD")
+	if *email == "" || password == "" {
+		logrus.Fatal("-email flag and ADMIN_PASSWORD env are required")
+	}
+
+	dataBase.InitRedis()
+	dataBase.InitPostgres()
+
+	passwordHasher, err := AuthService.NewPasswordHasherFromEnv()
+	if err != nil {
+		logrus.WithError(err).Fatal("can't configure password hashing")
+	}
+	passwordPolicy, err := AuthService.NewPasswordPolicyFromEnv()
+	if err != nil {
+		logrus.WithError(err).Fatal("can't load password policy")
+	}
+	userCacheTTL, err := AuthService.UserCacheTTLFromEnv()
+	if err != nil {
+		logrus.WithError(err).Fatal("can't configure user cache")
+	}
+
+	authService := AuthService.NewAuthService(
+		AuthService.NewAuthPostgresRepo(dataBase.PostgresDB),
+		AuthService.NewAuthRedisRepo(dataBase.RedisDB, userCacheTTL),
+		nil,
+		nil,
+		passwordHasher,
+		passwordPolicy,
+	)
+
+	user, err := authService.BootstrapAdmin(AuthService.UserSignUp{
+		UserName: *userName,
+		Email:    *email,
+		Password: password,
+	})
+	if err != nil {
+		logrus.WithError(err).Fatal("can't create admin")
+	}
+
+	logrus.WithField("user_id", user.ID).Info("admin is ready")
+}
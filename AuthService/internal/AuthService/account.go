@@ -0,0 +1,94 @@
+package AuthService
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+//go:generate mockgen -source=account.go -destination=../mocks/mockAccount.go
+
+// UserDataHolder is another service keeping data of the user, the cart id and the seller's user id
+// are both the user id.
+type UserDataHolder interface {
+	// Name is the key the exported data is stored under in AccountExport.Services.
+	Name() string
+	ExportUserData(ctx context.Context, userID int) (interface{}, error)
+	// DeleteUserData must succeed for a user without any data, so an interrupted deletion can be retried.
+	DeleteUserData(ctx context.Context, userID int) error
+}
+
+func (s *authService) ExportAccount(ctx context.Context, userID int) (AccountExport, error) {
+	profile, err := s.GetProfile(userID)
+	if err != nil {
+		return AccountExport{}, err
+	}
+
+	sessions, err := s.repoPostgres.ListSessions(userID)
+	if err != nil {
+		return AccountExport{}, err
+	}
+
+	export := AccountExport{
+		ExportedAt: time.Now().UTC(),
+		Profile:    profile,
+		Sessions:   sessions,
+		Services:   make(map[string]interface{}, len(s.dataHolders)),
+	}
+	for _, h := range s.dataHolders {
+		data, errExport := h.ExportUserData(ctx, userID)
+		if errExport != nil {
+			logrus.WithError(errExport).WithField("service", h.Name()).Error("can't export user data")
+			return AccountExport{}, ErrUserDataUnavailable
+		}
+		export.Services[h.Name()] = data
+	}
+
+	return export, nil
+}
+
+// DeleteAccount erases the user in every service. The other services go first, so if one of them fails
+// the account is kept and the user can try again. A user without a password confirms with reauthToken.
+func (s *authService) DeleteAccount(ctx context.Context, userID int, password, reauthToken string, client ClientInfo) error {
+	err := s.deleteAccount(ctx, userID, password, reauthToken)
+	s.audit(AuditEvent{Event: AuditAccountDeleted, UserID: auditUserID(userID)}, client, err)
+	return err
+}
+
+func (s *authService) deleteAccount(ctx context.Context, userID int, password, reauthToken string) error {
+	user, err := s.repoPostgres.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if err = s.confirmUser(user, password, reauthToken); err != nil {
+		return err
+	}
+
+	for _, h := range s.dataHolders {
+		if errDelete := h.DeleteUserData(ctx, userID); errDelete != nil {
+			logrus.WithError(errDelete).WithField("service", h.Name()).Error("can't delete user data")
+			return ErrUserDataUnavailable
+		}
+	}
+
+	hashes, err := s.repoPostgres.DeleteUser(userID)
+	if err != nil {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"event":   "account_deleted",
+		"user_id": userID,
+	}).Info("account deleted")
+
+	if errCache := s.repoRedis.DeleteRefreshTokens(hashes...); errCache != nil {
+		logrus.Warn("can't delete user refresh tokens from cache")
+	}
+	s.invalidateUser(userID)
+	if errRevoke := s.repoRedis.RevokeUserAccessTokens(userID, time.Now(), accessTTL); errRevoke != nil {
+		logrus.WithError(errRevoke).Error("can't revoke access tokens of deleted user")
+	}
+
+	return nil
+}
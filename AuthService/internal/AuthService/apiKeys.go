@@ -0,0 +1,169 @@
+package AuthService
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// apiKeyPrefix tells API keys apart from access tokens, and makes leaked keys easy to search for.
+	apiKeyPrefix       = "cs_"
+	apiKeyPrefixLength = len(apiKeyPrefix) + 8
+
+	// apiKeyTouchInterval limits how often the last use of a key is written, a busy script
+	// would otherwise update the row on every request.
+	apiKeyTouchInterval = time.Minute
+)
+
+// CreateAPIKey issues a key with a subset of the user's permissions, the key is only returned here.
+func (s *authService) CreateAPIKey(userID int, req CreateAPIKeyRequest) (APIKeyInfo, error) {
+	user, err := s.repoPostgres.GetUserByID(userID)
+	if err != nil {
+		return APIKeyInfo{}, err
+	}
+
+	permissions := PermissionsForRole(user.Role)
+	scopes := make([]string, 0, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		if !slices.Contains(permissions, scope) {
+			return APIKeyInfo{}, ErrInvalidScope
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	secret, err := s.tokenManager.NewRefreshToken()
+	if err != nil {
+		return APIKeyInfo{}, err
+	}
+	key := apiKeyPrefix + secret
+
+	apiKey := APIKey{
+		UserID:    user.ID,
+		Name:      req.Name,
+		Prefix:    key[:apiKeyPrefixLength],
+		KeyHash:   s.makeHash(key),
+		Scopes:    strings.Join(scopes, " "),
+		CreatedAt: time.Now(),
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := apiKey.CreatedAt.AddDate(0, 0, req.ExpiresInDays)
+		apiKey.ExpiresAt = &expiresAt
+	}
+
+	apiKey, err = s.repoPostgres.CreateAPIKey(apiKey)
+	if err != nil {
+		return APIKeyInfo{}, err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"event":      "api_key_created",
+		"user_id":    user.ID,
+		"api_key_id": apiKey.ID,
+	}).Info("api key created")
+
+	info := newAPIKeyInfo(apiKey)
+	info.Key = key
+	return info, nil
+}
+
+func (s *authService) ListAPIKeys(userID int) ([]APIKeyInfo, error) {
+	keys, err := s.repoPostgres.ListAPIKeys(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]APIKeyInfo, 0, len(keys))
+	for _, key := range keys {
+		infos = append(infos, newAPIKeyInfo(key))
+	}
+	return infos, nil
+}
+
+func (s *authService) RevokeAPIKey(userID, keyID int, client ClientInfo) error {
+	err := s.revokeAPIKey(userID, keyID)
+	s.audit(AuditEvent{Event: AuditAPIKeyRevoked, UserID: auditUserID(userID)}, client, err)
+	return err
+}
+
+func (s *authService) revokeAPIKey(userID, keyID int) error {
+	if err := s.repoPostgres.RevokeAPIKey(userID, keyID); err != nil {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"event":      "api_key_revoked",
+		"user_id":    userID,
+		"api_key_id": keyID,
+	}).Info("api key revoked")
+
+	return nil
+}
+
+// ValidateAPIKey returns the owner of the key like ParseToken does for access tokens. The permissions
+// are the scopes of the key the owner still has, so a key loses them along with the owner's role.
+func (s *authService) ValidateAPIKey(key string) (InfoFromToken, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return InfoFromToken{}, ErrAPIKeyInvalid
+	}
+
+	apiKey, err := s.repoPostgres.GetAPIKey(s.makeHash(key))
+	if err != nil {
+		if errors.Is(err, ErrAPIKeyNotFound) {
+			return InfoFromToken{}, ErrAPIKeyInvalid
+		}
+		return InfoFromToken{}, err
+	}
+
+	now := time.Now()
+	if apiKey.RevokedAt != nil || (apiKey.ExpiresAt != nil && now.After(*apiKey.ExpiresAt)) {
+		return InfoFromToken{}, ErrAPIKeyInvalid
+	}
+
+	user, err := s.repoPostgres.GetUserByID(apiKey.UserID)
+	if err != nil {
+		return InfoFromToken{}, err
+	}
+	if user.DeactivatedAt != nil {
+		return InfoFromToken{}, ErrUserDeactivated
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > apiKeyTouchInterval {
+		if errTouch := s.repoPostgres.TouchAPIKey(apiKey.ID, now); errTouch != nil {
+			logrus.WithError(errTouch).Warn("can't update api key last use")
+		}
+	}
+
+	rolePermissions := PermissionsForRole(user.Role)
+	permissions := make([]string, 0, len(rolePermissions))
+	for _, scope := range strings.Fields(apiKey.Scopes) {
+		if slices.Contains(rolePermissions, scope) {
+			permissions = append(permissions, scope)
+		}
+	}
+
+	return InfoFromToken{
+		ID:          user.ID,
+		Role:        user.Role,
+		Permissions: permissions,
+		UserName:    user.UserName,
+		CartID:      strconv.Itoa(user.ID),
+	}, nil
+}
+
+func newAPIKeyInfo(key APIKey) APIKeyInfo {
+	return APIKeyInfo{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     strings.Fields(key.Scopes),
+		CreatedAt:  key.CreatedAt,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+	}
+}
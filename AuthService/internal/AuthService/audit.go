@@ -0,0 +1,132 @@
+package AuthService
+
+import (
+	"github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+const (
+	AuditSignUp          = "sign_up"
+	AuditSignIn          = "sign_in"
+	AuditExternalSignIn  = "external_sign_in"
+	AuditTokenRefresh    = "token_refresh"
+	AuditRoleChange      = "role_change"
+	AuditLogout          = "logout"
+	AuditLogoutAll       = "logout_all"
+	AuditSessionRevoked  = "session_revoked"
+	AuditTokenRevoked    = "token_revoked"
+	AuditUserDeactivated = "user_deactivated"
+	AuditImpersonation   = "impersonation_started"
+	AuditAPIKeyRevoked   = "api_key_revoked"
+	// a password reset or change and an account deletion revoke every session of the user as well
+	AuditPasswordReset  = "password_reset"
+	AuditPasswordChange = "password_change"
+	AuditAccountDeleted = "account_deleted"
+
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+	// AuditOutcomeMFARequired is a sign in that passed the password and waits for the second factor.
+	AuditOutcomeMFARequired = "mfa_required"
+)
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 500
+	auditExportPageSize  = 1000
+)
+
+// audit appends the event with the client that caused it, the outcome is a failure with err as the reason
+// unless the event already has one. The request isn't failed when the event can't be written, the event
+// goes to the logs instead.
+func (s *authService) audit(event AuditEvent, client ClientInfo, err error) {
+	event.OccurredAt = time.Now()
+	event.IP = client.IP
+	event.UserAgent = truncate(client.UserAgent, maxUserAgentLength)
+	event.CorrelationID = client.CorrelationID
+	if event.Outcome == "" {
+		event.Outcome = AuditOutcomeSuccess
+		if err != nil {
+			event.Outcome = AuditOutcomeFailure
+			event.Reason = err.Error()
+		}
+	}
+
+	if errAppend := s.repoPostgres.AppendAuditEvent(event); errAppend != nil {
+		logrus.WithError(errAppend).WithFields(logrus.Fields{
+			"audit_event":    event.Event,
+			"outcome":        event.Outcome,
+			"reason":         event.Reason,
+			"user_id":        event.UserID,
+			"actor_id":       event.ActorID,
+			"ip":             event.IP,
+			"correlation_id": event.CorrelationID,
+		}).Error("can't append audit event")
+	}
+}
+
+// auditUserID is nil for events of users that are unknown, like sign ins with a wrong email.
+func auditUserID(userID int) *int {
+	if userID == 0 {
+		return nil
+	}
+	return &userID
+}
+
+func (s *authService) ListAuditEvents(req ListAuditEventsRequest) ([]AuditEvent, int64, error) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultAuditPageSize
+	}
+
+	filter := newAuditFilter(req)
+	filter.Limit = min(limit, maxAuditPageSize)
+	filter.Offset = max(req.Offset, 0)
+
+	events, err := s.repoPostgres.ListAuditEvents(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.repoPostgres.CountAuditEvents(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+// ExportAuditEvents passes every matching event to write, newest first. The log is paged by ID, so
+// events appended during the export don't shift the pages.
+func (s *authService) ExportAuditEvents(req ListAuditEventsRequest, write func(AuditEvent) error) error {
+	filter := newAuditFilter(req)
+	filter.Limit = auditExportPageSize
+
+	for {
+		events, err := s.repoPostgres.ListAuditEvents(filter)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			if errWrite := write(event); errWrite != nil {
+				return errWrite
+			}
+		}
+
+		if len(events) < auditExportPageSize {
+			return nil
+		}
+		filter.BeforeID = events[len(events)-1].ID
+	}
+}
+
+func newAuditFilter(req ListAuditEventsRequest) AuditFilter {
+	return AuditFilter{
+		Event:         strings.TrimSpace(req.Event),
+		Outcome:       strings.TrimSpace(req.Outcome),
+		UserID:        req.UserID,
+		ActorID:       req.ActorID,
+		CorrelationID: strings.TrimSpace(req.CorrelationID),
+		From:          req.From,
+		To:            req.To,
+	}
+}
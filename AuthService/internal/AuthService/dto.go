@@ -8,17 +8,79 @@ import (
 const (
 	refreshTTL = 720 * time.Hour
 	accessTTL  = 120 * time.Minute
+	idTokenTTL = time.Hour
+
+	// keyRetention must be longer than the TTL of any token signed by the TokenManager.
+	keyRetention = accessTTL + 24*time.Hour
+)
+
+const (
+	PurposeEmailVerification = "email_verification"
+	PurposeMFAChallenge      = "mfa_challenge"
+	PurposeReauthentication  = "reauthentication"
 )
 
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+)
+
+const (
+	defaultUsersPageSize = 20
+	maxUsersPageSize     = 100
+)
+
+const (
+	RoleUser   = "user"
+	RoleSeller = "seller"
+	RoleAdmin  = "admin"
+)
+
+// Tokens holds only MFAToken when the password was right but the user still has to pass the second factor.
 type Tokens struct {
 	AccessToken  string
 	RefreshToken string
+	MFAToken     string
+	// ReauthToken is all a reauthentication at an identity provider returns, see ReauthenticationURL
+	ReauthToken string
 }
 
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// CustomClaims with a non-empty Purpose are one-time purpose tokens and are never accepted as access tokens.
 type CustomClaims struct {
-	Role     string `json:"role"`
-	UserName string `json:"userName"`
-	CartID   string `json:"cartID"`
+	Purpose     string   `json:"purpose,omitempty"`
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+	UserName    string   `json:"userName"`
+	CartID      string   `json:"cartID"`
+	// ClientID and Scope are set on access tokens issued to OAuth clients, which carry no permissions.
+	ClientID string `json:"client_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+	// Actor is set on access tokens an admin is impersonating the subject with.
+	Actor *Actor `json:"act,omitempty"`
+	// IssuedAtMs is the issue time in milliseconds, iat only has seconds, which can't tell the tokens
+	// issued right after a revocation from the ones it revokes.
+	IssuedAtMs int64 `json:"iat_ms,omitempty"`
+	jwt.StandardClaims
+}
+
+// Actor is the act claim of RFC 8693, Subject is the ID of the admin acting as the subject of the token.
+type Actor struct {
+	Subject string `json:"sub"`
+}
+
+// IDTokenClaims are the claims of an OpenID Connect ID token, the profile and email claims are set
+// only when the matching scope was granted.
+type IDTokenClaims struct {
+	Nonce         string `json:"nonce,omitempty"`
+	Name          string `json:"name,omitempty"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
 	jwt.StandardClaims
 }
 
@@ -32,14 +94,274 @@ type UserSignUp struct {
 	Password string `json:"password" binding:"required"`
 }
 
+type ChangeRoleRequest struct {
+	UserID  int    `json:"id" binding:"required"`
+	NewRole string `json:"newRole" binding:"required"`
+}
+
+type EmailRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// ChangePasswordRequest of a user signed up through an identity provider, who has no password yet,
+// carries a ReauthToken instead of the OldPassword.
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required_without=ReauthToken"`
+	ReauthToken string `json:"reauth_token"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// DeleteAccountRequest of a user without a password carries a ReauthToken instead.
+type DeleteAccountRequest struct {
+	Password    string `json:"password" binding:"required_without=ReauthToken"`
+	ReauthToken string `json:"reauth_token"`
+}
+
+// UpdateProfileRequest changes only the fields that are set, a new Email takes effect once it's confirmed.
+type UpdateProfileRequest struct {
+	UserName *string `json:"user_name" binding:"omitempty,max=255"`
+	Email    *string `json:"email" binding:"omitempty,email,max=255"`
+}
+
+type ListUsersRequest struct {
+	Query  string `form:"query"`
+	Limit  int    `form:"limit"`
+	Offset int    `form:"offset"`
+}
+
 type UserSignIn struct {
 	Email    string `json:"email" binding:"required"`
 	Password string `json:"password" binding:"required"`
 }
 
+type SignInMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+type TOTPCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// ClientInfo describes where a request came from.
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+	// CorrelationID ties the audit events of a request to the logs of the proxy and the services.
+	CorrelationID string
+}
+
+// UserProfile is the part of User that is shown to the user and to admins.
+type UserProfile struct {
+	ID            int        `json:"id"`
+	UserName      string     `json:"user_name"`
+	Email         string     `json:"email"`
+	PendingEmail  string     `json:"pending_email,omitempty"`
+	Role          string     `json:"role"`
+	Permissions   []string   `json:"permissions"`
+	EmailVerified bool       `json:"email_verified"`
+	TOTPEnabled   bool       `json:"totp_enabled"`
+	CreatedAt     time.Time  `json:"created_at"`
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty"`
+}
+
+func NewUserProfile(u User) UserProfile {
+	return UserProfile{
+		ID:            u.ID,
+		UserName:      u.UserName,
+		Email:         u.Email,
+		Role:          u.Role,
+		Permissions:   PermissionsForRole(u.Role),
+		EmailVerified: u.EmailVerifiedAt != nil,
+		TOTPEnabled:   u.TOTPEnabled,
+		CreatedAt:     u.CreatedAt,
+		DeactivatedAt: u.DeactivatedAt,
+	}
+}
+
+// Session is a signed in device, its ID is the family ID shared by every refresh token of the sign in.
+type Session struct {
+	ID          string    `json:"id"`
+	DeviceLabel string    `json:"device_label"`
+	UserAgent   string    `json:"user_agent"`
+	IP          string    `json:"ip"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastUsedAt  time.Time `json:"last_used_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// AuditEvent is a row of the append-only audit log. UserID is the user the event is about, ActorID is set
+// when someone else acted on the user. Reason explains failures.
+type AuditEvent struct {
+	ID         int64     `json:"id"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Event      string    `json:"event"`
+	Outcome    string    `json:"outcome"`
+	Reason     string    `json:"reason,omitempty"`
+	UserID     *int      `json:"user_id,omitempty"`
+	ActorID    *int      `json:"actor_id,omitempty"`
+
+	IP            string `json:"ip"`
+	UserAgent     string `json:"user_agent"`
+	CorrelationID string `json:"correlation_id"`
+}
+
+// AuditFilter narrows the audit log, zero fields don't filter. BeforeID pages through the log from
+// the newest event, From is inclusive and To is exclusive.
+type AuditFilter struct {
+	Event         string
+	Outcome       string
+	UserID        int
+	ActorID       int
+	CorrelationID string
+	From          time.Time
+	To            time.Time
+	BeforeID      int64
+
+	Limit  int
+	Offset int
+}
+
+type ListAuditEventsRequest struct {
+	Event         string    `form:"event"`
+	Outcome       string    `form:"outcome"`
+	UserID        int       `form:"user_id"`
+	ActorID       int       `form:"actor_id"`
+	CorrelationID string    `form:"correlation_id"`
+	From          time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To            time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit         int       `form:"limit"`
+	Offset        int       `form:"offset"`
+}
+
 type InfoFromToken struct {
-	ID       int
-	Role     string
-	UserName string
-	CartID   string
+	ID          int
+	Role        string
+	Permissions []string
+	UserName    string
+	CartID      string
+
+	// TokenID is the jti claim, it is empty for tokens issued before access tokens had one.
+	TokenID   string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+
+	ClientID string
+	Scope    string
+
+	// ActorID is the admin impersonating the user, it is zero for tokens of the user itself.
+	ActorID int
+}
+
+// AccountExport is everything kept about the user, Services holds the data of the other services
+// keyed by UserDataHolder.Name.
+type AccountExport struct {
+	ExportedAt time.Time              `json:"exported_at"`
+	Profile    UserProfile            `json:"profile"`
+	Sessions   []Session              `json:"sessions"`
+	Services   map[string]interface{} `json:"services"`
+}
+
+type RegisterOAuthClientRequest struct {
+	Name         string   `json:"name" binding:"required,max=255"`
+	RedirectURIs []string `json:"redirect_uris" binding:"required,min=1,dive,required"`
+	Public       bool     `json:"public"`
+}
+
+// OAuthClientInfo is a registered client as shown to admins, ClientSecret is only returned on registration.
+type OAuthClientInfo struct {
+	ClientID     string    `json:"client_id"`
+	ClientSecret string    `json:"client_secret,omitempty"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Public       bool      `json:"public"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type AuthorizeRequest struct {
+	ResponseType        string `form:"response_type" json:"response_type"`
+	ClientID            string `form:"client_id" json:"client_id"`
+	RedirectURI         string `form:"redirect_uri" json:"redirect_uri"`
+	Scope               string `form:"scope" json:"scope"`
+	State               string `form:"state" json:"state"`
+	Nonce               string `form:"nonce" json:"nonce"`
+	CodeChallenge       string `form:"code_challenge" json:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method" json:"code_challenge_method"`
+}
+
+type TokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+type ImpersonationToken struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+type OAuthTokens struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope"`
+}
+
+type UserInfo struct {
+	Sub           string `json:"sub"`
+	Name          string `json:"name,omitempty"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+}
+
+type OpenIDConfiguration struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+// IdentityInfo is an identity provider account linked to the user.
+type IdentityInfo struct {
+	Provider  string    `json:"provider"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" binding:"required,max=255"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"min=0,max=3650"`
+}
+
+// APIKeyInfo is an API key as shown to its owner, Key is only returned on creation.
+type APIKeyInfo struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Key        string     `json:"key,omitempty"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
 }
@@ -0,0 +1,297 @@
+package AuthService
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+//go:generate mockgen -source=externalLogin.go -destination=../mocks/mockExternalLogin.go
+
+const maxUserNameLength = 255
+
+// ExternalLoginService signs users in through external OpenID Connect identity providers. A provider
+// account is linked to the user with the same verified email, or a new user is created for it.
+type ExternalLoginService interface {
+	Providers() []string
+	LoginURL(ctx context.Context, provider string) (string, error)
+	ReauthenticationURL(ctx context.Context, provider string, userID int) (string, error)
+	Callback(ctx context.Context, provider, code, state string, client ClientInfo) (Tokens, error)
+	ListIdentities(userID int) ([]IdentityInfo, error)
+	UnlinkIdentity(userID int, provider string) error
+}
+
+type externalLoginService struct {
+	*authService
+	publicURL string
+	providers map[string]*oidcProvider
+}
+
+// NewExternalLoginService makes a service for providers that signs users in with auth, publicURL is
+// the public URL of the API the providers redirect users back to.
+func NewExternalLoginService(auth *authService, publicURL string, providers ...ExternalProvider) ExternalLoginService {
+	s := &externalLoginService{
+		authService: auth,
+		publicURL:   strings.TrimSuffix(publicURL, "/"),
+		providers:   make(map[string]*oidcProvider, len(providers)),
+	}
+	for _, p := range providers {
+		s.providers[p.Name] = newOIDCProvider(p)
+	}
+	return s
+}
+
+func (s *externalLoginService) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoginURL starts a login at the provider and returns the URL of its authorization endpoint to send the user to.
+func (s *externalLoginService) LoginURL(ctx context.Context, provider string) (string, error) {
+	return s.startLogin(ctx, provider, 0)
+}
+
+// ReauthenticationURL starts a login at the provider like LoginURL, but the callback only checks that
+// the provider account is linked to the user and returns a reauthentication token. The token confirms
+// DeleteAccount and ChangePassword for users without a password.
+func (s *externalLoginService) ReauthenticationURL(ctx context.Context, provider string, userID int) (string, error) {
+	return s.startLogin(ctx, provider, userID)
+}
+
+func (s *externalLoginService) startLogin(ctx context.Context, provider string, reauthUserID int) (string, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return "", ErrExternalProviderNotFound
+	}
+
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		logrus.WithError(err).WithField("provider", provider).Error("can't discover identity provider")
+		return "", ErrExternalProviderUnavailable
+	}
+
+	var state, nonce, codeVerifier string
+	for _, v := range []*string{&state, &nonce, &codeVerifier} {
+		if *v, err = s.tokenManager.NewRefreshToken(); err != nil {
+			return "", err
+		}
+	}
+
+	errSave := s.repoRedis.SaveExternalLogin(s.makeHash(state), ExternalLogin{
+		Provider:     provider,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ReauthUserID: reauthUserID,
+	}, externalLoginTTL)
+	if errSave != nil {
+		return "", errSave
+	}
+
+	return p.authCodeURL(metadata, s.redirectURI(provider), state, nonce, s256CodeChallenge(codeVerifier)), nil
+}
+
+// Callback finishes the login the provider redirected the user back from and signs the user in like SignIn does.
+func (s *externalLoginService) Callback(ctx context.Context, provider, code, state string, client ClientInfo) (Tokens, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return Tokens{}, ErrExternalProviderNotFound
+	}
+
+	tokens, userID, err := s.callback(ctx, p, provider, code, state, client)
+	s.auditSignIn(AuditExternalSignIn, userID, tokens, client, err)
+	return tokens, err
+}
+
+// callback returns the ID of the user as soon as the identity is resolved, so failures are audited with it.
+func (s *externalLoginService) callback(ctx context.Context, p *oidcProvider, provider, code, state string, client ClientInfo) (Tokens, int, error) {
+	login, err := s.repoRedis.TakeExternalLogin(s.makeHash(state))
+	if err != nil {
+		return Tokens{}, 0, err
+	}
+	if login.Provider != provider {
+		return Tokens{}, 0, ErrExternalLoginInvalid
+	}
+
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		logrus.WithError(err).WithField("provider", provider).Error("can't discover identity provider")
+		return Tokens{}, 0, ErrExternalProviderUnavailable
+	}
+
+	idToken, err := p.exchange(ctx, metadata, code, s.redirectURI(provider), login.CodeVerifier)
+	if err != nil {
+		return Tokens{}, 0, fmt.Errorf("%w: %v", ErrExternalLoginFailed, err)
+	}
+
+	claims, err := p.verifyIDToken(ctx, metadata, idToken, login.Nonce)
+	if err != nil {
+		return Tokens{}, 0, fmt.Errorf("%w: %v", ErrExternalLoginFailed, err)
+	}
+
+	if login.ReauthUserID != 0 {
+		return s.reauthenticate(provider, claims, login.ReauthUserID)
+	}
+
+	user, err := s.userForIdentity(provider, claims)
+	if err != nil {
+		return Tokens{}, 0, err
+	}
+	if user.DeactivatedAt != nil {
+		return Tokens{}, user.ID, ErrUserDeactivated
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"event":    "external_login",
+		"provider": provider,
+		"user_id":  user.ID,
+	}).Info("user signed in through identity provider")
+
+	tokens, err := s.finishSignIn(user, client)
+	return tokens, user.ID, err
+}
+
+// reauthenticate accepts only a provider account already linked to the user who started the reauthentication.
+func (s *externalLoginService) reauthenticate(provider string, claims externalIDTokenClaims, userID int) (Tokens, int, error) {
+	user, err := s.repoPostgres.GetUserByIdentity(provider, claims.Subject)
+	if errors.Is(err, ErrIdentityNotFound) || (err == nil && user.ID != userID) {
+		return Tokens{}, userID, ErrIdentityNotFound
+	}
+	if err != nil {
+		return Tokens{}, userID, err
+	}
+
+	reauthToken, err := s.tokenManager.NewPurposeToken(user.ID, PurposeReauthentication, reauthenticationTTL)
+	if err != nil {
+		return Tokens{}, user.ID, err
+	}
+	return Tokens{ReauthToken: reauthToken}, user.ID, nil
+}
+
+// userForIdentity returns the user linked to the provider account, linking or creating one on first login.
+// The email of an unknown account is trusted only when the provider verified it, otherwise anyone
+// could take over a user by registering its email at the provider.
+func (s *externalLoginService) userForIdentity(provider string, claims externalIDTokenClaims) (User, error) {
+	user, err := s.repoPostgres.GetUserByIdentity(provider, claims.Subject)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, ErrIdentityNotFound) {
+		return User{}, err
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		return User{}, ErrEmailNotVerified
+	}
+
+	identity := Identity{
+		Provider: provider,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}
+
+	user, err = s.repoPostgres.GetUser(claims.Email)
+	if err == nil {
+		// whoever signed up with an email they didn't verify may not own it and would keep signing in with
+		// their password to the account the provider user is linked to
+		if user.EmailVerifiedAt == nil {
+			return User{}, ErrEmailNotVerified
+		}
+		identity.UserID = user.ID
+		if errCreate := s.repoPostgres.CreateIdentity(identity); errCreate != nil {
+			return User{}, errCreate
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"event":    "identity_linked",
+			"provider": provider,
+			"user_id":  user.ID,
+		}).Info("identity linked")
+
+		return user, nil
+	}
+	if !errors.Is(err, ErrUserNotFound) {
+		return User{}, err
+	}
+
+	verifiedAt := time.Now()
+
+	return s.repoPostgres.CreateUserWithIdentity(User{
+		UserName:        externalUserName(claims),
+		Email:           claims.Email,
+		Role:            RoleUser,
+		EmailVerifiedAt: &verifiedAt,
+	}, identity)
+}
+
+func (s *externalLoginService) ListIdentities(userID int) ([]IdentityInfo, error) {
+	identities, err := s.repoPostgres.ListIdentities(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]IdentityInfo, 0, len(identities))
+	for _, identity := range identities {
+		infos = append(infos, IdentityInfo{
+			Provider:  identity.Provider,
+			Email:     identity.Email,
+			CreatedAt: identity.CreatedAt,
+		})
+	}
+	return infos, nil
+}
+
+// UnlinkIdentity refuses to unlink the last identity of a user without a password, the user
+// would have no way to sign in left.
+func (s *externalLoginService) UnlinkIdentity(userID int, provider string) error {
+	user, err := s.repoPostgres.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	identities, err := s.repoPostgres.ListIdentities(userID)
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(identities, func(identity Identity) bool { return identity.Provider == provider }) {
+		return ErrIdentityNotFound
+	}
+	if user.PasswordHash == "" && len(identities) == 1 {
+		return ErrLastSignInMethod
+	}
+
+	if errDelete := s.repoPostgres.DeleteIdentity(userID, provider); errDelete != nil {
+		return errDelete
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"event":    "identity_unlinked",
+		"provider": provider,
+		"user_id":  userID,
+	}).Info("identity unlinked")
+
+	return nil
+}
+
+func (s *externalLoginService) redirectURI(provider string) string {
+	return s.publicURL + "/api/auth/external/" + provider + "/callback"
+}
+
+func externalUserName(claims externalIDTokenClaims) string {
+	name := strings.TrimSpace(claims.Name)
+	if name == "" {
+		name = strings.TrimSpace(claims.PreferredUsername)
+	}
+	if name == "" {
+		name, _, _ = strings.Cut(claims.Email, "@")
+	}
+	return truncate(name, maxUserNameLength)
+}
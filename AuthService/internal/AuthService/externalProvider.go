@@ -0,0 +1,311 @@
+package AuthService
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	providerRequestTimeout  = 10 * time.Second
+	maxProviderResponseSize = 1 << 20
+
+	// providerKeysRefreshInterval limits how often a token with an unknown kid makes the keys be fetched again.
+	providerKeysRefreshInterval = time.Minute
+	// idTokenLeeway tolerates the clock of the identity provider being a little ahead or behind.
+	idTokenLeeway = time.Minute
+)
+
+var providerScopes = []string{ScopeOpenID, ScopeEmail, ScopeProfile}
+
+// ExternalProvider is an OpenID Connect identity provider users can sign in with, its endpoints
+// and keys are discovered from Issuer.
+type ExternalProvider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+}
+
+type providerMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// externalIDTokenClaims are the claims read from the ID tokens of identity providers. Unlike
+// jwt.StandardClaims it accepts aud both as a string and as an array.
+type externalIDTokenClaims struct {
+	Issuer    string   `json:"iss"`
+	Subject   string   `json:"sub"`
+	Audience  audience `json:"aud"`
+	ExpiresAt int64    `json:"exp"`
+	Nonce     string   `json:"nonce"`
+
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+func (c *externalIDTokenClaims) Valid() error {
+	if c.ExpiresAt == 0 || time.Now().Add(-idTokenLeeway).Unix() > c.ExpiresAt {
+		return errors.New("id token is expired")
+	}
+	return nil
+}
+
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+// oidcProvider is the relying party side of the authorization code flow against one ExternalProvider.
+// The metadata is discovered once, the keys are fetched again when a token is signed with an unknown key.
+type oidcProvider struct {
+	config ExternalProvider
+	client *http.Client
+
+	mu            sync.Mutex
+	metadata      *providerMetadata
+	keys          map[string]interface{}
+	keysFetchedAt time.Time
+}
+
+func newOIDCProvider(config ExternalProvider) *oidcProvider {
+	return &oidcProvider{
+		config: config,
+		client: &http.Client{Timeout: providerRequestTimeout},
+	}
+}
+
+func (p *oidcProvider) discover(ctx context.Context) (providerMetadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.metadata != nil {
+		return *p.metadata, nil
+	}
+
+	var metadata providerMetadata
+	if err := p.getJSON(ctx, strings.TrimSuffix(p.config.Issuer, "/")+"/.well-known/openid-configuration", &metadata); err != nil {
+		return providerMetadata{}, err
+	}
+	if metadata.Issuer != p.config.Issuer {
+		return providerMetadata{}, fmt.Errorf("discovered issuer %q doesn't match %q", metadata.Issuer, p.config.Issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return providerMetadata{}, errors.New("provider metadata is incomplete")
+	}
+
+	p.metadata = &metadata
+	return metadata, nil
+}
+
+func (p *oidcProvider) authCodeURL(metadata providerMetadata, redirectURI, state, nonce, codeChallenge string) string {
+	return appendQuery(metadata.AuthorizationEndpoint, url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {redirectURI},
+		"scope":                 {strings.Join(providerScopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {codeChallengeMethodS256},
+	})
+}
+
+// exchange redeems the authorization code at the token endpoint and returns the ID token.
+func (p *oidcProvider) exchange(ctx context.Context, metadata providerMetadata, code, redirectURI, codeVerifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {grantTypeAuthorizationCode},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {codeVerifier},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if errDecode := json.NewDecoder(io.LimitReader(resp.Body, maxProviderResponseSize)).Decode(&body); errDecode != nil {
+		return "", fmt.Errorf("token endpoint answered %d with an unreadable body", resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint answered %d: %s %s", resp.StatusCode, body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return "", errors.New("token endpoint didn't return an id token")
+	}
+
+	return body.IDToken, nil
+}
+
+// verifyIDToken checks the signature of the ID token and that it was issued by the provider
+// to this client for the login started with nonce.
+func (p *oidcProvider) verifyIDToken(ctx context.Context, metadata providerMetadata, rawToken, nonce string) (externalIDTokenClaims, error) {
+	var claims externalIDTokenClaims
+	_, err := jwt.ParseWithClaims(rawToken, &claims, func(token *jwt.Token) (interface{}, error) {
+		alg := token.Method.Alg()
+		if alg != AlgorithmRS256 && alg != AlgorithmEdDSA {
+			return nil, fmt.Errorf("unexpected signing method %q", alg)
+		}
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, metadata, kid)
+	})
+	if err != nil {
+		return externalIDTokenClaims{}, err
+	}
+
+	if claims.Issuer != p.config.Issuer {
+		return externalIDTokenClaims{}, fmt.Errorf("id token is issued by %q", claims.Issuer)
+	}
+	if !slices.Contains(claims.Audience, p.config.ClientID) {
+		return externalIDTokenClaims{}, errors.New("id token is issued to another client")
+	}
+	if claims.Nonce != nonce {
+		return externalIDTokenClaims{}, errors.New("id token nonce doesn't match")
+	}
+	if claims.Subject == "" {
+		return externalIDTokenClaims{}, errors.New("id token has no subject")
+	}
+
+	return claims, nil
+}
+
+// key returns the public key kid of the provider, a token without kid is accepted when the provider
+// publishes a single key.
+func (p *oidcProvider) key(ctx context.Context, metadata providerMetadata, kid string) (interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	if time.Since(p.keysFetchedAt) < providerKeysRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	var set struct {
+		Keys []JWK `json:"keys"`
+	}
+	if err := p.getJSON(ctx, metadata.JWKSURI, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if key, err := parseJWK(jwk); err == nil {
+			keys[jwk.Kid] = key
+		}
+	}
+	p.keys = keys
+	p.keysFetchedAt = time.Now()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (p *oidcProvider) lookupKey(kid string) (interface{}, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+func (p *oidcProvider) getJSON(ctx context.Context, rawURL string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s answered %d", rawURL, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxProviderResponseSize)).Decode(v)
+}
+
+// parseJWK is the reverse of signingKey.jwk for the key types this service signs with itself.
+func parseJWK(jwk JWK) (interface{}, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+}
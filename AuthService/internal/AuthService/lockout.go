@@ -0,0 +1,105 @@
+package AuthService
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+const (
+	maxEmailLoginFailures = 5
+	maxIPLoginFailures    = 20
+	loginFailuresWindow   = 15 * time.Minute
+
+	baseLoginLockout = time.Minute
+	maxLoginLockout  = time.Hour
+)
+
+var (
+	ErrAccountLocked   = errors.New("account is temporarily locked")
+	ErrTooManyAttempts = errors.New("too many sign in attempts")
+)
+
+// LockoutError wraps ErrAccountLocked or ErrTooManyAttempts with the time left until the lock expires.
+type LockoutError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *LockoutError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", e.Err, e.RetryAfter.Round(time.Second))
+}
+
+func (e *LockoutError) Unwrap() error {
+	return e.Err
+}
+
+func emailLoginKey(email string) string {
+	return "email:" + email
+}
+
+func ipLoginKey(ip string) string {
+	return "ip:" + ip
+}
+
+// checkLoginLock fails open: when Redis is unavailable sign in keeps working without the limits.
+func (s *authService) checkLoginLock(email string, client ClientInfo) error {
+	if client.IP != "" {
+		if ttl := s.loginLockTTL(ipLoginKey(client.IP)); ttl > 0 {
+			return &LockoutError{Err: ErrTooManyAttempts, RetryAfter: ttl}
+		}
+	}
+
+	if ttl := s.loginLockTTL(emailLoginKey(email)); ttl > 0 {
+		return &LockoutError{Err: ErrAccountLocked, RetryAfter: ttl}
+	}
+
+	return nil
+}
+
+func (s *authService) loginLockTTL(key string) time.Duration {
+	ttl, err := s.repoRedis.GetLoginLock(key)
+	if err != nil {
+		logrus.WithError(err).Error("can't get login lock")
+		return 0
+	}
+	return ttl
+}
+
+func (s *authService) registerLoginFailure(email string, client ClientInfo) {
+	s.registerLoginFailureFor(emailLoginKey(email), maxEmailLoginFailures)
+	if client.IP != "" {
+		s.registerLoginFailureFor(ipLoginKey(client.IP), maxIPLoginFailures)
+	}
+}
+
+// registerLoginFailureFor locks key once it has maxFailures failures in loginFailuresWindow,
+// every further failure doubles the lock up to maxLoginLockout.
+func (s *authService) registerLoginFailureFor(key string, maxFailures int) {
+	failures, err := s.repoRedis.IncrLoginFailures(key, loginFailuresWindow)
+	if err != nil {
+		logrus.WithError(err).Error("can't register login failure")
+		return
+	}
+
+	if failures < maxFailures {
+		return
+	}
+
+	lockout := maxLoginLockout
+	if shift := failures - maxFailures; shift < 16 {
+		lockout = min(baseLoginLockout<<shift, maxLoginLockout)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"event":    "login_locked",
+		"key":      key,
+		"failures": failures,
+		"lockout":  lockout,
+	}).Warn("too many failed sign in attempts")
+
+	if errLock := s.repoRedis.SetLoginLock(key, lockout); errLock != nil {
+		logrus.WithError(errLock).Error("can't set login lock")
+	}
+}
@@ -0,0 +1,124 @@
+package AuthService
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:generate mockgen -source=mailer.go -destination=../mocks/mockMailer.go
+
+type Mailer interface {
+	SendEmailVerification(to, token string) error
+	SendPasswordReset(to, token string) error
+	SendEmailChange(to, token string) error
+}
+
+type message struct {
+	to      string
+	subject string
+	body    string
+}
+
+func emailVerificationMessage(publicURL, to, token string) message {
+	link := fmt.Sprintf("%s/api/auth/verify?token=%s", strings.TrimSuffix(publicURL, "/"), url.QueryEscape(token))
+	return message{
+		to:      to,
+		subject: "Confirm your email",
+		body:    fmt.Sprintf("Open the link to confirm your email, it is valid for %s:\n\n%s\n", emailVerificationTTL, link),
+	}
+}
+
+func passwordResetMessage(publicURL, to, token string) message {
+	link := fmt.Sprintf("%s/api/auth/password/reset", strings.TrimSuffix(publicURL, "/"))
+	return message{
+		to:      to,
+		subject: "Reset your password",
+		body: fmt.Sprintf("Send the token with your new password to %s, it is valid for %s:\n\n%s\n\n"+
+			"If you didn't ask to reset your password, ignore this email.\n", link, passwordResetTTL, token),
+	}
+}
+
+func emailChangeMessage(publicURL, to, token string) message {
+	link := fmt.Sprintf("%s/api/auth/email/confirm?token=%s", strings.TrimSuffix(publicURL, "/"), url.QueryEscape(token))
+	return message{
+		to:      to,
+		subject: "Confirm your new email",
+		body: fmt.Sprintf("Open the link to use this address for your account, it is valid for %s:\n\n%s\n\n"+
+			"If you didn't ask to change your email, ignore this email.\n", emailChangeTTL, link),
+	}
+}
+
+// logMailer writes emails to the log instead of sending them, it's meant for local development.
+type logMailer struct {
+	publicURL string
+}
+
+func NewLogMailer(publicURL string) Mailer {
+	return &logMailer{publicURL: publicURL}
+}
+
+func (m *logMailer) SendEmailVerification(to, token string) error {
+	return m.send(emailVerificationMessage(m.publicURL, to, token))
+}
+
+func (m *logMailer) SendPasswordReset(to, token string) error {
+	return m.send(passwordResetMessage(m.publicURL, to, token))
+}
+
+func (m *logMailer) SendEmailChange(to, token string) error {
+	return m.send(emailChangeMessage(m.publicURL, to, token))
+}
+
+func (m *logMailer) send(msg message) error {
+	logrus.WithFields(logrus.Fields{
+		"to":      msg.to,
+		"subject": msg.subject,
+	}).Info(msg.body)
+	return nil
+}
+
+// fileMailer drops every email into dir as a separate .eml file, it's meant for local development.
+type fileMailer struct {
+	dir       string
+	publicURL string
+	mu        sync.Mutex
+}
+
+func NewFileMailer(dir, publicURL string) Mailer {
+	return &fileMailer{
+		dir:       dir,
+		publicURL: publicURL,
+	}
+}
+
+func (m *fileMailer) SendEmailVerification(to, token string) error {
+	return m.send(emailVerificationMessage(m.publicURL, to, token))
+}
+
+func (m *fileMailer) SendPasswordReset(to, token string) error {
+	return m.send(passwordResetMessage(m.publicURL, to, token))
+}
+
+func (m *fileMailer) SendEmailChange(to, token string) error {
+	return m.send(emailChangeMessage(m.publicURL, to, token))
+}
+
+func (m *fileMailer) send(msg message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := os.MkdirAll(m.dir, 0700); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), strings.ReplaceAll(msg.to, string(filepath.Separator), "_"))
+	data := fmt.Sprintf("To: %s\r\nSubject: %s\r\nDate: %s\r\n\r\n%s", msg.to, msg.subject, time.Now().Format(time.RFC1123Z), msg.body)
+
+	return os.WriteFile(filepath.Join(m.dir, name), []byte(data), 0600)
+}
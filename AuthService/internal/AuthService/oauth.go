@@ -0,0 +1,498 @@
+package AuthService
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:generate mockgen -source=oauth.go -destination=../mocks/mockOAuth.go
+
+// Error codes of RFC 6749 and RFC 6750.
+const (
+	OAuthInvalidRequest          = "invalid_request"
+	OAuthInvalidClient           = "invalid_client"
+	OAuthInvalidGrant            = "invalid_grant"
+	OAuthInvalidScope            = "invalid_scope"
+	OAuthUnsupportedGrantType    = "unsupported_grant_type"
+	OAuthUnsupportedResponseType = "unsupported_response_type"
+	OAuthAccessDenied            = "access_denied"
+	OAuthInvalidToken            = "invalid_token"
+	OAuthInsufficientScope       = "insufficient_scope"
+)
+
+const (
+	grantTypeAuthorizationCode = "authorization_code"
+	grantTypeRefreshToken      = "refresh_token"
+
+	codeChallengeMethodS256 = "S256"
+)
+
+var supportedScopes = []string{ScopeOpenID, ScopeProfile, ScopeEmail}
+
+// OAuthService is the OAuth 2.0 authorization server with OpenID Connect on top, clients can only use
+// the authorization code flow with PKCE.
+type OAuthService interface {
+	RegisterClient(req RegisterOAuthClientRequest) (OAuthClientInfo, error)
+	ListClients() ([]OAuthClientInfo, error)
+	DeleteClient(clientID string) error
+	Authorize(userID int, req AuthorizeRequest) (string, error)
+	Token(req TokenRequest, client ClientInfo) (OAuthTokens, error)
+	UserInfo(accessToken string) (UserInfo, error)
+	Discovery() OpenIDConfiguration
+}
+
+// OAuthError is reported to the client as is, Code is one of the OAuth error codes.
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func newOAuthError(code, description string) *OAuthError {
+	return &OAuthError{Code: code, Description: description}
+}
+
+type oauthService struct {
+	*authService
+	issuer string
+}
+
+// NewOAuthService makes a provider whose issuer is the public URL of the API, the ID tokens it signs
+// are verified with the keys published at the JWKS endpoint. The provider signs users in with auth.
+func NewOAuthService(auth *authService, issuer string) OAuthService {
+	return &oauthService{
+		authService: auth,
+		issuer:      strings.TrimSuffix(issuer, "/"),
+	}
+}
+
+func (s *oauthService) RegisterClient(req RegisterOAuthClientRequest) (OAuthClientInfo, error) {
+	for _, uri := range req.RedirectURIs {
+		if !isValidRedirectURI(uri) {
+			return OAuthClientInfo{}, ErrInvalidRedirectURI
+		}
+	}
+
+	id, err := s.tokenManager.NewRefreshToken()
+	if err != nil {
+		return OAuthClientInfo{}, err
+	}
+
+	client := OAuthClient{
+		ID:           id[:32],
+		Name:         req.Name,
+		RedirectURIs: strings.Join(req.RedirectURIs, " "),
+		CreatedAt:    time.Now(),
+	}
+
+	var secret string
+	if !req.Public {
+		secret, err = s.tokenManager.NewRefreshToken()
+		if err != nil {
+			return OAuthClientInfo{}, err
+		}
+		client.SecretHash = s.makeHash(secret)
+	}
+
+	if errCreate := s.repoPostgres.CreateOAuthClient(client); errCreate != nil {
+		return OAuthClientInfo{}, errCreate
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"event":     "oauth_client_registered",
+		"client_id": client.ID,
+	}).Info("oauth client registered")
+
+	info := newOAuthClientInfo(client)
+	info.ClientSecret = secret
+	return info, nil
+}
+
+func (s *oauthService) ListClients() ([]OAuthClientInfo, error) {
+	clients, err := s.repoPostgres.ListOAuthClients()
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]OAuthClientInfo, 0, len(clients))
+	for _, c := range clients {
+		infos = append(infos, newOAuthClientInfo(c))
+	}
+	return infos, nil
+}
+
+// DeleteClient unregisters the client, the refresh tokens it was issued are deleted with it.
+func (s *oauthService) DeleteClient(clientID string) error {
+	if err := s.repoPostgres.DeleteOAuthClient(clientID); err != nil {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"event":     "oauth_client_deleted",
+		"client_id": clientID,
+	}).Info("oauth client deleted")
+
+	return nil
+}
+
+// Authorize issues an authorization code to the signed in user and returns the redirect_uri to send
+// the user back to, the frontend is expected to have asked the user for consent beforehand.
+// Errors about the request itself are returned in the redirect_uri as RFC 6749 requires, except for
+// an unknown client or redirect_uri, which are returned as an OAuthError and must not be redirected.
+func (s *oauthService) Authorize(userID int, req AuthorizeRequest) (string, error) {
+	client, err := s.repoPostgres.GetOAuthClient(req.ClientID)
+	if err != nil {
+		if errors.Is(err, ErrOAuthClientNotFound) {
+			return "", newOAuthError(OAuthInvalidRequest, "client_id is unknown")
+		}
+		return "", err
+	}
+
+	if !hasRedirectURI(client, req.RedirectURI) {
+		return "", newOAuthError(OAuthInvalidRequest, "redirect_uri is not registered for the client")
+	}
+
+	redirect := func(params url.Values) string {
+		if req.State != "" {
+			params.Set("state", req.State)
+		}
+		return appendQuery(req.RedirectURI, params)
+	}
+	redirectError := func(code, description string) string {
+		return redirect(url.Values{"error": {code}, "error_description": {description}})
+	}
+
+	if req.ResponseType != "code" {
+		return redirectError(OAuthUnsupportedResponseType, "only the code response type is supported"), nil
+	}
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != codeChallengeMethodS256 {
+		return redirectError(OAuthInvalidRequest, "code_challenge with the S256 method is required"), nil
+	}
+	scope, ok := normalizeScope(req.Scope)
+	if !ok {
+		return redirectError(OAuthInvalidScope, "supported scopes are "+strings.Join(supportedScopes, " ")), nil
+	}
+
+	user, err := s.repoPostgres.GetUserByID(userID)
+	if err != nil {
+		return "", err
+	}
+	if user.DeactivatedAt != nil {
+		return redirectError(OAuthAccessDenied, ErrUserDeactivated.Error()), nil
+	}
+
+	code, err := s.tokenManager.NewRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	errSave := s.repoRedis.SaveAuthorizationCode(s.makeHash(code), AuthorizationCode{
+		UserID:        user.ID,
+		ClientID:      client.ID,
+		RedirectURI:   req.RedirectURI,
+		Scope:         scope,
+		CodeChallenge: req.CodeChallenge,
+		Nonce:         req.Nonce,
+	}, authorizationCodeTTL)
+	if errSave != nil {
+		return "", errSave
+	}
+
+	return redirect(url.Values{"code": {code}}), nil
+}
+
+// Token implements the token endpoint for the authorization_code and refresh_token grants.
+func (s *oauthService) Token(req TokenRequest, client ClientInfo) (OAuthTokens, error) {
+	oauthClient, err := s.authenticateClient(req.ClientID, req.ClientSecret)
+	if err != nil {
+		return OAuthTokens{}, err
+	}
+
+	switch req.GrantType {
+	case grantTypeAuthorizationCode:
+		return s.exchangeCode(oauthClient, req, client)
+	case grantTypeRefreshToken:
+		return s.refreshClientTokens(oauthClient, req, client)
+	default:
+		return OAuthTokens{}, newOAuthError(OAuthUnsupportedGrantType, "supported grant types are authorization_code and refresh_token")
+	}
+}
+
+// authenticateClient checks the secret of confidential clients, public clients must not send one.
+func (s *oauthService) authenticateClient(clientID, secret string) (OAuthClient, error) {
+	if clientID == "" {
+		return OAuthClient{}, newOAuthError(OAuthInvalidClient, "client_id is required")
+	}
+
+	client, err := s.repoPostgres.GetOAuthClient(clientID)
+	if err != nil {
+		if errors.Is(err, ErrOAuthClientNotFound) {
+			return OAuthClient{}, newOAuthError(OAuthInvalidClient, "client authentication failed")
+		}
+		return OAuthClient{}, err
+	}
+
+	if client.SecretHash == "" {
+		if secret != "" {
+			return OAuthClient{}, newOAuthError(OAuthInvalidClient, "client authentication failed")
+		}
+		return client, nil
+	}
+
+	if subtle.ConstantTimeCompare([]byte(s.makeHash(secret)), []byte(client.SecretHash)) != 1 {
+		return OAuthClient{}, newOAuthError(OAuthInvalidClient, "client authentication failed")
+	}
+	return client, nil
+}
+
+func (s *oauthService) exchangeCode(oauthClient OAuthClient, req TokenRequest, client ClientInfo) (OAuthTokens, error) {
+	if req.Code == "" || req.CodeVerifier == "" {
+		return OAuthTokens{}, newOAuthError(OAuthInvalidRequest, "code and code_verifier are required")
+	}
+
+	code, err := s.repoRedis.TakeAuthorizationCode(s.makeHash(req.Code))
+	if err != nil {
+		if errors.Is(err, ErrAuthorizationCodeInvalid) {
+			return OAuthTokens{}, newOAuthError(OAuthInvalidGrant, err.Error())
+		}
+		return OAuthTokens{}, err
+	}
+
+	if code.ClientID != oauthClient.ID || code.RedirectURI != req.RedirectURI {
+		return OAuthTokens{}, newOAuthError(OAuthInvalidGrant, "code was issued to another client or redirect_uri")
+	}
+	if !verifyCodeChallenge(req.CodeVerifier, code.CodeChallenge) {
+		return OAuthTokens{}, newOAuthError(OAuthInvalidGrant, "code_verifier doesn't match code_challenge")
+	}
+
+	user, err := s.repoPostgres.GetUserByID(code.UserID)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return OAuthTokens{}, newOAuthError(OAuthInvalidGrant, err.Error())
+		}
+		return OAuthTokens{}, err
+	}
+	if user.DeactivatedAt != nil {
+		return OAuthTokens{}, newOAuthError(OAuthInvalidGrant, ErrUserDeactivated.Error())
+	}
+
+	familyID, err := s.newFamilyID()
+	if err != nil {
+		return OAuthTokens{}, err
+	}
+
+	return s.issueOAuthTokens(user, familyID, client, oauthGrant{client: oauthClient, scope: code.Scope}, code.Nonce)
+}
+
+func (s *oauthService) refreshClientTokens(oauthClient OAuthClient, req TokenRequest, client ClientInfo) (OAuthTokens, error) {
+	if req.RefreshToken == "" {
+		return OAuthTokens{}, newOAuthError(OAuthInvalidRequest, "refresh_token is required")
+	}
+
+	token, user, err := s.rotateRefreshToken(req.RefreshToken, oauthClient.ID)
+	if err != nil {
+		s.audit(AuditEvent{Event: AuditTokenRefresh, UserID: auditUserID(token.UserID)}, client, err)
+		if errors.Is(err, ErrRefreshTokenNotFound) ||
+			errors.Is(err, ErrRefreshTokenExpired) ||
+			errors.Is(err, ErrRefreshTokenReused) ||
+			errors.Is(err, ErrUserDeactivated) {
+			return OAuthTokens{}, newOAuthError(OAuthInvalidGrant, err.Error())
+		}
+		return OAuthTokens{}, err
+	}
+
+	tokens, err := s.issueOAuthTokens(user, token.FamilyID, client, oauthGrant{client: oauthClient, scope: token.Scope}, "")
+	s.audit(AuditEvent{Event: AuditTokenRefresh, UserID: auditUserID(user.ID)}, client, err)
+	return tokens, err
+}
+
+func (s *oauthService) issueOAuthTokens(user User, familyID string, client ClientInfo, grant oauthGrant, nonce string) (OAuthTokens, error) {
+	tokens, err := s.generateGrantTokens(user, familyID, client, grant)
+	if err != nil {
+		return OAuthTokens{}, err
+	}
+
+	resp := OAuthTokens{
+		AccessToken:  tokens.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(accessTTL.Seconds()),
+		RefreshToken: tokens.RefreshToken,
+		Scope:        grant.scope,
+	}
+
+	if hasScope(grant.scope, ScopeOpenID) {
+		info := userInfoForScope(user, grant.scope)
+		now := time.Now()
+
+		claims := IDTokenClaims{
+			Nonce:         nonce,
+			Name:          info.Name,
+			Email:         info.Email,
+			EmailVerified: info.EmailVerified,
+		}
+		claims.Issuer = s.issuer
+		claims.Subject = info.Sub
+		claims.Audience = grant.client.ID
+		claims.IssuedAt = now.Unix()
+		claims.ExpiresAt = now.Add(idTokenTTL).Unix()
+
+		resp.IDToken, err = s.tokenManager.NewIDToken(claims)
+		if err != nil {
+			return OAuthTokens{}, err
+		}
+	}
+
+	return resp, nil
+}
+
+// UserInfo accepts only access tokens issued to a client with the openid scope.
+func (s *oauthService) UserInfo(accessToken string) (UserInfo, error) {
+	info, err := s.ParseToken(accessToken)
+	if err != nil {
+		return UserInfo{}, newOAuthError(OAuthInvalidToken, "access token is invalid or expired")
+	}
+	if info.ClientID == "" || !hasScope(info.Scope, ScopeOpenID) {
+		return UserInfo{}, newOAuthError(OAuthInsufficientScope, "the openid scope is required")
+	}
+
+	user, err := s.repoPostgres.GetUserByID(info.ID)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return UserInfo{}, newOAuthError(OAuthInvalidToken, err.Error())
+		}
+		return UserInfo{}, err
+	}
+	if user.DeactivatedAt != nil {
+		return UserInfo{}, newOAuthError(OAuthInvalidToken, ErrUserDeactivated.Error())
+	}
+
+	return userInfoForScope(user, info.Scope), nil
+}
+
+func (s *oauthService) Discovery() OpenIDConfiguration {
+	algs := make([]string, 0, 1)
+	for _, k := range s.tokenManager.JWKS() {
+		if !slices.Contains(algs, k.Alg) {
+			algs = append(algs, k.Alg)
+		}
+	}
+	sort.Strings(algs)
+
+	return OpenIDConfiguration{
+		Issuer:                            s.issuer,
+		AuthorizationEndpoint:             s.issuer + "/api/auth/oauth/authorize",
+		TokenEndpoint:                     s.issuer + "/api/auth/oauth/token",
+		UserInfoEndpoint:                  s.issuer + "/api/auth/oauth/userinfo",
+		JWKSURI:                           s.issuer + "/.well-known/jwks.json",
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{grantTypeAuthorizationCode, grantTypeRefreshToken},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  algs,
+		ScopesSupported:                   supportedScopes,
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{codeChallengeMethodS256},
+		ClaimsSupported:                   []string{"sub", "iss", "aud", "exp", "iat", "nonce", "name", "email", "email_verified"},
+	}
+}
+
+func newOAuthClientInfo(c OAuthClient) OAuthClientInfo {
+	return OAuthClientInfo{
+		ClientID:     c.ID,
+		Name:         c.Name,
+		RedirectURIs: strings.Fields(c.RedirectURIs),
+		Public:       c.SecretHash == "",
+		CreatedAt:    c.CreatedAt,
+	}
+}
+
+func userInfoForScope(user User, scope string) UserInfo {
+	info := UserInfo{Sub: strconv.Itoa(user.ID)}
+	if hasScope(scope, ScopeProfile) {
+		info.Name = user.UserName
+	}
+	if hasScope(scope, ScopeEmail) {
+		verified := user.EmailVerifiedAt != nil
+		info.Email = user.Email
+		info.EmailVerified = &verified
+	}
+	return info
+}
+
+// isValidRedirectURI accepts absolute http(s) URIs without a fragment, redirect URIs are stored
+// space separated so they can't contain spaces either.
+func isValidRedirectURI(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || strings.ContainsAny(raw, " \t\n") {
+		return false
+	}
+	return (u.Scheme == "https" || u.Scheme == "http") && u.Host != "" && u.Fragment == ""
+}
+
+// hasRedirectURI compares redirect URIs as exact strings, as RFC 6749 requires for registered URIs.
+func hasRedirectURI(client OAuthClient, redirectURI string) bool {
+	return redirectURI != "" && slices.Contains(strings.Fields(client.RedirectURIs), redirectURI)
+}
+
+func appendQuery(redirectURI string, params url.Values) string {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+
+	query := u.Query()
+	for k, v := range params {
+		query[k] = v
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+// normalizeScope drops duplicates from the requested scope, an empty scope means openid.
+func normalizeScope(raw string) (string, bool) {
+	requested := strings.Fields(raw)
+	if len(requested) == 0 {
+		return ScopeOpenID, true
+	}
+
+	scopes := make([]string, 0, len(requested))
+	for _, scope := range requested {
+		if !slices.Contains(supportedScopes, scope) {
+			return "", false
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return strings.Join(scopes, " "), true
+}
+
+func hasScope(scope, want string) bool {
+	return slices.Contains(strings.Fields(scope), want)
+}
+
+// verifyCodeChallenge checks the code_verifier against an S256 code_challenge, see RFC 7636.
+func verifyCodeChallenge(verifier, challenge string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(s256CodeChallenge(verifier)), []byte(challenge)) == 1
+}
+
+func s256CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
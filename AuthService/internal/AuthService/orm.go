@@ -3,8 +3,17 @@ package AuthService
 import "time"
 
 const (
-	userEmailTTL        = 15 * time.Minute
+	defaultUserCacheTTL = 15 * time.Minute
 	userRefreshTokenTTL = 45 * time.Minute
+
+	emailVerificationTTL = 24 * time.Hour
+	passwordResetTTL     = time.Hour
+	emailChangeTTL       = 24 * time.Hour
+	mfaChallengeTTL      = 5 * time.Minute
+	authorizationCodeTTL = time.Minute
+	externalLoginTTL     = 10 * time.Minute
+	reauthenticationTTL  = 5 * time.Minute
+	impersonationTTL     = 15 * time.Minute
 )
 
 type User struct {
@@ -18,15 +27,163 @@ type User struct {
 
 	CreatedAt time.Time `redis:"CreatedAt"`
 	UpdatedAt time.Time `redis:"UpdatedAt"`
+
+	EmailVerifiedAt *time.Time `redis:"EmailVerifiedAt"`
+	TOTPEnabled     bool       `redis:"TOTPEnabled"`
+	DeactivatedAt   *time.Time `redis:"DeactivatedAt"`
 }
 
 type RefreshToken struct {
 	ID     int
 	UserID int
 
+	TokenHash string
+	FamilyID  string
+
+	UserAgent   string
+	IP          string
+	DeviceLabel string
+
+	// ClientID is set for tokens issued to an OAuth client, such tokens are only refreshed through the token endpoint.
+	ClientID *string
+	Scope    string
+
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	ExpiresAt  time.Time
+	RevokedAt  *time.Time
+	LastUsedAt time.Time
+}
+
+// RoleChange is an audit record of a user's role being changed, ActorID is nil for the bootstrap command.
+type RoleChange struct {
+	ID       int
+	ActorID  *int
+	TargetID int
+
+	OldRole string
+	NewRole string
+
+	CreatedAt time.Time
+}
+
+type EmailVerification struct {
+	ID     int
+	UserID int
+
+	Email     string
 	TokenHash string
 
 	CreatedAt time.Time
-	UpdatedAt time.Time
 	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+// EmailChange is a pending change of a user's email, it's applied once the new address is confirmed.
+type EmailChange struct {
+	ID     int
+	UserID int
+
+	NewEmail  string
+	TokenHash string
+
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+type PasswordReset struct {
+	ID     int
+	UserID int
+
+	TokenHash string
+
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+// TOTPCredential is the shared secret of a user's authenticator app, it is used for sign in once ConfirmedAt is set.
+// LastCounter is the period of the last accepted code, so a code can't be used twice.
+type TOTPCredential struct {
+	UserID int
+
+	Secret      string
+	LastCounter int64
+
+	CreatedAt   time.Time
+	ConfirmedAt *time.Time
+}
+
+type RecoveryCode struct {
+	ID     int
+	UserID int
+
+	CodeHash string
+
+	CreatedAt time.Time
+	UsedAt    *time.Time
+}
+
+// OAuthClient is an application registered to sign users in through the authorization code flow.
+// SecretHash is empty for public clients, which can't keep a secret and rely on PKCE alone.
+type OAuthClient struct {
+	ID   string
+	Name string
+
+	SecretHash   string
+	RedirectURIs string
+
+	CreatedAt time.Time
+}
+
+// AuthorizationCode is kept in redis under the hash of the code until it's redeemed or expires.
+type AuthorizationCode struct {
+	UserID      int
+	ClientID    string
+	RedirectURI string
+	Scope       string
+
+	CodeChallenge string
+	Nonce         string
+}
+
+// Identity links a user to its account at an external identity provider, Subject is the sub claim
+// of the provider's ID tokens.
+type Identity struct {
+	ID     int
+	UserID int
+
+	Provider string
+	Subject  string
+	Email    string
+
+	CreatedAt time.Time
+}
+
+// APIKey lets the scripts of a user call the services without signing in, only the hash of the key is stored.
+// Prefix is the start of the key, so the user can tell keys apart, Scopes are space separated permissions.
+type APIKey struct {
+	ID     int
+	UserID int
+
+	Name    string
+	Prefix  string
+	KeyHash string
+	Scopes  string
+
+	CreatedAt  time.Time
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+// ExternalLogin is kept in redis under the hash of the state sent to the identity provider
+// until the user comes back to the callback or it expires.
+type ExternalLogin struct {
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	// ReauthUserID is set when a signed in user reauthenticates rather than signs in
+	ReauthUserID int `json:",omitempty"`
 }
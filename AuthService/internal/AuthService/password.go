@@ -0,0 +1,340 @@
+package AuthService
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	PasswordHasherArgon2id = "argon2id"
+	PasswordHasherBcrypt   = "bcrypt"
+
+	argon2idHashPrefix = "$argon2id$"
+
+	defaultPasswordMinLength = 8
+	// bcrypt refuses passwords over 72 bytes, and long passwords make hashing slower for nothing
+	defaultPasswordMaxLength = 64
+	bcryptMaxPasswordBytes   = 72
+)
+
+// DefaultArgon2idParams follow the second recommended option of RFC 9106 with less memory.
+var DefaultArgon2idParams = Argon2idParams{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// PasswordHasher hashes passwords in a self-describing format, so hashes made with other parameters
+// or by another hasher can be told apart and replaced on the next sign in.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Verify(password, hash string) bool
+	// Supports reports whether the hash was made by the algorithm of the hasher.
+	Supports(hash string) bool
+	// NeedsRehash reports whether the hash isn't what Hash would make now.
+	NeedsRehash(hash string) bool
+}
+
+type bcryptHasher struct {
+	cost int
+}
+
+func NewBcryptHasher(cost int) PasswordHasher {
+	return &bcryptHasher{cost: cost}
+}
+
+func (h *bcryptHasher) Hash(password string) (string, error) {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	return string(bytes), err
+}
+
+func (h *bcryptHasher) Verify(password, hash string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+func (h *bcryptHasher) Supports(hash string) bool {
+	_, err := bcrypt.Cost([]byte(hash))
+	return err == nil
+}
+
+func (h *bcryptHasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != h.cost
+}
+
+// Argon2idParams are the cost parameters of Argon2id, Memory is in KiB.
+type Argon2idParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+type argon2idHasher struct {
+	params Argon2idParams
+}
+
+func NewArgon2idHasher(params Argon2idParams) PasswordHasher {
+	return &argon2idHasher{params: params}
+}
+
+// Hash encodes the hash in the PHC string format used by the reference implementation:
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>.
+func (h *argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.params.Iterations, h.params.Memory, h.params.Parallelism, h.params.KeyLength)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idHashPrefix, argon2.Version,
+		h.params.Memory, h.params.Iterations, h.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (h *argon2idHasher) Verify(password, hash string) bool {
+	params, salt, key, err := decodeArgon2idHash(hash)
+	if err != nil {
+		return false
+	}
+
+	otherKey := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	return subtle.ConstantTimeCompare(key, otherKey) == 1
+}
+
+func (h *argon2idHasher) Supports(hash string) bool {
+	_, _, _, err := decodeArgon2idHash(hash)
+	return err == nil
+}
+
+func (h *argon2idHasher) NeedsRehash(hash string) bool {
+	params, _, _, err := decodeArgon2idHash(hash)
+	return err != nil || params != h.params
+}
+
+func decodeArgon2idHash(hash string) (Argon2idParams, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=65536,t=3,p=2", salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || !strings.HasPrefix(hash, argon2idHashPrefix) {
+		return Argon2idParams{}, nil, nil, errors.New("not an argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return Argon2idParams{}, nil, nil, errors.New("unsupported argon2 version")
+	}
+
+	var params Argon2idParams
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return Argon2idParams{}, nil, nil, errors.New("invalid argon2id parameters")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Argon2idParams{}, nil, nil, err
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return Argon2idParams{}, nil, nil, err
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+
+	return params, salt, key, nil
+}
+
+// migratingHasher hashes with the current hasher and still verifies hashes of the previous ones,
+// which always need a rehash.
+type migratingHasher struct {
+	current  PasswordHasher
+	previous []PasswordHasher
+}
+
+func NewPasswordHasher(current PasswordHasher, previous ...PasswordHasher) PasswordHasher {
+	return &migratingHasher{current: current, previous: previous}
+}
+
+func (h *migratingHasher) Hash(password string) (string, error) {
+	return h.current.Hash(password)
+}
+
+func (h *migratingHasher) Verify(password, hash string) bool {
+	if hasher := h.hasherFor(hash); hasher != nil {
+		return hasher.Verify(password, hash)
+	}
+	return false
+}
+
+func (h *migratingHasher) Supports(hash string) bool {
+	return h.hasherFor(hash) != nil
+}
+
+func (h *migratingHasher) NeedsRehash(hash string) bool {
+	return !h.current.Supports(hash) || h.current.NeedsRehash(hash)
+}
+
+func (h *migratingHasher) hasherFor(hash string) PasswordHasher {
+	if h.current.Supports(hash) {
+		return h.current
+	}
+	for _, hasher := range h.previous {
+		if hasher.Supports(hash) {
+			return hasher
+		}
+	}
+	return nil
+}
+
+// NewPasswordHasherFromEnv hashes with the algorithm in PASSWORD_HASHER, argon2id by default, and
+// verifies hashes of the other one so switching back and forth doesn't lock anyone out.
+// The parameters are read from BCRYPT_COST, ARGON2ID_MEMORY_KIB, ARGON2ID_ITERATIONS and ARGON2ID_PARALLELISM.
+func NewPasswordHasherFromEnv() (PasswordHasher, error) {
+	cost, err := envInt("BCRYPT_COST", bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("BCRYPT_COST must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+
+	params := DefaultArgon2idParams
+	memory, err := envInt("ARGON2ID_MEMORY_KIB", int(params.Memory))
+	if err != nil {
+		return nil, err
+	}
+	iterations, err := envInt("ARGON2ID_ITERATIONS", int(params.Iterations))
+	if err != nil {
+		return nil, err
+	}
+	parallelism, err := envInt("ARGON2ID_PARALLELISM", int(params.Parallelism))
+	if err != nil {
+		return nil, err
+	}
+	if memory < 8*parallelism || iterations < 1 || parallelism < 1 || parallelism > 255 {
+		return nil, errors.New("invalid argon2id parameters")
+	}
+	params.Memory = uint32(memory)
+	params.Iterations = uint32(iterations)
+	params.Parallelism = uint8(parallelism)
+
+	bcryptHasher := NewBcryptHasher(cost)
+	argon2idHasher := NewArgon2idHasher(params)
+
+	switch os.Getenv("PASSWORD_HASHER") {
+	case "", PasswordHasherArgon2id:
+		return NewPasswordHasher(argon2idHasher, bcryptHasher), nil
+	case PasswordHasherBcrypt:
+		return NewPasswordHasher(bcryptHasher, argon2idHasher), nil
+	default:
+		return nil, fmt.Errorf("unknown password hasher %q", os.Getenv("PASSWORD_HASHER"))
+	}
+}
+
+// PasswordPolicy is checked for every new password. A zero MinLength, MaxLength or MaxBytes doesn't limit
+// the length, and a policy without a breached list lets any password through.
+type PasswordPolicy struct {
+	MinLength int
+	MaxLength int
+	MaxBytes  int
+
+	breached map[string]struct{}
+}
+
+// NewPasswordPolicy loads the breached passwords from a file with one password per line,
+// an empty path leaves the breached check out.
+func NewPasswordPolicy(minLength, maxLength int, breachedListPath string) (PasswordPolicy, error) {
+	policy := PasswordPolicy{MinLength: minLength, MaxLength: maxLength}
+	if breachedListPath == "" {
+		return policy, nil
+	}
+
+	file, err := os.Open(breachedListPath)
+	if err != nil {
+		return PasswordPolicy{}, err
+	}
+	defer file.Close()
+
+	policy.breached = make(map[string]struct{})
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if password := strings.TrimSpace(scanner.Text()); password != "" {
+			policy.breached[password] = struct{}{}
+		}
+	}
+	if errScan := scanner.Err(); errScan != nil {
+		return PasswordPolicy{}, errScan
+	}
+
+	return policy, nil
+}
+
+// NewPasswordPolicyFromEnv reads PASSWORD_MIN_LENGTH, PASSWORD_MAX_LENGTH and BREACHED_PASSWORDS_FILE,
+// and limits passwords to the 72 bytes bcrypt takes when PASSWORD_HASHER makes it hash new passwords.
+func NewPasswordPolicyFromEnv() (PasswordPolicy, error) {
+	minLength, err := envInt("PASSWORD_MIN_LENGTH", defaultPasswordMinLength)
+	if err != nil {
+		return PasswordPolicy{}, err
+	}
+	maxLength, err := envInt("PASSWORD_MAX_LENGTH", defaultPasswordMaxLength)
+	if err != nil {
+		return PasswordPolicy{}, err
+	}
+	if maxLength != 0 && maxLength < minLength {
+		return PasswordPolicy{}, errors.New("PASSWORD_MAX_LENGTH is less than PASSWORD_MIN_LENGTH")
+	}
+
+	policy, err := NewPasswordPolicy(minLength, maxLength, os.Getenv("BREACHED_PASSWORDS_FILE"))
+	if err != nil {
+		return PasswordPolicy{}, err
+	}
+	if os.Getenv("PASSWORD_HASHER") == PasswordHasherBcrypt {
+		policy.MaxBytes = bcryptMaxPasswordBytes
+	}
+	return policy, nil
+}
+
+// Validate counts the length in characters and checks MaxBytes against the UTF-8 encoding, then looks
+// the password up in the breached list as is.
+func (p PasswordPolicy) Validate(password string) error {
+	length := utf8.RuneCountInString(password)
+	if p.MinLength > 0 && length < p.MinLength {
+		return fmt.Errorf("%w: it must be at least %d characters long", ErrWeakPassword, p.MinLength)
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		return fmt.Errorf("%w: it must be at most %d characters long", ErrWeakPassword, p.MaxLength)
+	}
+	if p.MaxBytes > 0 && len(password) > p.MaxBytes {
+		return fmt.Errorf("%w: it must be at most %d bytes long, characters outside ASCII take several", ErrWeakPassword, p.MaxBytes)
+	}
+	if _, ok := p.breached[password]; ok {
+		return fmt.Errorf("%w: it appears in a list of breached passwords", ErrWeakPassword)
+	}
+	return nil
+}
+
+func envInt(name string, fallback int) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a number", name)
+	}
+	return n, nil
+}
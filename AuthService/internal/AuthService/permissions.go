@@ -0,0 +1,21 @@
+package AuthService
+
+import "github.com/jst-Frenzy/ControlSystem/protobuf/permissions"
+
+// rolePermissions is the single place where roles are granted permissions, services only check
+// the permissions carried in the access token.
+var rolePermissions = map[string][]string{
+	RoleUser:   {permissions.CartManage},
+	RoleSeller: {permissions.CartManage, permissions.GoodsWrite},
+	RoleAdmin:  {permissions.CartManage, permissions.UsersManage, permissions.CatalogManage, permissions.OrdersReadAny},
+}
+
+func PermissionsForRole(role string) []string {
+	granted := rolePermissions[role]
+	return append(make([]string, 0, len(granted)), granted...)
+}
+
+func isValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
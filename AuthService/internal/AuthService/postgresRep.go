@@ -1,18 +1,70 @@
 package AuthService
 
 import (
+	"errors"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
+	"time"
 )
 
 //go:generate mockgen -source=postgresRep.go -destination=../mocks/mockPostgres.go
 
 type AuthPostgresRepo interface {
 	GetUser(string) (User, error)
+	GetUserByID(int) (User, error)
 	CreateUser(User) (User, error)
 	SaveRefreshToken(RefreshToken) error
+	GetRefreshToken(string) (RefreshToken, error)
+	RevokeRefreshToken(string) (bool, error)
+	RevokeRefreshTokenFamily(string) ([]string, error)
 	GetUserByRefreshToken(string) (User, error)
+	DeleteRefreshToken(string) error
+	DeleteUserRefreshTokens(int) ([]string, error)
+	ListSessions(int) ([]Session, error)
+	DeleteSession(int, string) ([]string, error)
 
-	ChangeRole(int, string) (User, error)
+	ChangeRole(RoleChange) (User, error)
+	UpdateUserName(int, string) (User, error)
+	SaveEmailChange(EmailChange) error
+	ConfirmEmailChange(string) (User, string, error)
+	ListUsers(string, int, int) ([]User, int64, error)
+	SetUserDeactivated(int, *time.Time) (User, error)
+	DeleteUser(int) ([]string, error)
+
+	CreateOAuthClient(OAuthClient) error
+	GetOAuthClient(string) (OAuthClient, error)
+	ListOAuthClients() ([]OAuthClient, error)
+	DeleteOAuthClient(string) error
+
+	GetUserByIdentity(string, string) (User, error)
+	CreateUserWithIdentity(User, Identity) (User, error)
+	CreateIdentity(Identity) error
+	ListIdentities(int) ([]Identity, error)
+	DeleteIdentity(int, string) error
+
+	CreateAPIKey(APIKey) (APIKey, error)
+	GetAPIKey(string) (APIKey, error)
+	ListAPIKeys(int) ([]APIKey, error)
+	RevokeAPIKey(int, int) error
+	TouchAPIKey(int, time.Time) error
+
+	AppendAuditEvent(AuditEvent) error
+	ListAuditEvents(AuditFilter) ([]AuditEvent, error)
+	CountAuditEvents(AuditFilter) (int64, error)
+
+	SaveEmailVerification(EmailVerification) error
+	VerifyEmail(string) (User, error)
+
+	UpdatePassword(int, string) error
+	SavePasswordReset(PasswordReset) error
+	ResetPassword(string, string) (User, error)
+
+	SaveTOTP(TOTPCredential) error
+	GetTOTP(int) (TOTPCredential, error)
+	ConfirmTOTP(int, int64, []string) error
+	UseTOTPCounter(int, int64) (bool, error)
+	UseRecoveryCode(int, string) (bool, error)
 }
 
 type authPostgresRepo struct {
@@ -27,6 +79,20 @@ func (r *authPostgresRepo) GetUser(email string) (User, error) {
 	var err error
 	var foundUser User
 	if err = r.db.Table("users").Where("email = ?", email).First(&foundUser).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return User{}, ErrUserNotFound
+		}
+		return User{}, err
+	}
+	return foundUser, nil
+}
+
+func (r *authPostgresRepo) GetUserByID(id int) (User, error) {
+	var foundUser User
+	if err := r.db.Table("users").Where("id = ?", id).First(&foundUser).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return User{}, ErrUserNotFound
+		}
 		return User{}, err
 	}
 	return foundUser, nil
@@ -44,6 +110,43 @@ func (r *authPostgresRepo) SaveRefreshToken(token RefreshToken) error {
 	return r.db.Table("refresh_tokens").Create(&token).Error
 }
 
+func (r *authPostgresRepo) GetRefreshToken(refreshTokenHash string) (RefreshToken, error) {
+	var token RefreshToken
+	if err := r.db.Table("refresh_tokens").Where("token_hash = ?", refreshTokenHash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return RefreshToken{}, ErrRefreshTokenNotFound
+		}
+		return RefreshToken{}, err
+	}
+	return token, nil
+}
+
+func (r *authPostgresRepo) RevokeRefreshToken(refreshTokenHash string) (bool, error) {
+	res := r.db.Table("refresh_tokens").
+		Where("token_hash = ? AND revoked_at IS NULL", refreshTokenHash).
+		Update("revoked_at", time.Now())
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (r *authPostgresRepo) RevokeRefreshTokenFamily(familyID string) ([]string, error) {
+	var hashes []string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("refresh_tokens").Where("family_id = ?", familyID).Pluck("token_hash", &hashes).Error; err != nil {
+			return err
+		}
+		return tx.Table("refresh_tokens").
+			Where("family_id = ? AND revoked_at IS NULL", familyID).
+			Update("revoked_at", time.Now()).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
 func (r *authPostgresRepo) GetUserByRefreshToken(refreshTokenHash string) (User, error) {
 	var user User
 	var err error
@@ -55,13 +158,582 @@ func (r *authPostgresRepo) GetUserByRefreshToken(refreshTokenHash string) (User,
 	return user, nil
 }
 
-func (r *authPostgresRepo) ChangeRole(id int, newRole string) (User, error) {
+func (r *authPostgresRepo) DeleteRefreshToken(refreshTokenHash string) error {
+	res := r.db.Table("refresh_tokens").Where("token_hash = ?", refreshTokenHash).Delete(&RefreshToken{})
+	if res.Error != nil {
+		return res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return ErrRefreshTokenNotFound
+	}
+
+	return nil
+}
+
+func (r *authPostgresRepo) DeleteUserRefreshTokens(userID int) ([]string, error) {
+	var hashes []string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("refresh_tokens").Where("user_id = ?", userID).Pluck("token_hash", &hashes).Error; err != nil {
+			return err
+		}
+		return tx.Table("refresh_tokens").Where("user_id = ?", userID).Delete(&RefreshToken{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
+// ListSessions returns the live refresh token of every session of the user, CreatedAt is when the
+// session signed in rather than when the token was rotated.
+func (r *authPostgresRepo) ListSessions(userID int) ([]Session, error) {
+	var sessions []Session
+	err := r.db.Table("refresh_tokens AS t").
+		Select("t.family_id AS id, t.device_label, t.user_agent, t.ip, t.last_used_at, t.expires_at, "+
+			"(SELECT min(f.created_at) FROM refresh_tokens f WHERE f.family_id = t.family_id) AS created_at").
+		Where("t.user_id = ? AND t.revoked_at IS NULL AND t.expires_at > ?", userID, time.Now()).
+		Order("t.last_used_at DESC").
+		Scan(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// DeleteSession signs the session out, it returns ErrSessionNotFound if the session doesn't belong to the user.
+func (r *authPostgresRepo) DeleteSession(userID int, sessionID string) ([]string, error) {
+	var hashes []string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("refresh_tokens").Where("user_id = ? AND family_id = ?", userID, sessionID).
+			Pluck("token_hash", &hashes).Error; err != nil {
+			return err
+		}
+		if len(hashes) == 0 {
+			return ErrSessionNotFound
+		}
+		return tx.Table("refresh_tokens").Where("user_id = ? AND family_id = ?", userID, sessionID).
+			Delete(&RefreshToken{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
+// ChangeRole sets the new role of change.TargetID and records change in the audit table in one transaction.
+func (r *authPostgresRepo) ChangeRole(change RoleChange) (User, error) {
+	var u User
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("users").Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", change.TargetID).First(&u).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrUserNotFound
+			}
+			return err
+		}
+
+		change.OldRole = u.Role
+		u.Role = change.NewRole
+		u.UpdatedAt = time.Now()
+
+		if err := tx.Table("users").Where("id = ?", u.ID).
+			Updates(map[string]interface{}{"role": u.Role, "updated_at": u.UpdatedAt}).Error; err != nil {
+			return err
+		}
+
+		return tx.Table("role_changes").Create(&change).Error
+	})
+	if err != nil {
+		return User{}, err
+	}
+	return u, nil
+}
+
+func (r *authPostgresRepo) UpdateUserName(userID int, userName string) (User, error) {
+	res := r.db.Table("users").Where("id = ?", userID).
+		Updates(map[string]interface{}{"user_name": userName, "updated_at": time.Now()})
+	if res.Error != nil {
+		return User{}, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return User{}, ErrUserNotFound
+	}
+	return r.GetUserByID(userID)
+}
+
+func (r *authPostgresRepo) SaveEmailChange(change EmailChange) error {
+	return r.db.Table("email_changes").Create(&change).Error
+}
+
+// ConfirmEmailChange applies the email change of tokenHash and returns the updated user with its previous email.
+func (r *authPostgresRepo) ConfirmEmailChange(tokenHash string) (User, string, error) {
+	var u User
+	var oldEmail string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var change EmailChange
+		if err := tx.Table("email_changes").Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", tokenHash).First(&change).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrVerificationTokenInvalid
+			}
+			return err
+		}
+
+		now := time.Now()
+		if change.UsedAt != nil || now.After(change.ExpiresAt) {
+			return ErrVerificationTokenInvalid
+		}
+
+		var taken int64
+		if err := tx.Table("users").Where("email = ? AND id <> ?", change.NewEmail, change.UserID).
+			Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken > 0 {
+			return ErrEmailTaken
+		}
+
+		if err := tx.Table("users").Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", change.UserID).First(&u).Error; err != nil {
+			return err
+		}
+		oldEmail = u.Email
+
+		if err := tx.Table("email_changes").Where("user_id = ? AND used_at IS NULL", change.UserID).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Table("users").Where("id = ?", change.UserID).
+			Updates(map[string]interface{}{"email": change.NewEmail, "email_verified_at": now, "updated_at": now}).Error; err != nil {
+			return err
+		}
+
+		u.Email = change.NewEmail
+		u.EmailVerifiedAt = &now
+		u.UpdatedAt = now
+		return nil
+	})
+	if err != nil {
+		return User{}, "", err
+	}
+	return u, oldEmail, nil
+}
+
+// ListUsers returns a page of users whose email or user name contains query, along with the number of all matches.
+func (r *authPostgresRepo) ListUsers(query string, limit, offset int) ([]User, int64, error) {
+	db := r.db.Table("users")
+	if query != "" {
+		pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(query) + "%"
+		db = db.Where("email ILIKE ? OR user_name ILIKE ?", pattern, pattern)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []User
+	if err := db.Order("id").Limit(limit).Offset(offset).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// SetUserDeactivated deactivates the user at deactivatedAt, a nil deactivatedAt reactivates it.
+func (r *authPostgresRepo) SetUserDeactivated(userID int, deactivatedAt *time.Time) (User, error) {
+	res := r.db.Table("users").Where("id = ?", userID).
+		Updates(map[string]interface{}{"deactivated_at": deactivatedAt, "updated_at": time.Now()})
+	if res.Error != nil {
+		return User{}, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return User{}, ErrUserNotFound
+	}
+	return r.GetUserByID(userID)
+}
+
+func (r *authPostgresRepo) SaveEmailVerification(verification EmailVerification) error {
+	return r.db.Table("email_verifications").Create(&verification).Error
+}
+
+// VerifyEmail redeems the verification with tokenHash and marks its email as verified, a verification
+// can be redeemed once and only while the user still has the email it was issued for.
+func (r *authPostgresRepo) VerifyEmail(tokenHash string) (User, error) {
+	var u User
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var v EmailVerification
+		if err := tx.Table("email_verifications").Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", tokenHash).First(&v).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrVerificationTokenInvalid
+			}
+			return err
+		}
+
+		now := time.Now()
+		if v.UsedAt != nil || now.After(v.ExpiresAt) {
+			return ErrVerificationTokenInvalid
+		}
+
+		if err := tx.Table("email_verifications").Where("id = ?", v.ID).Update("used_at", now).Error; err != nil {
+			return err
+		}
+
+		res := tx.Table("users").Where("id = ? AND email = ?", v.UserID, v.Email).
+			Updates(map[string]interface{}{"email_verified_at": now, "updated_at": now})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrVerificationTokenInvalid
+		}
+
+		return tx.Table("users").Where("id = ?", v.UserID).First(&u).Error
+	})
+	if err != nil {
+		return User{}, err
+	}
+	return u, nil
+}
+
+func (r *authPostgresRepo) UpdatePassword(userID int, passwordHash string) error {
+	res := r.db.Table("users").Where("id = ?", userID).
+		Updates(map[string]interface{}{"password_hash": passwordHash, "updated_at": time.Now()})
+	if res.Error != nil {
+		return res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *authPostgresRepo) SavePasswordReset(reset PasswordReset) error {
+	return r.db.Table("password_resets").Create(&reset).Error
+}
+
+// ResetPassword redeems the reset with tokenHash and sets passwordHash, every other pending reset
+// of the user is redeemed too.
+func (r *authPostgresRepo) ResetPassword(tokenHash, passwordHash string) (User, error) {
 	var u User
-	if err := r.db.Table("users").Where("id = ?", id).Update("role", newRole).Error; err != nil {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var reset PasswordReset
+		if err := tx.Table("password_resets").Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", tokenHash).First(&reset).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrResetTokenInvalid
+			}
+			return err
+		}
+
+		now := time.Now()
+		if reset.UsedAt != nil || now.After(reset.ExpiresAt) {
+			return ErrResetTokenInvalid
+		}
+
+		if err := tx.Table("password_resets").Where("user_id = ? AND used_at IS NULL", reset.UserID).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Table("users").Where("id = ?", reset.UserID).
+			Updates(map[string]interface{}{"password_hash": passwordHash, "updated_at": now}).Error; err != nil {
+			return err
+		}
+
+		return tx.Table("users").Where("id = ?", reset.UserID).First(&u).Error
+	})
+	if err != nil {
+		return User{}, err
+	}
+	return u, nil
+}
+
+// SaveTOTP starts a new enrollment, replacing an unconfirmed secret of the user.
+func (r *authPostgresRepo) SaveTOTP(credential TOTPCredential) error {
+	return r.db.Table("totp_credentials").Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"secret":       credential.Secret,
+			"last_counter": 0,
+			"confirmed_at": nil,
+			"created_at":   time.Now(),
+		}),
+	}).Create(&credential).Error
+}
+
+func (r *authPostgresRepo) GetTOTP(userID int) (TOTPCredential, error) {
+	var credential TOTPCredential
+	if err := r.db.Table("totp_credentials").Where("user_id = ?", userID).First(&credential).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return TOTPCredential{}, ErrTOTPNotEnrolled
+		}
+		return TOTPCredential{}, err
+	}
+	return credential, nil
+}
+
+// ConfirmTOTP enables the second factor of the user and replaces its recovery codes.
+func (r *authPostgresRepo) ConfirmTOTP(userID int, counter int64, recoveryCodeHashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		res := tx.Table("totp_credentials").Where("user_id = ? AND confirmed_at IS NULL", userID).
+			Updates(map[string]interface{}{"confirmed_at": now, "last_counter": counter})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrTOTPNotEnrolled
+		}
+
+		if err := tx.Table("users").Where("id = ?", userID).
+			Updates(map[string]interface{}{"totp_enabled": true, "updated_at": now}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Table("recovery_codes").Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		codes := make([]RecoveryCode, 0, len(recoveryCodeHashes))
+		for _, hash := range recoveryCodeHashes {
+			codes = append(codes, RecoveryCode{UserID: userID, CodeHash: hash})
+		}
+		return tx.Table("recovery_codes").Create(&codes).Error
+	})
+}
+
+// UseTOTPCounter records counter as the last accepted period, it reports false if a code of the same
+// or a later period was already accepted.
+func (r *authPostgresRepo) UseTOTPCounter(userID int, counter int64) (bool, error) {
+	res := r.db.Table("totp_credentials").
+		Where("user_id = ? AND confirmed_at IS NOT NULL AND last_counter < ?", userID, counter).
+		Update("last_counter", counter)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (r *authPostgresRepo) UseRecoveryCode(userID int, codeHash string) (bool, error) {
+	res := r.db.Table("recovery_codes").
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// DeleteUser removes the user along with everything referencing it and returns the hashes of its refresh tokens,
+// so they can be dropped from cache.
+func (r *authPostgresRepo) DeleteUser(userID int) ([]string, error) {
+	var hashes []string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("refresh_tokens").Where("user_id = ?", userID).Pluck("token_hash", &hashes).Error; err != nil {
+			return err
+		}
+
+		res := tx.Table("users").Where("id = ?", userID).Delete(&User{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrUserNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
+func (r *authPostgresRepo) CreateOAuthClient(client OAuthClient) error {
+	return r.db.Table("oauth_clients").Create(&client).Error
+}
+
+func (r *authPostgresRepo) GetOAuthClient(clientID string) (OAuthClient, error) {
+	var client OAuthClient
+	if err := r.db.Table("oauth_clients").Where("id = ?", clientID).First(&client).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return OAuthClient{}, ErrOAuthClientNotFound
+		}
+		return OAuthClient{}, err
+	}
+	return client, nil
+}
+
+func (r *authPostgresRepo) ListOAuthClients() ([]OAuthClient, error) {
+	var clients []OAuthClient
+	if err := r.db.Table("oauth_clients").Order("created_at").Find(&clients).Error; err != nil {
+		return nil, err
+	}
+	return clients, nil
+}
+
+// DeleteOAuthClient also drops the refresh tokens issued to the client through the foreign key.
+func (r *authPostgresRepo) DeleteOAuthClient(clientID string) error {
+	res := r.db.Table("oauth_clients").Where("id = ?", clientID).Delete(&OAuthClient{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrOAuthClientNotFound
+	}
+	return nil
+}
+
+func (r *authPostgresRepo) GetUserByIdentity(provider, subject string) (User, error) {
+	var user User
+	if err := r.db.Table("users").
+		Where("id = (select user_id from identities where provider = ? and subject = ?)", provider, subject).
+		First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return User{}, ErrIdentityNotFound
+		}
 		return User{}, err
 	}
-	if err := r.db.Table("users").Where("id = ?", id).First(&u).Error; err != nil {
+	return user, nil
+}
+
+// CreateUserWithIdentity creates a user signing up through an identity provider along with its identity.
+func (r *authPostgresRepo) CreateUserWithIdentity(u User, identity Identity) (User, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("users").Create(&u).Error; err != nil {
+			return err
+		}
+
+		identity.UserID = u.ID
+		return tx.Table("identities").Create(&identity).Error
+	})
+	if err != nil {
 		return User{}, err
 	}
 	return u, nil
 }
+
+func (r *authPostgresRepo) CreateIdentity(identity Identity) error {
+	return r.db.Table("identities").Create(&identity).Error
+}
+
+func (r *authPostgresRepo) ListIdentities(userID int) ([]Identity, error) {
+	var identities []Identity
+	if err := r.db.Table("identities").Where("user_id = ?", userID).Order("created_at").Find(&identities).Error; err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
+
+func (r *authPostgresRepo) DeleteIdentity(userID int, provider string) error {
+	res := r.db.Table("identities").Where("user_id = ? AND provider = ?", userID, provider).Delete(&Identity{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrIdentityNotFound
+	}
+	return nil
+}
+
+func (r *authPostgresRepo) CreateAPIKey(key APIKey) (APIKey, error) {
+	if err := r.db.Table("api_keys").Create(&key).Error; err != nil {
+		return APIKey{}, err
+	}
+	return key, nil
+}
+
+func (r *authPostgresRepo) GetAPIKey(keyHash string) (APIKey, error) {
+	var key APIKey
+	if err := r.db.Table("api_keys").Where("key_hash = ?", keyHash).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return APIKey{}, ErrAPIKeyNotFound
+		}
+		return APIKey{}, err
+	}
+	return key, nil
+}
+
+// ListAPIKeys returns the keys of the user that aren't revoked, including the expired ones.
+func (r *authPostgresRepo) ListAPIKeys(userID int) ([]APIKey, error) {
+	var keys []APIKey
+	if err := r.db.Table("api_keys").Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("created_at").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey returns ErrAPIKeyNotFound if the key doesn't belong to the user or is already revoked.
+func (r *authPostgresRepo) RevokeAPIKey(userID, keyID int) error {
+	res := r.db.Table("api_keys").Where("id = ? AND user_id = ? AND revoked_at IS NULL", keyID, userID).
+		Update("revoked_at", time.Now())
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+func (r *authPostgresRepo) TouchAPIKey(keyID int, usedAt time.Time) error {
+	return r.db.Table("api_keys").Where("id = ?", keyID).Update("last_used_at", usedAt).Error
+}
+
+func (r *authPostgresRepo) AppendAuditEvent(event AuditEvent) error {
+	return r.db.Table("audit_log").Create(&event).Error
+}
+
+// ListAuditEvents returns the newest events first.
+func (r *authPostgresRepo) ListAuditEvents(filter AuditFilter) ([]AuditEvent, error) {
+	db := r.auditQuery(filter)
+	if filter.BeforeID > 0 {
+		db = db.Where("id < ?", filter.BeforeID)
+	}
+
+	var events []AuditEvent
+	if err := db.Order("id DESC").Limit(filter.Limit).Offset(filter.Offset).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (r *authPostgresRepo) CountAuditEvents(filter AuditFilter) (int64, error) {
+	var total int64
+	if err := r.auditQuery(filter).Count(&total).Error; err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+func (r *authPostgresRepo) auditQuery(filter AuditFilter) *gorm.DB {
+	db := r.db.Table("audit_log")
+	if filter.Event != "" {
+		db = db.Where("event = ?", filter.Event)
+	}
+	if filter.Outcome != "" {
+		db = db.Where("outcome = ?", filter.Outcome)
+	}
+	if filter.UserID != 0 {
+		db = db.Where("user_id = ?", filter.UserID)
+	}
+	if filter.ActorID != 0 {
+		db = db.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.CorrelationID != "" {
+		db = db.Where("correlation_id = ?", filter.CorrelationID)
+	}
+	if !filter.From.IsZero() {
+		db = db.Where("occurred_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		db = db.Where("occurred_at < ?", filter.To)
+	}
+	return db
+}
@@ -5,6 +5,9 @@ import (
 	"encoding/json"
 	"errors"
 	"github.com/redis/go-redis/v9"
+	"os"
+	"strconv"
+	"time"
 )
 
 //go:generate mockgen -source=redisRep.go -destination=../mocks/mockRedis.go
@@ -14,88 +17,315 @@ type AuthRedisRepo interface {
 	GetUserWithEmail(string) (User, error)
 	AddUserWithRefreshToken(User, string) error
 	GetUserWithRefreshToken(string) (User, error)
-	EditRoleWithRefreshToken(string, string) error
+	InvalidateUser(int) error
+	DeleteRefreshTokens(...string) error
+
+	IncrLoginFailures(string, time.Duration) (int, error)
+	SetLoginLock(string, time.Duration) error
+	GetLoginLock(string) (time.Duration, error)
+	DeleteLoginFailures(string) error
+
+	DenyAccessToken(string, time.Duration) error
+	RevokeUserAccessTokens(int, time.Time, time.Duration) error
+	IsAccessTokenRevoked(string, int, time.Time) (bool, error)
+
+	SaveAuthorizationCode(string, AuthorizationCode, time.Duration) error
+	TakeAuthorizationCode(string) (AuthorizationCode, error)
+
+	SaveExternalLogin(string, ExternalLogin, time.Duration) error
+	TakeExternalLogin(string) (ExternalLogin, error)
 }
 
+const (
+	// userCacheVersion is part of every user cache key, bump it when User changes shape so users cached
+	// in the old shape are never decoded into the new one.
+	userCacheVersion          = "v1"
+	userCachePrefix           = "user_cache:" + userCacheVersion + ":"
+	userKeyPrefix             = userCachePrefix + "user:"
+	userEmailKeyPrefix        = userCachePrefix + "email:"
+	userRefreshTokenKeyPrefix = userCachePrefix + "refresh_token:"
+	userInvalidatedKeyPrefix  = userCachePrefix + "invalidated:"
+
+	// userInvalidationWindow must outlast reading a user from the database and caching it
+	userInvalidationWindow = 10 * time.Second
+
+	loginFailuresPrefix = "login_failures:"
+	loginLockPrefix     = "login_lock:"
+
+	deniedTokenPrefix   = "denied_token:"
+	tokensRevokedPrefix = "tokens_revoked_before:"
+
+	authorizationCodePrefix = "oauth_code:"
+	externalLoginPrefix     = "external_login:"
+)
+
+// ErrUserCacheMiss is returned for users that aren't cached, the caller reads them from the database.
+var ErrUserCacheMiss = errors.New("user not found in cache")
+
+// addUserScript caches the user with KEYS[2] and points the index KEYS[3] at it, unless the user
+// was invalidated a moment ago and KEYS[1] is still set.
+var addUserScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return 0
+end
+redis.call("SET", KEYS[2], ARGV[1], "PX", ARGV[2])
+redis.call("SET", KEYS[3], ARGV[3], "PX", ARGV[4])
+return 1
+`)
+
 type authRedisRepo struct {
 	db  *redis.Client
 	ctx context.Context
+
+	userTTL time.Duration
 }
 
-func NewAuthRedisRepo(db *redis.Client) AuthRedisRepo {
+// NewAuthRedisRepo caches users for userTTL, a zero userTTL turns the user cache off and every
+// user is read from the database.
+func NewAuthRedisRepo(db *redis.Client, userTTL time.Duration) AuthRedisRepo {
 	return &authRedisRepo{
-		db:  db,
-		ctx: context.Background(),
+		db:      db,
+		ctx:     context.Background(),
+		userTTL: userTTL,
 	}
 }
 
-func (r *authRedisRepo) AddUserWithEmail(u User) error {
-	key := u.Email
+// UserCacheTTLFromEnv reads USER_CACHE_TTL as a duration, 15 minutes by default and 0 turns the cache off.
+func UserCacheTTLFromEnv() (time.Duration, error) {
+	value := os.Getenv("USER_CACHE_TTL")
+	if value == "" {
+		return defaultUserCacheTTL, nil
+	}
 
-	jsonData, err := json.Marshal(u)
-	if err != nil {
-		return err
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl < 0 {
+		return 0, errors.New("USER_CACHE_TTL must be a non-negative duration")
 	}
+	return ttl, nil
+}
 
-	return r.db.Set(r.ctx, key, jsonData, userEmailTTL).Err()
+// AddUserWithEmail caches the user for lookups by email. A user invalidated a moment ago isn't cached,
+// as it may have been read before the change that invalidated it.
+func (r *authRedisRepo) AddUserWithEmail(u User) error {
+	return r.addUser(u, userEmailKeyPrefix+u.Email, r.userTTL)
 }
 
+// AddUserWithRefreshToken caches the user for lookups by the refresh token hash, like AddUserWithEmail.
 func (r *authRedisRepo) AddUserWithRefreshToken(u User, refreshTokenHash string) error {
-	key := refreshTokenHash
+	return r.addUser(u, userRefreshTokenKeyPrefix+refreshTokenHash, userRefreshTokenTTL)
+}
+
+func (r *authRedisRepo) addUser(u User, indexKey string, indexTTL time.Duration) error {
+	if r.userTTL <= 0 {
+		return nil
+	}
 
 	jsonData, err := json.Marshal(u)
 	if err != nil {
 		return err
 	}
 
-	return r.db.Set(r.ctx, key, jsonData, userRefreshTokenTTL).Err()
+	userID := strconv.Itoa(u.ID)
+	return addUserScript.Run(r.ctx, r.db,
+		[]string{userInvalidatedKeyPrefix + userID, userKeyPrefix + userID, indexKey},
+		jsonData, r.userTTL.Milliseconds(), userID, indexTTL.Milliseconds(),
+	).Err()
 }
 
+// GetUserWithEmail returns ErrUserCacheMiss when the user isn't cached, or was cached under an email
+// it has since changed.
 func (r *authRedisRepo) GetUserWithEmail(email string) (User, error) {
-	var user User
-	jsonData, err := r.db.Get(r.ctx, email).Result()
+	user, err := r.getUser(userEmailKeyPrefix + email)
+	if err != nil {
+		return User{}, err
+	}
+	if user.Email != email {
+		return User{}, ErrUserCacheMiss
+	}
+	return user, nil
+}
+
+func (r *authRedisRepo) GetUserWithRefreshToken(refreshTokenHash string) (User, error) {
+	return r.getUser(userRefreshTokenKeyPrefix + refreshTokenHash)
+}
+
+// getUser follows the index key to the user it points to. A user that can't be decoded is dropped,
+// so it is read from the database and cached again.
+func (r *authRedisRepo) getUser(indexKey string) (User, error) {
+	if r.userTTL <= 0 {
+		return User{}, ErrUserCacheMiss
+	}
+
+	userID, err := r.db.Get(r.ctx, indexKey).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return User{}, ErrUserCacheMiss
+		}
+		return User{}, err
+	}
+
+	jsonData, err := r.db.Get(r.ctx, userKeyPrefix+userID).Result()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
-			return user, errors.New("user not found in cache")
+			return User{}, ErrUserCacheMiss
 		}
-		return user, err
+		return User{}, err
 	}
 
+	var user User
 	if errUnmarshal := json.Unmarshal([]byte(jsonData), &user); errUnmarshal != nil {
-		return user, err
+		if errDel := r.db.Del(r.ctx, userKeyPrefix+userID).Err(); errDel != nil {
+			return User{}, errors.Join(errUnmarshal, errDel)
+		}
+		return User{}, errUnmarshal
 	}
 
 	return user, nil
 }
 
-func (r *authRedisRepo) GetUserWithRefreshToken(refreshTokenHash string) (User, error) {
-	var user User
-	jsonData, err := r.db.Get(r.ctx, refreshTokenHash).Result()
+// InvalidateUser drops the cached user, every email and refresh token key of the user points to it,
+// so they all miss from now on. For a short while the user can't be cached again, which keeps out
+// a user that was read from the database before the change and cached after it.
+func (r *authRedisRepo) InvalidateUser(userID int) error {
+	id := strconv.Itoa(userID)
+	_, err := r.db.TxPipelined(r.ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(r.ctx, userInvalidatedKeyPrefix+id, 1, userInvalidationWindow)
+		pipe.Del(r.ctx, userKeyPrefix+id)
+		return nil
+	})
+	return err
+}
+
+func (r *authRedisRepo) DeleteRefreshTokens(refreshTokenHashes ...string) error {
+	if len(refreshTokenHashes) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(refreshTokenHashes))
+	for _, hash := range refreshTokenHashes {
+		keys = append(keys, userRefreshTokenKeyPrefix+hash)
+	}
+	return r.db.Del(r.ctx, keys...).Err()
+}
+
+// IncrLoginFailures counts a failed sign in for key, the counter expires window after the last failure.
+func (r *authRedisRepo) IncrLoginFailures(key string, window time.Duration) (int, error) {
+	var incr *redis.IntCmd
+	_, err := r.db.TxPipelined(r.ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(r.ctx, loginFailuresPrefix+key)
+		pipe.Expire(r.ctx, loginFailuresPrefix+key, window)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int(incr.Val()), nil
+}
+
+func (r *authRedisRepo) SetLoginLock(key string, ttl time.Duration) error {
+	return r.db.Set(r.ctx, loginLockPrefix+key, 1, ttl).Err()
+}
+
+// GetLoginLock returns the time left until the lock of key expires, zero if key isn't locked.
+func (r *authRedisRepo) GetLoginLock(key string) (time.Duration, error) {
+	ttl, err := r.db.PTTL(r.ctx, loginLockPrefix+key).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	return max(ttl, 0), nil
+}
+
+func (r *authRedisRepo) DeleteLoginFailures(key string) error {
+	return r.db.Del(r.ctx, loginFailuresPrefix+key, loginLockPrefix+key).Err()
+}
+
+// DenyAccessToken rejects the access token with the jti tokenID until ttl, the rest of its lifetime, passes.
+func (r *authRedisRepo) DenyAccessToken(tokenID string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+	return r.db.Set(r.ctx, deniedTokenPrefix+tokenID, 1, ttl).Err()
+}
+
+// RevokeUserAccessTokens rejects every access token of the user issued before revokedAt, to the millisecond,
+// ttl must be at least the access token TTL.
+func (r *authRedisRepo) RevokeUserAccessTokens(userID int, revokedAt time.Time, ttl time.Duration) error {
+	return r.db.Set(r.ctx, tokensRevokedPrefix+strconv.Itoa(userID), revokedAt.UnixMilli(), ttl).Err()
+}
+
+func (r *authRedisRepo) IsAccessTokenRevoked(tokenID string, userID int, issuedAt time.Time) (bool, error) {
+	values, err := r.db.MGet(r.ctx, deniedTokenPrefix+tokenID, tokensRevokedPrefix+strconv.Itoa(userID)).Result()
+	if err != nil {
+		return false, err
+	}
+
+	if values[0] != nil {
+		return true, nil
+	}
+
+	if revokedBefore, ok := values[1].(string); ok {
+		revokedMs, errParse := strconv.ParseInt(revokedBefore, 10, 64)
+		if errParse != nil {
+			return false, errParse
+		}
+		return issuedAt.UnixMilli() < revokedMs, nil
+	}
+
+	return false, nil
+}
+
+func (r *authRedisRepo) SaveAuthorizationCode(codeHash string, code AuthorizationCode, ttl time.Duration) error {
+	jsonData, err := json.Marshal(code)
+	if err != nil {
+		return err
+	}
+
+	return r.db.Set(r.ctx, authorizationCodePrefix+codeHash, jsonData, ttl).Err()
+}
+
+// TakeAuthorizationCode returns and deletes the code in one step, so a code can be redeemed only once.
+func (r *authRedisRepo) TakeAuthorizationCode(codeHash string) (AuthorizationCode, error) {
+	var code AuthorizationCode
+	jsonData, err := r.db.GetDel(r.ctx, authorizationCodePrefix+codeHash).Result()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
-			return user, errors.New("user not found in cache")
+			return code, ErrAuthorizationCodeInvalid
 		}
-		return user, err
+		return code, err
 	}
 
-	if errUnmarshal := json.Unmarshal([]byte(jsonData), &user); errUnmarshal != nil {
-		return user, err
+	if errUnmarshal := json.Unmarshal([]byte(jsonData), &code); errUnmarshal != nil {
+		return code, errUnmarshal
 	}
 
-	return user, nil
+	return code, nil
 }
 
-func (r *authRedisRepo) EditRoleWithRefreshToken(refreshTokenHash, newRole string) error {
-	u, err := r.GetUserWithRefreshToken(refreshTokenHash)
+func (r *authRedisRepo) SaveExternalLogin(stateHash string, login ExternalLogin, ttl time.Duration) error {
+	jsonData, err := json.Marshal(login)
 	if err != nil {
 		return err
 	}
-	u.Role = newRole
 
-	jsonUser, err := json.Marshal(u)
+	return r.db.Set(r.ctx, externalLoginPrefix+stateHash, jsonData, ttl).Err()
+}
+
+// TakeExternalLogin returns and deletes the login in one step, so a callback can't be replayed.
+func (r *authRedisRepo) TakeExternalLogin(stateHash string) (ExternalLogin, error) {
+	var login ExternalLogin
+	jsonData, err := r.db.GetDel(r.ctx, externalLoginPrefix+stateHash).Result()
 	if err != nil {
-		return err
+		if errors.Is(err, redis.Nil) {
+			return login, ErrExternalLoginInvalid
+		}
+		return login, err
+	}
+
+	if errUnmarshal := json.Unmarshal([]byte(jsonData), &login); errUnmarshal != nil {
+		return login, errUnmarshal
 	}
 
-	return r.db.Set(r.ctx, refreshTokenHash, jsonUser, userRefreshTokenTTL).Err()
+	return login, nil
 }
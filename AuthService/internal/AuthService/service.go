@@ -1,39 +1,144 @@
 package AuthService
 
 import (
+	"context"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"github.com/sirupsen/logrus"
-	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/sync/singleflight"
+	"strings"
 	"time"
 )
 
 //go:generate mockgen -source=service.go -destination=../mocks/mockServ.go
 
+var (
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrRefreshTokenExpired  = errors.New("refresh token expired")
+	ErrRefreshTokenReused   = errors.New("refresh token reuse detected")
+	ErrUserNotFound         = errors.New("user not found")
+	ErrInvalidRole          = errors.New("invalid role")
+	ErrSelfRoleChange       = errors.New("can't change own role")
+
+	ErrEmailNotVerified         = errors.New("email is not verified")
+	ErrVerificationTokenInvalid = errors.New("verification token is invalid or expired")
+
+	ErrWrongPassword     = errors.New("password is wrong")
+	ErrResetTokenInvalid = errors.New("reset token is invalid or expired")
+	ErrWeakPassword      = errors.New("password is too weak")
+
+	ErrTOTPNotEnrolled    = errors.New("two-factor authentication is not enrolled")
+	ErrTOTPAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrInvalidMFACode     = errors.New("authentication code is wrong")
+	ErrMFATokenInvalid    = errors.New("mfa token is invalid or expired")
+
+	ErrTokenRevoked    = errors.New("token is revoked")
+	ErrSessionNotFound = errors.New("session not found")
+
+	ErrEmailTaken       = errors.New("email is already taken")
+	ErrInvalidUserName  = errors.New("user name is empty")
+	ErrUserDeactivated  = errors.New("account is deactivated")
+	ErrSelfDeactivation = errors.New("can't deactivate own account")
+
+	ErrSelfImpersonation      = errors.New("can't impersonate yourself")
+	ErrImpersonationForbidden = errors.New("can't impersonate an admin")
+
+	ErrUserDataUnavailable = errors.New("services holding user data are unavailable")
+
+	ErrOAuthClientNotFound      = errors.New("oauth client not found")
+	ErrInvalidRedirectURI       = errors.New("redirect uri must be an absolute http(s) uri without a fragment")
+	ErrAuthorizationCodeInvalid = errors.New("authorization code is invalid or expired")
+
+	ErrExternalProviderNotFound    = errors.New("identity provider not found")
+	ErrExternalProviderUnavailable = errors.New("identity provider is unavailable")
+	ErrExternalLoginInvalid        = errors.New("external login is invalid or expired")
+	ErrExternalLoginFailed         = errors.New("identity provider rejected the login")
+	ErrIdentityNotFound            = errors.New("identity not found")
+	ErrLastSignInMethod            = errors.New("can't unlink the only way to sign in")
+	ErrReauthenticationNeeded      = errors.New("user has no password, reauthenticate at the identity provider")
+
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	ErrAPIKeyInvalid  = errors.New("api key is invalid, expired or revoked")
+	ErrInvalidScope   = errors.New("scopes must be permissions of the user")
+)
+
 type AuthService interface {
-	SignUp(u UserSignUp) (int, error)
-	SignIn(u UserSignIn) (Tokens, error)
-	RefreshTokens(refreshToken string) (string, error)
+	SignUp(u UserSignUp, client ClientInfo) (int, error)
+	SignIn(u UserSignIn, client ClientInfo) (Tokens, error)
+	SignInMFA(mfaToken, code string, client ClientInfo) (Tokens, error)
+	EnrollTOTP(userID int) (TOTPEnrollment, error)
+	ConfirmTOTP(userID int, code string) ([]string, error)
+	UnlockAccount(email string) error
+	VerifyEmail(token string) error
+	ResendEmailVerification(email string) error
+	ForgotPassword(email string) error
+	ResetPassword(token, password string, client ClientInfo) error
+	ChangePassword(userID int, oldPassword, newPassword, reauthToken string, client ClientInfo) error
+	RefreshTokens(refreshToken string, client ClientInfo) (Tokens, error)
+	ListSessions(userID int) ([]Session, error)
+	RevokeSession(userID int, sessionID string, client ClientInfo) error
+	Logout(refreshToken string, client ClientInfo) error
+	LogoutAll(userID int, client ClientInfo) error
 	ParseToken(accessToken string) (InfoFromToken, error)
-	ChangeRole(UserSignIn, int, string) (string, error)
+	RevokeToken(token string) error
+	GetUser(userID int) (User, error)
+	JWKS() []JWK
+	ChangeRole(actorID, userID int, newRole string, client ClientInfo) (User, error)
+	GetProfile(userID int) (UserProfile, error)
+	UpdateProfile(userID int, req UpdateProfileRequest) (UserProfile, error)
+	ConfirmEmailChange(token string) error
+	ListUsers(req ListUsersRequest) ([]UserProfile, int64, error)
+	DeactivateUser(actorID, userID int, client ClientInfo) error
+	ReactivateUser(userID int) error
+	Impersonate(actorID, userID int, client ClientInfo) (ImpersonationToken, error)
+	ExportAccount(ctx context.Context, userID int) (AccountExport, error)
+	DeleteAccount(ctx context.Context, userID int, password, reauthToken string, client ClientInfo) error
+	BootstrapAdmin(u UserSignUp) (User, error)
+	CreateAPIKey(userID int, req CreateAPIKeyRequest) (APIKeyInfo, error)
+	ListAPIKeys(userID int) ([]APIKeyInfo, error)
+	RevokeAPIKey(userID, keyID int, client ClientInfo) error
+	ValidateAPIKey(key string) (InfoFromToken, error)
+	ListAuditEvents(req ListAuditEventsRequest) ([]AuditEvent, int64, error)
+	ExportAuditEvents(req ListAuditEventsRequest, write func(AuditEvent) error) error
 }
 
 type authService struct {
-	repoPostgres AuthPostgresRepo
-	repoRedis    AuthRedisRepo
-	tokenManager TokenManager
+	repoPostgres   AuthPostgresRepo
+	repoRedis      AuthRedisRepo
+	tokenManager   TokenManager
+	mailer         Mailer
+	passwordHasher PasswordHasher
+	passwordPolicy PasswordPolicy
+	dataHolders    []UserDataHolder
+
+	// userLoads makes concurrent cache misses for the same user share one database query
+	userLoads singleflight.Group
 }
 
-func NewAuthService(repoPostgres AuthPostgresRepo, repoRedis AuthRedisRepo, tokenManager TokenManager) AuthService {
+// NewAuthService returns the service itself rather than AuthService, so the OAuth provider and the external
+// login service can be built on it.
+func NewAuthService(repoPostgres AuthPostgresRepo, repoRedis AuthRedisRepo, tokenManager TokenManager, mailer Mailer,
+	passwordHasher PasswordHasher, passwordPolicy PasswordPolicy, dataHolders ...UserDataHolder) *authService {
 	return &authService{
-		repoPostgres: repoPostgres,
-		repoRedis:    repoRedis,
-		tokenManager: tokenManager,
+		repoPostgres:   repoPostgres,
+		repoRedis:      repoRedis,
+		tokenManager:   tokenManager,
+		mailer:         mailer,
+		passwordHasher: passwordHasher,
+		passwordPolicy: passwordPolicy,
+		dataHolders:    dataHolders,
 	}
 }
 
-func (s *authService) SignUp(u UserSignUp) (int, error) {
+func (s *authService) SignUp(u UserSignUp, client ClientInfo) (int, error) {
+	id, err := s.signUp(u)
+	s.audit(AuditEvent{Event: AuditSignUp, UserID: auditUserID(id)}, client, err)
+	return id, err
+}
+
+func (s *authService) signUp(u UserSignUp) (int, error) {
 	passwordHash, err := s.makePasswordHash(u.Password)
 	if err != nil {
 		return 0, err
@@ -43,7 +148,7 @@ func (s *authService) SignUp(u UserSignUp) (int, error) {
 		UserName:     u.UserName,
 		Email:        u.Email,
 		PasswordHash: passwordHash,
-		Role:         "user",
+		Role:         RoleUser,
 	}
 
 	createdUser, err := s.repoPostgres.CreateUser(user)
@@ -57,78 +162,662 @@ func (s *authService) SignUp(u UserSignUp) (int, error) {
 		}
 	}()
 
+	if errSend := s.sendEmailVerification(createdUser); errSend != nil {
+		logrus.WithError(errSend).WithField("user_id", createdUser.ID).Error("can't send email verification")
+	}
+
 	return createdUser.ID, nil
 }
 
-func (s *authService) SignIn(u UserSignIn) (Tokens, error) {
-	var user User
-	var err error
-	if user, err = s.repoRedis.GetUserWithEmail(u.Email); err != nil {
-		logrus.Warn(err)
-		if user, err = s.repoPostgres.GetUser(u.Email); err != nil {
-			return Tokens{}, err
+func (s *authService) SignIn(u UserSignIn, client ClientInfo) (Tokens, error) {
+	tokens, userID, err := s.signIn(u, client)
+	s.auditSignIn(AuditSignIn, userID, tokens, client, err)
+	return tokens, err
+}
+
+// signIn returns the ID of the user as soon as the user is known, so failures are audited with it.
+func (s *authService) signIn(u UserSignIn, client ClientInfo) (Tokens, int, error) {
+	if err := s.checkLoginLock(u.Email, client); err != nil {
+		return Tokens{}, 0, err
+	}
+
+	user, err := s.getUserByEmail(u.Email)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			s.registerLoginFailure(u.Email, client)
 		}
+		return Tokens{}, 0, err
 	}
 
 	if !s.checkPassword(u.Password, user.PasswordHash) {
-		return Tokens{}, errors.New("password is wrong")
+		s.registerLoginFailure(u.Email, client)
+		return Tokens{}, user.ID, ErrWrongPassword
 	}
-	go func() {
-		if errCache := s.repoRedis.AddUserWithEmail(user); errCache != nil {
-			logrus.Warn("can't save user to cache")
+	// with two-factor authentication the password is only half of the sign in, the failures are
+	// reset once SignInMFA accepts the code, so wrong codes keep counting towards the lock
+	if !user.TOTPEnabled {
+		if errReset := s.repoRedis.DeleteLoginFailures(emailLoginKey(u.Email)); errReset != nil {
+			logrus.WithError(errReset).Warn("can't reset login failures")
 		}
-	}()
+	}
+	if user.DeactivatedAt != nil {
+		return Tokens{}, user.ID, ErrUserDeactivated
+	}
+	if user.EmailVerifiedAt == nil {
+		return Tokens{}, user.ID, ErrEmailNotVerified
+	}
+	if s.passwordHasher.NeedsRehash(user.PasswordHash) {
+		user.PasswordHash = s.rehashPassword(user, u.Password)
+	}
+
+	tokens, err := s.finishSignIn(user, client)
+	return tokens, user.ID, err
+}
+
+// auditSignIn tells a sign in waiting for the second factor apart from a finished one.
+func (s *authService) auditSignIn(event string, userID int, tokens Tokens, client ClientInfo, err error) {
+	auditEvent := AuditEvent{Event: event, UserID: auditUserID(userID)}
+	if err == nil && tokens.MFAToken != "" {
+		auditEvent.Outcome = AuditOutcomeMFARequired
+	}
+	s.audit(auditEvent, client, err)
+}
+
+// finishSignIn issues the tokens of a user who passed the first factor, a user with two-factor
+// authentication enabled gets an MFA token for SignInMFA instead.
+func (s *authService) finishSignIn(user User, client ClientInfo) (Tokens, error) {
+	if user.TOTPEnabled {
+		mfaToken, err := s.tokenManager.NewPurposeToken(user.ID, PurposeMFAChallenge, mfaChallengeTTL)
+		if err != nil {
+			return Tokens{}, err
+		}
+		return Tokens{MFAToken: mfaToken}, nil
+	}
+
+	familyID, err := s.newFamilyID()
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	return s.generateTokensPair(user, familyID, client)
+}
+
+// SignInMFA completes a sign in started by SignIn with either a TOTP code or a recovery code.
+// Wrong codes count as failed sign ins of the user.
+func (s *authService) SignInMFA(mfaToken, code string, client ClientInfo) (Tokens, error) {
+	userID, err := s.tokenManager.ParsePurposeToken(mfaToken, PurposeMFAChallenge)
+	if err != nil {
+		return Tokens{}, ErrMFATokenInvalid
+	}
+
+	tokens, err := s.signInMFA(userID, code, client)
+	s.audit(AuditEvent{Event: AuditSignIn, UserID: auditUserID(userID)}, client, err)
+	return tokens, err
+}
+
+func (s *authService) signInMFA(userID int, code string, client ClientInfo) (Tokens, error) {
+	user, err := s.repoPostgres.GetUserByID(userID)
+	if err != nil {
+		return Tokens{}, err
+	}
+	if user.DeactivatedAt != nil {
+		return Tokens{}, ErrUserDeactivated
+	}
+
+	if errLock := s.checkLoginLock(user.Email, client); errLock != nil {
+		return Tokens{}, errLock
+	}
+
+	ok, err := s.checkSecondFactor(user.ID, code)
+	if err != nil {
+		return Tokens{}, err
+	}
+	if !ok {
+		s.registerLoginFailure(user.Email, client)
+		return Tokens{}, ErrInvalidMFACode
+	}
+	if errReset := s.repoRedis.DeleteLoginFailures(emailLoginKey(user.Email)); errReset != nil {
+		logrus.WithError(errReset).Warn("can't reset login failures")
+	}
+
+	familyID, err := s.newFamilyID()
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	return s.generateTokensPair(user, familyID, client)
+}
+
+func (s *authService) checkSecondFactor(userID int, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+
+	if !isTOTPCode(code) {
+		used, err := s.repoPostgres.UseRecoveryCode(userID, s.makeHash(normalizeRecoveryCode(code)))
+		if used {
+			logrus.WithFields(logrus.Fields{
+				"event":   "recovery_code_used",
+				"user_id": userID,
+			}).Info("recovery code used")
+		}
+		return used, err
+	}
+
+	credential, err := s.repoPostgres.GetTOTP(userID)
+	if err != nil {
+		if errors.Is(err, ErrTOTPNotEnrolled) {
+			return false, nil
+		}
+		return false, err
+	}
+	if credential.ConfirmedAt == nil {
+		return false, nil
+	}
+
+	counter, ok := ValidateTOTP(credential.Secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	return s.repoPostgres.UseTOTPCounter(userID, counter)
+}
+
+// EnrollTOTP generates a new secret for the user, it isn't required at sign in until ConfirmTOTP succeeds.
+func (s *authService) EnrollTOTP(userID int) (TOTPEnrollment, error) {
+	user, err := s.repoPostgres.GetUserByID(userID)
+	if err != nil {
+		return TOTPEnrollment{}, err
+	}
+	if user.TOTPEnabled {
+		return TOTPEnrollment{}, ErrTOTPAlreadyEnabled
+	}
+
+	secret, err := NewTOTPSecret()
+	if err != nil {
+		return TOTPEnrollment{}, err
+	}
+
+	if errSave := s.repoPostgres.SaveTOTP(TOTPCredential{UserID: user.ID, Secret: secret}); errSave != nil {
+		return TOTPEnrollment{}, errSave
+	}
+
+	return TOTPEnrollment{
+		Secret: secret,
+		URI:    totpURI(secret, user.Email),
+	}, nil
+}
+
+// ConfirmTOTP enables two-factor authentication once the user proves the authenticator app works,
+// the returned recovery codes are only stored hashed and can't be shown again.
+func (s *authService) ConfirmTOTP(userID int, code string) ([]string, error) {
+	user, err := s.repoPostgres.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	credential, err := s.repoPostgres.GetTOTP(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	counter, ok := ValidateTOTP(credential.Secret, strings.TrimSpace(code), time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, 0, len(codes))
+	for _, c := range codes {
+		hashes = append(hashes, s.makeHash(normalizeRecoveryCode(c)))
+	}
+
+	if errConfirm := s.repoPostgres.ConfirmTOTP(user.ID, counter, hashes); errConfirm != nil {
+		return nil, errConfirm
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"event":   "totp_enabled",
+		"user_id": user.ID,
+	}).Info("two-factor authentication enabled")
+
+	s.invalidateUser(user.ID)
+
+	return codes, nil
+}
+
+func (s *authService) VerifyEmail(token string) error {
+	if _, err := s.tokenManager.ParsePurposeToken(token, PurposeEmailVerification); err != nil {
+		return ErrVerificationTokenInvalid
+	}
+
+	user, err := s.repoPostgres.VerifyEmail(s.makeHash(token))
+	if err != nil {
+		return err
+	}
+
+	s.invalidateUser(user.ID)
+
+	return nil
+}
+
+// ResendEmailVerification doesn't report unknown or already verified emails, so it can't be used to find accounts.
+func (s *authService) ResendEmailVerification(email string) error {
+	user, err := s.repoPostgres.GetUser(email)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	return s.sendEmailVerification(user)
+}
+
+func (s *authService) sendEmailVerification(user User) error {
+	token, err := s.tokenManager.NewPurposeToken(user.ID, PurposeEmailVerification, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	errSave := s.repoPostgres.SaveEmailVerification(EmailVerification{
+		UserID:    user.ID,
+		Email:     user.Email,
+		TokenHash: s.makeHash(token),
+		ExpiresAt: time.Now().Add(emailVerificationTTL),
+	})
+	if errSave != nil {
+		return errSave
+	}
+
+	return s.mailer.SendEmailVerification(user.Email, token)
+}
+
+// UnlockAccount lifts the sign in lock of email before it expires.
+func (s *authService) UnlockAccount(email string) error {
+	if _, err := s.repoPostgres.GetUser(email); err != nil {
+		return err
+	}
+
+	if err := s.repoRedis.DeleteLoginFailures(emailLoginKey(email)); err != nil {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"event": "account_unlocked",
+		"email": email,
+	}).Info("account unlocked")
+
+	return nil
+}
+
+// ForgotPassword doesn't report unknown emails, so it can't be used to find accounts.
+func (s *authService) ForgotPassword(email string) error {
+	user, err := s.repoPostgres.GetUser(email)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	token, err := s.tokenManager.NewRefreshToken()
+	if err != nil {
+		return err
+	}
+
+	errSave := s.repoPostgres.SavePasswordReset(PasswordReset{
+		UserID:    user.ID,
+		TokenHash: s.makeHash(token),
+		ExpiresAt: time.Now().Add(passwordResetTTL),
+	})
+	if errSave != nil {
+		return errSave
+	}
+
+	return s.mailer.SendPasswordReset(user.Email, token)
+}
+
+func (s *authService) ResetPassword(token, password string, client ClientInfo) error {
+	userID, err := s.resetPassword(token, password)
+	s.audit(AuditEvent{Event: AuditPasswordReset, UserID: auditUserID(userID)}, client, err)
+	return err
+}
+
+// resetPassword returns the ID of the user as soon as the token is accepted, so failures are audited with it.
+func (s *authService) resetPassword(token, password string) (int, error) {
+	passwordHash, err := s.makePasswordHash(password)
+	if err != nil {
+		return 0, err
+	}
+
+	user, err := s.repoPostgres.ResetPassword(s.makeHash(token), passwordHash)
+	if err != nil {
+		return 0, err
+	}
+
+	return user.ID, s.revokeUserSessions(user)
+}
+
+// ChangePassword sets the first password of a user signed up through an identity provider as well,
+// such a user confirms with reauthToken instead of the old password.
+func (s *authService) ChangePassword(userID int, oldPassword, newPassword, reauthToken string, client ClientInfo) error {
+	err := s.changePassword(userID, oldPassword, newPassword, reauthToken)
+	s.audit(AuditEvent{Event: AuditPasswordChange, UserID: auditUserID(userID)}, client, err)
+	return err
+}
+
+func (s *authService) changePassword(userID int, oldPassword, newPassword, reauthToken string) error {
+	user, err := s.repoPostgres.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if err = s.confirmUser(user, oldPassword, reauthToken); err != nil {
+		return err
+	}
+
+	passwordHash, err := s.makePasswordHash(newPassword)
+	if err != nil {
+		return err
+	}
+
+	if errUpdate := s.repoPostgres.UpdatePassword(user.ID, passwordHash); errUpdate != nil {
+		return errUpdate
+	}
+
+	return s.revokeUserSessions(user)
+}
+
+// revokeUserSessions logs the user out everywhere, including the access tokens that haven't expired yet,
+// and drops the cached user, so the old PasswordHash isn't served from cache.
+func (s *authService) revokeUserSessions(user User) error {
+	hashes, err := s.repoPostgres.DeleteUserRefreshTokens(user.ID)
+	if err != nil {
+		return err
+	}
+
+	if errCache := s.repoRedis.DeleteRefreshTokens(hashes...); errCache != nil {
+		logrus.Warn("can't delete user refresh tokens from cache")
+	}
+	s.invalidateUser(user.ID)
 
-	return s.generateTokensPair(user)
+	return s.repoRedis.RevokeUserAccessTokens(user.ID, time.Now(), accessTTL)
 }
 
-func (s *authService) RefreshTokens(refreshToken string) (string, error) {
+// RefreshTokens rotates the refresh token: the presented token is revoked and a new pair
+// from the same family is issued. Presenting an already rotated token revokes the whole family.
+func (s *authService) RefreshTokens(refreshToken string, client ClientInfo) (Tokens, error) {
+	token, user, err := s.rotateRefreshToken(refreshToken, "")
+	if err != nil {
+		s.audit(AuditEvent{Event: AuditTokenRefresh, UserID: auditUserID(token.UserID)}, client, err)
+		return Tokens{}, err
+	}
+
+	tokens, err := s.generateTokensPair(user, token.FamilyID, client)
+	s.audit(AuditEvent{Event: AuditTokenRefresh, UserID: auditUserID(user.ID)}, client, err)
+	return tokens, err
+}
+
+// rotateRefreshToken revokes the presented refresh token and returns it with its user. clientID is
+// the OAuth client the token must have been issued to, empty for first-party tokens.
+func (s *authService) rotateRefreshToken(refreshToken, clientID string) (RefreshToken, User, error) {
 	hashRefreshToken := s.makeHash(refreshToken)
 
-	var user User
-	var err error
+	token, err := s.repoPostgres.GetRefreshToken(hashRefreshToken)
+	if err != nil {
+		return RefreshToken{}, User{}, err
+	}
+
+	var tokenClientID string
+	if token.ClientID != nil {
+		tokenClientID = *token.ClientID
+	}
+	if tokenClientID != clientID {
+		return RefreshToken{}, User{}, ErrRefreshTokenNotFound
+	}
 
+	if token.RevokedAt != nil {
+		s.revokeTokenFamily(token)
+		return token, User{}, ErrRefreshTokenReused
+	}
+
+	if time.Now().After(token.ExpiresAt) {
+		return token, User{}, ErrRefreshTokenExpired
+	}
+
+	var user User
 	user, err = s.repoRedis.GetUserWithRefreshToken(hashRefreshToken)
 	if err != nil {
-		logrus.Warn(err)
+		if !errors.Is(err, ErrUserCacheMiss) {
+			logrus.WithError(err).Warn("can't get user from cache")
+		}
 
 		user, err = s.repoPostgres.GetUserByRefreshToken(hashRefreshToken)
 		if err != nil {
-			return "", err
+			return RefreshToken{}, User{}, err
 		}
+	}
+	if user.DeactivatedAt != nil {
+		return token, User{}, ErrUserDeactivated
+	}
 
-		go func() {
-			if errCache := s.repoRedis.AddUserWithRefreshToken(user, hashRefreshToken); errCache != nil {
-				logrus.Warn("can't save user to cache")
-			}
-		}()
+	rotated, err := s.repoPostgres.RevokeRefreshToken(hashRefreshToken)
+	if err != nil {
+		return RefreshToken{}, User{}, err
+	}
+	if !rotated {
+		s.revokeTokenFamily(token)
+		return token, User{}, ErrRefreshTokenReused
 	}
 
-	accessToken, errAccess := s.tokenManager.NewJWT(user, accessTTL)
-	if errAccess != nil {
-		return "", errAccess
+	if errCache := s.repoRedis.DeleteRefreshTokens(hashRefreshToken); errCache != nil {
+		logrus.Warn("can't delete rotated refresh token from cache")
 	}
 
-	return accessToken, nil
+	return token, user, nil
 }
 
-func (s *authService) ChangeRole(user UserSignIn, id int, newRole string) (string, error) {
-	if user.Email == "admin" && user.Password == "admin" {
-		u, err := s.repoPostgres.ChangeRole(id, newRole)
-		if err != nil {
-			return "", err
+func (s *authService) revokeTokenFamily(token RefreshToken) {
+	logrus.WithFields(logrus.Fields{
+		"event":     "refresh_token_reuse",
+		"user_id":   token.UserID,
+		"family_id": token.FamilyID,
+	}).Warn("revoked refresh token was presented, revoking token family")
+
+	hashes, err := s.repoPostgres.RevokeRefreshTokenFamily(token.FamilyID)
+	if err != nil {
+		logrus.WithError(err).Error("can't revoke refresh token family")
+		return
+	}
+
+	if errCache := s.repoRedis.DeleteRefreshTokens(hashes...); errCache != nil {
+		logrus.Warn("can't delete revoked refresh tokens from cache")
+	}
+}
+
+func (s *authService) Logout(refreshToken string, client ClientInfo) error {
+	hashRefreshToken := s.makeHash(refreshToken)
+
+	event := AuditEvent{Event: AuditLogout}
+	if token, errGet := s.repoPostgres.GetRefreshToken(hashRefreshToken); errGet == nil {
+		event.UserID = auditUserID(token.UserID)
+	}
+
+	err := s.logout(hashRefreshToken)
+	s.audit(event, client, err)
+	return err
+}
+
+func (s *authService) logout(hashRefreshToken string) error {
+	if err := s.repoPostgres.DeleteRefreshToken(hashRefreshToken); err != nil {
+		return err
+	}
+
+	return s.repoRedis.DeleteRefreshTokens(hashRefreshToken)
+}
+
+func (s *authService) LogoutAll(userID int, client ClientInfo) error {
+	err := s.logoutAll(userID)
+	s.audit(AuditEvent{Event: AuditLogoutAll, UserID: auditUserID(userID)}, client, err)
+	return err
+}
+
+func (s *authService) logoutAll(userID int) error {
+	hashes, err := s.repoPostgres.DeleteUserRefreshTokens(userID)
+	if err != nil {
+		return err
+	}
+
+	if errCache := s.repoRedis.DeleteRefreshTokens(hashes...); errCache != nil {
+		return errCache
+	}
+
+	return s.repoRedis.RevokeUserAccessTokens(userID, time.Now(), accessTTL)
+}
+
+// ChangeRole sets the role of userID on behalf of the admin actorID. Cached copies of the user are evicted
+// and its access tokens revoked, so the next refresh issues an access token with the new role.
+func (s *authService) ChangeRole(actorID, userID int, newRole string, client ClientInfo) (User, error) {
+	u, err := s.changeRole(actorID, userID, newRole)
+	s.audit(AuditEvent{Event: AuditRoleChange, UserID: auditUserID(userID), ActorID: auditUserID(actorID)}, client, err)
+	return u, err
+}
+
+func (s *authService) changeRole(actorID, userID int, newRole string) (User, error) {
+	if !isValidRole(newRole) {
+		return User{}, ErrInvalidRole
+	}
+	if actorID == userID {
+		return User{}, ErrSelfRoleChange
+	}
+
+	u, err := s.repoPostgres.ChangeRole(RoleChange{
+		ActorID:  &actorID,
+		TargetID: userID,
+		NewRole:  newRole,
+	})
+	if err != nil {
+		return User{}, err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"event":    "role_change",
+		"actor_id": actorID,
+		"user_id":  userID,
+		"new_role": newRole,
+	}).Info("user role changed")
+
+	s.invalidateUser(u.ID)
+	// access tokens carry the permissions of the role, the ones issued before the change must not outlive it
+	if err = s.repoRedis.RevokeUserAccessTokens(u.ID, time.Now(), accessTTL); err != nil {
+		return User{}, err
+	}
+
+	return u, nil
+}
+
+// BootstrapAdmin creates the first admin, an existing account with the same email is promoted
+// and keeps its password.
+func (s *authService) BootstrapAdmin(u UserSignUp) (User, error) {
+	user, err := s.repoPostgres.GetUser(u.Email)
+	if err == nil {
+		if user.Role == RoleAdmin {
+			return user, nil
 		}
-		accessToken, err := s.tokenManager.NewJWT(u, accessTTL)
+
+		user, err = s.repoPostgres.ChangeRole(RoleChange{
+			TargetID: user.ID,
+			NewRole:  RoleAdmin,
+		})
+		s.audit(AuditEvent{Event: AuditRoleChange, UserID: auditUserID(user.ID)}, ClientInfo{}, err)
 		if err != nil {
-			return "", err
+			return User{}, err
 		}
-		return accessToken, nil
+		s.invalidateUser(user.ID)
+
+		return user, nil
+	}
+	if !errors.Is(err, ErrUserNotFound) {
+		return User{}, err
+	}
+
+	passwordHash, err := s.makePasswordHash(u.Password)
+	if err != nil {
+		return User{}, err
+	}
+
+	verifiedAt := time.Now()
+
+	return s.repoPostgres.CreateUser(User{
+		UserName:        u.UserName,
+		Email:           u.Email,
+		PasswordHash:    passwordHash,
+		Role:            RoleAdmin,
+		EmailVerifiedAt: &verifiedAt,
+	})
+}
+
+// invalidateUser drops the cached user after every change of the user, a failure leaves the stale
+// user cached until it expires.
+func (s *authService) invalidateUser(userID int) {
+	if err := s.repoRedis.InvalidateUser(userID); err != nil {
+		logrus.WithError(err).WithField("user_id", userID).Warn("can't invalidate cached user")
 	}
-	return "", errors.New("not enough rights")
 }
 
-func (s *authService) generateTokensPair(user User) (Tokens, error) {
-	accessToken, errAccess := s.tokenManager.NewJWT(user, accessTTL)
+// getUserByEmail reads the user through the cache, concurrent misses for the same email share one query.
+func (s *authService) getUserByEmail(email string) (User, error) {
+	user, err := s.repoRedis.GetUserWithEmail(email)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, ErrUserCacheMiss) {
+		logrus.WithError(err).Warn("can't get user from cache")
+	}
+
+	loaded, err, _ := s.userLoads.Do(email, func() (interface{}, error) {
+		user, errGet := s.repoPostgres.GetUser(email)
+		if errGet != nil {
+			return User{}, errGet
+		}
+
+		if errCache := s.repoRedis.AddUserWithEmail(user); errCache != nil {
+			logrus.WithError(errCache).Warn("can't save user to cache")
+		}
+		return user, nil
+	})
+	return loaded.(User), err
+}
+
+func (s *authService) generateTokensPair(user User, familyID string, client ClientInfo) (Tokens, error) {
+	return s.generateGrantTokens(user, familyID, client, oauthGrant{})
+}
+
+// oauthGrant binds the issued tokens to an OAuth client and the scope granted to it,
+// the zero oauthGrant issues first-party tokens.
+type oauthGrant struct {
+	client OAuthClient
+	scope  string
+}
+
+func (s *authService) generateGrantTokens(user User, familyID string, client ClientInfo, grant oauthGrant) (Tokens, error) {
+	var accessToken string
+	var errAccess error
+	if grant.client.ID != "" {
+		accessToken, errAccess = s.tokenManager.NewClientJWT(user, grant.client.ID, grant.scope, accessTTL)
+	} else {
+		accessToken, errAccess = s.tokenManager.NewJWT(user, accessTTL)
+	}
 	if errAccess != nil {
 		return Tokens{}, errAccess
 	}
@@ -140,12 +829,23 @@ func (s *authService) generateTokensPair(user User) (Tokens, error) {
 
 	hashRefreshToken := s.makeHash(refreshToken)
 
-	refreshExpiresAt := time.Now().Add(refreshTTL)
+	now := time.Now()
+	userAgent := truncate(client.UserAgent, maxUserAgentLength)
 
 	refreshTokenStruct := RefreshToken{
-		UserID:    user.ID,
-		TokenHash: hashRefreshToken,
-		ExpiresAt: refreshExpiresAt,
+		UserID:      user.ID,
+		TokenHash:   hashRefreshToken,
+		FamilyID:    familyID,
+		UserAgent:   userAgent,
+		IP:          client.IP,
+		DeviceLabel: deviceLabel(userAgent),
+		Scope:       grant.scope,
+		ExpiresAt:   now.Add(refreshTTL),
+		LastUsedAt:  now,
+	}
+	if grant.client.ID != "" {
+		refreshTokenStruct.ClientID = &grant.client.ID
+		refreshTokenStruct.DeviceLabel = grant.client.Name
 	}
 
 	errSave := s.repoPostgres.SaveRefreshToken(refreshTokenStruct)
@@ -165,8 +865,48 @@ func (s *authService) generateTokensPair(user User) (Tokens, error) {
 	}, nil
 }
 
+// ParseToken also rejects revoked access tokens. The denylist fails open: when redis is unavailable
+// a valid token is accepted rather than every request failing.
 func (s *authService) ParseToken(accessToken string) (InfoFromToken, error) {
-	return s.tokenManager.Parse(accessToken)
+	info, err := s.tokenManager.Parse(accessToken)
+	if err != nil {
+		return InfoFromToken{}, err
+	}
+
+	revoked, err := s.repoRedis.IsAccessTokenRevoked(info.TokenID, info.ID, info.IssuedAt)
+	if err != nil {
+		logrus.WithError(err).Warn("can't check access token revocation")
+		return info, nil
+	}
+	if revoked {
+		return InfoFromToken{}, ErrTokenRevoked
+	}
+
+	return info, nil
+}
+
+// RevokeToken revokes an access or a refresh token. Like RFC 7009 it doesn't report tokens that are
+// unknown or already invalid. It is called by the services, so there is no client to audit.
+func (s *authService) RevokeToken(token string) error {
+	info, err := s.tokenManager.Parse(token)
+	if err != nil {
+		if errLogout := s.Logout(token, ClientInfo{}); errLogout != nil && !errors.Is(errLogout, ErrRefreshTokenNotFound) {
+			return errLogout
+		}
+		return nil
+	}
+
+	err = s.repoRedis.DenyAccessToken(info.TokenID, time.Until(info.ExpiresAt))
+	s.audit(AuditEvent{Event: AuditTokenRevoked, UserID: auditUserID(info.ID), ActorID: auditUserID(info.ActorID)}, ClientInfo{}, err)
+	return err
+}
+
+func (s *authService) GetUser(userID int) (User, error) {
+	return s.repoPostgres.GetUserByID(userID)
+}
+
+func (s *authService) JWKS() []JWK {
+	return s.tokenManager.JWKS()
 }
 
 func (s *authService) makeHash(str string) string {
@@ -174,12 +914,64 @@ func (s *authService) makeHash(str string) string {
 	return hex.EncodeToString(hash[:])
 }
 
+func (s *authService) newFamilyID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// makePasswordHash checks a new password against the policy before hashing it.
 func (s *authService) makePasswordHash(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	return string(bytes), err
+	if err := s.passwordPolicy.Validate(password); err != nil {
+		return "", err
+	}
+	return s.passwordHasher.Hash(password)
 }
 
 func (s *authService) checkPassword(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
+	return s.passwordHasher.Verify(password, hash)
+}
+
+// rehashPassword replaces a hash made with outdated parameters or algorithm while the password is at hand,
+// a failure only postpones it to the next sign in. It returns the hash the user is left with.
+func (s *authService) rehashPassword(user User, password string) string {
+	passwordHash, err := s.passwordHasher.Hash(password)
+	if err != nil {
+		logrus.WithError(err).Warn("can't rehash password")
+		return user.PasswordHash
+	}
+	if errUpdate := s.repoPostgres.UpdatePassword(user.ID, passwordHash); errUpdate != nil {
+		logrus.WithError(errUpdate).Warn("can't save rehashed password")
+		return user.PasswordHash
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"event":   "password_rehashed",
+		"user_id": user.ID,
+	}).Info("password rehashed")
+	s.invalidateUser(user.ID)
+
+	return passwordHash
+}
+
+// confirmUser checks that the user is the one asking, by the password or, for a user signed up through
+// an identity provider without one, by the token of a reauthentication at the provider.
+func (s *authService) confirmUser(user User, password, reauthToken string) error {
+	if user.PasswordHash != "" {
+		if !s.checkPassword(password, user.PasswordHash) {
+			return ErrWrongPassword
+		}
+		return nil
+	}
+
+	if reauthToken == "" {
+		return ErrReauthenticationNeeded
+	}
+	userID, err := s.tokenManager.ParsePurposeToken(reauthToken, PurposeReauthentication)
+	if err != nil || userID != user.ID {
+		return ErrReauthenticationNeeded
+	}
+	return nil
 }
@@ -0,0 +1,79 @@
+package AuthService
+
+import "strings"
+
+const maxUserAgentLength = 512
+
+// The patterns are checked in order: Edge and Opera user agents also mention Chrome and Safari,
+// Chrome ones mention Safari, and Android ones mention Linux.
+var (
+	browserPatterns = []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+		{"PostmanRuntime/", "Postman"},
+	}
+	platformPatterns = []struct{ token, name string }{
+		{"Android", "Android"},
+		{"iPhone", "iPhone"},
+		{"iPad", "iPad"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"Linux", "Linux"},
+	}
+)
+
+func (s *authService) ListSessions(userID int) ([]Session, error) {
+	return s.repoPostgres.ListSessions(userID)
+}
+
+// RevokeSession signs a single device out, access tokens it already holds stay valid until they expire.
+func (s *authService) RevokeSession(userID int, sessionID string, client ClientInfo) error {
+	err := s.revokeSession(userID, sessionID)
+	s.audit(AuditEvent{Event: AuditSessionRevoked, UserID: auditUserID(userID)}, client, err)
+	return err
+}
+
+func (s *authService) revokeSession(userID int, sessionID string) error {
+	hashes, err := s.repoPostgres.DeleteSession(userID, sessionID)
+	if err != nil {
+		return err
+	}
+
+	return s.repoRedis.DeleteRefreshTokens(hashes...)
+}
+
+// deviceLabel names the device of a user agent for the session list, like "Firefox on Windows".
+func deviceLabel(userAgent string) string {
+	browser := matchUserAgent(userAgent, browserPatterns)
+	platform := matchUserAgent(userAgent, platformPatterns)
+
+	switch {
+	case browser != "" && platform != "":
+		return browser + " on " + platform
+	case browser != "":
+		return browser
+	case platform != "":
+		return platform
+	}
+	return "Unknown device"
+}
+
+func matchUserAgent(userAgent string, patterns []struct{ token, name string }) string {
+	for _, p := range patterns {
+		if strings.Contains(userAgent, p.token) {
+			return p.name
+		}
+	}
+	return ""
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
@@ -0,0 +1,237 @@
+package AuthService
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+
+	rsaKeyBits     = 2048
+	keyFileExt     = ".pem"
+	keyFilePerm    = 0600
+	keysDirPerm    = 0700
+	pemPrivateKey  = "PRIVATE KEY"
+	pemRSAPrivKey  = "RSA PRIVATE KEY"
+	kidRandomBytes = 4
+)
+
+// SigningMethodEdDSA signs tokens with Ed25519 keys, jwt-go v3 has no built-in EdDSA support.
+var SigningMethodEdDSA = &signingMethodEdDSA{}
+
+func init() {
+	jwt.RegisterSigningMethod(SigningMethodEdDSA.Alg(), func() jwt.SigningMethod {
+		return SigningMethodEdDSA
+	})
+}
+
+type signingMethodEdDSA struct{}
+
+func (m *signingMethodEdDSA) Alg() string {
+	return AlgorithmEdDSA
+}
+
+func (m *signingMethodEdDSA) Sign(signingString string, key interface{}) (string, error) {
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return "", jwt.ErrInvalidKeyType
+	}
+	return jwt.EncodeSegment(ed25519.Sign(privateKey, []byte(signingString))), nil
+}
+
+func (m *signingMethodEdDSA) Verify(signingString, signature string, key interface{}) error {
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return jwt.ErrInvalidKeyType
+	}
+
+	sig, err := jwt.DecodeSegment(signature)
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(publicKey, []byte(signingString), sig) {
+		return errors.New("EdDSA signature is invalid")
+	}
+	return nil
+}
+
+// JWK is a public signing key in the RFC 7517 format served from the JWKS endpoint.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type signingKey struct {
+	id        string
+	method    jwt.SigningMethod
+	private   crypto.Signer
+	createdAt time.Time
+}
+
+func (k signingKey) jwk() JWK {
+	switch pub := k.private.Public().(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			Kid: k.id,
+			Use: "sig",
+			Alg: k.method.Alg(),
+			N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP",
+			Kid: k.id,
+			Use: "sig",
+			Alg: k.method.Alg(),
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(pub),
+		}
+	}
+	return JWK{}
+}
+
+func generateSigningKey(algorithm string) (signingKey, error) {
+	id, err := newKeyID()
+	if err != nil {
+		return signingKey{}, err
+	}
+
+	switch algorithm {
+	case AlgorithmRS256:
+		key, errGen := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if errGen != nil {
+			return signingKey{}, errGen
+		}
+		return signingKey{id: id, method: jwt.SigningMethodRS256, private: key, createdAt: time.Now()}, nil
+	case AlgorithmEdDSA:
+		_, key, errGen := ed25519.GenerateKey(rand.Reader)
+		if errGen != nil {
+			return signingKey{}, errGen
+		}
+		return signingKey{id: id, method: SigningMethodEdDSA, private: key, createdAt: time.Now()}, nil
+	}
+
+	return signingKey{}, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+}
+
+func newKeyID() (string, error) {
+	b := make([]byte, kidRandomBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d-%s", time.Now().Unix(), hex.EncodeToString(b)), nil
+}
+
+func loadSigningKeys(dir string) ([]signingKey, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var keys []signingKey
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != keyFileExt {
+			continue
+		}
+
+		info, errInfo := entry.Info()
+		if errInfo != nil {
+			return nil, errInfo
+		}
+
+		key, errLoad := loadSigningKey(filepath.Join(dir, entry.Name()))
+		if errLoad != nil {
+			return nil, fmt.Errorf("can't load signing key %s: %w", entry.Name(), errLoad)
+		}
+		key.id = strings.TrimSuffix(entry.Name(), keyFileExt)
+		key.createdAt = info.ModTime()
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+func loadSigningKey(path string) (signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return signingKey{}, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return signingKey{}, errors.New("no PEM data found")
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case pemPrivateKey:
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case pemRSAPrivKey:
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return signingKey{}, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return signingKey{}, err
+	}
+
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		return signingKey{method: jwt.SigningMethodRS256, private: key}, nil
+	case ed25519.PrivateKey:
+		return signingKey{method: SigningMethodEdDSA, private: key}, nil
+	}
+
+	return signingKey{}, errors.New("unsupported private key type")
+}
+
+func saveSigningKey(dir string, key signingKey) error {
+	if err := os.MkdirAll(dir, keysDirPerm); err != nil {
+		return err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key.private)
+	if err != nil {
+		return err
+	}
+
+	data := pem.EncodeToMemory(&pem.Block{Type: pemPrivateKey, Bytes: der})
+	return os.WriteFile(filepath.Join(dir, key.id+keyFileExt), data, keyFilePerm)
+}
+
+func removeSigningKey(dir string, key signingKey) error {
+	err := os.Remove(filepath.Join(dir, key.id+keyFileExt))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -1,11 +1,14 @@
 package AuthService
 
 import (
+	"crypto/rand"
 	"errors"
 	"fmt"
 	"github.com/dgrijalva/jwt-go"
-	"math/rand"
+	"github.com/sirupsen/logrus"
+	"sort"
 	"strconv"
+	"sync"
 	"time"
 )
 
@@ -13,41 +16,166 @@ import (
 
 type TokenManager interface {
 	NewJWT(user User, ttl time.Duration) (string, error)
+	NewClientJWT(user User, clientID, scope string, ttl time.Duration) (string, error)
+	NewImpersonationJWT(user User, actorID int, ttl time.Duration) (string, error)
+	NewIDToken(claims IDTokenClaims) (string, error)
 	NewRefreshToken() (string, error)
 	Parse(accessToken string) (InfoFromToken, error)
+	NewPurposeToken(userID int, purpose string, ttl time.Duration) (string, error)
+	ParsePurposeToken(token, purpose string) (int, error)
+	JWKS() []JWK
+	Rotate() error
 }
 
 type manager struct {
-	signingKey string
+	keysDir   string
+	algorithm string
+
+	mu      sync.RWMutex
+	keys    map[string]signingKey
+	current signingKey
 }
 
-func NewManager(signingKey string) TokenManager {
-	return &manager{signingKey: signingKey}
+// NewManager loads the signing keys from keysDir, the newest key signs new tokens and all of them
+// verify. A fresh key is generated when the directory is empty or the newest key is older than
+// rotationInterval; a positive rotationInterval also keeps rotating keys in the background.
+func NewManager(keysDir, algorithm string, rotationInterval time.Duration) (TokenManager, error) {
+	m := &manager{
+		keysDir:   keysDir,
+		algorithm: algorithm,
+		keys:      make(map[string]signingKey),
+	}
+
+	keys, err := loadSigningKeys(keysDir)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].createdAt.Before(keys[j].createdAt)
+	})
+	for _, k := range keys {
+		m.keys[k.id] = k
+		m.current = k
+	}
+
+	expired := rotationInterval > 0 && time.Since(m.current.createdAt) > rotationInterval
+	if len(keys) == 0 || m.current.method.Alg() != algorithm || expired {
+		if errRotate := m.Rotate(); errRotate != nil {
+			return nil, errRotate
+		}
+	}
+
+	if rotationInterval > 0 {
+		go m.rotateEvery(rotationInterval)
+	}
+
+	return m, nil
 }
 
 func (m *manager) NewJWT(user User, ttl time.Duration) (string, error) {
-	claims := &CustomClaims{
-		Role:     user.Role,
-		UserName: user.UserName,
-		CartID:   strconv.Itoa(user.ID),
+	claims, err := m.userClaims(user, ttl)
+	if err != nil {
+		return "", err
+	}
+
+	return m.sign(claims)
+}
+
+// NewImpersonationJWT issues an access token of the user with the admin as the act claim, services
+// accept it like the user's own token and tell the admin apart by the claim.
+func (m *manager) NewImpersonationJWT(user User, actorID int, ttl time.Duration) (string, error) {
+	claims, err := m.userClaims(user, ttl)
+	if err != nil {
+		return "", err
+	}
+	claims.Actor = &Actor{Subject: fmt.Sprintf("%d", actorID)}
+
+	return m.sign(claims)
+}
+
+func (m *manager) userClaims(user User, ttl time.Duration) (*CustomClaims, error) {
+	id, err := m.NewRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return &CustomClaims{
+		Role:        user.Role,
+		Permissions: PermissionsForRole(user.Role),
+		UserName:    user.UserName,
+		CartID:      strconv.Itoa(user.ID),
+		IssuedAtMs:  now.UnixMilli(),
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(ttl).Unix(),
-			IssuedAt:  time.Now().Unix(),
+			Id:        id,
+			ExpiresAt: now.Add(ttl).Unix(),
+			IssuedAt:  now.Unix(),
 			Subject:   fmt.Sprintf("%d", user.ID),
 		},
+	}, nil
+}
+
+// NewClientJWT issues an access token for an OAuth client. It carries no role or permissions,
+// so the token is only good for the userinfo endpoint and not for the APIs of the services.
+func (m *manager) NewClientJWT(user User, clientID, scope string, ttl time.Duration) (string, error) {
+	id, err := m.NewRefreshToken()
+	if err != nil {
+		return "", err
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
-	return token.SignedString([]byte(m.signingKey))
+	now := time.Now()
+	return m.sign(&CustomClaims{
+		UserName:   user.UserName,
+		ClientID:   clientID,
+		Scope:      scope,
+		IssuedAtMs: now.UnixMilli(),
+		StandardClaims: jwt.StandardClaims{
+			Id:        id,
+			ExpiresAt: now.Add(ttl).Unix(),
+			IssuedAt:  now.Unix(),
+			Subject:   fmt.Sprintf("%d", user.ID),
+		},
+	})
+}
+
+func (m *manager) NewIDToken(claims IDTokenClaims) (string, error) {
+	return m.sign(&claims)
+}
+
+// NewPurposeToken issues a short-lived token that can only be redeemed by ParsePurposeToken with the same purpose.
+func (m *manager) NewPurposeToken(userID int, purpose string, ttl time.Duration) (string, error) {
+	id, err := m.NewRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	return m.sign(&CustomClaims{
+		Purpose: purpose,
+		StandardClaims: jwt.StandardClaims{
+			Id:        id,
+			ExpiresAt: time.Now().Add(ttl).Unix(),
+			IssuedAt:  time.Now().Unix(),
+			Subject:   fmt.Sprintf("%d", userID),
+		},
+	})
+}
+
+func (m *manager) sign(claims jwt.Claims) (string, error) {
+	m.mu.RLock()
+	key := m.current
+	m.mu.RUnlock()
+
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.id
+
+	return token.SignedString(key.private)
 }
 
 func (m *manager) NewRefreshToken() (string, error) {
 	b := make([]byte, 32)
 
-	s := rand.NewSource(time.Now().Unix())
-	r := rand.New(s)
-
-	_, err := r.Read(b)
+	_, err := rand.Read(b)
 	if err != nil {
 		return "", err
 	}
@@ -56,28 +184,150 @@ func (m *manager) NewRefreshToken() (string, error) {
 }
 
 func (m *manager) Parse(accessToken string) (InfoFromToken, error) {
-	token, err := jwt.ParseWithClaims(accessToken, &CustomClaims{}, func(token *jwt.Token) (i interface{}, err error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(m.signingKey), nil
-	})
+	token, err := jwt.ParseWithClaims(accessToken, &CustomClaims{}, m.keyFunc)
 	if err != nil {
 		return InfoFromToken{}, err
 	}
-	if claims, ok := token.Claims.(*CustomClaims); ok && token.Valid {
+	// ID tokens are the only tokens with an audience, they must not pass for access tokens
+	if claims, ok := token.Claims.(*CustomClaims); ok && token.Valid && claims.Purpose == "" && claims.Audience == "" {
 		var userID int
 		_, errScan := fmt.Sscanf(claims.Subject, "%d", &userID)
 		if errScan != nil {
 			return InfoFromToken{}, errors.New("invalid user ID in token")
 		}
+		var actorID int
+		if claims.Actor != nil {
+			if _, errScan = fmt.Sscanf(claims.Actor.Subject, "%d", &actorID); errScan != nil {
+				return InfoFromToken{}, errors.New("invalid actor ID in token")
+			}
+		}
 		return InfoFromToken{
-			ID:       userID,
-			Role:     claims.Role,
-			UserName: claims.UserName,
-			CartID:   claims.CartID,
+			ID:          userID,
+			Role:        claims.Role,
+			Permissions: claims.Permissions,
+			UserName:    claims.UserName,
+			CartID:      claims.CartID,
+			TokenID:     claims.Id,
+			IssuedAt:    time.UnixMilli(claims.IssuedAtMs),
+			ExpiresAt:   time.Unix(claims.ExpiresAt, 0),
+			ClientID:    claims.ClientID,
+			Scope:       claims.Scope,
+			ActorID:     actorID,
 		}, nil
 	}
 
 	return InfoFromToken{}, errors.New("invalid token")
 }
+
+func (m *manager) ParsePurposeToken(purposeToken, purpose string) (int, error) {
+	token, err := jwt.ParseWithClaims(purposeToken, &CustomClaims{}, m.keyFunc)
+	if err != nil {
+		return 0, err
+	}
+	if claims, ok := token.Claims.(*CustomClaims); ok && token.Valid && purpose != "" && claims.Purpose == purpose {
+		var userID int
+		_, errScan := fmt.Sscanf(claims.Subject, "%d", &userID)
+		if errScan != nil {
+			return 0, errors.New("invalid user ID in token")
+		}
+		return userID, nil
+	}
+
+	return 0, errors.New("invalid token")
+}
+
+func (m *manager) JWKS() []JWK {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	jwks := make([]JWK, 0, len(m.keys))
+	for _, k := range m.keys {
+		jwks = append(jwks, k.jwk())
+	}
+
+	sort.Slice(jwks, func(i, j int) bool {
+		return jwks[i].Kid < jwks[j].Kid
+	})
+
+	return jwks
+}
+
+// Rotate makes a freshly generated key the signing key. Previous keys stay in the JWKS until
+// every token they could have signed has expired.
+func (m *manager) Rotate() error {
+	key, err := generateSigningKey(m.algorithm)
+	if err != nil {
+		return err
+	}
+
+	if errSave := saveSigningKey(m.keysDir, key); errSave != nil {
+		return errSave
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.keys[key.id] = key
+	m.current = key
+	m.retireKeys()
+
+	return nil
+}
+
+// retireKeys drops keys whose successor has been signing for longer than keyRetention.
+func (m *manager) retireKeys() {
+	keys := make([]signingKey, 0, len(m.keys))
+	for _, k := range m.keys {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].createdAt.Before(keys[j].createdAt)
+	})
+
+	for i := 0; i < len(keys)-1; i++ {
+		if time.Since(keys[i+1].createdAt) < keyRetention {
+			continue
+		}
+		if err := removeSigningKey(m.keysDir, keys[i]); err != nil {
+			logrus.WithError(err).Warn("can't remove retired signing key")
+			continue
+		}
+		delete(m.keys, keys[i].id)
+	}
+}
+
+func (m *manager) rotateEvery(interval time.Duration) {
+	for {
+		m.mu.RLock()
+		next := m.current.createdAt.Add(interval)
+		m.mu.RUnlock()
+
+		time.Sleep(time.Until(next))
+
+		if err := m.Rotate(); err != nil {
+			logrus.WithError(err).Error("can't rotate signing key")
+			time.Sleep(time.Minute)
+		}
+	}
+}
+
+func (m *manager) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, ok := token.Header["kid"].(string)
+	if !ok {
+		return nil, errors.New("token has no key id")
+	}
+
+	m.mu.RLock()
+	key, found := m.keys[kid]
+	m.mu.RUnlock()
+
+	if !found {
+		return nil, errors.New("unknown signing key")
+	}
+
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+
+	return key.private.Public(), nil
+}
@@ -0,0 +1,198 @@
+package AuthService
+
+import (
+	"errors"
+	"github.com/jst-Frenzy/ControlSystem/protobuf/permissions"
+	"github.com/sirupsen/logrus"
+	"slices"
+	"strings"
+	"time"
+)
+
+func (s *authService) GetProfile(userID int) (UserProfile, error) {
+	user, err := s.repoPostgres.GetUserByID(userID)
+	if err != nil {
+		return UserProfile{}, err
+	}
+	return NewUserProfile(user), nil
+}
+
+// UpdateProfile renames the user right away, while a new email is only mailed a confirmation link and
+// is reported as PendingEmail until the link is opened.
+func (s *authService) UpdateProfile(userID int, req UpdateProfileRequest) (UserProfile, error) {
+	user, err := s.repoPostgres.GetUserByID(userID)
+	if err != nil {
+		return UserProfile{}, err
+	}
+
+	if req.UserName != nil && *req.UserName != user.UserName {
+		userName := strings.TrimSpace(*req.UserName)
+		if userName == "" {
+			return UserProfile{}, ErrInvalidUserName
+		}
+
+		if user, err = s.repoPostgres.UpdateUserName(user.ID, userName); err != nil {
+			return UserProfile{}, err
+		}
+		s.invalidateUser(user.ID)
+	}
+
+	profile := NewUserProfile(user)
+
+	if req.Email != nil && strings.TrimSpace(*req.Email) != user.Email {
+		newEmail := strings.TrimSpace(*req.Email)
+		if errChange := s.requestEmailChange(user, newEmail); errChange != nil {
+			return UserProfile{}, errChange
+		}
+		profile.PendingEmail = newEmail
+	}
+
+	return profile, nil
+}
+
+func (s *authService) requestEmailChange(user User, newEmail string) error {
+	if _, err := s.repoPostgres.GetUser(newEmail); err == nil {
+		return ErrEmailTaken
+	} else if !errors.Is(err, ErrUserNotFound) {
+		return err
+	}
+
+	token, err := s.tokenManager.NewRefreshToken()
+	if err != nil {
+		return err
+	}
+
+	errSave := s.repoPostgres.SaveEmailChange(EmailChange{
+		UserID:    user.ID,
+		NewEmail:  newEmail,
+		TokenHash: s.makeHash(token),
+		ExpiresAt: time.Now().Add(emailChangeTTL),
+	})
+	if errSave != nil {
+		return errSave
+	}
+
+	return s.mailer.SendEmailChange(newEmail, token)
+}
+
+// ConfirmEmailChange switches the user to the confirmed email, the cached user is dropped so the old email
+// can't be used to sign in anymore.
+func (s *authService) ConfirmEmailChange(token string) error {
+	user, _, err := s.repoPostgres.ConfirmEmailChange(s.makeHash(token))
+	if err != nil {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"event":   "email_changed",
+		"user_id": user.ID,
+	}).Info("user email changed")
+
+	s.invalidateUser(user.ID)
+
+	return nil
+}
+
+func (s *authService) ListUsers(req ListUsersRequest) ([]UserProfile, int64, error) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultUsersPageSize
+	}
+	limit = min(limit, maxUsersPageSize)
+
+	users, total, err := s.repoPostgres.ListUsers(strings.TrimSpace(req.Query), limit, max(req.Offset, 0))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	profiles := make([]UserProfile, 0, len(users))
+	for _, u := range users {
+		profiles = append(profiles, NewUserProfile(u))
+	}
+	return profiles, total, nil
+}
+
+// DeactivateUser blocks sign in for the user and revokes its sessions along with the access tokens
+// that haven't expired yet.
+func (s *authService) DeactivateUser(actorID, userID int, client ClientInfo) error {
+	err := s.deactivateUser(actorID, userID)
+	s.audit(AuditEvent{Event: AuditUserDeactivated, UserID: auditUserID(userID), ActorID: auditUserID(actorID)}, client, err)
+	return err
+}
+
+func (s *authService) deactivateUser(actorID, userID int) error {
+	if actorID == userID {
+		return ErrSelfDeactivation
+	}
+
+	now := time.Now()
+	user, err := s.repoPostgres.SetUserDeactivated(userID, &now)
+	if err != nil {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"event":    "user_deactivated",
+		"actor_id": actorID,
+		"user_id":  userID,
+	}).Info("user deactivated")
+
+	return s.revokeUserSessions(user)
+}
+
+func (s *authService) ReactivateUser(userID int) error {
+	user, err := s.repoPostgres.SetUserDeactivated(userID, nil)
+	if err != nil {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"event":   "user_reactivated",
+		"user_id": userID,
+	}).Info("user reactivated")
+
+	s.invalidateUser(user.ID)
+
+	return nil
+}
+
+// Impersonate issues a short-lived access token of the user for an admin to see what the user sees.
+// There is no refresh token, and admins can't be impersonated so the token never carries users:manage.
+func (s *authService) Impersonate(actorID, userID int, client ClientInfo) (ImpersonationToken, error) {
+	token, err := s.impersonate(actorID, userID)
+	s.audit(AuditEvent{Event: AuditImpersonation, UserID: auditUserID(userID), ActorID: auditUserID(actorID)}, client, err)
+	return token, err
+}
+
+func (s *authService) impersonate(actorID, userID int) (ImpersonationToken, error) {
+	if actorID == userID {
+		return ImpersonationToken{}, ErrSelfImpersonation
+	}
+
+	user, err := s.repoPostgres.GetUserByID(userID)
+	if err != nil {
+		return ImpersonationToken{}, err
+	}
+	if user.DeactivatedAt != nil {
+		return ImpersonationToken{}, ErrUserDeactivated
+	}
+	if slices.Contains(PermissionsForRole(user.Role), permissions.UsersManage) {
+		return ImpersonationToken{}, ErrImpersonationForbidden
+	}
+
+	accessToken, err := s.tokenManager.NewImpersonationJWT(user, actorID, impersonationTTL)
+	if err != nil {
+		return ImpersonationToken{}, err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"event":    "impersonation_started",
+		"actor_id": actorID,
+		"user_id":  userID,
+	}).Info("admin started impersonating user")
+
+	return ImpersonationToken{
+		AccessToken: accessToken,
+		ExpiresIn:   int64(impersonationTTL.Seconds()),
+	}, nil
+}
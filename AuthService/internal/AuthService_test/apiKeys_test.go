@@ -0,0 +1,187 @@
+package AuthService_test
+
+import (
+	"errors"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/jst-Frenzy/ControlSystem/AuthService/internal/AuthService"
+	mockauthservice "github.com/jst-Frenzy/ControlSystem/AuthService/internal/mocks"
+	"testing"
+	"time"
+)
+
+const testAPISecret = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func TestService_CreateAPIKey(t *testing.T) {
+	type mockBehavior func(r *mockauthservice.MockAuthPostgresRepo, tm *mockauthservice.MockTokenManager)
+
+	seller := AuthService.User{ID: 1, Role: AuthService.RoleSeller}
+
+	testTable := []struct {
+		name          string
+		inputRequest  AuthService.CreateAPIKeyRequest
+		mockBehavior  mockBehavior
+		expectedKey   string
+		expectedError error
+	}{
+		{
+			name:         "OK",
+			inputRequest: AuthService.CreateAPIKeyRequest{Name: "inventory sync", Scopes: []string{"goods:write", "goods:write"}, ExpiresInDays: 30},
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, tm *mockauthservice.MockTokenManager) {
+				r.EXPECT().GetUserByID(1).Return(seller, nil)
+				tm.EXPECT().NewRefreshToken().Return(testAPISecret, nil)
+				r.EXPECT().CreateAPIKey(gomock.Any()).DoAndReturn(func(key AuthService.APIKey) (AuthService.APIKey, error) {
+					if key.UserID != 1 || key.Name != "inventory sync" || key.Prefix != "cs_01234567" ||
+						key.KeyHash != sha256Hex("cs_"+testAPISecret) || key.Scopes != "goods:write" || key.ExpiresAt == nil {
+						return AuthService.APIKey{}, errors.New("unexpected api key")
+					}
+					key.ID = 7
+					return key, nil
+				})
+			},
+			expectedKey:   "cs_" + testAPISecret,
+			expectedError: nil,
+		},
+		{
+			name:         "Scope Not Granted To Role",
+			inputRequest: AuthService.CreateAPIKeyRequest{Name: "admin script", Scopes: []string{"users:manage"}},
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, tm *mockauthservice.MockTokenManager) {
+				r.EXPECT().GetUserByID(1).Return(seller, nil)
+			},
+			expectedKey:   "",
+			expectedError: AuthService.ErrInvalidScope,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+			authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+			tokenManager := mockauthservice.NewMockTokenManager(c)
+			testCase.mockBehavior(authPostgresRepo, tokenManager)
+
+			service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, tokenManager, nil, testPasswordHasher, AuthService.PasswordPolicy{})
+
+			info, err := service.CreateAPIKey(1, testCase.inputRequest)
+
+			assert.Equal(t, info.Key, testCase.expectedKey)
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
+
+func TestService_ValidateAPIKey(t *testing.T) {
+	type mockBehavior func(r *mockauthservice.MockAuthPostgresRepo)
+
+	key := "cs_" + testAPISecret
+	recently := time.Now().Add(-10 * time.Second)
+	past := time.Now().Add(-time.Hour)
+	apiKey := AuthService.APIKey{ID: 7, UserID: 1, Scopes: "goods:write cart:manage", LastUsedAt: &recently}
+
+	testTable := []struct {
+		name          string
+		inputKey      string
+		mockBehavior  mockBehavior
+		expectedInfo  AuthService.InfoFromToken
+		expectedError error
+	}{
+		{
+			name:     "OK",
+			inputKey: key,
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo) {
+				r.EXPECT().GetAPIKey(sha256Hex(key)).Return(apiKey, nil)
+				r.EXPECT().GetUserByID(1).Return(AuthService.User{ID: 1, UserName: "test", Role: AuthService.RoleSeller}, nil)
+			},
+			expectedInfo: AuthService.InfoFromToken{
+				ID:          1,
+				Role:        AuthService.RoleSeller,
+				Permissions: []string{"goods:write", "cart:manage"},
+				UserName:    "test",
+				CartID:      "1",
+			},
+			expectedError: nil,
+		},
+		{
+			name:     "Owner Lost Role",
+			inputKey: key,
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo) {
+				stale := apiKey
+				stale.LastUsedAt = &past
+
+				r.EXPECT().GetAPIKey(sha256Hex(key)).Return(stale, nil)
+				r.EXPECT().GetUserByID(1).Return(AuthService.User{ID: 1, UserName: "test", Role: AuthService.RoleUser}, nil)
+				r.EXPECT().TouchAPIKey(7, gomock.Any()).Return(nil)
+			},
+			expectedInfo: AuthService.InfoFromToken{
+				ID:          1,
+				Role:        AuthService.RoleUser,
+				Permissions: []string{"cart:manage"},
+				UserName:    "test",
+				CartID:      "1",
+			},
+			expectedError: nil,
+		},
+		{
+			name:     "Revoked",
+			inputKey: key,
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo) {
+				revoked := apiKey
+				revoked.RevokedAt = &past
+
+				r.EXPECT().GetAPIKey(sha256Hex(key)).Return(revoked, nil)
+			},
+			expectedInfo:  AuthService.InfoFromToken{},
+			expectedError: AuthService.ErrAPIKeyInvalid,
+		},
+		{
+			name:     "Expired",
+			inputKey: key,
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo) {
+				expired := apiKey
+				expired.ExpiresAt = &past
+
+				r.EXPECT().GetAPIKey(sha256Hex(key)).Return(expired, nil)
+			},
+			expectedInfo:  AuthService.InfoFromToken{},
+			expectedError: AuthService.ErrAPIKeyInvalid,
+		},
+		{
+			name:     "Unknown",
+			inputKey: key,
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo) {
+				r.EXPECT().GetAPIKey(sha256Hex(key)).Return(AuthService.APIKey{}, AuthService.ErrAPIKeyNotFound)
+			},
+			expectedInfo:  AuthService.InfoFromToken{},
+			expectedError: AuthService.ErrAPIKeyInvalid,
+		},
+		{
+			name:          "Access Token",
+			inputKey:      "eyJhbGciOiJSUzI1NiJ9.e30.sig",
+			mockBehavior:  func(r *mockauthservice.MockAuthPostgresRepo) {},
+			expectedInfo:  AuthService.InfoFromToken{},
+			expectedError: AuthService.ErrAPIKeyInvalid,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+			authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+			tokenManager := mockauthservice.NewMockTokenManager(c)
+			testCase.mockBehavior(authPostgresRepo)
+
+			service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, tokenManager, nil, testPasswordHasher, AuthService.PasswordPolicy{})
+
+			info, err := service.ValidateAPIKey(testCase.inputKey)
+
+			assert.Equal(t, info, testCase.expectedInfo)
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
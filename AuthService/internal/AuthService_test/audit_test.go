@@ -0,0 +1,175 @@
+package AuthService_test
+
+import (
+	"errors"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/jst-Frenzy/ControlSystem/AuthService/internal/AuthService"
+	mockauthservice "github.com/jst-Frenzy/ControlSystem/AuthService/internal/mocks"
+	"golang.org/x/crypto/bcrypt"
+	"testing"
+	"time"
+)
+
+func TestService_AuditSignIn(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("qwerty"), bcrypt.MinCost)
+	verifiedAt := time.Now()
+	user := AuthService.User{ID: 1, Email: "test@test.com", PasswordHash: string(hashedPassword), EmailVerifiedAt: &verifiedAt}
+	client := AuthService.ClientInfo{IP: "192.0.2.1", UserAgent: "curl/8.5.0", CorrelationID: "req-1"}
+
+	authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+	authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+
+	authRedisRepo.EXPECT().GetLoginLock(gomock.Any()).Return(time.Duration(0), nil).Times(2)
+	authRedisRepo.EXPECT().GetUserWithEmail(user.Email).Return(user, nil)
+	authRedisRepo.EXPECT().IncrLoginFailures(gomock.Any(), gomock.Any()).Return(1, nil).Times(2)
+
+	var appended AuthService.AuditEvent
+	authPostgresRepo.EXPECT().AppendAuditEvent(gomock.Any()).DoAndReturn(func(event AuthService.AuditEvent) error {
+		appended = event
+		return nil
+	})
+
+	service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, nil, nil, testPasswordHasher, AuthService.PasswordPolicy{})
+
+	_, err := service.SignIn(AuthService.UserSignIn{Email: user.Email, Password: "wrong"}, client)
+
+	assert.Equal(t, err, AuthService.ErrWrongPassword)
+	assert.Equal(t, appended.Event, AuthService.AuditSignIn)
+	assert.Equal(t, appended.Outcome, AuthService.AuditOutcomeFailure)
+	assert.Equal(t, appended.Reason, AuthService.ErrWrongPassword.Error())
+	assert.Equal(t, *appended.UserID, 1)
+	assert.Equal(t, appended.IP, "192.0.2.1")
+	assert.Equal(t, appended.UserAgent, "curl/8.5.0")
+	assert.Equal(t, appended.CorrelationID, "req-1")
+	assert.Equal(t, appended.OccurredAt.IsZero(), false)
+}
+
+func TestService_AuditFailureDoesNotFailRequest(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+	authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+
+	authPostgresRepo.EXPECT().DeleteUserRefreshTokens(1).Return([]string{"hash"}, nil)
+	authRedisRepo.EXPECT().DeleteRefreshTokens("hash").Return(nil)
+	authRedisRepo.EXPECT().RevokeUserAccessTokens(1, gomock.Any(), gomock.Any()).Return(nil)
+	authPostgresRepo.EXPECT().AppendAuditEvent(gomock.Any()).Return(errors.New("DB Failure"))
+
+	service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, nil, nil, testPasswordHasher, AuthService.PasswordPolicy{})
+
+	err := service.LogoutAll(1, AuthService.ClientInfo{})
+
+	assert.Equal(t, err, nil)
+}
+
+func TestService_AuditPasswordReset(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	client := AuthService.ClientInfo{IP: "192.0.2.1", UserAgent: "curl/8.5.0", CorrelationID: "req-1"}
+
+	authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+	authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+
+	authPostgresRepo.EXPECT().ResetPassword(gomock.Any(), gomock.Any()).Return(AuthService.User{ID: 1}, nil)
+	authPostgresRepo.EXPECT().DeleteUserRefreshTokens(1).Return([]string{"hash"}, nil)
+	authRedisRepo.EXPECT().DeleteRefreshTokens("hash").Return(nil)
+	authRedisRepo.EXPECT().InvalidateUser(1).Return(nil)
+	authRedisRepo.EXPECT().RevokeUserAccessTokens(1, gomock.Any(), gomock.Any()).Return(nil)
+
+	var appended AuthService.AuditEvent
+	authPostgresRepo.EXPECT().AppendAuditEvent(gomock.Any()).DoAndReturn(func(event AuthService.AuditEvent) error {
+		appended = event
+		return nil
+	})
+
+	service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, nil, nil, testPasswordHasher, AuthService.PasswordPolicy{})
+
+	err := service.ResetPassword("reset_token", "new_password", client)
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, appended.Event, AuthService.AuditPasswordReset)
+	assert.Equal(t, appended.Outcome, AuthService.AuditOutcomeSuccess)
+	assert.Equal(t, *appended.UserID, 1)
+	assert.Equal(t, appended.IP, "192.0.2.1")
+}
+
+func TestService_AuditAPIKeyRevoked(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+	authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+
+	authPostgresRepo.EXPECT().RevokeAPIKey(1, 7).Return(AuthService.ErrAPIKeyNotFound)
+
+	var appended AuthService.AuditEvent
+	authPostgresRepo.EXPECT().AppendAuditEvent(gomock.Any()).DoAndReturn(func(event AuthService.AuditEvent) error {
+		appended = event
+		return nil
+	})
+
+	service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, nil, nil, testPasswordHasher, AuthService.PasswordPolicy{})
+
+	err := service.RevokeAPIKey(1, 7, AuthService.ClientInfo{})
+
+	assert.Equal(t, err, AuthService.ErrAPIKeyNotFound)
+	assert.Equal(t, appended.Event, AuthService.AuditAPIKeyRevoked)
+	assert.Equal(t, appended.Outcome, AuthService.AuditOutcomeFailure)
+	assert.Equal(t, appended.Reason, AuthService.ErrAPIKeyNotFound.Error())
+	assert.Equal(t, *appended.UserID, 1)
+}
+
+func TestService_ListAuditEvents(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+	events := []AuthService.AuditEvent{{ID: 2, Event: AuthService.AuditLogout}, {ID: 1, Event: AuthService.AuditLogout}}
+
+	filter := AuthService.AuditFilter{Event: AuthService.AuditLogout, Limit: 500}
+	authPostgresRepo.EXPECT().ListAuditEvents(filter).Return(events, nil)
+	authPostgresRepo.EXPECT().CountAuditEvents(filter).Return(int64(2), nil)
+
+	service := AuthService.NewAuthService(authPostgresRepo, nil, nil, nil, testPasswordHasher, AuthService.PasswordPolicy{})
+
+	got, total, err := service.ListAuditEvents(AuthService.ListAuditEventsRequest{Event: " logout ", Limit: 10000, Offset: -1})
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, got, events)
+	assert.Equal(t, total, int64(2))
+}
+
+func TestService_ExportAuditEvents(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+
+	firstPage := make([]AuthService.AuditEvent, 1000)
+	for i := range firstPage {
+		firstPage[i] = AuthService.AuditEvent{ID: int64(1500 - i)}
+	}
+	secondPage := []AuthService.AuditEvent{{ID: 10}, {ID: 9}}
+
+	gomock.InOrder(
+		authPostgresRepo.EXPECT().ListAuditEvents(AuthService.AuditFilter{Limit: 1000}).Return(firstPage, nil),
+		authPostgresRepo.EXPECT().ListAuditEvents(AuthService.AuditFilter{Limit: 1000, BeforeID: 501}).Return(secondPage, nil),
+	)
+
+	service := AuthService.NewAuthService(authPostgresRepo, nil, nil, nil, testPasswordHasher, AuthService.PasswordPolicy{})
+
+	written := 0
+	err := service.ExportAuditEvents(AuthService.ListAuditEventsRequest{}, func(AuthService.AuditEvent) error {
+		written++
+		return nil
+	})
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, written, 1002)
+}
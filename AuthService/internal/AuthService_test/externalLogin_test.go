@@ -0,0 +1,494 @@
+package AuthService_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/jst-Frenzy/ControlSystem/AuthService/internal/AuthService"
+	mockauthservice "github.com/jst-Frenzy/ControlSystem/AuthService/internal/mocks"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+const (
+	idpClientID     = "auth-service"
+	idpClientSecret = "idp-secret"
+	idpKeyID        = "idp-key"
+	idpCallbackURL  = "https://auth.test/api/auth/external/corp/callback"
+	idpVerifier     = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+)
+
+type fakeGrant struct {
+	redirectURI   string
+	codeChallenge string
+	nonce         string
+}
+
+// fakeIdP is an OpenID Connect provider serving discovery, JWKS and the token endpoint in process.
+type fakeIdP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	grants map[string]fakeGrant
+
+	// claims edits the claims of the next ID token
+	claims func(claims jwt.MapClaims)
+}
+
+func newFakeIdP(t *testing.T) *fakeIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idp := &fakeIdP{key: key, grants: map[string]fakeGrant{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string][]AuthService.JWK{"keys": {{
+			Kty: "RSA",
+			Kid: idpKeyID,
+			Use: "sig",
+			Alg: AuthService.AlgorithmRS256,
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", idp.token)
+
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+
+	return idp
+}
+
+func (idp *fakeIdP) token(w http.ResponseWriter, r *http.Request) {
+	clientID, secret, _ := r.BasicAuth()
+	if clientID != idpClientID || secret != idpClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	grant, ok := idp.grants[r.PostFormValue("code")]
+	if !ok || r.PostFormValue("grant_type") != "authorization_code" || r.PostFormValue("redirect_uri") != grant.redirectURI ||
+		codeChallenge(r.PostFormValue("code_verifier")) != grant.codeChallenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":            idp.server.URL,
+		"sub":            "sub-1",
+		"aud":            []string{idpClientID},
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"nonce":          grant.nonce,
+		"email":          "test@test.com",
+		"email_verified": true,
+		"name":           "Test User",
+	}
+	if idp.claims != nil {
+		idp.claims(claims)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = idpKeyID
+	idToken, err := token.SignedString(idp.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"access_token": "idp-access-token",
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestExternalLogin_LoginURL(t *testing.T) {
+	idp := newFakeIdP(t)
+
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+	authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+	tokenManager := mockauthservice.NewMockTokenManager(c)
+
+	tokenManager.EXPECT().NewRefreshToken().Return("state", nil)
+	tokenManager.EXPECT().NewRefreshToken().Return("nonce", nil)
+	tokenManager.EXPECT().NewRefreshToken().Return(idpVerifier, nil)
+	authRedisRepo.EXPECT().SaveExternalLogin(sha256Hex("state"), AuthService.ExternalLogin{
+		Provider:     "corp",
+		Nonce:        "nonce",
+		CodeVerifier: idpVerifier,
+	}, 10*time.Minute).Return(nil)
+
+	auth := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, tokenManager, nil, testPasswordHasher, AuthService.PasswordPolicy{})
+	service := AuthService.NewExternalLoginService(auth, "https://auth.test/",
+		AuthService.ExternalProvider{Name: "corp", Issuer: idp.server.URL, ClientID: idpClientID, ClientSecret: idpClientSecret})
+
+	loginURL, err := service.LoginURL(context.Background(), "corp")
+	assert.Equal(t, err, nil)
+
+	u, err := url.Parse(loginURL)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, u.Scheme+"://"+u.Host+u.Path, idp.server.URL+"/authorize")
+	assert.Equal(t, u.Query(), url.Values{
+		"response_type":         {"code"},
+		"client_id":             {idpClientID},
+		"redirect_uri":          {idpCallbackURL},
+		"scope":                 {"openid email profile"},
+		"state":                 {"state"},
+		"nonce":                 {"nonce"},
+		"code_challenge":        {codeChallenge(idpVerifier)},
+		"code_challenge_method": {"S256"},
+	})
+
+	_, err = service.LoginURL(context.Background(), "unknown")
+	assert.Equal(t, err, AuthService.ErrExternalProviderNotFound)
+}
+
+func TestExternalLogin_Callback(t *testing.T) {
+	type mockBehavior func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager)
+
+	idp := newFakeIdP(t)
+	idp.grants["code"] = fakeGrant{redirectURI: idpCallbackURL, codeChallenge: codeChallenge(idpVerifier), nonce: "nonce"}
+
+	login := AuthService.ExternalLogin{Provider: "corp", Nonce: "nonce", CodeVerifier: idpVerifier}
+	user := AuthService.User{ID: 1, UserName: "test", Email: "test@test.com", PasswordHash: "hash"}
+	identity := AuthService.Identity{Provider: "corp", Subject: "sub-1", Email: "test@test.com"}
+
+	issueTokens := func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager, u AuthService.User) {
+		tm.EXPECT().NewJWT(u, 2*time.Hour).Return("access_token", nil)
+		tm.EXPECT().NewRefreshToken().Return("refresh_token", nil)
+		r.EXPECT().SaveRefreshToken(gomock.Any()).Return(nil)
+		redisMock.EXPECT().AddUserWithRefreshToken(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	}
+	takeLogin := func(redisMock *mockauthservice.MockAuthRedisRepo) {
+		redisMock.EXPECT().TakeExternalLogin(sha256Hex("state")).Return(login, nil)
+	}
+
+	testTable := []struct {
+		name           string
+		provider       string
+		clientSecret   string
+		claims         func(claims jwt.MapClaims)
+		mockBehavior   mockBehavior
+		expectedTokens AuthService.Tokens
+		expectedError  error
+	}{
+		{
+			name: "Linked Identity",
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				takeLogin(redisMock)
+				r.EXPECT().GetUserByIdentity("corp", "sub-1").Return(user, nil)
+				issueTokens(r, redisMock, tm, user)
+			},
+			expectedTokens: AuthService.Tokens{AccessToken: "access_token", RefreshToken: "refresh_token"},
+			expectedError:  nil,
+		},
+		{
+			name: "Reauthentication",
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				reauth := login
+				reauth.ReauthUserID = 1
+				redisMock.EXPECT().TakeExternalLogin(sha256Hex("state")).Return(reauth, nil)
+				r.EXPECT().GetUserByIdentity("corp", "sub-1").Return(user, nil)
+				tm.EXPECT().NewPurposeToken(1, AuthService.PurposeReauthentication, 5*time.Minute).Return("reauth_token", nil)
+			},
+			expectedTokens: AuthService.Tokens{ReauthToken: "reauth_token"},
+			expectedError:  nil,
+		},
+		{
+			name: "Reauthentication With Identity Of Another User",
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				reauth := login
+				reauth.ReauthUserID = 2
+				redisMock.EXPECT().TakeExternalLogin(sha256Hex("state")).Return(reauth, nil)
+				r.EXPECT().GetUserByIdentity("corp", "sub-1").Return(user, nil)
+			},
+			expectedTokens: AuthService.Tokens{},
+			expectedError:  AuthService.ErrIdentityNotFound,
+		},
+		{
+			name: "Link By Verified Email",
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				verifiedAt := time.Now()
+				mfaUser := user
+				mfaUser.TOTPEnabled = true
+				mfaUser.EmailVerifiedAt = &verifiedAt
+				linked := identity
+				linked.UserID = 1
+
+				takeLogin(redisMock)
+				r.EXPECT().GetUserByIdentity("corp", "sub-1").Return(AuthService.User{}, AuthService.ErrIdentityNotFound)
+				r.EXPECT().GetUser("test@test.com").Return(mfaUser, nil)
+				r.EXPECT().CreateIdentity(linked).Return(nil)
+				tm.EXPECT().NewPurposeToken(1, AuthService.PurposeMFAChallenge, 5*time.Minute).Return("mfa_token", nil)
+			},
+			expectedTokens: AuthService.Tokens{MFAToken: "mfa_token"},
+			expectedError:  nil,
+		},
+		{
+			name: "Link To Unverified Account",
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				takeLogin(redisMock)
+				r.EXPECT().GetUserByIdentity("corp", "sub-1").Return(AuthService.User{}, AuthService.ErrIdentityNotFound)
+				r.EXPECT().GetUser("test@test.com").Return(user, nil)
+			},
+			expectedTokens: AuthService.Tokens{},
+			expectedError:  AuthService.ErrEmailNotVerified,
+		},
+		{
+			name: "New User",
+			claims: func(claims jwt.MapClaims) {
+				claims["aud"] = idpClientID
+			},
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				created := AuthService.User{ID: 2, UserName: "Test User", Email: "test@test.com"}
+
+				takeLogin(redisMock)
+				r.EXPECT().GetUserByIdentity("corp", "sub-1").Return(AuthService.User{}, AuthService.ErrIdentityNotFound)
+				r.EXPECT().GetUser("test@test.com").Return(AuthService.User{}, AuthService.ErrUserNotFound)
+				r.EXPECT().CreateUserWithIdentity(gomock.Any(), identity).DoAndReturn(func(u AuthService.User, _ AuthService.Identity) (AuthService.User, error) {
+					if u.UserName != "Test User" || u.Email != "test@test.com" || u.PasswordHash != "" ||
+						u.Role != AuthService.RoleUser || u.EmailVerifiedAt == nil {
+						return AuthService.User{}, errors.New("unexpected user")
+					}
+					return created, nil
+				})
+				issueTokens(r, redisMock, tm, created)
+			},
+			expectedTokens: AuthService.Tokens{AccessToken: "access_token", RefreshToken: "refresh_token"},
+			expectedError:  nil,
+		},
+		{
+			name: "Unverified Email",
+			claims: func(claims jwt.MapClaims) {
+				claims["email_verified"] = false
+			},
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				takeLogin(redisMock)
+				r.EXPECT().GetUserByIdentity("corp", "sub-1").Return(AuthService.User{}, AuthService.ErrIdentityNotFound)
+			},
+			expectedTokens: AuthService.Tokens{},
+			expectedError:  AuthService.ErrEmailNotVerified,
+		},
+		{
+			name: "Deactivated User",
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				deactivated := user
+				deactivatedAt := time.Now()
+				deactivated.DeactivatedAt = &deactivatedAt
+
+				takeLogin(redisMock)
+				r.EXPECT().GetUserByIdentity("corp", "sub-1").Return(deactivated, nil)
+			},
+			expectedTokens: AuthService.Tokens{},
+			expectedError:  AuthService.ErrUserDeactivated,
+		},
+		{
+			name: "Wrong Nonce",
+			claims: func(claims jwt.MapClaims) {
+				claims["nonce"] = "other"
+			},
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				takeLogin(redisMock)
+			},
+			expectedTokens: AuthService.Tokens{},
+			expectedError:  AuthService.ErrExternalLoginFailed,
+		},
+		{
+			name: "Other Audience",
+			claims: func(claims jwt.MapClaims) {
+				claims["aud"] = []string{"other-client"}
+			},
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				takeLogin(redisMock)
+			},
+			expectedTokens: AuthService.Tokens{},
+			expectedError:  AuthService.ErrExternalLoginFailed,
+		},
+		{
+			name: "Other Issuer",
+			claims: func(claims jwt.MapClaims) {
+				claims["iss"] = "https://evil.test"
+			},
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				takeLogin(redisMock)
+			},
+			expectedTokens: AuthService.Tokens{},
+			expectedError:  AuthService.ErrExternalLoginFailed,
+		},
+		{
+			name: "Expired ID Token",
+			claims: func(claims jwt.MapClaims) {
+				claims["exp"] = time.Now().Add(-time.Hour).Unix()
+			},
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				takeLogin(redisMock)
+			},
+			expectedTokens: AuthService.Tokens{},
+			expectedError:  AuthService.ErrExternalLoginFailed,
+		},
+		{
+			name:         "Wrong Client Secret",
+			clientSecret: "wrong",
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				takeLogin(redisMock)
+			},
+			expectedTokens: AuthService.Tokens{},
+			expectedError:  AuthService.ErrExternalLoginFailed,
+		},
+		{
+			name: "Unknown State",
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				redisMock.EXPECT().TakeExternalLogin(sha256Hex("state")).Return(AuthService.ExternalLogin{}, AuthService.ErrExternalLoginInvalid)
+			},
+			expectedTokens: AuthService.Tokens{},
+			expectedError:  AuthService.ErrExternalLoginInvalid,
+		},
+		{
+			name: "State Of Another Provider",
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				redisMock.EXPECT().TakeExternalLogin(sha256Hex("state")).Return(AuthService.ExternalLogin{Provider: "other"}, nil)
+			},
+			expectedTokens: AuthService.Tokens{},
+			expectedError:  AuthService.ErrExternalLoginInvalid,
+		},
+		{
+			name:     "Unknown Provider",
+			provider: "unknown",
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+			},
+			expectedTokens: AuthService.Tokens{},
+			expectedError:  AuthService.ErrExternalProviderNotFound,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+			authPostgresRepo.EXPECT().AppendAuditEvent(gomock.Any()).Return(nil).AnyTimes()
+			authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+			tokenManager := mockauthservice.NewMockTokenManager(c)
+			testCase.mockBehavior(authPostgresRepo, authRedisRepo, tokenManager)
+
+			idp.claims = testCase.claims
+
+			provider, clientSecret := testCase.provider, testCase.clientSecret
+			if provider == "" {
+				provider = "corp"
+			}
+			if clientSecret == "" {
+				clientSecret = idpClientSecret
+			}
+
+			auth := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, tokenManager, nil, testPasswordHasher, AuthService.PasswordPolicy{})
+			service := AuthService.NewExternalLoginService(auth, "https://auth.test",
+				AuthService.ExternalProvider{Name: "corp", Issuer: idp.server.URL, ClientID: idpClientID, ClientSecret: clientSecret})
+
+			tokens, err := service.Callback(context.Background(), provider, "code", "state", AuthService.ClientInfo{})
+
+			assert.Equal(t, tokens, testCase.expectedTokens)
+			assert.Equal(t, errors.Is(err, testCase.expectedError), true)
+		})
+	}
+}
+
+func TestExternalLogin_UnlinkIdentity(t *testing.T) {
+	type mockBehavior func(r *mockauthservice.MockAuthPostgresRepo)
+
+	testTable := []struct {
+		name          string
+		provider      string
+		mockBehavior  mockBehavior
+		expectedError error
+	}{
+		{
+			name:     "OK",
+			provider: "corp",
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo) {
+				r.EXPECT().GetUserByID(1).Return(AuthService.User{ID: 1, PasswordHash: "hash"}, nil)
+				r.EXPECT().ListIdentities(1).Return([]AuthService.Identity{{UserID: 1, Provider: "corp"}}, nil)
+				r.EXPECT().DeleteIdentity(1, "corp").Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:     "Last Sign In Method",
+			provider: "corp",
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo) {
+				r.EXPECT().GetUserByID(1).Return(AuthService.User{ID: 1}, nil)
+				r.EXPECT().ListIdentities(1).Return([]AuthService.Identity{{UserID: 1, Provider: "corp"}}, nil)
+			},
+			expectedError: AuthService.ErrLastSignInMethod,
+		},
+		{
+			name:     "Another Identity Left",
+			provider: "corp",
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo) {
+				r.EXPECT().GetUserByID(1).Return(AuthService.User{ID: 1}, nil)
+				r.EXPECT().ListIdentities(1).Return([]AuthService.Identity{{UserID: 1, Provider: "corp"}, {UserID: 1, Provider: "other"}}, nil)
+				r.EXPECT().DeleteIdentity(1, "corp").Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:     "Not Linked",
+			provider: "other",
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo) {
+				r.EXPECT().GetUserByID(1).Return(AuthService.User{ID: 1, PasswordHash: "hash"}, nil)
+				r.EXPECT().ListIdentities(1).Return([]AuthService.Identity{{UserID: 1, Provider: "corp"}}, nil)
+			},
+			expectedError: AuthService.ErrIdentityNotFound,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+			authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+			tokenManager := mockauthservice.NewMockTokenManager(c)
+			testCase.mockBehavior(authPostgresRepo)
+
+			auth := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, tokenManager, nil, testPasswordHasher, AuthService.PasswordPolicy{})
+			service := AuthService.NewExternalLoginService(auth, "https://auth.test")
+
+			err := service.UnlinkIdentity(1, testCase.provider)
+
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
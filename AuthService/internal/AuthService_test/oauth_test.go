@@ -0,0 +1,354 @@
+package AuthService_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/jst-Frenzy/ControlSystem/AuthService/internal/AuthService"
+	mockauthservice "github.com/jst-Frenzy/ControlSystem/AuthService/internal/mocks"
+	"testing"
+	"time"
+)
+
+func sha256Hex(s string) string {
+	hash := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(hash[:])
+}
+
+func codeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func TestOAuth_Authorize(t *testing.T) {
+	type mockBehavior func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager)
+
+	client := AuthService.OAuthClient{ID: "client", Name: "Shop", RedirectURIs: "https://shop.test/cb https://shop.test/other"}
+	validRequest := AuthService.AuthorizeRequest{
+		ResponseType:        "code",
+		ClientID:            "client",
+		RedirectURI:         "https://shop.test/cb",
+		Scope:               "openid email openid",
+		State:               "xyz",
+		Nonce:               "nonce",
+		CodeChallenge:       "challenge",
+		CodeChallengeMethod: "S256",
+	}
+
+	testTable := []struct {
+		name             string
+		inputRequest     func() AuthService.AuthorizeRequest
+		mockBehavior     mockBehavior
+		expectedRedirect string
+		expectedError    error
+	}{
+		{
+			name:         "OK",
+			inputRequest: func() AuthService.AuthorizeRequest { return validRequest },
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				r.EXPECT().GetOAuthClient("client").Return(client, nil)
+				r.EXPECT().GetUserByID(1).Return(AuthService.User{ID: 1}, nil)
+				tm.EXPECT().NewRefreshToken().Return("code", nil)
+				redisMock.EXPECT().SaveAuthorizationCode(sha256Hex("code"), AuthService.AuthorizationCode{
+					UserID:        1,
+					ClientID:      "client",
+					RedirectURI:   "https://shop.test/cb",
+					Scope:         "openid email",
+					CodeChallenge: "challenge",
+					Nonce:         "nonce",
+				}, time.Minute).Return(nil)
+			},
+			expectedRedirect: "https://shop.test/cb?code=code&state=xyz",
+			expectedError:    nil,
+		},
+		{
+			name: "Unknown Client",
+			inputRequest: func() AuthService.AuthorizeRequest {
+				req := validRequest
+				req.ClientID = "unknown"
+				return req
+			},
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				r.EXPECT().GetOAuthClient("unknown").Return(AuthService.OAuthClient{}, AuthService.ErrOAuthClientNotFound)
+			},
+			expectedRedirect: "",
+			expectedError:    &AuthService.OAuthError{Code: AuthService.OAuthInvalidRequest, Description: "client_id is unknown"},
+		},
+		{
+			name: "Unregistered Redirect URI",
+			inputRequest: func() AuthService.AuthorizeRequest {
+				req := validRequest
+				req.RedirectURI = "https://evil.test/cb"
+				return req
+			},
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				r.EXPECT().GetOAuthClient("client").Return(client, nil)
+			},
+			expectedRedirect: "",
+			expectedError:    &AuthService.OAuthError{Code: AuthService.OAuthInvalidRequest, Description: "redirect_uri is not registered for the client"},
+		},
+		{
+			name: "No PKCE",
+			inputRequest: func() AuthService.AuthorizeRequest {
+				req := validRequest
+				req.CodeChallengeMethod = "plain"
+				return req
+			},
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				r.EXPECT().GetOAuthClient("client").Return(client, nil)
+			},
+			expectedRedirect: "https://shop.test/cb?error=invalid_request&error_description=code_challenge+with+the+S256+method+is+required&state=xyz",
+			expectedError:    nil,
+		},
+		{
+			name: "Unsupported Scope",
+			inputRequest: func() AuthService.AuthorizeRequest {
+				req := validRequest
+				req.Scope = "openid admin"
+				return req
+			},
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				r.EXPECT().GetOAuthClient("client").Return(client, nil)
+			},
+			expectedRedirect: "https://shop.test/cb?error=invalid_scope&error_description=supported+scopes+are+openid+profile+email&state=xyz",
+			expectedError:    nil,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+			authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+			tokenManager := mockauthservice.NewMockTokenManager(c)
+			testCase.mockBehavior(authPostgresRepo, authRedisRepo, tokenManager)
+
+			auth := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, tokenManager, nil, testPasswordHasher, AuthService.PasswordPolicy{})
+			service := AuthService.NewOAuthService(auth, "https://auth.test/")
+
+			redirect, err := service.Authorize(1, testCase.inputRequest())
+
+			assert.Equal(t, redirect, testCase.expectedRedirect)
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
+
+func TestOAuth_TokenAuthorizationCode(t *testing.T) {
+	type mockBehavior func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager)
+
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	client := AuthService.OAuthClient{ID: "client", Name: "Shop", SecretHash: sha256Hex("secret"), RedirectURIs: "https://shop.test/cb"}
+	code := AuthService.AuthorizationCode{
+		UserID:        1,
+		ClientID:      "client",
+		RedirectURI:   "https://shop.test/cb",
+		Scope:         "openid email",
+		CodeChallenge: codeChallenge(verifier),
+		Nonce:         "nonce",
+	}
+	verifiedAt := time.Now()
+	user := AuthService.User{ID: 1, UserName: "test", Email: "test@test.com", EmailVerifiedAt: &verifiedAt}
+	validRequest := AuthService.TokenRequest{
+		GrantType:    "authorization_code",
+		Code:         "code",
+		RedirectURI:  "https://shop.test/cb",
+		CodeVerifier: verifier,
+		ClientID:     "client",
+		ClientSecret: "secret",
+	}
+
+	testTable := []struct {
+		name           string
+		inputRequest   func() AuthService.TokenRequest
+		mockBehavior   mockBehavior
+		expectedTokens AuthService.OAuthTokens
+		expectedError  error
+	}{
+		{
+			name:         "OK",
+			inputRequest: func() AuthService.TokenRequest { return validRequest },
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				r.EXPECT().GetOAuthClient("client").Return(client, nil)
+				redisMock.EXPECT().TakeAuthorizationCode(sha256Hex("code")).Return(code, nil)
+				r.EXPECT().GetUserByID(1).Return(user, nil)
+				tm.EXPECT().NewClientJWT(user, "client", "openid email", 2*time.Hour).Return("access_token", nil)
+				tm.EXPECT().NewRefreshToken().Return("refresh_token", nil)
+				r.EXPECT().SaveRefreshToken(gomock.Any()).DoAndReturn(func(token AuthService.RefreshToken) error {
+					if token.ClientID == nil || *token.ClientID != "client" || token.Scope != "openid email" || token.DeviceLabel != "Shop" {
+						return errors.New("refresh token isn't bound to the client")
+					}
+					return nil
+				})
+				redisMock.EXPECT().AddUserWithRefreshToken(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+				tm.EXPECT().NewIDToken(gomock.Any()).DoAndReturn(func(claims AuthService.IDTokenClaims) (string, error) {
+					if claims.Issuer != "https://auth.test" || claims.Audience != "client" || claims.Subject != "1" ||
+						claims.Nonce != "nonce" || claims.Email != "test@test.com" || claims.Name != "" {
+						return "", errors.New("unexpected id token claims")
+					}
+					return "id_token", nil
+				})
+			},
+			expectedTokens: AuthService.OAuthTokens{
+				AccessToken:  "access_token",
+				TokenType:    "Bearer",
+				ExpiresIn:    7200,
+				RefreshToken: "refresh_token",
+				IDToken:      "id_token",
+				Scope:        "openid email",
+			},
+			expectedError: nil,
+		},
+		{
+			name: "Wrong Secret",
+			inputRequest: func() AuthService.TokenRequest {
+				req := validRequest
+				req.ClientSecret = "wrong"
+				return req
+			},
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				r.EXPECT().GetOAuthClient("client").Return(client, nil)
+			},
+			expectedTokens: AuthService.OAuthTokens{},
+			expectedError:  &AuthService.OAuthError{Code: AuthService.OAuthInvalidClient, Description: "client authentication failed"},
+		},
+		{
+			name: "Wrong Verifier",
+			inputRequest: func() AuthService.TokenRequest {
+				req := validRequest
+				req.CodeVerifier = "wrong-verifier-wrong-verifier-wrong-verifier"
+				return req
+			},
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				r.EXPECT().GetOAuthClient("client").Return(client, nil)
+				redisMock.EXPECT().TakeAuthorizationCode(sha256Hex("code")).Return(code, nil)
+			},
+			expectedTokens: AuthService.OAuthTokens{},
+			expectedError:  &AuthService.OAuthError{Code: AuthService.OAuthInvalidGrant, Description: "code_verifier doesn't match code_challenge"},
+		},
+		{
+			name: "Other Redirect URI",
+			inputRequest: func() AuthService.TokenRequest {
+				req := validRequest
+				req.RedirectURI = "https://shop.test/other"
+				return req
+			},
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				r.EXPECT().GetOAuthClient("client").Return(client, nil)
+				redisMock.EXPECT().TakeAuthorizationCode(sha256Hex("code")).Return(code, nil)
+			},
+			expectedTokens: AuthService.OAuthTokens{},
+			expectedError:  &AuthService.OAuthError{Code: AuthService.OAuthInvalidGrant, Description: "code was issued to another client or redirect_uri"},
+		},
+		{
+			name:         "Code Already Used",
+			inputRequest: func() AuthService.TokenRequest { return validRequest },
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				r.EXPECT().GetOAuthClient("client").Return(client, nil)
+				redisMock.EXPECT().TakeAuthorizationCode(sha256Hex("code")).Return(AuthService.AuthorizationCode{}, AuthService.ErrAuthorizationCodeInvalid)
+			},
+			expectedTokens: AuthService.OAuthTokens{},
+			expectedError:  &AuthService.OAuthError{Code: AuthService.OAuthInvalidGrant, Description: AuthService.ErrAuthorizationCodeInvalid.Error()},
+		},
+		{
+			name: "Unsupported Grant",
+			inputRequest: func() AuthService.TokenRequest {
+				req := validRequest
+				req.GrantType = "password"
+				return req
+			},
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				r.EXPECT().GetOAuthClient("client").Return(client, nil)
+			},
+			expectedTokens: AuthService.OAuthTokens{},
+			expectedError:  &AuthService.OAuthError{Code: AuthService.OAuthUnsupportedGrantType, Description: "supported grant types are authorization_code and refresh_token"},
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+			authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+			tokenManager := mockauthservice.NewMockTokenManager(c)
+			testCase.mockBehavior(authPostgresRepo, authRedisRepo, tokenManager)
+
+			auth := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, tokenManager, nil, testPasswordHasher, AuthService.PasswordPolicy{})
+			service := AuthService.NewOAuthService(auth, "https://auth.test/")
+
+			tokens, err := service.Token(testCase.inputRequest(), AuthService.ClientInfo{})
+
+			assert.Equal(t, tokens, testCase.expectedTokens)
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
+
+func TestOAuth_UserInfo(t *testing.T) {
+	type mockBehavior func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager)
+
+	verified := false
+
+	testTable := []struct {
+		name             string
+		mockBehavior     mockBehavior
+		expectedUserInfo AuthService.UserInfo
+		expectedError    error
+	}{
+		{
+			name: "OK",
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				info := AuthService.InfoFromToken{ID: 1, TokenID: "jti", ClientID: "client", Scope: "openid email"}
+				tm.EXPECT().Parse("token").Return(info, nil)
+				redisMock.EXPECT().IsAccessTokenRevoked("jti", 1, gomock.Any()).Return(false, nil)
+				r.EXPECT().GetUserByID(1).Return(AuthService.User{ID: 1, UserName: "test", Email: "test@test.com"}, nil)
+			},
+			expectedUserInfo: AuthService.UserInfo{Sub: "1", Email: "test@test.com", EmailVerified: &verified},
+			expectedError:    nil,
+		},
+		{
+			name: "First-party Token",
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				tm.EXPECT().Parse("token").Return(AuthService.InfoFromToken{ID: 1, TokenID: "jti"}, nil)
+				redisMock.EXPECT().IsAccessTokenRevoked("jti", 1, gomock.Any()).Return(false, nil)
+			},
+			expectedUserInfo: AuthService.UserInfo{},
+			expectedError:    &AuthService.OAuthError{Code: AuthService.OAuthInsufficientScope, Description: "the openid scope is required"},
+		},
+		{
+			name: "Invalid Token",
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				tm.EXPECT().Parse("token").Return(AuthService.InfoFromToken{}, errors.New("token is expired"))
+			},
+			expectedUserInfo: AuthService.UserInfo{},
+			expectedError:    &AuthService.OAuthError{Code: AuthService.OAuthInvalidToken, Description: "access token is invalid or expired"},
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+			authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+			tokenManager := mockauthservice.NewMockTokenManager(c)
+			testCase.mockBehavior(authPostgresRepo, authRedisRepo, tokenManager)
+
+			auth := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, tokenManager, nil, testPasswordHasher, AuthService.PasswordPolicy{})
+			service := AuthService.NewOAuthService(auth, "https://auth.test")
+
+			info, err := service.UserInfo("token")
+
+			assert.Equal(t, info, testCase.expectedUserInfo)
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package AuthService_test
+
+import (
+	"errors"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/jst-Frenzy/ControlSystem/AuthService/internal/AuthService"
+	mockauthservice "github.com/jst-Frenzy/ControlSystem/AuthService/internal/mocks"
+	"golang.org/x/crypto/bcrypt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testPasswordHasher matches the bcrypt hashes the service tests make, so signing in never rehashes.
+var testPasswordHasher = AuthService.NewBcryptHasher(bcrypt.DefaultCost)
+
+// testArgon2idParams keep the tests fast, they are far too cheap for real passwords.
+var testArgon2idParams = AuthService.Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestPasswordHasher_Argon2id(t *testing.T) {
+	hasher := AuthService.NewArgon2idHasher(testArgon2idParams)
+
+	hash, err := hasher.Hash("qwerty")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$"), true)
+
+	assert.Equal(t, hasher.Supports(hash), true)
+	assert.Equal(t, hasher.Verify("qwerty", hash), true)
+	assert.Equal(t, hasher.Verify("wrong", hash), false)
+	assert.Equal(t, hasher.NeedsRehash(hash), false)
+
+	stronger := testArgon2idParams
+	stronger.Iterations = 2
+	assert.Equal(t, AuthService.NewArgon2idHasher(stronger).NeedsRehash(hash), true)
+
+	otherHash, _ := hasher.Hash("qwerty")
+	assert.NotEqual(t, otherHash, hash)
+}
+
+func TestPasswordHasher_Migrating(t *testing.T) {
+	bcryptHash, _ := bcrypt.GenerateFromPassword([]byte("qwerty"), bcrypt.MinCost)
+
+	hasher := AuthService.NewPasswordHasher(AuthService.NewArgon2idHasher(testArgon2idParams), AuthService.NewBcryptHasher(bcrypt.MinCost))
+
+	assert.Equal(t, hasher.Verify("qwerty", string(bcryptHash)), true)
+	assert.Equal(t, hasher.Verify("wrong", string(bcryptHash)), false)
+	assert.Equal(t, hasher.NeedsRehash(string(bcryptHash)), true)
+
+	hash, err := hasher.Hash("qwerty")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, strings.HasPrefix(hash, "$argon2id$"), true)
+	assert.Equal(t, hasher.NeedsRehash(hash), false)
+
+	// users signed up through an identity provider have no password
+	assert.Equal(t, hasher.Verify("", ""), false)
+}
+
+func TestPasswordPolicy_Validate(t *testing.T) {
+	breachedList := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(breachedList, []byte("password\n\n12345678\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	policy, err := AuthService.NewPasswordPolicy(8, 16, breachedList)
+	assert.Equal(t, err, nil)
+
+	testTable := []struct {
+		name          string
+		inputPassword string
+		expectedError string
+	}{
+		{
+			name:          "OK",
+			inputPassword: "correct horse",
+			expectedError: "",
+		},
+		{
+			name:          "Multibyte Characters",
+			inputPassword: "пароль12",
+			expectedError: "",
+		},
+		{
+			name:          "Too Short",
+			inputPassword: "qwerty",
+			expectedError: "password is too weak: it must be at least 8 characters long",
+		},
+		{
+			name:          "Too Long",
+			inputPassword: "correct horse battery staple",
+			expectedError: "password is too weak: it must be at most 16 characters long",
+		},
+		{
+			name:          "Breached",
+			inputPassword: "12345678",
+			expectedError: "password is too weak: it appears in a list of breached passwords",
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			errValidate := policy.Validate(testCase.inputPassword)
+
+			if testCase.expectedError == "" {
+				assert.Equal(t, errValidate, nil)
+				return
+			}
+			assert.Equal(t, errors.Is(errValidate, AuthService.ErrWeakPassword), true)
+			assert.Equal(t, errValidate.Error(), testCase.expectedError)
+		})
+	}
+}
+
+// bcrypt fails on passwords over 72 bytes, 42 Cyrillic letters are 84 of them but within the length in characters.
+func TestNewPasswordPolicyFromEnv_Bcrypt(t *testing.T) {
+	t.Setenv("PASSWORD_HASHER", AuthService.PasswordHasherBcrypt)
+	t.Setenv("BREACHED_PASSWORDS_FILE", "")
+
+	policy, err := AuthService.NewPasswordPolicyFromEnv()
+	assert.Equal(t, err, nil)
+
+	errValidate := policy.Validate(strings.Repeat("пароль", 7))
+	assert.Equal(t, errors.Is(errValidate, AuthService.ErrWeakPassword), true)
+	assert.Equal(t, policy.Validate(strings.Repeat("password", 5)), nil)
+}
+
+func TestService_SignInRehashesPassword(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	bcryptHash, _ := bcrypt.GenerateFromPassword([]byte("qwerty"), bcrypt.MinCost)
+	verifiedAt := time.Now()
+	user := AuthService.User{ID: 1, Email: "test@test.com", PasswordHash: string(bcryptHash), Role: AuthService.RoleUser, EmailVerifiedAt: &verifiedAt}
+
+	authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+	authPostgresRepo.EXPECT().AppendAuditEvent(gomock.Any()).Return(nil).AnyTimes()
+	authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+	tokenManager := mockauthservice.NewMockTokenManager(c)
+
+	authRedisRepo.EXPECT().GetLoginLock(gomock.Any()).Return(time.Duration(0), nil).AnyTimes()
+	authRedisRepo.EXPECT().DeleteLoginFailures(gomock.Any()).Return(nil).AnyTimes()
+	authRedisRepo.EXPECT().GetUserWithEmail("test@test.com").Return(user, nil)
+	authPostgresRepo.EXPECT().UpdatePassword(1, gomock.Any()).DoAndReturn(func(userID int, passwordHash string) error {
+		if !strings.HasPrefix(passwordHash, "$argon2id$") {
+			return errors.New("password isn't rehashed with argon2id")
+		}
+		return nil
+	})
+	// the cached user must not keep the old hash
+	authRedisRepo.EXPECT().InvalidateUser(1).Return(nil)
+	authRedisRepo.EXPECT().AddUserWithRefreshToken(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	authPostgresRepo.EXPECT().SaveRefreshToken(gomock.Any()).Return(nil)
+	tokenManager.EXPECT().NewJWT(gomock.Any(), gomock.Any()).Return("access", nil)
+	tokenManager.EXPECT().NewRefreshToken().Return("refresh", nil)
+
+	hasher := AuthService.NewPasswordHasher(AuthService.NewArgon2idHasher(testArgon2idParams), AuthService.NewBcryptHasher(bcrypt.MinCost))
+	service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, tokenManager, nil, hasher, AuthService.PasswordPolicy{})
+
+	tokens, err := service.SignIn(AuthService.UserSignIn{Email: "test@test.com", Password: "qwerty"}, AuthService.ClientInfo{})
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, tokens.AccessToken, "access")
+}
+
+func TestService_ChangePasswordPolicy(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("qwerty"), bcrypt.DefaultCost)
+
+	authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+	authPostgresRepo.EXPECT().AppendAuditEvent(gomock.Any()).Return(nil).AnyTimes()
+	authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+	authPostgresRepo.EXPECT().GetUserByID(1).Return(AuthService.User{ID: 1, PasswordHash: string(hashedPassword)}, nil)
+
+	service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, nil, nil, testPasswordHasher, AuthService.PasswordPolicy{MinLength: 8})
+
+	err := service.ChangePassword(1, "qwerty", "short", "", AuthService.ClientInfo{})
+
+	assert.Equal(t, errors.Is(err, AuthService.ErrWeakPassword), true)
+}
@@ -78,7 +78,7 @@ func TestPostgresRep_CreateUser(t *testing.T) {
 					AddRow(expectedId, args.item.UserName, args.item.Email, args.item.PasswordHash, args.item.Role,
 						args.item.CreatedAt, args.item.UpdatedAt)
 				mock.ExpectQuery(`^INSERT INTO "users"`).
-					WithArgs(args.item.UserName, args.item.Email, args.item.PasswordHash, args.item.Role, sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnRows(rows)
+					WithArgs(args.item.UserName, args.item.Email, args.item.PasswordHash, args.item.Role, sqlmock.AnyArg(), sqlmock.AnyArg(), nil, false, nil).WillReturnRows(rows)
 
 				mock.ExpectCommit()
 			},
@@ -99,7 +99,7 @@ func TestPostgresRep_CreateUser(t *testing.T) {
 
 				rows := sqlmock.NewRows([]string{"id"}).AddRow(id).RowError(1, errors.New("some error"))
 				mock.ExpectQuery(`^INSERT INTO "users"`).
-					WithArgs(args.item.UserName, args.item.Email, args.item.PasswordHash, args.item.Role, sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnRows(rows)
+					WithArgs(args.item.UserName, args.item.Email, args.item.PasswordHash, args.item.Role, sqlmock.AnyArg(), sqlmock.AnyArg(), nil, false, nil).WillReturnRows(rows)
 
 				mock.ExpectRollback()
 			},
@@ -166,6 +166,7 @@ func TestPostgresRep_SaveRefreshToken(t *testing.T) {
 				item: AuthService.RefreshToken{
 					UserID:    1,
 					TokenHash: "qwerty",
+					FamilyID:  "family",
 					ExpiresAt: time.Now().Add(5 * time.Second),
 				},
 			},
@@ -174,7 +175,7 @@ func TestPostgresRep_SaveRefreshToken(t *testing.T) {
 
 				rows := sqlmock.NewRows([]string{"id"}).AddRow(1)
 				mock.ExpectQuery(`^INSERT INTO "refresh_tokens"`).
-					WithArgs(1, "qwerty", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnRows(rows)
+					WithArgs(1, "qwerty", "family", "", "", "", nil, "", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), nil, sqlmock.AnyArg()).WillReturnRows(rows)
 				mock.ExpectCommit()
 			},
 		},
@@ -190,7 +191,7 @@ func TestPostgresRep_SaveRefreshToken(t *testing.T) {
 
 				rows := sqlmock.NewRows([]string{"id"}).AddRow(id).RowError(1, errors.New("some error"))
 				mock.ExpectQuery(`^INSERT INTO "refresh_tokens"`).
-					WithArgs(args.item.UserID, args.item.TokenHash, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnRows(rows)
+					WithArgs(args.item.UserID, args.item.TokenHash, args.item.FamilyID, "", "", "", nil, "", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), nil, sqlmock.AnyArg()).WillReturnRows(rows)
 
 				mock.ExpectRollback()
 			},
@@ -383,3 +384,809 @@ func TestPostgresRep_GetUserByRefreshToken(t *testing.T) {
 		})
 	}
 }
+
+func TestPostgresRep_DeleteRefreshToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	defer func() {
+		if errClose := db.Close(); errClose != nil {
+			logrus.Error("can't close db")
+		}
+	}()
+
+	gormDB, errGorm := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+
+	if errGorm != nil {
+		logrus.Fatal(errGorm)
+	}
+
+	r := AuthService.NewAuthPostgresRepo(gormDB)
+
+	testTable := []struct {
+		name          string
+		mock          func()
+		refreshToken  string
+		expectedError error
+	}{
+		{
+			name: "OK",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(`^DELETE FROM "refresh_tokens" WHERE token_hash = .*`).
+					WithArgs("refreshToken").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			refreshToken: "refreshToken",
+		},
+		{
+			name: "Not Found",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(`^DELETE FROM "refresh_tokens" WHERE token_hash = .*`).
+					WithArgs("notFoundRefreshToken").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+			},
+			refreshToken:  "notFoundRefreshToken",
+			expectedError: AuthService.ErrRefreshTokenNotFound,
+		},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			testCase.mock()
+
+			errDelete := r.DeleteRefreshToken(testCase.refreshToken)
+
+			assert.Equal(t, testCase.expectedError, errDelete)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPostgresRep_RevokeRefreshToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	defer func() {
+		if errClose := db.Close(); errClose != nil {
+			logrus.Error("can't close db")
+		}
+	}()
+
+	gormDB, errGorm := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+
+	if errGorm != nil {
+		logrus.Fatal(errGorm)
+	}
+
+	r := AuthService.NewAuthPostgresRepo(gormDB)
+
+	testTable := []struct {
+		name         string
+		mock         func()
+		refreshToken string
+		want         bool
+	}{
+		{
+			name: "Rotated",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(`^UPDATE "refresh_tokens" SET "revoked_at"=.* WHERE token_hash = .* AND revoked_at IS NULL`).
+					WithArgs(sqlmock.AnyArg(), "refreshToken").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			refreshToken: "refreshToken",
+			want:         true,
+		},
+		{
+			name: "Already Revoked",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(`^UPDATE "refresh_tokens" SET "revoked_at"=.* WHERE token_hash = .* AND revoked_at IS NULL`).
+					WithArgs(sqlmock.AnyArg(), "revokedRefreshToken").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+			},
+			refreshToken: "revokedRefreshToken",
+			want:         false,
+		},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			testCase.mock()
+
+			got, errRevoke := r.RevokeRefreshToken(testCase.refreshToken)
+
+			assert.NoError(t, errRevoke)
+			assert.Equal(t, testCase.want, got)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPostgresRep_ChangeRole(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	defer func() {
+		if errClose := db.Close(); errClose != nil {
+			logrus.Error("can't close db")
+		}
+	}()
+
+	gormDB, errGorm := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+
+	if errGorm != nil {
+		logrus.Fatal(errGorm)
+	}
+
+	r := AuthService.NewAuthPostgresRepo(gormDB)
+
+	actorID := 1
+
+	testTable := []struct {
+		name    string
+		mock    func()
+		input   AuthService.RoleChange
+		want    AuthService.User
+		wantErr error
+	}{
+		{
+			name: "OK",
+			mock: func() {
+				rows := sqlmock.NewRows([]string{"id", "user_name", "email", "password_hash", "role"}).
+					AddRow(2, "test", "test@test.com", "qwerty", "user")
+
+				mock.ExpectBegin()
+				mock.ExpectQuery(`^SELECT \* FROM "users" WHERE id = .* FOR UPDATE`).
+					WithArgs(2, 1).WillReturnRows(rows)
+				mock.ExpectExec(`^UPDATE "users" SET "role"=.*,"updated_at"=.* WHERE id = .*`).
+					WithArgs("seller", sqlmock.AnyArg(), 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(`^INSERT INTO "role_changes"`).
+					WithArgs(&actorID, 2, "user", "seller", sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectCommit()
+			},
+			input: AuthService.RoleChange{ActorID: &actorID, TargetID: 2, NewRole: "seller"},
+			want:  AuthService.User{ID: 2, UserName: "test", Email: "test@test.com", PasswordHash: "qwerty", Role: "seller"},
+		},
+		{
+			name: "User Not Found",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`^SELECT \* FROM "users" WHERE id = .* FOR UPDATE`).
+					WithArgs(3, 1).WillReturnError(gorm.ErrRecordNotFound)
+				mock.ExpectRollback()
+			},
+			input:   AuthService.RoleChange{ActorID: &actorID, TargetID: 3, NewRole: "seller"},
+			wantErr: AuthService.ErrUserNotFound,
+		},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			testCase.mock()
+
+			got, errChange := r.ChangeRole(testCase.input)
+			if testCase.wantErr != nil {
+				assert.ErrorIs(t, errChange, testCase.wantErr)
+			} else {
+				assert.NoError(t, errChange)
+				assert.Equal(t, testCase.want.ID, got.ID)
+				assert.Equal(t, testCase.want.Email, got.Email)
+				assert.Equal(t, testCase.want.Role, got.Role)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPostgresRep_VerifyEmail(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	defer func() {
+		if errClose := db.Close(); errClose != nil {
+			logrus.Error("can't close db")
+		}
+	}()
+
+	gormDB, errGorm := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+
+	if errGorm != nil {
+		logrus.Fatal(errGorm)
+	}
+
+	r := AuthService.NewAuthPostgresRepo(gormDB)
+
+	verificationColumns := []string{"id", "user_id", "email", "token_hash", "expires_at", "used_at"}
+
+	testTable := []struct {
+		name    string
+		mock    func()
+		input   string
+		want    AuthService.User
+		wantErr error
+	}{
+		{
+			name: "OK",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`^SELECT \* FROM "email_verifications" WHERE token_hash = .* FOR UPDATE`).
+					WithArgs("hash", 1).
+					WillReturnRows(sqlmock.NewRows(verificationColumns).AddRow(1, 2, "test@test.com", "hash", time.Now().Add(time.Hour), nil))
+				mock.ExpectExec(`^UPDATE "email_verifications" SET "used_at"=.* WHERE id = .*`).
+					WithArgs(sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`^UPDATE "users" SET "email_verified_at"=.*,"updated_at"=.* WHERE id = .* AND email = .*`).
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 2, "test@test.com").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(`^SELECT \* FROM "users" WHERE id = .*`).
+					WithArgs(2, 1).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "email", "role"}).AddRow(2, "test@test.com", "user"))
+				mock.ExpectCommit()
+			},
+			input: "hash",
+			want:  AuthService.User{ID: 2, Email: "test@test.com", Role: "user"},
+		},
+		{
+			name: "Already Used",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`^SELECT \* FROM "email_verifications" WHERE token_hash = .* FOR UPDATE`).
+					WithArgs("usedHash", 1).
+					WillReturnRows(sqlmock.NewRows(verificationColumns).AddRow(1, 2, "test@test.com", "usedHash", time.Now().Add(time.Hour), time.Now()))
+				mock.ExpectRollback()
+			},
+			input:   "usedHash",
+			wantErr: AuthService.ErrVerificationTokenInvalid,
+		},
+		{
+			name: "Email Changed",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`^SELECT \* FROM "email_verifications" WHERE token_hash = .* FOR UPDATE`).
+					WithArgs("oldHash", 1).
+					WillReturnRows(sqlmock.NewRows(verificationColumns).AddRow(1, 2, "old@test.com", "oldHash", time.Now().Add(time.Hour), nil))
+				mock.ExpectExec(`^UPDATE "email_verifications" SET "used_at"=.* WHERE id = .*`).
+					WithArgs(sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`^UPDATE "users" SET "email_verified_at"=.*,"updated_at"=.* WHERE id = .* AND email = .*`).
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 2, "old@test.com").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			input:   "oldHash",
+			wantErr: AuthService.ErrVerificationTokenInvalid,
+		},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			testCase.mock()
+
+			got, errVerify := r.VerifyEmail(testCase.input)
+			if testCase.wantErr != nil {
+				assert.ErrorIs(t, errVerify, testCase.wantErr)
+			} else {
+				assert.NoError(t, errVerify)
+				assert.Equal(t, testCase.want.ID, got.ID)
+				assert.Equal(t, testCase.want.Email, got.Email)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPostgresRep_ResetPassword(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	defer func() {
+		if errClose := db.Close(); errClose != nil {
+			logrus.Error("can't close db")
+		}
+	}()
+
+	gormDB, errGorm := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+
+	if errGorm != nil {
+		logrus.Fatal(errGorm)
+	}
+
+	r := AuthService.NewAuthPostgresRepo(gormDB)
+
+	resetColumns := []string{"id", "user_id", "token_hash", "expires_at", "used_at"}
+
+	testTable := []struct {
+		name    string
+		mock    func()
+		input   string
+		want    AuthService.User
+		wantErr error
+	}{
+		{
+			name: "OK",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`^SELECT \* FROM "password_resets" WHERE token_hash = .* FOR UPDATE`).
+					WithArgs("hash", 1).
+					WillReturnRows(sqlmock.NewRows(resetColumns).AddRow(1, 2, "hash", time.Now().Add(time.Hour), nil))
+				mock.ExpectExec(`^UPDATE "password_resets" SET "used_at"=.* WHERE user_id = .* AND used_at IS NULL`).
+					WithArgs(sqlmock.AnyArg(), 2).WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec(`^UPDATE "users" SET "password_hash"=.*,"updated_at"=.* WHERE id = .*`).
+					WithArgs("newHash", sqlmock.AnyArg(), 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(`^SELECT \* FROM "users" WHERE id = .*`).
+					WithArgs(2, 1).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password_hash"}).AddRow(2, "test@test.com", "newHash"))
+				mock.ExpectCommit()
+			},
+			input: "hash",
+			want:  AuthService.User{ID: 2, Email: "test@test.com", PasswordHash: "newHash"},
+		},
+		{
+			name: "Expired",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`^SELECT \* FROM "password_resets" WHERE token_hash = .* FOR UPDATE`).
+					WithArgs("expiredHash", 1).
+					WillReturnRows(sqlmock.NewRows(resetColumns).AddRow(1, 2, "expiredHash", time.Now().Add(-time.Minute), nil))
+				mock.ExpectRollback()
+			},
+			input:   "expiredHash",
+			wantErr: AuthService.ErrResetTokenInvalid,
+		},
+		{
+			name: "Not Found",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`^SELECT \* FROM "password_resets" WHERE token_hash = .* FOR UPDATE`).
+					WithArgs("unknownHash", 1).WillReturnError(gorm.ErrRecordNotFound)
+				mock.ExpectRollback()
+			},
+			input:   "unknownHash",
+			wantErr: AuthService.ErrResetTokenInvalid,
+		},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			testCase.mock()
+
+			got, errReset := r.ResetPassword(testCase.input, "newHash")
+			if testCase.wantErr != nil {
+				assert.ErrorIs(t, errReset, testCase.wantErr)
+			} else {
+				assert.NoError(t, errReset)
+				assert.Equal(t, testCase.want.ID, got.ID)
+				assert.Equal(t, testCase.want.PasswordHash, got.PasswordHash)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPostgresRep_DeleteSession(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	defer func() {
+		if errClose := db.Close(); errClose != nil {
+			logrus.Error("can't close db")
+		}
+	}()
+
+	gormDB, errGorm := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+
+	if errGorm != nil {
+		logrus.Fatal(errGorm)
+	}
+
+	r := AuthService.NewAuthPostgresRepo(gormDB)
+
+	testTable := []struct {
+		name          string
+		mock          func()
+		sessionID     string
+		want          []string
+		expectedError error
+	}{
+		{
+			name: "OK",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`^SELECT "token_hash" FROM "refresh_tokens" WHERE user_id = .* AND family_id = .*`).
+					WithArgs(1, "family").WillReturnRows(sqlmock.NewRows([]string{"token_hash"}).AddRow("hash1").AddRow("hash2"))
+				mock.ExpectExec(`^DELETE FROM "refresh_tokens" WHERE user_id = .* AND family_id = .*`).
+					WithArgs(1, "family").WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectCommit()
+			},
+			sessionID:     "family",
+			want:          []string{"hash1", "hash2"},
+			expectedError: nil,
+		},
+		{
+			name: "Foreign Session",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`^SELECT "token_hash" FROM "refresh_tokens" WHERE user_id = .* AND family_id = .*`).
+					WithArgs(1, "foreign").WillReturnRows(sqlmock.NewRows([]string{"token_hash"}))
+				mock.ExpectRollback()
+			},
+			sessionID:     "foreign",
+			want:          nil,
+			expectedError: AuthService.ErrSessionNotFound,
+		},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			testCase.mock()
+
+			got, errDelete := r.DeleteSession(1, testCase.sessionID)
+
+			assert.Equal(t, testCase.expectedError, errDelete)
+			assert.Equal(t, testCase.want, got)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPostgresRep_ListSessions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	defer func() {
+		if errClose := db.Close(); errClose != nil {
+			logrus.Error("can't close db")
+		}
+	}()
+
+	gormDB, errGorm := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+
+	if errGorm != nil {
+		logrus.Fatal(errGorm)
+	}
+
+	r := AuthService.NewAuthPostgresRepo(gormDB)
+
+	createdAt := time.Date(2026, 2, 4, 10, 0, 0, 0, time.UTC)
+	lastUsedAt := createdAt.Add(time.Hour)
+	expiresAt := createdAt.Add(720 * time.Hour)
+
+	rows := sqlmock.NewRows([]string{"id", "device_label", "user_agent", "ip", "last_used_at", "expires_at", "created_at"}).
+		AddRow("family", "Firefox on Linux", "Mozilla/5.0 (X11; Linux x86_64) Firefox/130.0", "192.0.2.1", lastUsedAt, expiresAt, createdAt)
+	mock.ExpectQuery(`^SELECT t.family_id AS id, .* FROM refresh_tokens AS t WHERE t.user_id = .* AND t.revoked_at IS NULL AND t.expires_at > .* ORDER BY t.last_used_at DESC`).
+		WithArgs(1, sqlmock.AnyArg()).WillReturnRows(rows)
+
+	got, errList := r.ListSessions(1)
+
+	assert.NoError(t, errList)
+	assert.Equal(t, []AuthService.Session{{
+		ID:          "family",
+		DeviceLabel: "Firefox on Linux",
+		UserAgent:   "Mozilla/5.0 (X11; Linux x86_64) Firefox/130.0",
+		IP:          "192.0.2.1",
+		CreatedAt:   createdAt,
+		LastUsedAt:  lastUsedAt,
+		ExpiresAt:   expiresAt,
+	}}, got)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresRep_ConfirmEmailChange(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	defer func() {
+		if errClose := db.Close(); errClose != nil {
+			logrus.Error("can't close db")
+		}
+	}()
+
+	gormDB, errGorm := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+
+	if errGorm != nil {
+		logrus.Fatal(errGorm)
+	}
+
+	r := AuthService.NewAuthPostgresRepo(gormDB)
+
+	changeColumns := []string{"id", "user_id", "new_email", "token_hash", "expires_at", "used_at"}
+
+	testTable := []struct {
+		name         string
+		mock         func()
+		input        string
+		want         AuthService.User
+		wantOldEmail string
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`^SELECT \* FROM "email_changes" WHERE token_hash = .* FOR UPDATE`).
+					WithArgs("hash", 1).
+					WillReturnRows(sqlmock.NewRows(changeColumns).AddRow(1, 2, "new@test.com", "hash", time.Now().Add(time.Hour), nil))
+				mock.ExpectQuery(`^SELECT count\(\*\) FROM "users" WHERE email = .* AND id <> .*`).
+					WithArgs("new@test.com", 2).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+				mock.ExpectQuery(`^SELECT \* FROM "users" WHERE id = .* FOR UPDATE`).
+					WithArgs(2, 1).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "email", "role"}).AddRow(2, "old@test.com", "user"))
+				mock.ExpectExec(`^UPDATE "email_changes" SET "used_at"=.* WHERE user_id = .* AND used_at IS NULL`).
+					WithArgs(sqlmock.AnyArg(), 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`^UPDATE "users" SET "email"=.*,"email_verified_at"=.*,"updated_at"=.* WHERE id = .*`).
+					WithArgs("new@test.com", sqlmock.AnyArg(), sqlmock.AnyArg(), 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			input:        "hash",
+			want:         AuthService.User{ID: 2, Email: "new@test.com", Role: "user"},
+			wantOldEmail: "old@test.com",
+		},
+		{
+			name: "Expired",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`^SELECT \* FROM "email_changes" WHERE token_hash = .* FOR UPDATE`).
+					WithArgs("expiredHash", 1).
+					WillReturnRows(sqlmock.NewRows(changeColumns).AddRow(1, 2, "new@test.com", "expiredHash", time.Now().Add(-time.Hour), nil))
+				mock.ExpectRollback()
+			},
+			input:   "expiredHash",
+			wantErr: AuthService.ErrVerificationTokenInvalid,
+		},
+		{
+			name: "Email Taken",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`^SELECT \* FROM "email_changes" WHERE token_hash = .* FOR UPDATE`).
+					WithArgs("takenHash", 1).
+					WillReturnRows(sqlmock.NewRows(changeColumns).AddRow(1, 2, "taken@test.com", "takenHash", time.Now().Add(time.Hour), nil))
+				mock.ExpectQuery(`^SELECT count\(\*\) FROM "users" WHERE email = .* AND id <> .*`).
+					WithArgs("taken@test.com", 2).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+				mock.ExpectRollback()
+			},
+			input:   "takenHash",
+			wantErr: AuthService.ErrEmailTaken,
+		},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			testCase.mock()
+
+			got, oldEmail, errConfirm := r.ConfirmEmailChange(testCase.input)
+			if testCase.wantErr != nil {
+				assert.ErrorIs(t, errConfirm, testCase.wantErr)
+			} else {
+				assert.NoError(t, errConfirm)
+				assert.Equal(t, testCase.want.ID, got.ID)
+				assert.Equal(t, testCase.want.Email, got.Email)
+				assert.NotNil(t, got.EmailVerifiedAt)
+				assert.Equal(t, testCase.wantOldEmail, oldEmail)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPostgresRep_DeleteUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	defer func() {
+		if errClose := db.Close(); errClose != nil {
+			logrus.Error("can't close db")
+		}
+	}()
+
+	gormDB, errGorm := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+
+	if errGorm != nil {
+		logrus.Fatal(errGorm)
+	}
+
+	r := AuthService.NewAuthPostgresRepo(gormDB)
+
+	testTable := []struct {
+		name          string
+		mock          func()
+		userID        int
+		want          []string
+		expectedError error
+	}{
+		{
+			name: "OK",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`^SELECT "token_hash" FROM "refresh_tokens" WHERE user_id = .*`).
+					WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"token_hash"}).AddRow("hash1"))
+				mock.ExpectExec(`^DELETE FROM "users" WHERE id = .*`).
+					WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			userID:        1,
+			want:          []string{"hash1"},
+			expectedError: nil,
+		},
+		{
+			name: "Not Found",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`^SELECT "token_hash" FROM "refresh_tokens" WHERE user_id = .*`).
+					WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"token_hash"}))
+				mock.ExpectExec(`^DELETE FROM "users" WHERE id = .*`).
+					WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			userID:        2,
+			want:          nil,
+			expectedError: AuthService.ErrUserNotFound,
+		},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			testCase.mock()
+
+			got, errDelete := r.DeleteUser(testCase.userID)
+
+			assert.Equal(t, testCase.expectedError, errDelete)
+			assert.Equal(t, testCase.want, got)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPostgresRep_GetUserByIdentity(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	defer func() {
+		if errClose := db.Close(); errClose != nil {
+			logrus.Error("can't close db")
+		}
+	}()
+
+	gormDB, errGorm := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+
+	if errGorm != nil {
+		logrus.Fatal(errGorm)
+	}
+
+	r := AuthService.NewAuthPostgresRepo(gormDB)
+
+	type args struct {
+		provider string
+		subject  string
+	}
+
+	testTable := []struct {
+		name    string
+		mock    func()
+		input   args
+		want    AuthService.User
+		wantErr error
+	}{
+		{
+			name: "OK",
+			mock: func() {
+				rows := sqlmock.NewRows([]string{"id", "user_name", "email", "password_hash", "role"}).
+					AddRow(1, "test", "test@test.com", "", "user")
+
+				mock.ExpectQuery(`.*WHERE id = \(select user_id from identities where provider = .* and subject = .*`).
+					WithArgs("corp", "sub-1", 1).WillReturnRows(rows)
+			},
+			input: args{provider: "corp", subject: "sub-1"},
+			want: AuthService.User{
+				ID:       1,
+				UserName: "test",
+				Email:    "test@test.com",
+				Role:     "user",
+			},
+		},
+		{
+			name: "Not Linked",
+			mock: func() {
+				mock.ExpectQuery(`.*WHERE id = \(select user_id from identities where provider = .* and subject = .*`).
+					WithArgs("corp", "sub-2", 1).
+					WillReturnError(gorm.ErrRecordNotFound)
+			},
+			input:   args{provider: "corp", subject: "sub-2"},
+			wantErr: AuthService.ErrIdentityNotFound,
+		},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			testCase.mock()
+
+			got, errGet := r.GetUserByIdentity(testCase.input.provider, testCase.input.subject)
+			if testCase.wantErr != nil {
+				assert.ErrorIs(t, errGet, testCase.wantErr)
+			} else {
+				assert.NoError(t, errGet)
+				assert.Equal(t, testCase.want.ID, got.ID)
+				assert.Equal(t, testCase.want.Email, got.Email)
+				assert.Equal(t, testCase.want.PasswordHash, got.PasswordHash)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPostgresRep_ListAuditEvents(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	defer func() {
+		if errClose := db.Close(); errClose != nil {
+			logrus.Error("can't close db")
+		}
+	}()
+
+	gormDB, errGorm := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+
+	if errGorm != nil {
+		logrus.Fatal(errGorm)
+	}
+
+	r := AuthService.NewAuthPostgresRepo(gormDB)
+
+	occurredAt := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+	userID := 1
+
+	rows := sqlmock.NewRows([]string{"id", "occurred_at", "event", "outcome", "reason", "user_id", "actor_id", "ip", "user_agent", "correlation_id"}).
+		AddRow(41, occurredAt, AuthService.AuditSignIn, AuthService.AuditOutcomeFailure, "password is wrong", userID, nil, "192.0.2.1", "curl/8.5.0", "req-1")
+	mock.ExpectQuery(`^SELECT \* FROM "audit_log" WHERE event = .* AND user_id = .* AND id < .* ORDER BY id DESC LIMIT .*`).
+		WithArgs(AuthService.AuditSignIn, userID, int64(42), 50).WillReturnRows(rows)
+
+	got, errList := r.ListAuditEvents(AuthService.AuditFilter{Event: AuthService.AuditSignIn, UserID: userID, BeforeID: 42, Limit: 50})
+
+	assert.NoError(t, errList)
+	assert.Equal(t, []AuthService.AuditEvent{{
+		ID:            41,
+		OccurredAt:    occurredAt,
+		Event:         AuthService.AuditSignIn,
+		Outcome:       AuthService.AuditOutcomeFailure,
+		Reason:        "password is wrong",
+		UserID:        &userID,
+		IP:            "192.0.2.1",
+		UserAgent:     "curl/8.5.0",
+		CorrelationID: "req-1",
+	}}, got)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,101 @@
+package AuthService_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/jst-Frenzy/ControlSystem/AuthService/internal/AuthService"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memoryRedis answers the SET and MGET commands of the client from a map, so the repo runs without a server.
+type memoryRedis map[string]string
+
+func (m memoryRedis) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return nil, fmt.Errorf("memoryRedis doesn't dial %s", addr)
+	}
+}
+
+func (m memoryRedis) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		args := cmd.Args()
+		switch c := cmd.(type) {
+		case *redis.StatusCmd:
+			if cmd.Name() != "set" {
+				break
+			}
+			m[fmt.Sprint(args[1])] = fmt.Sprint(args[2])
+			c.SetVal("OK")
+			return nil
+		case *redis.SliceCmd:
+			if cmd.Name() != "mget" {
+				break
+			}
+			values := make([]interface{}, 0, len(args)-1)
+			for _, key := range args[1:] {
+				if value, ok := m[fmt.Sprint(key)]; ok {
+					values = append(values, value)
+				} else {
+					values = append(values, nil)
+				}
+			}
+			c.SetVal(values)
+			return nil
+		}
+		return fmt.Errorf("memoryRedis doesn't support %s", strings.ToUpper(cmd.Name()))
+	}
+}
+
+func (m memoryRedis) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func TestRedisRep_IsAccessTokenRevoked(t *testing.T) {
+	revokedAt := time.Date(2026, 2, 4, 10, 0, 0, 500*int(time.Millisecond), time.UTC)
+
+	testTable := []struct {
+		name     string
+		issuedAt time.Time
+		revoked  bool
+	}{
+		{
+			name:     "Issued Before",
+			issuedAt: revokedAt.Add(-time.Minute),
+			revoked:  true,
+		},
+		{
+			name:     "Earlier In The Same Second",
+			issuedAt: revokedAt.Add(-200 * time.Millisecond),
+			revoked:  true,
+		},
+		{
+			name:     "At The Revocation",
+			issuedAt: revokedAt,
+			revoked:  false,
+		},
+		{
+			name:     "Later In The Same Second",
+			issuedAt: revokedAt.Add(200 * time.Millisecond),
+			revoked:  false,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			db := redis.NewClient(&redis.Options{Addr: "memory:6379"})
+			db.AddHook(memoryRedis{})
+
+			r := AuthService.NewAuthRedisRepo(db, 0)
+			assert.NoError(t, r.RevokeUserAccessTokens(1, revokedAt, time.Hour))
+
+			revoked, err := r.IsAccessTokenRevoked("jti", 1, testCase.issuedAt)
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.revoked, revoked)
+		})
+	}
+}
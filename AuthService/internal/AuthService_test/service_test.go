@@ -1,6 +1,7 @@
 package AuthService_test
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
@@ -8,18 +9,24 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/jst-Frenzy/ControlSystem/AuthService/internal/AuthService"
 	mockauthservice "github.com/jst-Frenzy/ControlSystem/AuthService/internal/mocks"
+	"github.com/jst-Frenzy/ControlSystem/protobuf/permissions"
 	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestService_signUp(t *testing.T) {
 	type mockBehavior func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, u AuthService.User)
+	type sendBehavior func(t *mockauthservice.MockTokenManager, r *mockauthservice.MockAuthPostgresRepo, m *mockauthservice.MockMailer)
 
 	testTable := []struct {
 		name          string
 		inputUser     AuthService.UserSignUp
 		repoUser      AuthService.User
 		mockBehavior  mockBehavior
+		sendBehavior  sendBehavior
 		expectedID    int
 		expectedError error
 	}{
@@ -40,6 +47,37 @@ func TestService_signUp(t *testing.T) {
 				r.EXPECT().CreateUser(gomock.Any()).Return(expectedUser, nil)
 				redisMock.EXPECT().AddUserWithEmail(gomock.Any()).Return(nil).AnyTimes()
 			},
+			sendBehavior: func(t *mockauthservice.MockTokenManager, r *mockauthservice.MockAuthPostgresRepo, m *mockauthservice.MockMailer) {
+				t.EXPECT().NewPurposeToken(1, AuthService.PurposeEmailVerification, gomock.Any()).Return("verification_token", nil)
+				hash := sha256.Sum256([]byte("verification_token"))
+				r.EXPECT().SaveEmailVerification(gomock.Any()).DoAndReturn(func(v AuthService.EmailVerification) error {
+					if v.UserID != 1 || v.Email != "test@test.com" || v.TokenHash != hex.EncodeToString(hash[:]) {
+						return errors.New("unexpected verification")
+					}
+					return nil
+				})
+				m.EXPECT().SendEmailVerification("test@test.com", "verification_token").Return(nil)
+			},
+			expectedID:    1,
+			expectedError: nil,
+		},
+		{
+			name: "Fail Send Verification",
+			inputUser: AuthService.UserSignUp{
+				UserName: "test",
+				Email:    "test@test.com",
+				Password: "qwerty",
+			},
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, u AuthService.User) {
+				expectedUser := AuthService.User{ID: 1, UserName: "test", Email: "test@test.com"}
+				r.EXPECT().CreateUser(gomock.Any()).Return(expectedUser, nil)
+				redisMock.EXPECT().AddUserWithEmail(gomock.Any()).Return(nil).AnyTimes()
+			},
+			sendBehavior: func(t *mockauthservice.MockTokenManager, r *mockauthservice.MockAuthPostgresRepo, m *mockauthservice.MockMailer) {
+				t.EXPECT().NewPurposeToken(1, AuthService.PurposeEmailVerification, gomock.Any()).Return("verification_token", nil)
+				r.EXPECT().SaveEmailVerification(gomock.Any()).Return(nil)
+				m.EXPECT().SendEmailVerification("test@test.com", "verification_token").Return(errors.New("mailer failure"))
+			},
 			expectedID:    1,
 			expectedError: nil,
 		},
@@ -58,6 +96,8 @@ func TestService_signUp(t *testing.T) {
 			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, u AuthService.User) {
 				r.EXPECT().CreateUser(gomock.Any()).Return(AuthService.User{}, errors.New("DB Failure"))
 			},
+			sendBehavior: func(t *mockauthservice.MockTokenManager, r *mockauthservice.MockAuthPostgresRepo, m *mockauthservice.MockMailer) {
+			},
 			expectedID:    0,
 			expectedError: errors.New("DB Failure"),
 		},
@@ -69,16 +109,20 @@ func TestService_signUp(t *testing.T) {
 			defer c.Finish()
 
 			authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+			authPostgresRepo.EXPECT().AppendAuditEvent(gomock.Any()).Return(nil).AnyTimes()
 
 			tokenManager := mockauthservice.NewMockTokenManager(c)
 
 			authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
 
+			mailer := mockauthservice.NewMockMailer(c)
+
 			testCase.mockBehavior(authPostgresRepo, authRedisRepo, testCase.repoUser)
+			testCase.sendBehavior(tokenManager, authPostgresRepo, mailer)
 
-			service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, tokenManager)
+			service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, tokenManager, mailer, testPasswordHasher, AuthService.PasswordPolicy{})
 
-			id, err := service.SignUp(testCase.inputUser)
+			id, err := service.SignUp(testCase.inputUser, AuthService.ClientInfo{})
 
 			assert.Equal(t, id, testCase.expectedID)
 			assert.Equal(t, err, testCase.expectedError)
@@ -90,6 +134,8 @@ func TestService_signIn(t *testing.T) {
 	type repoBehavior func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, email string)
 	type tokenBehavior func(t *mockauthservice.MockTokenManager)
 
+	verifiedAt := time.Now()
+
 	testTable := []struct {
 		name           string
 		inputUser      AuthService.UserSignIn
@@ -113,10 +159,11 @@ func TestService_signIn(t *testing.T) {
 				redisMock.EXPECT().GetUserWithEmail(email).Return(AuthService.User{}, errors.New("cache miss"))
 				redisMock.EXPECT().AddUserWithRefreshToken(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 				r.EXPECT().GetUser(email).Return(AuthService.User{
-					ID:           1,
-					UserName:     "test",
-					Email:        "test@test.com",
-					PasswordHash: string(hashedPassword),
+					ID:              1,
+					UserName:        "test",
+					Email:           "test@test.com",
+					PasswordHash:    string(hashedPassword),
+					EmailVerifiedAt: &verifiedAt,
 				}, nil)
 
 				redisMock.EXPECT().AddUserWithEmail(gomock.Any()).Return(nil).AnyTimes()
@@ -159,10 +206,11 @@ func TestService_signIn(t *testing.T) {
 				hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("qwerty"), bcrypt.DefaultCost)
 
 				redisMock.EXPECT().GetUserWithEmail(email).Return(AuthService.User{
-					ID:           1,
-					UserName:     "test",
-					Email:        "test@test.com",
-					PasswordHash: string(hashedPassword),
+					ID:              1,
+					UserName:        "test",
+					Email:           "test@test.com",
+					PasswordHash:    string(hashedPassword),
+					EmailVerifiedAt: &verifiedAt,
 				}, nil)
 
 				redisMock.EXPECT().AddUserWithEmail(gomock.Any()).Return(nil).AnyTimes()
@@ -195,10 +243,11 @@ func TestService_signIn(t *testing.T) {
 				redisMock.EXPECT().GetUserWithEmail(email).Return(AuthService.User{}, errors.New("cache miss"))
 
 				r.EXPECT().GetUser(email).Return(AuthService.User{
-					ID:           1,
-					UserName:     "test",
-					Email:        "test@test.com",
-					PasswordHash: string(hashedPassword),
+					ID:              1,
+					UserName:        "test",
+					Email:           "test@test.com",
+					PasswordHash:    string(hashedPassword),
+					EmailVerifiedAt: &verifiedAt,
 				}, nil)
 
 				redisMock.EXPECT().AddUserWithEmail(gomock.Any()).Return(nil).AnyTimes()
@@ -227,10 +276,11 @@ func TestService_signIn(t *testing.T) {
 				redisMock.EXPECT().GetUserWithEmail(email).Return(AuthService.User{}, errors.New("cache miss"))
 
 				r.EXPECT().GetUser(email).Return(AuthService.User{
-					ID:           1,
-					UserName:     "test",
-					Email:        "test@test.com",
-					PasswordHash: string(hashedPassword),
+					ID:              1,
+					UserName:        "test",
+					Email:           "test@test.com",
+					PasswordHash:    string(hashedPassword),
+					EmailVerifiedAt: &verifiedAt,
 				}, nil)
 
 				redisMock.EXPECT().AddUserWithEmail(gomock.Any()).Return(nil).AnyTimes()
@@ -259,10 +309,11 @@ func TestService_signIn(t *testing.T) {
 				redisMock.EXPECT().GetUserWithEmail(email).Return(AuthService.User{}, errors.New("cache miss"))
 
 				r.EXPECT().GetUser(email).Return(AuthService.User{
-					ID:           1,
-					UserName:     "test",
-					Email:        "test@test.com",
-					PasswordHash: string(hashedPassword),
+					ID:              1,
+					UserName:        "test",
+					Email:           "test@test.com",
+					PasswordHash:    string(hashedPassword),
+					EmailVerifiedAt: &verifiedAt,
 				}, nil)
 
 				redisMock.EXPECT().AddUserWithEmail(gomock.Any()).Return(errors.New("error save user")).AnyTimes()
@@ -279,6 +330,58 @@ func TestService_signIn(t *testing.T) {
 			},
 			expectedError: errors.New("error save refresh token"),
 		},
+		{
+			name: "Email Not Verified",
+			inputUser: AuthService.UserSignIn{
+				Email:    "test@test.com",
+				Password: "qwerty",
+			},
+			inputEmail: "test@test.com",
+
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, email string) {
+				hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("qwerty"), bcrypt.DefaultCost)
+
+				redisMock.EXPECT().GetUserWithEmail(email).Return(AuthService.User{}, errors.New("cache miss"))
+
+				r.EXPECT().GetUser(email).Return(AuthService.User{
+					ID:           1,
+					UserName:     "test",
+					Email:        "test@test.com",
+					PasswordHash: string(hashedPassword),
+				}, nil)
+				redisMock.EXPECT().AddUserWithEmail(gomock.Any()).Return(nil)
+			},
+			tokenBehavior:  func(t *mockauthservice.MockTokenManager) {},
+			expectedTokens: AuthService.Tokens{},
+			expectedError:  AuthService.ErrEmailNotVerified,
+		},
+		{
+			name: "Deactivated",
+			inputUser: AuthService.UserSignIn{
+				Email:    "test@test.com",
+				Password: "qwerty",
+			},
+			inputEmail: "test@test.com",
+
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, email string) {
+				hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("qwerty"), bcrypt.DefaultCost)
+
+				redisMock.EXPECT().GetUserWithEmail(email).Return(AuthService.User{}, errors.New("cache miss"))
+
+				r.EXPECT().GetUser(email).Return(AuthService.User{
+					ID:              1,
+					UserName:        "test",
+					Email:           "test@test.com",
+					PasswordHash:    string(hashedPassword),
+					EmailVerifiedAt: &verifiedAt,
+					DeactivatedAt:   &verifiedAt,
+				}, nil)
+				redisMock.EXPECT().AddUserWithEmail(gomock.Any()).Return(nil)
+			},
+			tokenBehavior:  func(t *mockauthservice.MockTokenManager) {},
+			expectedTokens: AuthService.Tokens{},
+			expectedError:  AuthService.ErrUserDeactivated,
+		},
 	}
 
 	for _, testCase := range testTable {
@@ -287,6 +390,7 @@ func TestService_signIn(t *testing.T) {
 			defer c.Finish()
 
 			authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+			authPostgresRepo.EXPECT().AppendAuditEvent(gomock.Any()).Return(nil).AnyTimes()
 
 			tokenManager := mockauthservice.NewMockTokenManager(c)
 			testCase.tokenBehavior(tokenManager)
@@ -294,10 +398,13 @@ func TestService_signIn(t *testing.T) {
 			authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
 
 			testCase.repoBehavior(authPostgresRepo, authRedisRepo, testCase.inputEmail)
+			authRedisRepo.EXPECT().GetLoginLock(gomock.Any()).Return(time.Duration(0), nil).AnyTimes()
+			authRedisRepo.EXPECT().IncrLoginFailures(gomock.Any(), gomock.Any()).Return(1, nil).AnyTimes()
+			authRedisRepo.EXPECT().DeleteLoginFailures(gomock.Any()).Return(nil).AnyTimes()
 
-			service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, tokenManager)
+			service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, tokenManager, nil, testPasswordHasher, AuthService.PasswordPolicy{})
 
-			tokens, err := service.SignIn(testCase.inputUser)
+			tokens, err := service.SignIn(testCase.inputUser, AuthService.ClientInfo{IP: "192.0.2.1"})
 
 			assert.Equal(t, err, testCase.expectedError)
 			assert.Equal(t, tokens, testCase.expectedTokens)
@@ -309,83 +416,169 @@ func TestService_RefreshTokens(t *testing.T) {
 	type repoBehavior func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, refreshToken string)
 	type tokenBehavior func(t *mockauthservice.MockTokenManager)
 
+	revokedAt := time.Now().Add(-time.Minute)
+	activeToken := AuthService.RefreshToken{
+		UserID:    1,
+		FamilyID:  "family",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	user := AuthService.User{
+		ID:       1,
+		UserName: "test",
+		Email:    "test@test.com",
+		Role:     "user",
+	}
+
 	testTable := []struct {
 		name              string
 		inputRefreshToken string
 		repoBehavior      repoBehavior
 		tokenBehavior     tokenBehavior
-		expectedToken     string
+		expectedTokens    AuthService.Tokens
 		expectedError     error
 	}{
 		{
 			name:              "OK",
 			inputRefreshToken: "refresh_token",
 			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, refreshToken string) {
+				r.EXPECT().GetRefreshToken(refreshToken).Return(activeToken, nil)
 				redisMock.EXPECT().GetUserWithRefreshToken(refreshToken).Return(AuthService.User{}, errors.New("cache miss"))
-
-				r.EXPECT().GetUserByRefreshToken(refreshToken).Return(AuthService.User{
-					ID:       1,
-					UserName: "test",
-					Email:    "test@test.com",
-					Role:     "user",
-				}, nil)
-
+				r.EXPECT().GetUserByRefreshToken(refreshToken).Return(user, nil)
+				r.EXPECT().RevokeRefreshToken(refreshToken).Return(true, nil)
+				redisMock.EXPECT().DeleteRefreshTokens(refreshToken).Return(nil)
+				r.EXPECT().SaveRefreshToken(gomock.Any()).DoAndReturn(func(token AuthService.RefreshToken) error {
+					if token.FamilyID != activeToken.FamilyID {
+						return errors.New("rotated token left the family")
+					}
+					return nil
+				})
 				redisMock.EXPECT().AddUserWithRefreshToken(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 			},
 			tokenBehavior: func(t *mockauthservice.MockTokenManager) {
 				t.EXPECT().NewJWT(gomock.Any(), gomock.Any()).Return("access_token", nil)
+				t.EXPECT().NewRefreshToken().Return("new_refresh_token", nil)
+			},
+			expectedTokens: AuthService.Tokens{
+				AccessToken:  "access_token",
+				RefreshToken: "new_refresh_token",
 			},
-			expectedToken: "access_token",
 			expectedError: nil,
 		},
+		{
+			name:              "OAuth Client Token",
+			inputRefreshToken: "refresh_token",
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, refreshToken string) {
+				clientID := "client"
+				clientToken := activeToken
+				clientToken.ClientID = &clientID
+				r.EXPECT().GetRefreshToken(refreshToken).Return(clientToken, nil)
+			},
+			tokenBehavior:  func(t *mockauthservice.MockTokenManager) {},
+			expectedTokens: AuthService.Tokens{},
+			expectedError:  AuthService.ErrRefreshTokenNotFound,
+		},
 		{
 			name:              "OK with cache hit",
 			inputRefreshToken: "refresh_token",
-			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, refreshTokenHash string) {
-				redisMock.EXPECT().GetUserWithRefreshToken(refreshTokenHash).
-					Return(AuthService.User{
-						ID:       1,
-						UserName: "test",
-						Email:    "test@test.com",
-						Role:     "user",
-					}, nil)
-
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, refreshToken string) {
+				r.EXPECT().GetRefreshToken(refreshToken).Return(activeToken, nil)
+				redisMock.EXPECT().GetUserWithRefreshToken(refreshToken).Return(user, nil)
+				r.EXPECT().RevokeRefreshToken(refreshToken).Return(true, nil)
+				redisMock.EXPECT().DeleteRefreshTokens(refreshToken).Return(nil)
+				r.EXPECT().SaveRefreshToken(gomock.Any()).Return(nil)
+				redisMock.EXPECT().AddUserWithRefreshToken(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 			},
 			tokenBehavior: func(t *mockauthservice.MockTokenManager) {
 				t.EXPECT().NewJWT(gomock.Any(), gomock.Any()).Return("access_token", nil)
+				t.EXPECT().NewRefreshToken().Return("new_refresh_token", nil)
+			},
+			expectedTokens: AuthService.Tokens{
+				AccessToken:  "access_token",
+				RefreshToken: "new_refresh_token",
 			},
-			expectedToken: "access_token",
 			expectedError: nil,
 		},
+		{
+			name:              "Unknown Refresh Token",
+			inputRefreshToken: "refresh_token",
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, refreshToken string) {
+				r.EXPECT().GetRefreshToken(refreshToken).Return(AuthService.RefreshToken{}, AuthService.ErrRefreshTokenNotFound)
+			},
+			tokenBehavior:  func(t *mockauthservice.MockTokenManager) {},
+			expectedTokens: AuthService.Tokens{},
+			expectedError:  AuthService.ErrRefreshTokenNotFound,
+		},
+		{
+			name:              "Expired Refresh Token",
+			inputRefreshToken: "refresh_token",
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, refreshToken string) {
+				r.EXPECT().GetRefreshToken(refreshToken).Return(AuthService.RefreshToken{
+					UserID:    1,
+					FamilyID:  "family",
+					ExpiresAt: time.Now().Add(-time.Hour),
+				}, nil)
+			},
+			tokenBehavior:  func(t *mockauthservice.MockTokenManager) {},
+			expectedTokens: AuthService.Tokens{},
+			expectedError:  AuthService.ErrRefreshTokenExpired,
+		},
+		{
+			name:              "Reuse Of Rotated Token",
+			inputRefreshToken: "refresh_token",
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, refreshToken string) {
+				r.EXPECT().GetRefreshToken(refreshToken).Return(AuthService.RefreshToken{
+					UserID:    1,
+					FamilyID:  "family",
+					ExpiresAt: time.Now().Add(time.Hour),
+					RevokedAt: &revokedAt,
+				}, nil)
+				r.EXPECT().RevokeRefreshTokenFamily("family").Return([]string{refreshToken, "next_hash"}, nil)
+				redisMock.EXPECT().DeleteRefreshTokens(refreshToken, "next_hash").Return(nil)
+			},
+			tokenBehavior:  func(t *mockauthservice.MockTokenManager) {},
+			expectedTokens: AuthService.Tokens{},
+			expectedError:  AuthService.ErrRefreshTokenReused,
+		},
+		{
+			name:              "Concurrent Rotation",
+			inputRefreshToken: "refresh_token",
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, refreshToken string) {
+				r.EXPECT().GetRefreshToken(refreshToken).Return(activeToken, nil)
+				redisMock.EXPECT().GetUserWithRefreshToken(refreshToken).Return(user, nil)
+				r.EXPECT().RevokeRefreshToken(refreshToken).Return(false, nil)
+				r.EXPECT().RevokeRefreshTokenFamily("family").Return([]string{refreshToken}, nil)
+				redisMock.EXPECT().DeleteRefreshTokens(refreshToken).Return(nil)
+			},
+			tokenBehavior:  func(t *mockauthservice.MockTokenManager) {},
+			expectedTokens: AuthService.Tokens{},
+			expectedError:  AuthService.ErrRefreshTokenReused,
+		},
 		{
 			name:              "Fail Get User From Repo",
 			inputRefreshToken: "refresh_token",
 			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, refreshToken string) {
+				r.EXPECT().GetRefreshToken(refreshToken).Return(activeToken, nil)
 				redisMock.EXPECT().GetUserWithRefreshToken(refreshToken).Return(AuthService.User{}, errors.New("cache miss"))
 				r.EXPECT().GetUserByRefreshToken(refreshToken).Return(AuthService.User{}, errors.New("error get user"))
 			},
-			tokenBehavior: func(t *mockauthservice.MockTokenManager) {},
-			expectedToken: "",
-			expectedError: errors.New("error get user"),
+			tokenBehavior:  func(t *mockauthservice.MockTokenManager) {},
+			expectedTokens: AuthService.Tokens{},
+			expectedError:  errors.New("error get user"),
 		},
 		{
 			name:              "Fail Create New JWT",
 			inputRefreshToken: "refresh_token",
 			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, refreshToken string) {
-				redisMock.EXPECT().GetUserWithRefreshToken(refreshToken).Return(AuthService.User{}, errors.New("cache miss"))
-
-				r.EXPECT().GetUserByRefreshToken(refreshToken).Return(AuthService.User{
-					ID:       1,
-					UserName: "test",
-					Email:    "test@test.com",
-					Role:     "user",
-				}, nil)
+				r.EXPECT().GetRefreshToken(refreshToken).Return(activeToken, nil)
+				redisMock.EXPECT().GetUserWithRefreshToken(refreshToken).Return(user, nil)
+				r.EXPECT().RevokeRefreshToken(refreshToken).Return(true, nil)
+				redisMock.EXPECT().DeleteRefreshTokens(refreshToken).Return(nil)
 			},
 			tokenBehavior: func(t *mockauthservice.MockTokenManager) {
 				t.EXPECT().NewJWT(gomock.Any(), gomock.Any()).Return("", errors.New("error create new jwt"))
 			},
-			expectedToken: "",
-			expectedError: errors.New("error create new jwt"),
+			expectedTokens: AuthService.Tokens{},
+			expectedError:  errors.New("error create new jwt"),
 		},
 	}
 
@@ -395,6 +588,7 @@ func TestService_RefreshTokens(t *testing.T) {
 			defer c.Finish()
 
 			authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+			authPostgresRepo.EXPECT().AppendAuditEvent(gomock.Any()).Return(nil).AnyTimes()
 			hash := sha256.Sum256([]byte(testCase.inputRefreshToken))
 
 			tokenManager := mockauthservice.NewMockTokenManager(c)
@@ -403,12 +597,1839 @@ func TestService_RefreshTokens(t *testing.T) {
 			authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
 			testCase.repoBehavior(authPostgresRepo, authRedisRepo, hex.EncodeToString(hash[:]))
 
-			service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, tokenManager)
+			service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, tokenManager, nil, testPasswordHasher, AuthService.PasswordPolicy{})
 
-			token, err := service.RefreshTokens(testCase.inputRefreshToken)
+			tokens, err := service.RefreshTokens(testCase.inputRefreshToken, AuthService.ClientInfo{})
+
+			assert.Equal(t, tokens, testCase.expectedTokens)
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
+
+func TestService_Logout(t *testing.T) {
+	type repoBehavior func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, refreshTokenHash string)
+
+	testTable := []struct {
+		name              string
+		inputRefreshToken string
+		repoBehavior      repoBehavior
+		expectedError     error
+	}{
+		{
+			name:              "OK",
+			inputRefreshToken: "refresh_token",
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, refreshTokenHash string) {
+				r.EXPECT().GetRefreshToken(refreshTokenHash).Return(AuthService.RefreshToken{UserID: 1, TokenHash: refreshTokenHash}, nil)
+				r.EXPECT().DeleteRefreshToken(refreshTokenHash).Return(nil)
+				redisMock.EXPECT().DeleteRefreshTokens(refreshTokenHash).Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:              "Unknown Refresh Token",
+			inputRefreshToken: "refresh_token",
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, refreshTokenHash string) {
+				r.EXPECT().GetRefreshToken(refreshTokenHash).Return(AuthService.RefreshToken{}, AuthService.ErrRefreshTokenNotFound)
+				r.EXPECT().DeleteRefreshToken(refreshTokenHash).Return(AuthService.ErrRefreshTokenNotFound)
+			},
+			expectedError: AuthService.ErrRefreshTokenNotFound,
+		},
+		{
+			name:              "Fail Evict Cache",
+			inputRefreshToken: "refresh_token",
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, refreshTokenHash string) {
+				r.EXPECT().GetRefreshToken(refreshTokenHash).Return(AuthService.RefreshToken{UserID: 1, TokenHash: refreshTokenHash}, nil)
+				r.EXPECT().DeleteRefreshToken(refreshTokenHash).Return(nil)
+				redisMock.EXPECT().DeleteRefreshTokens(refreshTokenHash).Return(errors.New("redis failure"))
+			},
+			expectedError: errors.New("redis failure"),
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+			authPostgresRepo.EXPECT().AppendAuditEvent(gomock.Any()).Return(nil).AnyTimes()
+			authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+			tokenManager := mockauthservice.NewMockTokenManager(c)
+
+			hash := sha256.Sum256([]byte(testCase.inputRefreshToken))
+			testCase.repoBehavior(authPostgresRepo, authRedisRepo, hex.EncodeToString(hash[:]))
+
+			service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, tokenManager, nil, testPasswordHasher, AuthService.PasswordPolicy{})
+
+			err := service.Logout(testCase.inputRefreshToken, AuthService.ClientInfo{})
+
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
+
+func TestService_LogoutAll(t *testing.T) {
+	type repoBehavior func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, userID int)
+
+	testTable := []struct {
+		name          string
+		inputUserID   int
+		repoBehavior  repoBehavior
+		expectedError error
+	}{
+		{
+			name:        "OK",
+			inputUserID: 1,
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, userID int) {
+				r.EXPECT().DeleteUserRefreshTokens(userID).Return([]string{"hash1", "hash2"}, nil)
+				redisMock.EXPECT().DeleteRefreshTokens("hash1", "hash2").Return(nil)
+				redisMock.EXPECT().RevokeUserAccessTokens(userID, gomock.Any(), 2*time.Hour).Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:        "DB Error",
+			inputUserID: 1,
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, userID int) {
+				r.EXPECT().DeleteUserRefreshTokens(userID).Return(nil, errors.New("DB Failure"))
+			},
+			expectedError: errors.New("DB Failure"),
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+			authPostgresRepo.EXPECT().AppendAuditEvent(gomock.Any()).Return(nil).AnyTimes()
+			authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+			tokenManager := mockauthservice.NewMockTokenManager(c)
+			testCase.repoBehavior(authPostgresRepo, authRedisRepo, testCase.inputUserID)
+
+			service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, tokenManager, nil, testPasswordHasher, AuthService.PasswordPolicy{})
+
+			err := service.LogoutAll(testCase.inputUserID, AuthService.ClientInfo{})
 
-			assert.Equal(t, token, testCase.expectedToken)
 			assert.Equal(t, err, testCase.expectedError)
 		})
 	}
 }
+
+func TestService_ChangeRole(t *testing.T) {
+	type repoBehavior func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, actorID, userID int, newRole string)
+
+	type args struct {
+		actorID int
+		userID  int
+		newRole string
+	}
+
+	testTable := []struct {
+		name          string
+		input         args
+		repoBehavior  repoBehavior
+		expectedUser  AuthService.User
+		expectedError error
+	}{
+		{
+			name:  "OK",
+			input: args{actorID: 1, userID: 2, newRole: AuthService.RoleSeller},
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, actorID, userID int, newRole string) {
+				r.EXPECT().ChangeRole(AuthService.RoleChange{
+					ActorID:  &actorID,
+					TargetID: userID,
+					NewRole:  newRole,
+				}).Return(AuthService.User{ID: userID, Email: "test@test.com", Role: newRole}, nil)
+				redisMock.EXPECT().InvalidateUser(userID).Return(nil)
+				redisMock.EXPECT().RevokeUserAccessTokens(userID, gomock.Any(), 2*time.Hour).Return(nil)
+			},
+			expectedUser:  AuthService.User{ID: 2, Email: "test@test.com", Role: AuthService.RoleSeller},
+			expectedError: nil,
+		},
+		{
+			name:  "Revoke Error",
+			input: args{actorID: 1, userID: 2, newRole: AuthService.RoleUser},
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, actorID, userID int, newRole string) {
+				r.EXPECT().ChangeRole(gomock.Any()).Return(AuthService.User{ID: userID, Role: newRole}, nil)
+				redisMock.EXPECT().InvalidateUser(userID).Return(nil)
+				redisMock.EXPECT().RevokeUserAccessTokens(userID, gomock.Any(), 2*time.Hour).Return(errors.New("redis down"))
+			},
+			expectedError: errors.New("redis down"),
+		},
+		{
+			name:  "Invalid Role",
+			input: args{actorID: 1, userID: 2, newRole: "superuser"},
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, actorID, userID int, newRole string) {
+			},
+			expectedError: AuthService.ErrInvalidRole,
+		},
+		{
+			name:  "Own Role",
+			input: args{actorID: 1, userID: 1, newRole: AuthService.RoleUser},
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, actorID, userID int, newRole string) {
+			},
+			expectedError: AuthService.ErrSelfRoleChange,
+		},
+		{
+			name:  "User Not Found",
+			input: args{actorID: 1, userID: 2, newRole: AuthService.RoleAdmin},
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, actorID, userID int, newRole string) {
+				r.EXPECT().ChangeRole(gomock.Any()).Return(AuthService.User{}, AuthService.ErrUserNotFound)
+			},
+			expectedError: AuthService.ErrUserNotFound,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+			authPostgresRepo.EXPECT().AppendAuditEvent(gomock.Any()).Return(nil).AnyTimes()
+			authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+			tokenManager := mockauthservice.NewMockTokenManager(c)
+			testCase.repoBehavior(authPostgresRepo, authRedisRepo, testCase.input.actorID, testCase.input.userID, testCase.input.newRole)
+
+			service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, tokenManager, nil, testPasswordHasher, AuthService.PasswordPolicy{})
+
+			user, err := service.ChangeRole(testCase.input.actorID, testCase.input.userID, testCase.input.newRole, AuthService.ClientInfo{})
+
+			assert.Equal(t, err, testCase.expectedError)
+			assert.Equal(t, user, testCase.expectedUser)
+		})
+	}
+}
+
+func TestService_BootstrapAdmin(t *testing.T) {
+	type repoBehavior func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, u AuthService.UserSignUp)
+
+	testTable := []struct {
+		name          string
+		input         AuthService.UserSignUp
+		repoBehavior  repoBehavior
+		expectedUser  AuthService.User
+		expectedError error
+	}{
+		{
+			name:  "New Admin",
+			input: AuthService.UserSignUp{UserName: "admin", Email: "admin@test.com", Password: "qwerty"},
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, u AuthService.UserSignUp) {
+				r.EXPECT().GetUser(u.Email).Return(AuthService.User{}, AuthService.ErrUserNotFound)
+				r.EXPECT().CreateUser(gomock.Any()).DoAndReturn(func(user AuthService.User) (AuthService.User, error) {
+					if user.Role != AuthService.RoleAdmin || user.EmailVerifiedAt == nil ||
+						bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(u.Password)) != nil {
+						return AuthService.User{}, errors.New("unexpected user")
+					}
+					user.ID = 1
+					user.PasswordHash = ""
+					user.EmailVerifiedAt = nil
+					return user, nil
+				})
+			},
+			expectedUser:  AuthService.User{ID: 1, UserName: "admin", Email: "admin@test.com", Role: AuthService.RoleAdmin},
+			expectedError: nil,
+		},
+		{
+			name:  "Promote Existing User",
+			input: AuthService.UserSignUp{UserName: "admin", Email: "user@test.com", Password: "qwerty"},
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, u AuthService.UserSignUp) {
+				r.EXPECT().GetUser(u.Email).Return(AuthService.User{ID: 2, Email: u.Email, Role: AuthService.RoleUser}, nil)
+				r.EXPECT().ChangeRole(AuthService.RoleChange{TargetID: 2, NewRole: AuthService.RoleAdmin}).
+					Return(AuthService.User{ID: 2, Email: u.Email, Role: AuthService.RoleAdmin}, nil)
+				redisMock.EXPECT().InvalidateUser(2).Return(nil)
+			},
+			expectedUser:  AuthService.User{ID: 2, Email: "user@test.com", Role: AuthService.RoleAdmin},
+			expectedError: nil,
+		},
+		{
+			name:  "Already Admin",
+			input: AuthService.UserSignUp{UserName: "admin", Email: "admin@test.com", Password: "qwerty"},
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, u AuthService.UserSignUp) {
+				r.EXPECT().GetUser(u.Email).Return(AuthService.User{ID: 1, Email: u.Email, Role: AuthService.RoleAdmin}, nil)
+			},
+			expectedUser:  AuthService.User{ID: 1, Email: "admin@test.com", Role: AuthService.RoleAdmin},
+			expectedError: nil,
+		},
+		{
+			name:  "DB Error",
+			input: AuthService.UserSignUp{UserName: "admin", Email: "admin@test.com", Password: "qwerty"},
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, u AuthService.UserSignUp) {
+				r.EXPECT().GetUser(u.Email).Return(AuthService.User{}, errors.New("DB Failure"))
+			},
+			expectedError: errors.New("DB Failure"),
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+			authPostgresRepo.EXPECT().AppendAuditEvent(gomock.Any()).Return(nil).AnyTimes()
+			authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+			testCase.repoBehavior(authPostgresRepo, authRedisRepo, testCase.input)
+
+			service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, nil, nil, testPasswordHasher, AuthService.PasswordPolicy{})
+
+			user, err := service.BootstrapAdmin(testCase.input)
+
+			assert.Equal(t, err, testCase.expectedError)
+			assert.Equal(t, user, testCase.expectedUser)
+		})
+	}
+}
+
+func TestService_VerifyEmail(t *testing.T) {
+	type behavior func(t *mockauthservice.MockTokenManager, r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tokenHash string)
+
+	testTable := []struct {
+		name          string
+		inputToken    string
+		behavior      behavior
+		expectedError error
+	}{
+		{
+			name:       "OK",
+			inputToken: "verification_token",
+			behavior: func(t *mockauthservice.MockTokenManager, r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tokenHash string) {
+				t.EXPECT().ParsePurposeToken("verification_token", AuthService.PurposeEmailVerification).Return(1, nil)
+				r.EXPECT().VerifyEmail(tokenHash).Return(AuthService.User{ID: 1, Email: "test@test.com"}, nil)
+				redisMock.EXPECT().InvalidateUser(1).Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "Bad Signature",
+			inputToken: "forged_token",
+			behavior: func(t *mockauthservice.MockTokenManager, r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tokenHash string) {
+				t.EXPECT().ParsePurposeToken("forged_token", AuthService.PurposeEmailVerification).Return(0, errors.New("invalid token"))
+			},
+			expectedError: AuthService.ErrVerificationTokenInvalid,
+		},
+		{
+			name:       "Already Used",
+			inputToken: "verification_token",
+			behavior: func(t *mockauthservice.MockTokenManager, r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tokenHash string) {
+				t.EXPECT().ParsePurposeToken("verification_token", AuthService.PurposeEmailVerification).Return(1, nil)
+				r.EXPECT().VerifyEmail(tokenHash).Return(AuthService.User{}, AuthService.ErrVerificationTokenInvalid)
+			},
+			expectedError: AuthService.ErrVerificationTokenInvalid,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+			authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+			tokenManager := mockauthservice.NewMockTokenManager(c)
+
+			hash := sha256.Sum256([]byte(testCase.inputToken))
+			testCase.behavior(tokenManager, authPostgresRepo, authRedisRepo, hex.EncodeToString(hash[:]))
+
+			service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, tokenManager, nil, testPasswordHasher, AuthService.PasswordPolicy{})
+
+			err := service.VerifyEmail(testCase.inputToken)
+
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
+
+func TestService_ResendEmailVerification(t *testing.T) {
+	type behavior func(t *mockauthservice.MockTokenManager, r *mockauthservice.MockAuthPostgresRepo, m *mockauthservice.MockMailer)
+
+	verifiedAt := time.Now()
+
+	testTable := []struct {
+		name          string
+		inputEmail    string
+		behavior      behavior
+		expectedError error
+	}{
+		{
+			name:       "OK",
+			inputEmail: "test@test.com",
+			behavior: func(t *mockauthservice.MockTokenManager, r *mockauthservice.MockAuthPostgresRepo, m *mockauthservice.MockMailer) {
+				r.EXPECT().GetUser("test@test.com").Return(AuthService.User{ID: 1, Email: "test@test.com"}, nil)
+				t.EXPECT().NewPurposeToken(1, AuthService.PurposeEmailVerification, gomock.Any()).Return("verification_token", nil)
+				r.EXPECT().SaveEmailVerification(gomock.Any()).Return(nil)
+				m.EXPECT().SendEmailVerification("test@test.com", "verification_token").Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "Unknown Email",
+			inputEmail: "unknown@test.com",
+			behavior: func(t *mockauthservice.MockTokenManager, r *mockauthservice.MockAuthPostgresRepo, m *mockauthservice.MockMailer) {
+				r.EXPECT().GetUser("unknown@test.com").Return(AuthService.User{}, AuthService.ErrUserNotFound)
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "Already Verified",
+			inputEmail: "test@test.com",
+			behavior: func(t *mockauthservice.MockTokenManager, r *mockauthservice.MockAuthPostgresRepo, m *mockauthservice.MockMailer) {
+				r.EXPECT().GetUser("test@test.com").Return(AuthService.User{ID: 1, Email: "test@test.com", EmailVerifiedAt: &verifiedAt}, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "Mailer Error",
+			inputEmail: "test@test.com",
+			behavior: func(t *mockauthservice.MockTokenManager, r *mockauthservice.MockAuthPostgresRepo, m *mockauthservice.MockMailer) {
+				r.EXPECT().GetUser("test@test.com").Return(AuthService.User{ID: 1, Email: "test@test.com"}, nil)
+				t.EXPECT().NewPurposeToken(1, AuthService.PurposeEmailVerification, gomock.Any()).Return("verification_token", nil)
+				r.EXPECT().SaveEmailVerification(gomock.Any()).Return(nil)
+				m.EXPECT().SendEmailVerification("test@test.com", "verification_token").Return(errors.New("mailer failure"))
+			},
+			expectedError: errors.New("mailer failure"),
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+			authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+			tokenManager := mockauthservice.NewMockTokenManager(c)
+			mailer := mockauthservice.NewMockMailer(c)
+			testCase.behavior(tokenManager, authPostgresRepo, mailer)
+
+			service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, tokenManager, mailer, testPasswordHasher, AuthService.PasswordPolicy{})
+
+			err := service.ResendEmailVerification(testCase.inputEmail)
+
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
+
+func TestService_ForgotPassword(t *testing.T) {
+	type behavior func(t *mockauthservice.MockTokenManager, r *mockauthservice.MockAuthPostgresRepo, m *mockauthservice.MockMailer)
+
+	testTable := []struct {
+		name          string
+		inputEmail    string
+		behavior      behavior
+		expectedError error
+	}{
+		{
+			name:       "OK",
+			inputEmail: "test@test.com",
+			behavior: func(t *mockauthservice.MockTokenManager, r *mockauthservice.MockAuthPostgresRepo, m *mockauthservice.MockMailer) {
+				r.EXPECT().GetUser("test@test.com").Return(AuthService.User{ID: 1, Email: "test@test.com"}, nil)
+				t.EXPECT().NewRefreshToken().Return("reset_token", nil)
+				hash := sha256.Sum256([]byte("reset_token"))
+				r.EXPECT().SavePasswordReset(gomock.Any()).DoAndReturn(func(reset AuthService.PasswordReset) error {
+					if reset.UserID != 1 || reset.TokenHash != hex.EncodeToString(hash[:]) || !reset.ExpiresAt.After(time.Now()) {
+						return errors.New("unexpected reset")
+					}
+					return nil
+				})
+				m.EXPECT().SendPasswordReset("test@test.com", "reset_token").Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "Unknown Email",
+			inputEmail: "unknown@test.com",
+			behavior: func(t *mockauthservice.MockTokenManager, r *mockauthservice.MockAuthPostgresRepo, m *mockauthservice.MockMailer) {
+				r.EXPECT().GetUser("unknown@test.com").Return(AuthService.User{}, AuthService.ErrUserNotFound)
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "DB Error",
+			inputEmail: "test@test.com",
+			behavior: func(t *mockauthservice.MockTokenManager, r *mockauthservice.MockAuthPostgresRepo, m *mockauthservice.MockMailer) {
+				r.EXPECT().GetUser("test@test.com").Return(AuthService.User{ID: 1, Email: "test@test.com"}, nil)
+				t.EXPECT().NewRefreshToken().Return("reset_token", nil)
+				r.EXPECT().SavePasswordReset(gomock.Any()).Return(errors.New("DB Failure"))
+			},
+			expectedError: errors.New("DB Failure"),
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+			authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+			tokenManager := mockauthservice.NewMockTokenManager(c)
+			mailer := mockauthservice.NewMockMailer(c)
+			testCase.behavior(tokenManager, authPostgresRepo, mailer)
+
+			service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, tokenManager, mailer, testPasswordHasher, AuthService.PasswordPolicy{})
+
+			err := service.ForgotPassword(testCase.inputEmail)
+
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
+
+func TestService_ResetPassword(t *testing.T) {
+	type repoBehavior func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tokenHash string)
+
+	testTable := []struct {
+		name          string
+		inputToken    string
+		inputPassword string
+		repoBehavior  repoBehavior
+		expectedError error
+	}{
+		{
+			name:          "OK",
+			inputToken:    "reset_token",
+			inputPassword: "new_password",
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tokenHash string) {
+				r.EXPECT().ResetPassword(tokenHash, gomock.Any()).DoAndReturn(func(tokenHash, passwordHash string) (AuthService.User, error) {
+					if bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte("new_password")) != nil {
+						return AuthService.User{}, errors.New("unexpected password hash")
+					}
+					return AuthService.User{ID: 1, Email: "test@test.com", PasswordHash: passwordHash}, nil
+				})
+				r.EXPECT().DeleteUserRefreshTokens(1).Return([]string{"hash1", "hash2"}, nil)
+				redisMock.EXPECT().DeleteRefreshTokens("hash1", "hash2").Return(nil)
+				redisMock.EXPECT().InvalidateUser(1).Return(nil)
+				redisMock.EXPECT().RevokeUserAccessTokens(1, gomock.Any(), 2*time.Hour).Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:          "Invalid Token",
+			inputToken:    "reset_token",
+			inputPassword: "new_password",
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tokenHash string) {
+				r.EXPECT().ResetPassword(tokenHash, gomock.Any()).Return(AuthService.User{}, AuthService.ErrResetTokenInvalid)
+			},
+			expectedError: AuthService.ErrResetTokenInvalid,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+			authPostgresRepo.EXPECT().AppendAuditEvent(gomock.Any()).Return(nil).AnyTimes()
+			authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+			tokenManager := mockauthservice.NewMockTokenManager(c)
+
+			hash := sha256.Sum256([]byte(testCase.inputToken))
+			testCase.repoBehavior(authPostgresRepo, authRedisRepo, hex.EncodeToString(hash[:]))
+
+			service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, tokenManager, nil, testPasswordHasher, AuthService.PasswordPolicy{})
+
+			err := service.ResetPassword(testCase.inputToken, testCase.inputPassword, AuthService.ClientInfo{})
+
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
+
+func TestService_ChangePassword(t *testing.T) {
+	type repoBehavior func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, user AuthService.User)
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("qwerty"), bcrypt.DefaultCost)
+	user := AuthService.User{ID: 1, Email: "test@test.com", PasswordHash: string(hashedPassword)}
+
+	testTable := []struct {
+		name             string
+		inputOldPassword string
+		repoBehavior     repoBehavior
+		expectedError    error
+	}{
+		{
+			name:             "OK",
+			inputOldPassword: "qwerty",
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, user AuthService.User) {
+				r.EXPECT().GetUserByID(user.ID).Return(user, nil)
+				r.EXPECT().UpdatePassword(user.ID, gomock.Any()).Return(nil)
+				r.EXPECT().DeleteUserRefreshTokens(user.ID).Return([]string{"hash"}, nil)
+				redisMock.EXPECT().DeleteRefreshTokens("hash").Return(nil)
+				redisMock.EXPECT().InvalidateUser(user.ID).Return(nil)
+				redisMock.EXPECT().RevokeUserAccessTokens(1, gomock.Any(), 2*time.Hour).Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:             "Wrong Password",
+			inputOldPassword: "wrong",
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, user AuthService.User) {
+				r.EXPECT().GetUserByID(user.ID).Return(user, nil)
+			},
+			expectedError: AuthService.ErrWrongPassword,
+		},
+		{
+			name:             "Fail Revoke Sessions",
+			inputOldPassword: "qwerty",
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, user AuthService.User) {
+				r.EXPECT().GetUserByID(user.ID).Return(user, nil)
+				r.EXPECT().UpdatePassword(user.ID, gomock.Any()).Return(nil)
+				r.EXPECT().DeleteUserRefreshTokens(user.ID).Return(nil, errors.New("DB Failure"))
+			},
+			expectedError: errors.New("DB Failure"),
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+			authPostgresRepo.EXPECT().AppendAuditEvent(gomock.Any()).Return(nil).AnyTimes()
+			authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+			tokenManager := mockauthservice.NewMockTokenManager(c)
+			testCase.repoBehavior(authPostgresRepo, authRedisRepo, user)
+
+			service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, tokenManager, nil, testPasswordHasher, AuthService.PasswordPolicy{})
+
+			err := service.ChangePassword(user.ID, testCase.inputOldPassword, "new_password", "", AuthService.ClientInfo{})
+
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
+
+func TestService_SignInLockout(t *testing.T) {
+	type repoBehavior func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo)
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("qwerty"), bcrypt.DefaultCost)
+	verifiedAt := time.Now()
+	user := AuthService.User{ID: 1, Email: "test@test.com", PasswordHash: string(hashedPassword), EmailVerifiedAt: &verifiedAt}
+
+	testTable := []struct {
+		name          string
+		inputPassword string
+		repoBehavior  repoBehavior
+		expectedError error
+	}{
+		{
+			name:          "IP Locked",
+			inputPassword: "qwerty",
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo) {
+				redisMock.EXPECT().GetLoginLock("ip:192.0.2.1").Return(time.Minute, nil)
+			},
+			expectedError: &AuthService.LockoutError{Err: AuthService.ErrTooManyAttempts, RetryAfter: time.Minute},
+		},
+		{
+			name:          "Account Locked",
+			inputPassword: "qwerty",
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo) {
+				redisMock.EXPECT().GetLoginLock("ip:192.0.2.1").Return(time.Duration(0), nil)
+				redisMock.EXPECT().GetLoginLock("email:test@test.com").Return(2*time.Minute, nil)
+			},
+			expectedError: &AuthService.LockoutError{Err: AuthService.ErrAccountLocked, RetryAfter: 2 * time.Minute},
+		},
+		{
+			name:          "Wrong Password Below Limit",
+			inputPassword: "wrong",
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo) {
+				redisMock.EXPECT().GetLoginLock(gomock.Any()).Return(time.Duration(0), nil).Times(2)
+				redisMock.EXPECT().GetUserWithEmail(user.Email).Return(user, nil)
+				redisMock.EXPECT().IncrLoginFailures("email:test@test.com", gomock.Any()).Return(4, nil)
+				redisMock.EXPECT().IncrLoginFailures("ip:192.0.2.1", gomock.Any()).Return(4, nil)
+			},
+			expectedError: AuthService.ErrWrongPassword,
+		},
+		{
+			name:          "Wrong Password Locks Account",
+			inputPassword: "wrong",
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo) {
+				redisMock.EXPECT().GetLoginLock(gomock.Any()).Return(time.Duration(0), nil).Times(2)
+				redisMock.EXPECT().GetUserWithEmail(user.Email).Return(user, nil)
+				redisMock.EXPECT().IncrLoginFailures("email:test@test.com", gomock.Any()).Return(5, nil)
+				redisMock.EXPECT().SetLoginLock("email:test@test.com", time.Minute).Return(nil)
+				redisMock.EXPECT().IncrLoginFailures("ip:192.0.2.1", gomock.Any()).Return(5, nil)
+			},
+			expectedError: AuthService.ErrWrongPassword,
+		},
+		{
+			name:          "Lockout Grows Exponentially",
+			inputPassword: "wrong",
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo) {
+				redisMock.EXPECT().GetLoginLock(gomock.Any()).Return(time.Duration(0), nil).Times(2)
+				redisMock.EXPECT().GetUserWithEmail(user.Email).Return(user, nil)
+				redisMock.EXPECT().IncrLoginFailures("email:test@test.com", gomock.Any()).Return(8, nil)
+				redisMock.EXPECT().SetLoginLock("email:test@test.com", 8*time.Minute).Return(nil)
+				redisMock.EXPECT().IncrLoginFailures("ip:192.0.2.1", gomock.Any()).Return(100, nil)
+				redisMock.EXPECT().SetLoginLock("ip:192.0.2.1", time.Hour).Return(nil)
+			},
+			expectedError: AuthService.ErrWrongPassword,
+		},
+		{
+			name:          "Unknown Email Counts As Failure",
+			inputPassword: "qwerty",
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo) {
+				redisMock.EXPECT().GetLoginLock(gomock.Any()).Return(time.Duration(0), nil).Times(2)
+				redisMock.EXPECT().GetUserWithEmail(user.Email).Return(AuthService.User{}, errors.New("cache miss"))
+				r.EXPECT().GetUser(user.Email).Return(AuthService.User{}, AuthService.ErrUserNotFound)
+				redisMock.EXPECT().IncrLoginFailures("email:test@test.com", gomock.Any()).Return(1, nil)
+				redisMock.EXPECT().IncrLoginFailures("ip:192.0.2.1", gomock.Any()).Return(1, nil)
+			},
+			expectedError: AuthService.ErrUserNotFound,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+			authPostgresRepo.EXPECT().AppendAuditEvent(gomock.Any()).Return(nil).AnyTimes()
+			authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+			tokenManager := mockauthservice.NewMockTokenManager(c)
+			testCase.repoBehavior(authPostgresRepo, authRedisRepo)
+
+			service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, tokenManager, nil, testPasswordHasher, AuthService.PasswordPolicy{})
+
+			_, err := service.SignIn(AuthService.UserSignIn{Email: user.Email, Password: testCase.inputPassword}, AuthService.ClientInfo{IP: "192.0.2.1"})
+
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
+
+// A correct password must not reset the failures of a user with two-factor authentication, or knowing the
+// password would give fresh guesses of the code after every lock.
+func TestService_SignInMFAKeepsLoginFailures(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("qwerty"), bcrypt.DefaultCost)
+	verifiedAt := time.Now()
+	user := AuthService.User{ID: 1, Email: "test@test.com", PasswordHash: string(hashedPassword), EmailVerifiedAt: &verifiedAt, TOTPEnabled: true}
+	client := AuthService.ClientInfo{IP: "192.0.2.1"}
+
+	authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+	authPostgresRepo.EXPECT().AppendAuditEvent(gomock.Any()).Return(nil).AnyTimes()
+	authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+	tokenManager := mockauthservice.NewMockTokenManager(c)
+
+	gomock.InOrder(
+		authRedisRepo.EXPECT().GetLoginLock(gomock.Any()).Return(time.Duration(0), nil).Times(2),
+		authRedisRepo.EXPECT().GetUserWithEmail(user.Email).Return(user, nil),
+		tokenManager.EXPECT().NewPurposeToken(user.ID, AuthService.PurposeMFAChallenge, gomock.Any()).Return("mfa token", nil),
+
+		tokenManager.EXPECT().ParsePurposeToken("mfa token", AuthService.PurposeMFAChallenge).Return(user.ID, nil),
+		authPostgresRepo.EXPECT().GetUserByID(user.ID).Return(user, nil),
+		authRedisRepo.EXPECT().GetLoginLock(gomock.Any()).Return(time.Duration(0), nil).Times(2),
+		authPostgresRepo.EXPECT().UseRecoveryCode(user.ID, gomock.Any()).Return(false, nil),
+		// the failures of the earlier locks are still counted, so the lock keeps doubling
+		authRedisRepo.EXPECT().IncrLoginFailures("email:test@test.com", gomock.Any()).Return(7, nil),
+		authRedisRepo.EXPECT().SetLoginLock("email:test@test.com", 4*time.Minute).Return(nil),
+		authRedisRepo.EXPECT().IncrLoginFailures("ip:192.0.2.1", gomock.Any()).Return(7, nil),
+	)
+
+	service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, tokenManager, nil, testPasswordHasher, AuthService.PasswordPolicy{})
+
+	tokens, err := service.SignIn(AuthService.UserSignIn{Email: user.Email, Password: "qwerty"}, client)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, tokens.MFAToken, "mfa token")
+
+	_, err = service.SignInMFA(tokens.MFAToken, "01234-56789-ABCDEF-0123", client)
+	assert.Equal(t, err, AuthService.ErrInvalidMFACode)
+}
+
+func TestService_UnlockAccount(t *testing.T) {
+	type repoBehavior func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo)
+
+	testTable := []struct {
+		name          string
+		inputEmail    string
+		repoBehavior  repoBehavior
+		expectedError error
+	}{
+		{
+			name:       "OK",
+			inputEmail: "test@test.com",
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo) {
+				r.EXPECT().GetUser("test@test.com").Return(AuthService.User{ID: 1, Email: "test@test.com"}, nil)
+				redisMock.EXPECT().DeleteLoginFailures("email:test@test.com").Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "Unknown Email",
+			inputEmail: "unknown@test.com",
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo) {
+				r.EXPECT().GetUser("unknown@test.com").Return(AuthService.User{}, AuthService.ErrUserNotFound)
+			},
+			expectedError: AuthService.ErrUserNotFound,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+			authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+			testCase.repoBehavior(authPostgresRepo, authRedisRepo)
+
+			service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, nil, nil, testPasswordHasher, AuthService.PasswordPolicy{})
+
+			err := service.UnlockAccount(testCase.inputEmail)
+
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
+
+func TestService_SignInMFA(t *testing.T) {
+	type repoBehavior func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager)
+
+	secret, _ := AuthService.NewTOTPSecret()
+	code, _ := AuthService.TOTPCode(secret, time.Now())
+	confirmedAt := time.Now()
+	user := AuthService.User{ID: 1, Email: "test@test.com", TOTPEnabled: true}
+	recoveryHash := sha256.Sum256([]byte("0123456789abcdef0123"))
+
+	testTable := []struct {
+		name          string
+		inputCode     string
+		repoBehavior  repoBehavior
+		expectedError error
+	}{
+		{
+			name:      "OK TOTP",
+			inputCode: code,
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				tm.EXPECT().ParsePurposeToken("mfaToken", AuthService.PurposeMFAChallenge).Return(user.ID, nil)
+				r.EXPECT().GetUserByID(user.ID).Return(user, nil)
+				redisMock.EXPECT().GetLoginLock(gomock.Any()).Return(time.Duration(0), nil).Times(2)
+				r.EXPECT().GetTOTP(user.ID).Return(AuthService.TOTPCredential{UserID: user.ID, Secret: secret, ConfirmedAt: &confirmedAt}, nil)
+				r.EXPECT().UseTOTPCounter(user.ID, gomock.Any()).Return(true, nil)
+				redisMock.EXPECT().DeleteLoginFailures("email:test@test.com").Return(nil)
+				tm.EXPECT().NewJWT(user, gomock.Any()).Return("access", nil)
+				tm.EXPECT().NewRefreshToken().Return("refresh", nil)
+				r.EXPECT().SaveRefreshToken(gomock.Any()).Return(nil)
+				redisMock.EXPECT().AddUserWithRefreshToken(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			},
+			expectedError: nil,
+		},
+		{
+			name:      "OK Recovery Code",
+			inputCode: "01234-56789-ABCDEF-0123",
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				tm.EXPECT().ParsePurposeToken("mfaToken", AuthService.PurposeMFAChallenge).Return(user.ID, nil)
+				r.EXPECT().GetUserByID(user.ID).Return(user, nil)
+				redisMock.EXPECT().GetLoginLock(gomock.Any()).Return(time.Duration(0), nil).Times(2)
+				r.EXPECT().UseRecoveryCode(user.ID, hex.EncodeToString(recoveryHash[:])).Return(true, nil)
+				redisMock.EXPECT().DeleteLoginFailures("email:test@test.com").Return(nil)
+				tm.EXPECT().NewJWT(user, gomock.Any()).Return("access", nil)
+				tm.EXPECT().NewRefreshToken().Return("refresh", nil)
+				r.EXPECT().SaveRefreshToken(gomock.Any()).Return(nil)
+				redisMock.EXPECT().AddUserWithRefreshToken(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			},
+			expectedError: nil,
+		},
+		{
+			name:      "Replayed TOTP",
+			inputCode: code,
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				tm.EXPECT().ParsePurposeToken("mfaToken", AuthService.PurposeMFAChallenge).Return(user.ID, nil)
+				r.EXPECT().GetUserByID(user.ID).Return(user, nil)
+				redisMock.EXPECT().GetLoginLock(gomock.Any()).Return(time.Duration(0), nil).Times(2)
+				r.EXPECT().GetTOTP(user.ID).Return(AuthService.TOTPCredential{UserID: user.ID, Secret: secret, ConfirmedAt: &confirmedAt}, nil)
+				r.EXPECT().UseTOTPCounter(user.ID, gomock.Any()).Return(false, nil)
+				redisMock.EXPECT().IncrLoginFailures("email:test@test.com", gomock.Any()).Return(1, nil)
+				redisMock.EXPECT().IncrLoginFailures("ip:192.0.2.1", gomock.Any()).Return(1, nil)
+			},
+			expectedError: AuthService.ErrInvalidMFACode,
+		},
+		{
+			name:      "Used Recovery Code",
+			inputCode: "0123456789-abcdef0123",
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				tm.EXPECT().ParsePurposeToken("mfaToken", AuthService.PurposeMFAChallenge).Return(user.ID, nil)
+				r.EXPECT().GetUserByID(user.ID).Return(user, nil)
+				redisMock.EXPECT().GetLoginLock(gomock.Any()).Return(time.Duration(0), nil).Times(2)
+				r.EXPECT().UseRecoveryCode(user.ID, hex.EncodeToString(recoveryHash[:])).Return(false, nil)
+				redisMock.EXPECT().IncrLoginFailures("email:test@test.com", gomock.Any()).Return(1, nil)
+				redisMock.EXPECT().IncrLoginFailures("ip:192.0.2.1", gomock.Any()).Return(1, nil)
+			},
+			expectedError: AuthService.ErrInvalidMFACode,
+		},
+		{
+			name:      "Invalid MFA Token",
+			inputCode: code,
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				tm.EXPECT().ParsePurposeToken("mfaToken", AuthService.PurposeMFAChallenge).Return(0, errors.New("token is expired"))
+			},
+			expectedError: AuthService.ErrMFATokenInvalid,
+		},
+		{
+			name:      "Account Locked",
+			inputCode: code,
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				tm.EXPECT().ParsePurposeToken("mfaToken", AuthService.PurposeMFAChallenge).Return(user.ID, nil)
+				r.EXPECT().GetUserByID(user.ID).Return(user, nil)
+				redisMock.EXPECT().GetLoginLock("ip:192.0.2.1").Return(time.Duration(0), nil)
+				redisMock.EXPECT().GetLoginLock("email:test@test.com").Return(time.Minute, nil)
+			},
+			expectedError: &AuthService.LockoutError{Err: AuthService.ErrAccountLocked, RetryAfter: time.Minute},
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+			authPostgresRepo.EXPECT().AppendAuditEvent(gomock.Any()).Return(nil).AnyTimes()
+			authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+			tokenManager := mockauthservice.NewMockTokenManager(c)
+			testCase.repoBehavior(authPostgresRepo, authRedisRepo, tokenManager)
+
+			service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, tokenManager, nil, testPasswordHasher, AuthService.PasswordPolicy{})
+
+			_, err := service.SignInMFA("mfaToken", testCase.inputCode, AuthService.ClientInfo{IP: "192.0.2.1"})
+
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
+
+func TestService_ConfirmTOTP(t *testing.T) {
+	type repoBehavior func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo)
+
+	secret, _ := AuthService.NewTOTPSecret()
+	code, _ := AuthService.TOTPCode(secret, time.Now())
+	user := AuthService.User{ID: 1, Email: "test@test.com"}
+
+	testTable := []struct {
+		name          string
+		inputCode     string
+		repoBehavior  repoBehavior
+		expectedCodes int
+		expectedError error
+	}{
+		{
+			name:      "OK",
+			inputCode: code,
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo) {
+				r.EXPECT().GetUserByID(user.ID).Return(user, nil)
+				r.EXPECT().GetTOTP(user.ID).Return(AuthService.TOTPCredential{UserID: user.ID, Secret: secret}, nil)
+				r.EXPECT().ConfirmTOTP(user.ID, gomock.Any(), gomock.Len(10)).Return(nil)
+				redisMock.EXPECT().InvalidateUser(user.ID).Return(nil)
+			},
+			expectedCodes: 10,
+			expectedError: nil,
+		},
+		{
+			name:      "Wrong Code",
+			inputCode: "abcdef",
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo) {
+				r.EXPECT().GetUserByID(user.ID).Return(user, nil)
+				r.EXPECT().GetTOTP(user.ID).Return(AuthService.TOTPCredential{UserID: user.ID, Secret: secret}, nil)
+			},
+			expectedError: AuthService.ErrInvalidMFACode,
+		},
+		{
+			name:      "Not Enrolled",
+			inputCode: code,
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo) {
+				r.EXPECT().GetUserByID(user.ID).Return(user, nil)
+				r.EXPECT().GetTOTP(user.ID).Return(AuthService.TOTPCredential{}, AuthService.ErrTOTPNotEnrolled)
+			},
+			expectedError: AuthService.ErrTOTPNotEnrolled,
+		},
+		{
+			name:      "Already Enabled",
+			inputCode: code,
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo) {
+				r.EXPECT().GetUserByID(user.ID).Return(AuthService.User{ID: 1, Email: "test@test.com", TOTPEnabled: true}, nil)
+			},
+			expectedError: AuthService.ErrTOTPAlreadyEnabled,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+			authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+			testCase.repoBehavior(authPostgresRepo, authRedisRepo)
+
+			service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, nil, nil, testPasswordHasher, AuthService.PasswordPolicy{})
+
+			codes, err := service.ConfirmTOTP(user.ID, testCase.inputCode)
+
+			assert.Equal(t, err, testCase.expectedError)
+			assert.Equal(t, len(codes), testCase.expectedCodes)
+		})
+	}
+}
+
+func TestService_ParseToken(t *testing.T) {
+	type behavior func(redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager)
+
+	info := AuthService.InfoFromToken{ID: 1, Role: "user", TokenID: "jti", IssuedAt: time.Unix(1700000000, 0)}
+
+	testTable := []struct {
+		name          string
+		behavior      behavior
+		expectedInfo  AuthService.InfoFromToken
+		expectedError error
+	}{
+		{
+			name: "OK",
+			behavior: func(redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				tm.EXPECT().Parse("access_token").Return(info, nil)
+				redisMock.EXPECT().IsAccessTokenRevoked("jti", 1, info.IssuedAt).Return(false, nil)
+			},
+			expectedInfo:  info,
+			expectedError: nil,
+		},
+		{
+			name: "Revoked",
+			behavior: func(redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				tm.EXPECT().Parse("access_token").Return(info, nil)
+				redisMock.EXPECT().IsAccessTokenRevoked("jti", 1, info.IssuedAt).Return(true, nil)
+			},
+			expectedInfo:  AuthService.InfoFromToken{},
+			expectedError: AuthService.ErrTokenRevoked,
+		},
+		{
+			name: "Redis Failure",
+			behavior: func(redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				tm.EXPECT().Parse("access_token").Return(info, nil)
+				redisMock.EXPECT().IsAccessTokenRevoked("jti", 1, info.IssuedAt).Return(false, errors.New("redis failure"))
+			},
+			expectedInfo:  info,
+			expectedError: nil,
+		},
+		{
+			name: "Invalid Token",
+			behavior: func(redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				tm.EXPECT().Parse("access_token").Return(AuthService.InfoFromToken{}, errors.New("invalid token"))
+			},
+			expectedInfo:  AuthService.InfoFromToken{},
+			expectedError: errors.New("invalid token"),
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+			tokenManager := mockauthservice.NewMockTokenManager(c)
+			testCase.behavior(authRedisRepo, tokenManager)
+
+			service := AuthService.NewAuthService(nil, authRedisRepo, tokenManager, nil, testPasswordHasher, AuthService.PasswordPolicy{})
+
+			got, err := service.ParseToken("access_token")
+
+			assert.Equal(t, got, testCase.expectedInfo)
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
+
+func TestService_RevokeToken(t *testing.T) {
+	type behavior func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager)
+
+	hash := sha256.Sum256([]byte("token"))
+	tokenHash := hex.EncodeToString(hash[:])
+
+	testTable := []struct {
+		name          string
+		behavior      behavior
+		expectedError error
+	}{
+		{
+			name: "Access Token",
+			behavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				tm.EXPECT().Parse("token").Return(AuthService.InfoFromToken{ID: 1, TokenID: "jti", ExpiresAt: time.Now().Add(time.Hour)}, nil)
+				redisMock.EXPECT().DenyAccessToken("jti", gomock.Any()).Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name: "Refresh Token",
+			behavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				tm.EXPECT().Parse("token").Return(AuthService.InfoFromToken{}, errors.New("invalid token"))
+				r.EXPECT().GetRefreshToken(tokenHash).Return(AuthService.RefreshToken{}, AuthService.ErrRefreshTokenNotFound)
+				r.EXPECT().DeleteRefreshToken(tokenHash).Return(nil)
+				redisMock.EXPECT().DeleteRefreshTokens(tokenHash).Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name: "Unknown Token",
+			behavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				tm.EXPECT().Parse("token").Return(AuthService.InfoFromToken{}, errors.New("invalid token"))
+				r.EXPECT().GetRefreshToken(tokenHash).Return(AuthService.RefreshToken{}, AuthService.ErrRefreshTokenNotFound)
+				r.EXPECT().DeleteRefreshToken(tokenHash).Return(AuthService.ErrRefreshTokenNotFound)
+			},
+			expectedError: nil,
+		},
+		{
+			name: "DB Failure",
+			behavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				tm.EXPECT().Parse("token").Return(AuthService.InfoFromToken{}, errors.New("invalid token"))
+				r.EXPECT().GetRefreshToken(tokenHash).Return(AuthService.RefreshToken{}, AuthService.ErrRefreshTokenNotFound)
+				r.EXPECT().DeleteRefreshToken(tokenHash).Return(errors.New("DB Failure"))
+			},
+			expectedError: errors.New("DB Failure"),
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+			authPostgresRepo.EXPECT().AppendAuditEvent(gomock.Any()).Return(nil).AnyTimes()
+			authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+			tokenManager := mockauthservice.NewMockTokenManager(c)
+			testCase.behavior(authPostgresRepo, authRedisRepo, tokenManager)
+
+			service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, tokenManager, nil, testPasswordHasher, AuthService.PasswordPolicy{})
+
+			err := service.RevokeToken("token")
+
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
+
+func TestService_SessionDevice(t *testing.T) {
+	testTable := []struct {
+		name                string
+		inputUserAgent      string
+		expectedDeviceLabel string
+	}{
+		{
+			name:                "Chrome On Windows",
+			inputUserAgent:      "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36",
+			expectedDeviceLabel: "Chrome on Windows",
+		},
+		{
+			name:                "Edge On Windows",
+			inputUserAgent:      "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36 Edg/129.0.0.0",
+			expectedDeviceLabel: "Edge on Windows",
+		},
+		{
+			name:                "Safari On iPhone",
+			inputUserAgent:      "Mozilla/5.0 (iPhone; CPU iPhone OS 17_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.6 Mobile/15E148 Safari/604.1",
+			expectedDeviceLabel: "Safari on iPhone",
+		},
+		{
+			name:                "Chrome On Android",
+			inputUserAgent:      "Mozilla/5.0 (Linux; Android 14) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Mobile Safari/537.36",
+			expectedDeviceLabel: "Chrome on Android",
+		},
+		{
+			name:                "Unknown",
+			inputUserAgent:      "",
+			expectedDeviceLabel: "Unknown device",
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			hash := sha256.Sum256([]byte("refresh_token"))
+			client := AuthService.ClientInfo{IP: "192.0.2.1", UserAgent: testCase.inputUserAgent}
+
+			authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+			authPostgresRepo.EXPECT().AppendAuditEvent(gomock.Any()).Return(nil).AnyTimes()
+			authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+			tokenManager := mockauthservice.NewMockTokenManager(c)
+
+			authPostgresRepo.EXPECT().GetRefreshToken(hex.EncodeToString(hash[:])).Return(AuthService.RefreshToken{
+				UserID:    1,
+				FamilyID:  "family",
+				ExpiresAt: time.Now().Add(time.Hour),
+			}, nil)
+			authRedisRepo.EXPECT().GetUserWithRefreshToken(hex.EncodeToString(hash[:])).Return(AuthService.User{ID: 1}, nil)
+			authPostgresRepo.EXPECT().RevokeRefreshToken(hex.EncodeToString(hash[:])).Return(true, nil)
+			authRedisRepo.EXPECT().DeleteRefreshTokens(hex.EncodeToString(hash[:])).Return(nil)
+			tokenManager.EXPECT().NewJWT(gomock.Any(), gomock.Any()).Return("access_token", nil)
+			tokenManager.EXPECT().NewRefreshToken().Return("next_refresh_token", nil)
+			authPostgresRepo.EXPECT().SaveRefreshToken(gomock.Any()).DoAndReturn(func(token AuthService.RefreshToken) error {
+				assert.Equal(t, token.FamilyID, "family")
+				assert.Equal(t, token.IP, client.IP)
+				assert.Equal(t, token.UserAgent, client.UserAgent)
+				assert.Equal(t, token.DeviceLabel, testCase.expectedDeviceLabel)
+				return nil
+			})
+			authRedisRepo.EXPECT().AddUserWithRefreshToken(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+			service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, tokenManager, nil, testPasswordHasher, AuthService.PasswordPolicy{})
+
+			_, err := service.RefreshTokens("refresh_token", client)
+
+			assert.Equal(t, err, nil)
+		})
+	}
+}
+
+func TestService_RevokeSession(t *testing.T) {
+	type repoBehavior func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo)
+
+	testTable := []struct {
+		name           string
+		inputSessionID string
+		repoBehavior   repoBehavior
+		expectedError  error
+	}{
+		{
+			name:           "OK",
+			inputSessionID: "family",
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo) {
+				r.EXPECT().DeleteSession(1, "family").Return([]string{"hash1", "hash2"}, nil)
+				redisMock.EXPECT().DeleteRefreshTokens("hash1", "hash2").Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:           "Not Found",
+			inputSessionID: "foreign",
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo) {
+				r.EXPECT().DeleteSession(1, "foreign").Return(nil, AuthService.ErrSessionNotFound)
+			},
+			expectedError: AuthService.ErrSessionNotFound,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+			authPostgresRepo.EXPECT().AppendAuditEvent(gomock.Any()).Return(nil).AnyTimes()
+			authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+			testCase.repoBehavior(authPostgresRepo, authRedisRepo)
+
+			service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, nil, nil, testPasswordHasher, AuthService.PasswordPolicy{})
+
+			err := service.RevokeSession(1, testCase.inputSessionID, AuthService.ClientInfo{})
+
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
+
+func TestService_UpdateProfile(t *testing.T) {
+	type repoBehavior func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager, mailer *mockauthservice.MockMailer)
+
+	user := AuthService.User{ID: 1, UserName: "test", Email: "test@test.com", Role: AuthService.RoleUser}
+	newName := "renamed"
+	newEmail := "new@test.com"
+	blankName := "  "
+
+	testTable := []struct {
+		name            string
+		inputRequest    AuthService.UpdateProfileRequest
+		repoBehavior    repoBehavior
+		expectedProfile AuthService.UserProfile
+		expectedError   error
+	}{
+		{
+			name:         "Rename",
+			inputRequest: AuthService.UpdateProfileRequest{UserName: &newName},
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager, mailer *mockauthservice.MockMailer) {
+				r.EXPECT().GetUserByID(user.ID).Return(user, nil)
+				r.EXPECT().UpdateUserName(user.ID, newName).Return(AuthService.User{ID: 1, UserName: newName, Email: user.Email, Role: user.Role}, nil)
+				redisMock.EXPECT().InvalidateUser(user.ID).Return(nil)
+			},
+			expectedProfile: AuthService.UserProfile{
+				ID:          1,
+				UserName:    newName,
+				Email:       user.Email,
+				Role:        user.Role,
+				Permissions: []string{permissions.CartManage},
+			},
+			expectedError: nil,
+		},
+		{
+			name:         "Change Email",
+			inputRequest: AuthService.UpdateProfileRequest{Email: &newEmail},
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager, mailer *mockauthservice.MockMailer) {
+				r.EXPECT().GetUserByID(user.ID).Return(user, nil)
+				r.EXPECT().GetUser(newEmail).Return(AuthService.User{}, AuthService.ErrUserNotFound)
+				tm.EXPECT().NewRefreshToken().Return("change_token", nil)
+				r.EXPECT().SaveEmailChange(gomock.Any()).DoAndReturn(func(change AuthService.EmailChange) error {
+					hash := sha256.Sum256([]byte("change_token"))
+					if change.UserID != user.ID || change.NewEmail != newEmail || change.TokenHash != hex.EncodeToString(hash[:]) {
+						return errors.New("unexpected email change")
+					}
+					return nil
+				})
+				mailer.EXPECT().SendEmailChange(newEmail, "change_token").Return(nil)
+			},
+			expectedProfile: AuthService.UserProfile{
+				ID:           1,
+				UserName:     user.UserName,
+				Email:        user.Email,
+				PendingEmail: newEmail,
+				Role:         user.Role,
+				Permissions:  []string{permissions.CartManage},
+			},
+			expectedError: nil,
+		},
+		{
+			name:         "Email Taken",
+			inputRequest: AuthService.UpdateProfileRequest{Email: &newEmail},
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager, mailer *mockauthservice.MockMailer) {
+				r.EXPECT().GetUserByID(user.ID).Return(user, nil)
+				r.EXPECT().GetUser(newEmail).Return(AuthService.User{ID: 2, Email: newEmail}, nil)
+			},
+			expectedProfile: AuthService.UserProfile{},
+			expectedError:   AuthService.ErrEmailTaken,
+		},
+		{
+			name:         "Blank User Name",
+			inputRequest: AuthService.UpdateProfileRequest{UserName: &blankName},
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager, mailer *mockauthservice.MockMailer) {
+				r.EXPECT().GetUserByID(user.ID).Return(user, nil)
+			},
+			expectedProfile: AuthService.UserProfile{},
+			expectedError:   AuthService.ErrInvalidUserName,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+			authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+			tokenManager := mockauthservice.NewMockTokenManager(c)
+			mailer := mockauthservice.NewMockMailer(c)
+			testCase.repoBehavior(authPostgresRepo, authRedisRepo, tokenManager, mailer)
+
+			service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, tokenManager, mailer, testPasswordHasher, AuthService.PasswordPolicy{})
+
+			profile, err := service.UpdateProfile(user.ID, testCase.inputRequest)
+
+			assert.Equal(t, err, testCase.expectedError)
+			assert.Equal(t, profile, testCase.expectedProfile)
+		})
+	}
+}
+
+func TestService_ConfirmEmailChange(t *testing.T) {
+	type repoBehavior func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tokenHash string)
+
+	testTable := []struct {
+		name          string
+		repoBehavior  repoBehavior
+		expectedError error
+	}{
+		{
+			name: "OK",
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tokenHash string) {
+				r.EXPECT().ConfirmEmailChange(tokenHash).Return(AuthService.User{ID: 1, Email: "new@test.com"}, "old@test.com", nil)
+				redisMock.EXPECT().InvalidateUser(1).Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name: "Invalid Token",
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tokenHash string) {
+				r.EXPECT().ConfirmEmailChange(tokenHash).Return(AuthService.User{}, "", AuthService.ErrVerificationTokenInvalid)
+			},
+			expectedError: AuthService.ErrVerificationTokenInvalid,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			hash := sha256.Sum256([]byte("change_token"))
+
+			authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+			authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+			testCase.repoBehavior(authPostgresRepo, authRedisRepo, hex.EncodeToString(hash[:]))
+
+			service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, nil, nil, testPasswordHasher, AuthService.PasswordPolicy{})
+
+			err := service.ConfirmEmailChange("change_token")
+
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
+
+func TestService_ListUsers(t *testing.T) {
+	testTable := []struct {
+		name          string
+		inputRequest  AuthService.ListUsersRequest
+		expectedLimit int
+		expectedOff   int
+	}{
+		{
+			name:          "Default Page",
+			inputRequest:  AuthService.ListUsersRequest{},
+			expectedLimit: 20,
+			expectedOff:   0,
+		},
+		{
+			name:          "Limit Capped",
+			inputRequest:  AuthService.ListUsersRequest{Query: " test ", Limit: 1000, Offset: 40},
+			expectedLimit: 100,
+			expectedOff:   40,
+		},
+		{
+			name:          "Negative Offset",
+			inputRequest:  AuthService.ListUsersRequest{Limit: 5, Offset: -1},
+			expectedLimit: 5,
+			expectedOff:   0,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+			authPostgresRepo.EXPECT().ListUsers(gomock.Any(), testCase.expectedLimit, testCase.expectedOff).
+				DoAndReturn(func(query string, limit, offset int) ([]AuthService.User, int64, error) {
+					assert.Equal(t, query, strings.TrimSpace(testCase.inputRequest.Query))
+					return []AuthService.User{{ID: 1, Email: "test@test.com", Role: AuthService.RoleSeller}}, 41, nil
+				})
+
+			service := AuthService.NewAuthService(authPostgresRepo, nil, nil, nil, testPasswordHasher, AuthService.PasswordPolicy{})
+
+			users, total, err := service.ListUsers(testCase.inputRequest)
+
+			assert.Equal(t, err, nil)
+			assert.Equal(t, total, int64(41))
+			assert.Equal(t, users, []AuthService.UserProfile{{
+				ID:          1,
+				Email:       "test@test.com",
+				Role:        AuthService.RoleSeller,
+				Permissions: []string{permissions.CartManage, permissions.GoodsWrite},
+			}})
+		})
+	}
+}
+
+func TestService_DeactivateUser(t *testing.T) {
+	type repoBehavior func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo)
+
+	testTable := []struct {
+		name          string
+		inputActorID  int
+		inputUserID   int
+		repoBehavior  repoBehavior
+		expectedError error
+	}{
+		{
+			name:         "OK",
+			inputActorID: 1,
+			inputUserID:  2,
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo) {
+				r.EXPECT().SetUserDeactivated(2, gomock.Not(gomock.Nil())).Return(AuthService.User{ID: 2, Email: "test@test.com"}, nil)
+				r.EXPECT().DeleteUserRefreshTokens(2).Return([]string{"hash"}, nil)
+				redisMock.EXPECT().DeleteRefreshTokens("hash").Return(nil)
+				redisMock.EXPECT().InvalidateUser(2).Return(nil)
+				redisMock.EXPECT().RevokeUserAccessTokens(2, gomock.Any(), 2*time.Hour).Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:          "Self Deactivation",
+			inputActorID:  1,
+			inputUserID:   1,
+			repoBehavior:  func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo) {},
+			expectedError: AuthService.ErrSelfDeactivation,
+		},
+		{
+			name:         "Not Found",
+			inputActorID: 1,
+			inputUserID:  3,
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo) {
+				r.EXPECT().SetUserDeactivated(3, gomock.Any()).Return(AuthService.User{}, AuthService.ErrUserNotFound)
+			},
+			expectedError: AuthService.ErrUserNotFound,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+			authPostgresRepo.EXPECT().AppendAuditEvent(gomock.Any()).Return(nil).AnyTimes()
+			authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+			testCase.repoBehavior(authPostgresRepo, authRedisRepo)
+
+			service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, nil, nil, testPasswordHasher, AuthService.PasswordPolicy{})
+
+			err := service.DeactivateUser(testCase.inputActorID, testCase.inputUserID, AuthService.ClientInfo{})
+
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
+
+func TestService_Impersonate(t *testing.T) {
+	type mockBehavior func(r *mockauthservice.MockAuthPostgresRepo, tm *mockauthservice.MockTokenManager)
+
+	deactivatedAt := time.Now()
+
+	testTable := []struct {
+		name          string
+		inputActorID  int
+		inputUserID   int
+		mockBehavior  mockBehavior
+		expectedToken AuthService.ImpersonationToken
+		expectedError error
+	}{
+		{
+			name:         "OK",
+			inputActorID: 1,
+			inputUserID:  2,
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, tm *mockauthservice.MockTokenManager) {
+				user := AuthService.User{ID: 2, Role: AuthService.RoleUser}
+				r.EXPECT().GetUserByID(2).Return(user, nil)
+				tm.EXPECT().NewImpersonationJWT(user, 1, 15*time.Minute).Return("access", nil)
+			},
+			expectedToken: AuthService.ImpersonationToken{AccessToken: "access", ExpiresIn: 900},
+			expectedError: nil,
+		},
+		{
+			name:          "Self Impersonation",
+			inputActorID:  1,
+			inputUserID:   1,
+			mockBehavior:  func(r *mockauthservice.MockAuthPostgresRepo, tm *mockauthservice.MockTokenManager) {},
+			expectedToken: AuthService.ImpersonationToken{},
+			expectedError: AuthService.ErrSelfImpersonation,
+		},
+		{
+			name:         "Admin",
+			inputActorID: 1,
+			inputUserID:  2,
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, tm *mockauthservice.MockTokenManager) {
+				r.EXPECT().GetUserByID(2).Return(AuthService.User{ID: 2, Role: AuthService.RoleAdmin}, nil)
+			},
+			expectedToken: AuthService.ImpersonationToken{},
+			expectedError: AuthService.ErrImpersonationForbidden,
+		},
+		{
+			name:         "Deactivated",
+			inputActorID: 1,
+			inputUserID:  2,
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, tm *mockauthservice.MockTokenManager) {
+				r.EXPECT().GetUserByID(2).Return(AuthService.User{ID: 2, Role: AuthService.RoleUser, DeactivatedAt: &deactivatedAt}, nil)
+			},
+			expectedToken: AuthService.ImpersonationToken{},
+			expectedError: AuthService.ErrUserDeactivated,
+		},
+		{
+			name:         "Not Found",
+			inputActorID: 1,
+			inputUserID:  3,
+			mockBehavior: func(r *mockauthservice.MockAuthPostgresRepo, tm *mockauthservice.MockTokenManager) {
+				r.EXPECT().GetUserByID(3).Return(AuthService.User{}, AuthService.ErrUserNotFound)
+			},
+			expectedToken: AuthService.ImpersonationToken{},
+			expectedError: AuthService.ErrUserNotFound,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+			authPostgresRepo.EXPECT().AppendAuditEvent(gomock.Any()).Return(nil).AnyTimes()
+			authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+			tokenManager := mockauthservice.NewMockTokenManager(c)
+			testCase.mockBehavior(authPostgresRepo, tokenManager)
+
+			service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, tokenManager, nil, testPasswordHasher, AuthService.PasswordPolicy{})
+
+			token, err := service.Impersonate(testCase.inputActorID, testCase.inputUserID, AuthService.ClientInfo{})
+
+			assert.Equal(t, token, testCase.expectedToken)
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
+
+func TestService_DeleteAccount(t *testing.T) {
+	type repoBehavior func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, holder *mockauthservice.MockUserDataHolder)
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("qwerty"), bcrypt.DefaultCost)
+	user := AuthService.User{ID: 1, Email: "test@test.com", PasswordHash: string(hashedPassword)}
+
+	testTable := []struct {
+		name          string
+		inputPassword string
+		repoBehavior  repoBehavior
+		expectedError error
+	}{
+		{
+			name:          "OK",
+			inputPassword: "qwerty",
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, holder *mockauthservice.MockUserDataHolder) {
+				r.EXPECT().GetUserByID(1).Return(user, nil)
+				holder.EXPECT().DeleteUserData(gomock.Any(), 1).Return(nil)
+				r.EXPECT().DeleteUser(1).Return([]string{"hash"}, nil)
+				redisMock.EXPECT().DeleteRefreshTokens("hash").Return(nil)
+				redisMock.EXPECT().InvalidateUser(1).Return(nil)
+				redisMock.EXPECT().RevokeUserAccessTokens(1, gomock.Any(), 2*time.Hour).Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:          "Wrong Password",
+			inputPassword: "wrong",
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, holder *mockauthservice.MockUserDataHolder) {
+				r.EXPECT().GetUserByID(1).Return(user, nil)
+			},
+			expectedError: AuthService.ErrWrongPassword,
+		},
+		{
+			name:          "Holder Unavailable",
+			inputPassword: "qwerty",
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, holder *mockauthservice.MockUserDataHolder) {
+				r.EXPECT().GetUserByID(1).Return(user, nil)
+				holder.EXPECT().DeleteUserData(gomock.Any(), 1).Return(errors.New("unavailable"))
+				holder.EXPECT().Name().Return("cart")
+			},
+			expectedError: AuthService.ErrUserDataUnavailable,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+			authPostgresRepo.EXPECT().AppendAuditEvent(gomock.Any()).Return(nil).AnyTimes()
+			authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+			holder := mockauthservice.NewMockUserDataHolder(c)
+			testCase.repoBehavior(authPostgresRepo, authRedisRepo, holder)
+
+			service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, nil, nil, testPasswordHasher, AuthService.PasswordPolicy{}, holder)
+
+			err := service.DeleteAccount(context.Background(), 1, testCase.inputPassword, "", AuthService.ClientInfo{})
+
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
+
+func TestService_ConfirmUserWithoutPassword(t *testing.T) {
+	type behavior func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager)
+
+	// signed up through an identity provider
+	user := AuthService.User{ID: 1, Email: "test@test.com"}
+
+	testTable := []struct {
+		name             string
+		inputPassword    string
+		inputReauthToken string
+		behavior         behavior
+		expectedError    error
+	}{
+		{
+			name:             "OK",
+			inputReauthToken: "reauth",
+			behavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				tm.EXPECT().ParsePurposeToken("reauth", AuthService.PurposeReauthentication).Return(1, nil).Times(2)
+				r.EXPECT().UpdatePassword(1, gomock.Any()).Return(nil)
+				r.EXPECT().DeleteUserRefreshTokens(1).Return(nil, nil)
+				r.EXPECT().DeleteUser(1).Return(nil, nil)
+				redisMock.EXPECT().DeleteRefreshTokens().Return(nil).Times(2)
+				redisMock.EXPECT().InvalidateUser(1).Return(nil).Times(2)
+				redisMock.EXPECT().RevokeUserAccessTokens(1, gomock.Any(), 2*time.Hour).Return(nil).Times(2)
+			},
+		},
+		{
+			name:          "Empty Password",
+			inputPassword: "",
+			behavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+			},
+			expectedError: AuthService.ErrReauthenticationNeeded,
+		},
+		{
+			name:             "Token Of Another User",
+			inputReauthToken: "reauth",
+			behavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				tm.EXPECT().ParsePurposeToken("reauth", AuthService.PurposeReauthentication).Return(2, nil).Times(2)
+			},
+			expectedError: AuthService.ErrReauthenticationNeeded,
+		},
+		{
+			name:             "Expired Token",
+			inputReauthToken: "reauth",
+			behavior: func(r *mockauthservice.MockAuthPostgresRepo, redisMock *mockauthservice.MockAuthRedisRepo, tm *mockauthservice.MockTokenManager) {
+				tm.EXPECT().ParsePurposeToken("reauth", AuthService.PurposeReauthentication).Return(0, errors.New("token is expired")).Times(2)
+			},
+			expectedError: AuthService.ErrReauthenticationNeeded,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+			authPostgresRepo.EXPECT().AppendAuditEvent(gomock.Any()).Return(nil).AnyTimes()
+			authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+			tokenManager := mockauthservice.NewMockTokenManager(c)
+			authPostgresRepo.EXPECT().GetUserByID(1).Return(user, nil).Times(2)
+			testCase.behavior(authPostgresRepo, authRedisRepo, tokenManager)
+
+			service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, tokenManager, nil, testPasswordHasher, AuthService.PasswordPolicy{})
+
+			errChange := service.ChangePassword(1, testCase.inputPassword, "new_password", testCase.inputReauthToken, AuthService.ClientInfo{})
+			assert.Equal(t, errChange, testCase.expectedError)
+
+			errDelete := service.DeleteAccount(context.Background(), 1, testCase.inputPassword, testCase.inputReauthToken, AuthService.ClientInfo{})
+			assert.Equal(t, errDelete, testCase.expectedError)
+		})
+	}
+}
+
+func TestService_ExportAccount(t *testing.T) {
+	type repoBehavior func(r *mockauthservice.MockAuthPostgresRepo, holder *mockauthservice.MockUserDataHolder)
+
+	createdAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	sessions := []AuthService.Session{{ID: "family", DeviceLabel: "Chrome on Windows", CreatedAt: createdAt}}
+	cart := []string{"item"}
+
+	testTable := []struct {
+		name             string
+		repoBehavior     repoBehavior
+		expectedSessions []AuthService.Session
+		expectedServices map[string]interface{}
+		expectedError    error
+	}{
+		{
+			name: "OK",
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, holder *mockauthservice.MockUserDataHolder) {
+				r.EXPECT().GetUserByID(1).Return(AuthService.User{ID: 1, Role: AuthService.RoleUser, CreatedAt: createdAt}, nil)
+				r.EXPECT().ListSessions(1).Return(sessions, nil)
+				holder.EXPECT().ExportUserData(gomock.Any(), 1).Return(cart, nil)
+				holder.EXPECT().Name().Return("cart")
+			},
+			expectedSessions: sessions,
+			expectedServices: map[string]interface{}{"cart": cart},
+			expectedError:    nil,
+		},
+		{
+			name: "Holder Unavailable",
+			repoBehavior: func(r *mockauthservice.MockAuthPostgresRepo, holder *mockauthservice.MockUserDataHolder) {
+				r.EXPECT().GetUserByID(1).Return(AuthService.User{ID: 1, Role: AuthService.RoleUser}, nil)
+				r.EXPECT().ListSessions(1).Return(sessions, nil)
+				holder.EXPECT().ExportUserData(gomock.Any(), 1).Return(nil, errors.New("unavailable"))
+				holder.EXPECT().Name().Return("cart")
+			},
+			expectedSessions: nil,
+			expectedServices: nil,
+			expectedError:    AuthService.ErrUserDataUnavailable,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+			holder := mockauthservice.NewMockUserDataHolder(c)
+			testCase.repoBehavior(authPostgresRepo, holder)
+
+			service := AuthService.NewAuthService(authPostgresRepo, nil, nil, nil, testPasswordHasher, AuthService.PasswordPolicy{}, holder)
+
+			export, err := service.ExportAccount(context.Background(), 1)
+
+			assert.Equal(t, export.Sessions, testCase.expectedSessions)
+			assert.Equal(t, export.Services, testCase.expectedServices)
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
+
+func TestService_SignInSharesUserLoad(t *testing.T) {
+	const signIns = 5
+
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("qwerty"), bcrypt.MinCost)
+	user := AuthService.User{ID: 1, Email: "test@test.com", PasswordHash: string(hashedPassword)}
+
+	authPostgresRepo := mockauthservice.NewMockAuthPostgresRepo(c)
+	authRedisRepo := mockauthservice.NewMockAuthRedisRepo(c)
+
+	authPostgresRepo.EXPECT().AppendAuditEvent(gomock.Any()).Return(nil).AnyTimes()
+	authRedisRepo.EXPECT().GetLoginLock(gomock.Any()).Return(time.Duration(0), nil).AnyTimes()
+	authRedisRepo.EXPECT().DeleteLoginFailures(gomock.Any()).Return(nil).AnyTimes()
+
+	// every sign in misses the cache before the first one reads the database
+	var missed sync.WaitGroup
+	missed.Add(signIns)
+	authRedisRepo.EXPECT().GetUserWithEmail(user.Email).DoAndReturn(func(string) (AuthService.User, error) {
+		missed.Done()
+		missed.Wait()
+		return AuthService.User{}, AuthService.ErrUserCacheMiss
+	}).Times(signIns)
+	authPostgresRepo.EXPECT().GetUser(user.Email).DoAndReturn(func(string) (AuthService.User, error) {
+		time.Sleep(100 * time.Millisecond)
+		return user, nil
+	}).Times(1)
+	authRedisRepo.EXPECT().AddUserWithEmail(user).Return(nil).Times(1)
+
+	service := AuthService.NewAuthService(authPostgresRepo, authRedisRepo, nil, nil, testPasswordHasher, AuthService.PasswordPolicy{})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, signIns)
+	for range signIns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := service.SignIn(AuthService.UserSignIn{Email: user.Email, Password: "qwerty"}, AuthService.ClientInfo{})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.Equal(t, err, AuthService.ErrEmailNotVerified)
+	}
+}
@@ -0,0 +1,222 @@
+package AuthService_test
+
+import (
+	"github.com/dgrijalva/jwt-go"
+	"github.com/jst-Frenzy/ControlSystem/AuthService/internal/AuthService"
+	"github.com/jst-Frenzy/ControlSystem/protobuf/permissions"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestManager_NewJWTAndParse(t *testing.T) {
+	testTable := []struct {
+		name      string
+		algorithm string
+	}{
+		{
+			name:      "RS256",
+			algorithm: AuthService.AlgorithmRS256,
+		},
+		{
+			name:      "EdDSA",
+			algorithm: AuthService.AlgorithmEdDSA,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			m, err := AuthService.NewManager(t.TempDir(), testCase.algorithm, 0)
+			assert.NoError(t, err)
+
+			token, errJWT := m.NewJWT(AuthService.User{ID: 7, UserName: "test", Role: "user"}, time.Minute)
+			assert.NoError(t, errJWT)
+
+			parsed, _, errParse := new(jwt.Parser).ParseUnverified(token, &AuthService.CustomClaims{})
+			assert.NoError(t, errParse)
+			assert.Equal(t, testCase.algorithm, parsed.Method.Alg())
+			assert.Equal(t, m.JWKS()[0].Kid, parsed.Header["kid"])
+
+			claims := parsed.Claims.(*AuthService.CustomClaims)
+			assert.NotEmpty(t, claims.Id)
+
+			info, errInfo := m.Parse(token)
+			assert.NoError(t, errInfo)
+			assert.Equal(t, AuthService.InfoFromToken{
+				ID:          7,
+				Role:        "user",
+				Permissions: []string{permissions.CartManage},
+				UserName:    "test",
+				CartID:      "7",
+				TokenID:     claims.Id,
+				IssuedAt:    time.UnixMilli(claims.IssuedAtMs),
+				ExpiresAt:   time.Unix(claims.ExpiresAt, 0),
+			}, info)
+		})
+	}
+}
+
+func TestManager_Rotate(t *testing.T) {
+	dir := t.TempDir()
+
+	m, err := AuthService.NewManager(dir, AuthService.AlgorithmRS256, 0)
+	assert.NoError(t, err)
+
+	oldToken, err := m.NewJWT(AuthService.User{ID: 1}, time.Minute)
+	assert.NoError(t, err)
+
+	assert.NoError(t, m.Rotate())
+
+	newToken, err := m.NewJWT(AuthService.User{ID: 1}, time.Minute)
+	assert.NoError(t, err)
+
+	_, errOld := m.Parse(oldToken)
+	assert.NoError(t, errOld)
+	_, errNew := m.Parse(newToken)
+	assert.NoError(t, errNew)
+	assert.Len(t, m.JWKS(), 2)
+
+	reloaded, err := AuthService.NewManager(dir, AuthService.AlgorithmRS256, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, m.JWKS(), reloaded.JWKS())
+
+	_, errReloaded := reloaded.Parse(oldToken)
+	assert.NoError(t, errReloaded)
+}
+
+func TestManager_ParseForeignToken(t *testing.T) {
+	m, err := AuthService.NewManager(t.TempDir(), AuthService.AlgorithmRS256, 0)
+	assert.NoError(t, err)
+
+	other, err := AuthService.NewManager(t.TempDir(), AuthService.AlgorithmRS256, 0)
+	assert.NoError(t, err)
+
+	foreignToken, err := other.NewJWT(AuthService.User{ID: 1}, time.Minute)
+	assert.NoError(t, err)
+
+	_, errParse := m.Parse(foreignToken)
+	assert.Error(t, errParse)
+
+	hmacToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &AuthService.CustomClaims{}).SignedString([]byte("secret"))
+	assert.NoError(t, err)
+
+	_, errHMAC := m.Parse(hmacToken)
+	assert.Error(t, errHMAC)
+}
+
+func TestManager_RotationRetiresOldKeys(t *testing.T) {
+	dir := t.TempDir()
+
+	m, err := AuthService.NewManager(dir, AuthService.AlgorithmEdDSA, 0)
+	assert.NoError(t, err)
+	first := m.JWKS()[0]
+
+	assert.NoError(t, m.Rotate())
+	var second AuthService.JWK
+	for _, k := range m.JWKS() {
+		if k.Kid != first.Kid {
+			second = k
+		}
+	}
+
+	for kid, age := range map[string]time.Duration{first.Kid: 101 * 24 * time.Hour, second.Kid: 100 * 24 * time.Hour} {
+		modTime := time.Now().Add(-age)
+		assert.NoError(t, os.Chtimes(filepath.Join(dir, kid+".pem"), modTime, modTime))
+	}
+
+	// the outdated signing key is rotated, the key before it is retired
+	// and the outdated one is kept to verify tokens it has already signed
+	reloaded, err := AuthService.NewManager(dir, AuthService.AlgorithmEdDSA, 720*time.Hour)
+	assert.NoError(t, err)
+
+	jwks := reloaded.JWKS()
+	assert.Len(t, jwks, 2)
+	assert.NotContains(t, jwks, first)
+	assert.Contains(t, jwks, second)
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestManager_PurposeToken(t *testing.T) {
+	m, err := AuthService.NewManager(t.TempDir(), AuthService.AlgorithmEdDSA, 0)
+	assert.NoError(t, err)
+
+	purposeToken, err := m.NewPurposeToken(7, AuthService.PurposeEmailVerification, time.Minute)
+	assert.NoError(t, err)
+
+	userID, err := m.ParsePurposeToken(purposeToken, AuthService.PurposeEmailVerification)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, userID)
+
+	_, errPurpose := m.ParsePurposeToken(purposeToken, "other")
+	assert.Error(t, errPurpose)
+
+	_, errAccess := m.Parse(purposeToken)
+	assert.Error(t, errAccess)
+
+	accessToken, err := m.NewJWT(AuthService.User{ID: 7}, time.Minute)
+	assert.NoError(t, err)
+
+	_, errNoPurpose := m.ParsePurposeToken(accessToken, AuthService.PurposeEmailVerification)
+	assert.Error(t, errNoPurpose)
+
+	expiredToken, err := m.NewPurposeToken(7, AuthService.PurposeEmailVerification, -time.Minute)
+	assert.NoError(t, err)
+
+	_, errExpired := m.ParsePurposeToken(expiredToken, AuthService.PurposeEmailVerification)
+	assert.Error(t, errExpired)
+}
+
+func TestManager_ClientAndIDTokens(t *testing.T) {
+	m, err := AuthService.NewManager(t.TempDir(), AuthService.AlgorithmRS256, 0)
+	assert.NoError(t, err)
+
+	clientToken, err := m.NewClientJWT(AuthService.User{ID: 7, UserName: "test", Role: "admin"}, "client", "openid email", time.Minute)
+	assert.NoError(t, err)
+
+	info, err := m.Parse(clientToken)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, info.ID)
+	assert.Equal(t, "client", info.ClientID)
+	assert.Equal(t, "openid email", info.Scope)
+	assert.Empty(t, info.Role)
+	assert.Empty(t, info.Permissions)
+
+	claims := AuthService.IDTokenClaims{Nonce: "nonce"}
+	claims.Subject = "7"
+	claims.Audience = "client"
+	claims.ExpiresAt = time.Now().Add(time.Minute).Unix()
+
+	idToken, err := m.NewIDToken(claims)
+	assert.NoError(t, err)
+
+	_, errParse := m.Parse(idToken)
+	assert.Error(t, errParse)
+}
+
+func TestManager_ImpersonationToken(t *testing.T) {
+	m, err := AuthService.NewManager(t.TempDir(), AuthService.AlgorithmRS256, 0)
+	assert.NoError(t, err)
+
+	user := AuthService.User{ID: 7, UserName: "test", Role: AuthService.RoleUser}
+
+	token, err := m.NewImpersonationJWT(user, 1, time.Minute)
+	assert.NoError(t, err)
+
+	info, err := m.Parse(token)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, info.ID)
+	assert.Equal(t, 1, info.ActorID)
+	assert.Equal(t, AuthService.PermissionsForRole(AuthService.RoleUser), info.Permissions)
+
+	ownToken, err := m.NewJWT(user, time.Minute)
+	assert.NoError(t, err)
+
+	ownInfo, err := m.Parse(ownToken)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, ownInfo.ActorID)
+}
@@ -0,0 +1,73 @@
+package AuthService_test
+
+import (
+	"github.com/jst-Frenzy/ControlSystem/AuthService/internal/AuthService"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA1 test key of RFC 6238 appendix B, "12345678901234567890" in base32.
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTP_Code(t *testing.T) {
+	testTable := []struct {
+		name         string
+		unixTime     int64
+		expectedCode string
+	}{
+		{name: "59", unixTime: 59, expectedCode: "287082"},
+		{name: "1111111109", unixTime: 1111111109, expectedCode: "081804"},
+		{name: "1111111111", unixTime: 1111111111, expectedCode: "050471"},
+		{name: "1234567890", unixTime: 1234567890, expectedCode: "005924"},
+		{name: "2000000000", unixTime: 2000000000, expectedCode: "279037"},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			code, err := AuthService.TOTPCode(rfc6238Secret, time.Unix(testCase.unixTime, 0))
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedCode, code)
+		})
+	}
+}
+
+func TestTOTP_Validate(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+
+	testTable := []struct {
+		name        string
+		codeTime    time.Time
+		expectedOK  bool
+		counterDiff int64
+	}{
+		{name: "Current Period", codeTime: now, expectedOK: true},
+		{name: "Previous Period", codeTime: now.Add(-30 * time.Second), expectedOK: true, counterDiff: -1},
+		{name: "Next Period", codeTime: now.Add(30 * time.Second), expectedOK: true, counterDiff: 1},
+		{name: "Too Old", codeTime: now.Add(-90 * time.Second), expectedOK: false},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			code, err := AuthService.TOTPCode(rfc6238Secret, testCase.codeTime)
+			assert.NoError(t, err)
+
+			counter, ok := AuthService.ValidateTOTP(rfc6238Secret, code, now)
+			assert.Equal(t, testCase.expectedOK, ok)
+			if ok {
+				assert.Equal(t, now.Unix()/30+testCase.counterDiff, counter)
+			}
+		})
+	}
+}
+
+func TestTOTP_NewSecret(t *testing.T) {
+	secret, err := AuthService.NewTOTPSecret()
+	assert.NoError(t, err)
+
+	code, err := AuthService.TOTPCode(secret, time.Now())
+	assert.NoError(t, err)
+
+	_, ok := AuthService.ValidateTOTP(secret, code, time.Now())
+	assert.True(t, ok)
+}
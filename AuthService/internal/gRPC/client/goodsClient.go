@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"github.com/jst-Frenzy/ControlSystem/AuthService/internal/AuthService"
+	gen "github.com/jst-Frenzy/ControlSystem/protobuf/gen/goods"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"time"
+)
+
+type GoodsClient interface {
+	AuthService.UserDataHolder
+	Close() error
+}
+
+type SellerExport struct {
+	ID    string             `json:"id"`
+	Name  string             `json:"name"`
+	Items []SellerItemExport `json:"items"`
+}
+
+type SellerItemExport struct {
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Quantity    int     `json:"quantity"`
+	Price       float64 `json:"price"`
+}
+
+type goodsClient struct {
+	conn   *grpc.ClientConn
+	client gen.GoodsServiceClient
+}
+
+func NewGoodsClient(addr string) (GoodsClient, error) {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to goods service: %w", err)
+	}
+
+	return &goodsClient{
+		conn:   conn,
+		client: gen.NewGoodsServiceClient(conn),
+	}, nil
+}
+
+func (c *goodsClient) Name() string {
+	return "seller"
+}
+
+// ExportUserData returns a SellerExport, or nil for a user that has never sold anything.
+func (c *goodsClient) ExportUserData(ctx context.Context, userID int) (interface{}, error) {
+	req := &gen.UserDataRequest{UserId: int64(userID)}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	resp, err := c.client.ExportUserData(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.GetSellerId() == "" {
+		return nil, nil
+	}
+
+	seller := SellerExport{
+		ID:    resp.GetSellerId(),
+		Name:  resp.GetSellerName(),
+		Items: make([]SellerItemExport, 0, len(resp.GetItems())),
+	}
+	for _, i := range resp.GetItems() {
+		seller.Items = append(seller.Items, SellerItemExport{
+			ID:          i.GetId(),
+			Name:        i.GetName(),
+			Description: i.GetDescription(),
+			Quantity:    int(i.GetQuantity()),
+			Price:       i.GetPrice(),
+		})
+	}
+
+	return seller, nil
+}
+
+func (c *goodsClient) DeleteUserData(ctx context.Context, userID int) error {
+	req := &gen.UserDataRequest{UserId: int64(userID)}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := c.client.DeleteUserData(ctx, req)
+	return err
+}
+
+func (c *goodsClient) Close() error {
+	if c.conn != nil {
+		return c.conn.Close()
+	}
+	return nil
+}
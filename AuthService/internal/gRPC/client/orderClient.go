@@ -0,0 +1,88 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"github.com/jst-Frenzy/ControlSystem/AuthService/internal/AuthService"
+	gen "github.com/jst-Frenzy/ControlSystem/protobuf/gen/order"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"time"
+)
+
+type OrderClient interface {
+	AuthService.UserDataHolder
+	Close() error
+}
+
+type CartItemExport struct {
+	ID        int     `json:"id"`
+	ProductID string  `json:"product_id"`
+	Name      string  `json:"name"`
+	Quantity  int     `json:"quantity"`
+	Price     float64 `json:"price"`
+}
+
+type orderClient struct {
+	conn   *grpc.ClientConn
+	client gen.OrderServiceClient
+}
+
+func NewOrderClient(addr string) (OrderClient, error) {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to order service: %w", err)
+	}
+
+	return &orderClient{
+		conn:   conn,
+		client: gen.NewOrderServiceClient(conn),
+	}, nil
+}
+
+func (c *orderClient) Name() string {
+	return "cart"
+}
+
+// ExportUserData returns the cart as a []CartItemExport.
+func (c *orderClient) ExportUserData(ctx context.Context, userID int) (interface{}, error) {
+	req := &gen.UserDataRequest{UserId: int64(userID)}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	resp, err := c.client.ExportUserData(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	cart := make([]CartItemExport, 0, len(resp.GetItems()))
+	for _, i := range resp.GetItems() {
+		cart = append(cart, CartItemExport{
+			ID:        int(i.GetId()),
+			ProductID: i.GetProductId(),
+			Name:      i.GetName(),
+			Quantity:  int(i.GetQuantity()),
+			Price:     i.GetPrice(),
+		})
+	}
+
+	return cart, nil
+}
+
+func (c *orderClient) DeleteUserData(ctx context.Context, userID int) error {
+	req := &gen.UserDataRequest{UserId: int64(userID)}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := c.client.DeleteUserData(ctx, req)
+	return err
+}
+
+func (c *orderClient) Close() error {
+	if c.conn != nil {
+		return c.conn.Close()
+	}
+	return nil
+}
@@ -8,13 +8,14 @@ import (
 	grpc_logrus "github.com/grpc-ecosystem/go-grpc-middleware/logging/logrus"
 	grpc_recovery "github.com/grpc-ecosystem/go-grpc-middleware/recovery"
 	"github.com/jst-Frenzy/ControlSystem/AuthService/internal/AuthService"
-	gen "github.com/jst-Frenzy/ControlSystem/protobuf/gen"
+	gen "github.com/jst-Frenzy/ControlSystem/protobuf/gen/auth"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"net"
 	"strconv"
+	"strings"
 )
 
 type Deps struct {
@@ -76,12 +77,109 @@ func (s *Server) ValidateToken(ctx context.Context, req *gen.ValidateTokenReques
 	if err != nil {
 		return &gen.ValidateTokenResponse{Valid: false}, errors.New("can't parse token")
 	}
+	if info.ClientID != "" {
+		return &gen.ValidateTokenResponse{Valid: false}, errors.New("token is issued to an oauth client")
+	}
+
+	response := &gen.ValidateTokenResponse{
+		Valid:       true,
+		UserId:      strconv.Itoa(info.ID),
+		Role:        info.Role,
+		UserName:    info.UserName,
+		CartId:      info.CartID,
+		Permissions: info.Permissions,
+	}
+	if info.ActorID != 0 {
+		response.ActorId = strconv.Itoa(info.ActorID)
+	}
+
+	return response, nil
+}
+
+func (s *Server) ValidateAPIKey(ctx context.Context, req *gen.ValidateAPIKeyRequest) (*gen.ValidateTokenResponse, error) {
+	if req.GetApiKey() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "api_key is required")
+	}
+
+	info, err := s.authService.ValidateAPIKey(req.GetApiKey())
+	if err != nil {
+		return &gen.ValidateTokenResponse{Valid: false}, errors.New("can't validate api key")
+	}
 
 	return &gen.ValidateTokenResponse{
-		Valid:    true,
-		UserId:   strconv.Itoa(info.ID),
-		Role:     info.Role,
-		UserName: info.UserName,
-		CartId:   info.CartID,
+		Valid:       true,
+		UserId:      strconv.Itoa(info.ID),
+		Role:        info.Role,
+		UserName:    info.UserName,
+		CartId:      info.CartID,
+		Permissions: info.Permissions,
+	}, nil
+}
+
+func (s *Server) IntrospectToken(ctx context.Context, req *gen.IntrospectTokenRequest) (*gen.IntrospectTokenResponse, error) {
+	if req.GetToken() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "token is required")
+	}
+
+	info, err := s.authService.ParseToken(req.GetToken())
+	if err != nil {
+		return &gen.IntrospectTokenResponse{Active: false}, nil
+	}
+
+	// first-party tokens report their permissions as the scope
+	scope := strings.Join(info.Permissions, " ")
+	if info.ClientID != "" {
+		scope = info.Scope
+	}
+
+	return &gen.IntrospectTokenResponse{
+		Active:    true,
+		Scope:     scope,
+		Username:  info.UserName,
+		TokenType: "access_token",
+		Exp:       info.ExpiresAt.Unix(),
+		Iat:       info.IssuedAt.Unix(),
+		Sub:       strconv.Itoa(info.ID),
+		Jti:       info.TokenID,
+		Role:      info.Role,
+		CartId:    info.CartID,
+	}, nil
+}
+
+func (s *Server) RevokeToken(ctx context.Context, req *gen.RevokeTokenRequest) (*gen.RevokeTokenResponse, error) {
+	if req.GetToken() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "token is required")
+	}
+
+	if err := s.authService.RevokeToken(req.GetToken()); err != nil {
+		s.logger.WithError(err).Error("can't revoke token")
+		return nil, status.Errorf(codes.Internal, "can't revoke token")
+	}
+
+	return &gen.RevokeTokenResponse{}, nil
+}
+
+func (s *Server) GetUser(ctx context.Context, req *gen.GetUserRequest) (*gen.GetUserResponse, error) {
+	if req.GetUserId() <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "user_id is required")
+	}
+
+	user, err := s.authService.GetUser(int(req.GetUserId()))
+	if err != nil {
+		if errors.Is(err, AuthService.ErrUserNotFound) {
+			return nil, status.Errorf(codes.NotFound, "user not found")
+		}
+		s.logger.WithError(err).Error("can't get user")
+		return nil, status.Errorf(codes.Internal, "can't get user")
+	}
+
+	return &gen.GetUserResponse{
+		UserId:        int64(user.ID),
+		UserName:      user.UserName,
+		Email:         user.Email,
+		Role:          user.Role,
+		Permissions:   AuthService.PermissionsForRole(user.Role),
+		EmailVerified: user.EmailVerifiedAt != nil,
+		CreatedAt:     user.CreatedAt.Unix(),
 	}, nil
 }
@@ -7,10 +7,12 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/jst-Frenzy/ControlSystem/AuthService/internal/AuthService"
 	mock_AuthService "github.com/jst-Frenzy/ControlSystem/AuthService/internal/mocks"
-	gen "github.com/jst-Frenzy/ControlSystem/protobuf/gen"
+	gen "github.com/jst-Frenzy/ControlSystem/protobuf/gen/auth"
+	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"testing"
+	"time"
 )
 
 func TestServer_ValidateToken(t *testing.T) {
@@ -30,18 +32,45 @@ func TestServer_ValidateToken(t *testing.T) {
 			inputAccessToken:          "access_token",
 			mockBehavior: func(s *mock_AuthService.MockAuthService, accessToken string) {
 				s.EXPECT().ParseToken(accessToken).Return(AuthService.InfoFromToken{
-					ID:       1,
-					Role:     "user",
-					UserName: "test name",
-					CartID:   "cart id",
+					ID:          1,
+					Role:        "user",
+					Permissions: []string{"cart:manage"},
+					UserName:    "test name",
+					CartID:      "cart id",
 				}, nil)
 			},
 			expectedValidateTokenResponse: &gen.ValidateTokenResponse{
-				Valid:    true,
-				UserId:   "1",
-				Role:     "user",
-				UserName: "test name",
-				CartId:   "cart id",
+				Valid:       true,
+				UserId:      "1",
+				Role:        "user",
+				UserName:    "test name",
+				CartId:      "cart id",
+				Permissions: []string{"cart:manage"},
+			},
+			expectedError: nil,
+		},
+		{
+			name:                      "Impersonated",
+			inputValidateTokenRequest: &gen.ValidateTokenRequest{AccessToken: "access_token"},
+			inputAccessToken:          "access_token",
+			mockBehavior: func(s *mock_AuthService.MockAuthService, accessToken string) {
+				s.EXPECT().ParseToken(accessToken).Return(AuthService.InfoFromToken{
+					ID:          2,
+					Role:        "user",
+					Permissions: []string{"cart:manage"},
+					UserName:    "test name",
+					CartID:      "2",
+					ActorID:     1,
+				}, nil)
+			},
+			expectedValidateTokenResponse: &gen.ValidateTokenResponse{
+				Valid:       true,
+				UserId:      "2",
+				Role:        "user",
+				UserName:    "test name",
+				CartId:      "2",
+				Permissions: []string{"cart:manage"},
+				ActorId:     "1",
 			},
 			expectedError: nil,
 		},
@@ -89,3 +118,236 @@ func TestServer_ValidateToken(t *testing.T) {
 		})
 	}
 }
+
+func TestServer_ValidateAPIKey(t *testing.T) {
+	type mockBehavior func(s *mock_AuthService.MockAuthService, apiKey string)
+
+	testTable := []struct {
+		name             string
+		inputAPIKey      string
+		mockBehavior     mockBehavior
+		expectedResponse *gen.ValidateTokenResponse
+		expectedError    error
+	}{
+		{
+			name:        "OK",
+			inputAPIKey: "cs_key",
+			mockBehavior: func(s *mock_AuthService.MockAuthService, apiKey string) {
+				s.EXPECT().ValidateAPIKey(apiKey).Return(AuthService.InfoFromToken{
+					ID:          1,
+					Role:        "seller",
+					Permissions: []string{"goods:write"},
+					UserName:    "test name",
+					CartID:      "1",
+				}, nil)
+			},
+			expectedResponse: &gen.ValidateTokenResponse{
+				Valid:       true,
+				UserId:      "1",
+				Role:        "seller",
+				UserName:    "test name",
+				CartId:      "1",
+				Permissions: []string{"goods:write"},
+			},
+			expectedError: nil,
+		},
+		{
+			name:             "Empty API Key",
+			inputAPIKey:      "",
+			mockBehavior:     func(s *mock_AuthService.MockAuthService, apiKey string) {},
+			expectedResponse: nil,
+			expectedError:    status.Error(codes.InvalidArgument, "api_key is required"),
+		},
+		{
+			name:        "Revoked API Key",
+			inputAPIKey: "cs_key",
+			mockBehavior: func(s *mock_AuthService.MockAuthService, apiKey string) {
+				s.EXPECT().ValidateAPIKey(apiKey).Return(AuthService.InfoFromToken{}, AuthService.ErrAPIKeyInvalid)
+			},
+			expectedResponse: &gen.ValidateTokenResponse{Valid: false},
+			expectedError:    errors.New("can't validate api key"),
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			servMock := mock_AuthService.NewMockAuthService(c)
+			testCase.mockBehavior(servMock, testCase.inputAPIKey)
+
+			gRPCServ := NewGRPCServer(Deps{
+				Logger:      nil,
+				AuthService: servMock,
+			})
+
+			resp, err := gRPCServ.ValidateAPIKey(context.Background(), &gen.ValidateAPIKeyRequest{ApiKey: testCase.inputAPIKey})
+
+			assert.Equal(t, resp, testCase.expectedResponse)
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
+
+func TestServer_IntrospectToken(t *testing.T) {
+	type mockBehavior func(s *mock_AuthService.MockAuthService, token string)
+
+	issuedAt := time.Unix(1700000000, 0)
+
+	testTable := []struct {
+		name             string
+		inputToken       string
+		mockBehavior     mockBehavior
+		expectedResponse *gen.IntrospectTokenResponse
+		expectedError    error
+	}{
+		{
+			name:       "Active",
+			inputToken: "access_token",
+			mockBehavior: func(s *mock_AuthService.MockAuthService, token string) {
+				s.EXPECT().ParseToken(token).Return(AuthService.InfoFromToken{
+					ID:          1,
+					Role:        "seller",
+					Permissions: []string{"cart:manage", "goods:write"},
+					UserName:    "test name",
+					CartID:      "1",
+					TokenID:     "jti",
+					IssuedAt:    issuedAt,
+					ExpiresAt:   issuedAt.Add(2 * time.Hour),
+				}, nil)
+			},
+			expectedResponse: &gen.IntrospectTokenResponse{
+				Active:    true,
+				Scope:     "cart:manage goods:write",
+				Username:  "test name",
+				TokenType: "access_token",
+				Exp:       issuedAt.Add(2 * time.Hour).Unix(),
+				Iat:       issuedAt.Unix(),
+				Sub:       "1",
+				Jti:       "jti",
+				Role:      "seller",
+				CartId:    "1",
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "Revoked",
+			inputToken: "access_token",
+			mockBehavior: func(s *mock_AuthService.MockAuthService, token string) {
+				s.EXPECT().ParseToken(token).Return(AuthService.InfoFromToken{}, AuthService.ErrTokenRevoked)
+			},
+			expectedResponse: &gen.IntrospectTokenResponse{Active: false},
+			expectedError:    nil,
+		},
+		{
+			name:             "Empty Token",
+			inputToken:       "",
+			mockBehavior:     func(s *mock_AuthService.MockAuthService, token string) {},
+			expectedResponse: nil,
+			expectedError:    status.Error(codes.InvalidArgument, "token is required"),
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			servMock := mock_AuthService.NewMockAuthService(c)
+			testCase.mockBehavior(servMock, testCase.inputToken)
+
+			gRPCServ := NewGRPCServer(Deps{
+				Logger:      logrus.New(),
+				AuthService: servMock,
+			})
+
+			resp, err := gRPCServ.IntrospectToken(context.Background(), &gen.IntrospectTokenRequest{Token: testCase.inputToken})
+
+			assert.Equal(t, resp, testCase.expectedResponse)
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
+
+func TestServer_GetUser(t *testing.T) {
+	type mockBehavior func(s *mock_AuthService.MockAuthService, userID int)
+
+	createdAt := time.Unix(1700000000, 0)
+
+	testTable := []struct {
+		name             string
+		inputUserID      int64
+		mockBehavior     mockBehavior
+		expectedResponse *gen.GetUserResponse
+		expectedError    error
+	}{
+		{
+			name:        "OK",
+			inputUserID: 1,
+			mockBehavior: func(s *mock_AuthService.MockAuthService, userID int) {
+				s.EXPECT().GetUser(userID).Return(AuthService.User{
+					ID:        1,
+					UserName:  "test",
+					Email:     "test@test.com",
+					Role:      "user",
+					CreatedAt: createdAt,
+				}, nil)
+			},
+			expectedResponse: &gen.GetUserResponse{
+				UserId:      1,
+				UserName:    "test",
+				Email:       "test@test.com",
+				Role:        "user",
+				Permissions: []string{"cart:manage"},
+				CreatedAt:   createdAt.Unix(),
+			},
+			expectedError: nil,
+		},
+		{
+			name:        "Not Found",
+			inputUserID: 2,
+			mockBehavior: func(s *mock_AuthService.MockAuthService, userID int) {
+				s.EXPECT().GetUser(userID).Return(AuthService.User{}, AuthService.ErrUserNotFound)
+			},
+			expectedResponse: nil,
+			expectedError:    status.Error(codes.NotFound, "user not found"),
+		},
+		{
+			name:        "Service Failure",
+			inputUserID: 1,
+			mockBehavior: func(s *mock_AuthService.MockAuthService, userID int) {
+				s.EXPECT().GetUser(userID).Return(AuthService.User{}, errors.New("DB Failure"))
+			},
+			expectedResponse: nil,
+			expectedError:    status.Error(codes.Internal, "can't get user"),
+		},
+		{
+			name:             "Invalid User ID",
+			inputUserID:      0,
+			mockBehavior:     func(s *mock_AuthService.MockAuthService, userID int) {},
+			expectedResponse: nil,
+			expectedError:    status.Error(codes.InvalidArgument, "user_id is required"),
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			servMock := mock_AuthService.NewMockAuthService(c)
+			testCase.mockBehavior(servMock, int(testCase.inputUserID))
+
+			gRPCServ := NewGRPCServer(Deps{
+				Logger:      logrus.New(),
+				AuthService: servMock,
+			})
+
+			resp, err := gRPCServ.GetUser(context.Background(), &gen.GetUserRequest{UserId: testCase.inputUserID})
+
+			assert.Equal(t, resp, testCase.expectedResponse)
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
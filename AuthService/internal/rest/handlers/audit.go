@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"github.com/gin-gonic/gin"
+	"github.com/jst-Frenzy/ControlSystem/AuthService/internal/AuthService"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var auditCSVHeader = []string{"id", "occurred_at", "event", "outcome", "reason", "user_id", "actor_id", "ip", "user_agent", "correlation_id"}
+
+func (h *AuthHandler) ListAuditEvents(ctx *gin.Context) {
+	nameHandler := "ListAuditEvents"
+	var req AuthService.ListAuditEventsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "invalid query")
+		return
+	}
+
+	events, total, err := h.service.ListAuditEvents(req)
+	if err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"events": events,
+		"total":  total,
+	})
+}
+
+// ExportAuditEvents streams the events matching the filters of ListAuditEvents as CSV. The status is
+// only sent with the first event, an error after it can just cut the file short.
+func (h *AuthHandler) ExportAuditEvents(ctx *gin.Context) {
+	nameHandler := "ExportAuditEvents"
+	var req AuthService.ListAuditEventsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "invalid query")
+		return
+	}
+
+	w := csv.NewWriter(ctx.Writer)
+	started := false
+	start := func() error {
+		started = true
+		ctx.Header("Content-Type", "text/csv; charset=utf-8")
+		ctx.Header("Content-Disposition", `attachment; filename="audit-log.csv"`)
+		ctx.Status(http.StatusOK)
+		return w.Write(auditCSVHeader)
+	}
+
+	err := h.service.ExportAuditEvents(req, func(event AuthService.AuditEvent) error {
+		if !started {
+			if errStart := start(); errStart != nil {
+				return errStart
+			}
+		}
+		return w.Write(auditCSVRecord(event))
+	})
+	if err != nil {
+		if !started {
+			newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+			return
+		}
+		logrus.WithError(err).Error("audit log export cut short")
+	}
+
+	if !started {
+		if errStart := start(); errStart != nil {
+			logrus.WithError(errStart).Error("can't write audit log export")
+			return
+		}
+	}
+	w.Flush()
+}
+
+func auditCSVRecord(event AuthService.AuditEvent) []string {
+	return []string{
+		strconv.FormatInt(event.ID, 10),
+		event.OccurredAt.UTC().Format(time.RFC3339),
+		event.Event,
+		event.Outcome,
+		csvSafe(event.Reason),
+		optionalID(event.UserID),
+		optionalID(event.ActorID),
+		event.IP,
+		csvSafe(event.UserAgent),
+		csvSafe(event.CorrelationID),
+	}
+}
+
+func optionalID(id *int) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.Itoa(*id)
+}
+
+// csvSafe keeps spreadsheets from running client controlled values, like the user agent, as formulas.
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/jst-Frenzy/ControlSystem/AuthService/internal/AuthService"
+	mock_AuthService "github.com/jst-Frenzy/ControlSystem/AuthService/internal/mocks"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandler_ExportAuditEvents(t *testing.T) {
+	type mockBehavior func(s *mock_AuthService.MockAuthService)
+
+	userID := 1
+	occurredAt := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+
+	testTable := []struct {
+		name                 string
+		inputQuery           string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:       "OK",
+			inputQuery: "?event=sign_in",
+			mockBehavior: func(s *mock_AuthService.MockAuthService) {
+				s.EXPECT().ExportAuditEvents(AuthService.ListAuditEventsRequest{Event: "sign_in"}, gomock.Any()).
+					DoAndReturn(func(req AuthService.ListAuditEventsRequest, write func(AuthService.AuditEvent) error) error {
+						return write(AuthService.AuditEvent{
+							ID:         7,
+							OccurredAt: occurredAt,
+							Event:      AuthService.AuditSignIn,
+							Outcome:    AuthService.AuditOutcomeFailure,
+							Reason:     "password is wrong",
+							UserID:     &userID,
+							IP:         "192.0.2.1",
+							UserAgent:  "=HYPERLINK(\"https://evil.test\")",
+						})
+					})
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: "id,occurred_at,event,outcome,reason,user_id,actor_id,ip,user_agent,correlation_id\n" +
+				"7,2026-10-18T10:00:00Z,sign_in,failure,password is wrong,1,,192.0.2.1,\"'=HYPERLINK(\"\"https://evil.test\"\")\",\n",
+		},
+		{
+			name:       "No Events",
+			inputQuery: "",
+			mockBehavior: func(s *mock_AuthService.MockAuthService) {
+				s.EXPECT().ExportAuditEvents(AuthService.ListAuditEventsRequest{}, gomock.Any()).Return(nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: "id,occurred_at,event,outcome,reason,user_id,actor_id,ip,user_agent,correlation_id\n",
+		},
+		{
+			name:                 "Invalid Query",
+			inputQuery:           "?from=yesterday",
+			mockBehavior:         func(s *mock_AuthService.MockAuthService) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"Message":"invalid query"}`,
+		},
+		{
+			name:       "Service Failure",
+			inputQuery: "",
+			mockBehavior: func(s *mock_AuthService.MockAuthService) {
+				s.EXPECT().ExportAuditEvents(AuthService.ListAuditEventsRequest{}, gomock.Any()).Return(errors.New("DB Failure"))
+			},
+			expectedStatusCode:   500,
+			expectedResponseBody: `{"Message":"DB Failure"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			serv := mock_AuthService.NewMockAuthService(c)
+			testCase.mockBehavior(serv)
+
+			handler := NewAuthHandler(serv)
+
+			r := gin.New()
+			r.GET("/audit/export", handler.ExportAuditEvents)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/audit/export"+testCase.inputQuery, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, testCase.expectedStatusCode)
+			assert.Equal(t, w.Body.String(), testCase.expectedResponseBody)
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/jst-Frenzy/ControlSystem/AuthService/internal/AuthService"
+	"net/http"
+)
+
+type ExternalLoginHandler struct {
+	service AuthService.ExternalLoginService
+}
+
+func NewExternalLoginHandler(service AuthService.ExternalLoginService) *ExternalLoginHandler {
+	return &ExternalLoginHandler{service: service}
+}
+
+func (h *ExternalLoginHandler) Providers(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{
+		"providers": h.service.Providers(),
+	})
+}
+
+func (h *ExternalLoginHandler) Login(ctx *gin.Context) {
+	nameHandler := "ExternalLogin"
+
+	loginURL, err := h.service.LoginURL(ctx.Request.Context(), ctx.Param("provider"))
+	if err != nil {
+		externalLoginErrorResponse(ctx, nameHandler, err)
+		return
+	}
+
+	ctx.Redirect(http.StatusFound, loginURL)
+}
+
+// Reauthenticate returns the URL to send a signed in user to, the callback answers with a reauthentication token.
+func (h *ExternalLoginHandler) Reauthenticate(ctx *gin.Context) {
+	nameHandler := "ExternalReauthenticate"
+	userID := ctx.MustGet(userIDCtx).(int)
+
+	loginURL, err := h.service.ReauthenticationURL(ctx.Request.Context(), ctx.Param("provider"), userID)
+	if err != nil {
+		externalLoginErrorResponse(ctx, nameHandler, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"url": loginURL,
+	})
+}
+
+func (h *ExternalLoginHandler) Callback(ctx *gin.Context) {
+	nameHandler := "ExternalLoginCallback"
+
+	if providerErr := ctx.Query("error"); providerErr != "" {
+		newErrorResponse(ctx, nameHandler, http.StatusUnauthorized, AuthService.ErrExternalLoginFailed.Error()+": "+providerErr)
+		return
+	}
+
+	code, state := ctx.Query("code"), ctx.Query("state")
+	if code == "" || state == "" {
+		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "code and state are required")
+		return
+	}
+
+	tokens, err := h.service.Callback(ctx.Request.Context(), ctx.Param("provider"), code, state, clientInfo(ctx))
+	if err != nil {
+		externalLoginErrorResponse(ctx, nameHandler, err)
+		return
+	}
+
+	signInResponse(ctx, tokens)
+}
+
+func externalLoginErrorResponse(ctx *gin.Context, nameHandler string, err error) {
+	switch {
+	case errors.Is(err, AuthService.ErrExternalProviderNotFound):
+		newErrorResponse(ctx, nameHandler, http.StatusNotFound, err.Error())
+	case errors.Is(err, AuthService.ErrExternalLoginInvalid):
+		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, err.Error())
+	case errors.Is(err, AuthService.ErrExternalLoginFailed):
+		newErrorResponse(ctx, nameHandler, http.StatusUnauthorized, AuthService.ErrExternalLoginFailed.Error())
+	case errors.Is(err, AuthService.ErrExternalProviderUnavailable):
+		newErrorResponse(ctx, nameHandler, http.StatusServiceUnavailable, err.Error())
+	case errors.Is(err, AuthService.ErrIdentityNotFound):
+		newErrorResponse(ctx, nameHandler, http.StatusForbidden, "identity isn't linked to the user")
+	default:
+		signInErrorResponse(ctx, nameHandler, err)
+	}
+}
+
+func (h *ExternalLoginHandler) ListIdentities(ctx *gin.Context) {
+	nameHandler := "ListIdentities"
+	userID := ctx.MustGet(userIDCtx).(int)
+
+	identities, err := h.service.ListIdentities(userID)
+	if err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"identities": identities,
+	})
+}
+
+func (h *ExternalLoginHandler) UnlinkIdentity(ctx *gin.Context) {
+	nameHandler := "UnlinkIdentity"
+	userID := ctx.MustGet(userIDCtx).(int)
+
+	if err := h.service.UnlinkIdentity(userID, ctx.Param("provider")); err != nil {
+		switch {
+		case errors.Is(err, AuthService.ErrIdentityNotFound), errors.Is(err, AuthService.ErrUserNotFound):
+			newErrorResponse(ctx, nameHandler, http.StatusNotFound, err.Error())
+		case errors.Is(err, AuthService.ErrLastSignInMethod):
+			newErrorResponse(ctx, nameHandler, http.StatusConflict, err.Error())
+		default:
+			newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, gin.H{})
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/jst-Frenzy/ControlSystem/AuthService/internal/AuthService"
+	mock_AuthService "github.com/jst-Frenzy/ControlSystem/AuthService/internal/mocks"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExternalLoginHandler_Callback(t *testing.T) {
+	type mockBehavior func(s *mock_AuthService.MockExternalLoginService)
+
+	testTable := []struct {
+		name                 string
+		query                string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:  "OK",
+			query: "?code=code&state=state",
+			mockBehavior: func(s *mock_AuthService.MockExternalLoginService) {
+				s.EXPECT().Callback(gomock.Any(), "corp", "code", "state", gomock.Any()).
+					Return(AuthService.Tokens{AccessToken: "access", RefreshToken: "refresh"}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"access token":"access","refresh token":"refresh"}`,
+		},
+		{
+			name:  "MFA Required",
+			query: "?code=code&state=state",
+			mockBehavior: func(s *mock_AuthService.MockExternalLoginService) {
+				s.EXPECT().Callback(gomock.Any(), "corp", "code", "state", gomock.Any()).
+					Return(AuthService.Tokens{MFAToken: "mfa"}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"mfa required":true,"mfa token":"mfa"}`,
+		},
+		{
+			name:  "Reauthenticated",
+			query: "?code=code&state=state",
+			mockBehavior: func(s *mock_AuthService.MockExternalLoginService) {
+				s.EXPECT().Callback(gomock.Any(), "corp", "code", "state", gomock.Any()).
+					Return(AuthService.Tokens{ReauthToken: "reauth"}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"reauth token":"reauth"}`,
+		},
+		{
+			name:  "Identity Of Another User",
+			query: "?code=code&state=state",
+			mockBehavior: func(s *mock_AuthService.MockExternalLoginService) {
+				s.EXPECT().Callback(gomock.Any(), "corp", "code", "state", gomock.Any()).
+					Return(AuthService.Tokens{}, AuthService.ErrIdentityNotFound)
+			},
+			expectedStatusCode:   403,
+			expectedResponseBody: `{"Message":"identity isn't linked to the user"}`,
+		},
+		{
+			name:                 "Provider Error",
+			query:                "?error=access_denied&state=state",
+			mockBehavior:         func(s *mock_AuthService.MockExternalLoginService) {},
+			expectedStatusCode:   401,
+			expectedResponseBody: `{"Message":"identity provider rejected the login: access_denied"}`,
+		},
+		{
+			name:                 "No Code",
+			query:                "?state=state",
+			mockBehavior:         func(s *mock_AuthService.MockExternalLoginService) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"Message":"code and state are required"}`,
+		},
+		{
+			name:  "Rejected ID Token",
+			query: "?code=code&state=state",
+			mockBehavior: func(s *mock_AuthService.MockExternalLoginService) {
+				s.EXPECT().Callback(gomock.Any(), "corp", "code", "state", gomock.Any()).
+					Return(AuthService.Tokens{}, fmt.Errorf("%w: id token nonce doesn't match", AuthService.ErrExternalLoginFailed))
+			},
+			expectedStatusCode:   401,
+			expectedResponseBody: `{"Message":"identity provider rejected the login"}`,
+		},
+		{
+			name:  "Expired State",
+			query: "?code=code&state=state",
+			mockBehavior: func(s *mock_AuthService.MockExternalLoginService) {
+				s.EXPECT().Callback(gomock.Any(), "corp", "code", "state", gomock.Any()).
+					Return(AuthService.Tokens{}, AuthService.ErrExternalLoginInvalid)
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"Message":"external login is invalid or expired"}`,
+		},
+		{
+			name:  "Unverified Email",
+			query: "?code=code&state=state",
+			mockBehavior: func(s *mock_AuthService.MockExternalLoginService) {
+				s.EXPECT().Callback(gomock.Any(), "corp", "code", "state", gomock.Any()).
+					Return(AuthService.Tokens{}, AuthService.ErrEmailNotVerified)
+			},
+			expectedStatusCode:   403,
+			expectedResponseBody: `{"Message":"email is not verified"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			//Init Deps
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			externalServ := mock_AuthService.NewMockExternalLoginService(c)
+			testCase.mockBehavior(externalServ)
+
+			handler := NewExternalLoginHandler(externalServ)
+
+			//Test Server
+			r := gin.New()
+			r.GET("/external/:provider/callback", handler.Callback)
+
+			//Test Request
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/external/corp/callback"+testCase.query, nil)
+
+			//Perform Request
+			r.ServeHTTP(w, req)
+
+			//Assert
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestExternalLoginHandler_Reauthenticate(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	externalServ := mock_AuthService.NewMockExternalLoginService(c)
+	externalServ.EXPECT().ReauthenticationURL(gomock.Any(), "corp", 1).Return("https://idp.test/authorize?state=state", nil)
+
+	handler := NewExternalLoginHandler(externalServ)
+
+	r := gin.New()
+	r.POST("/external/:provider/reauthenticate", func(ctx *gin.Context) {
+		ctx.Set(userIDCtx, 1)
+	}, handler.Reauthenticate)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/external/corp/reauthenticate", nil)
+
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, `{"url":"https://idp.test/authorize?state=state"}`, w.Body.String())
+}
@@ -4,7 +4,9 @@ import (
 	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/jst-Frenzy/ControlSystem/AuthService/internal/AuthService"
+	"math"
 	"net/http"
+	"strconv"
 )
 
 type AuthHandler struct {
@@ -23,8 +25,12 @@ func (h *AuthHandler) SignUp(ctx *gin.Context) {
 		return
 	}
 
-	id, err := h.service.SignUp(user)
+	id, err := h.service.SignUp(user, clientInfo(ctx))
 	if err != nil {
+		if errors.Is(err, AuthService.ErrWeakPassword) {
+			newErrorResponse(ctx, nameHandler, http.StatusBadRequest, err.Error())
+			return
+		}
 		newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -42,9 +48,27 @@ func (h *AuthHandler) SignIn(ctx *gin.Context) {
 		return
 	}
 
-	tokens, err := h.service.SignIn(user)
+	tokens, err := h.service.SignIn(user, clientInfo(ctx))
 	if err != nil {
-		newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+		signInErrorResponse(ctx, nameHandler, err)
+		return
+	}
+
+	signInResponse(ctx, tokens)
+}
+
+func signInResponse(ctx *gin.Context, tokens AuthService.Tokens) {
+	if tokens.ReauthToken != "" {
+		ctx.JSON(http.StatusOK, gin.H{
+			"reauth token": tokens.ReauthToken,
+		})
+		return
+	}
+	if tokens.MFAToken != "" {
+		ctx.JSON(http.StatusOK, gin.H{
+			"mfa required": true,
+			"mfa token":    tokens.MFAToken,
+		})
 		return
 	}
 
@@ -54,6 +78,193 @@ func (h *AuthHandler) SignIn(ctx *gin.Context) {
 	})
 }
 
+func (h *AuthHandler) SignInMFA(ctx *gin.Context) {
+	nameHandler := "SignInMFA"
+	var req AuthService.SignInMFARequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "invalid input body")
+		return
+	}
+
+	tokens, err := h.service.SignInMFA(req.MFAToken, req.Code, clientInfo(ctx))
+	if err != nil {
+		signInErrorResponse(ctx, nameHandler, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"access token":  tokens.AccessToken,
+		"refresh token": tokens.RefreshToken,
+	})
+}
+
+func signInErrorResponse(ctx *gin.Context, nameHandler string, err error) {
+	var lockoutErr *AuthService.LockoutError
+	switch {
+	case errors.As(err, &lockoutErr):
+		ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(lockoutErr.RetryAfter.Seconds()))))
+		if errors.Is(err, AuthService.ErrAccountLocked) {
+			newErrorResponse(ctx, nameHandler, http.StatusLocked, err.Error())
+			return
+		}
+		newErrorResponse(ctx, nameHandler, http.StatusTooManyRequests, err.Error())
+	case errors.Is(err, AuthService.ErrWrongPassword), errors.Is(err, AuthService.ErrUserNotFound),
+		errors.Is(err, AuthService.ErrInvalidMFACode), errors.Is(err, AuthService.ErrMFATokenInvalid):
+		newErrorResponse(ctx, nameHandler, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, AuthService.ErrEmailNotVerified), errors.Is(err, AuthService.ErrUserDeactivated):
+		newErrorResponse(ctx, nameHandler, http.StatusForbidden, err.Error())
+	default:
+		newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func (h *AuthHandler) EnrollTOTP(ctx *gin.Context) {
+	nameHandler := "EnrollTOTP"
+	userID := ctx.MustGet(userIDCtx).(int)
+
+	enrollment, err := h.service.EnrollTOTP(userID)
+	if err != nil {
+		if errors.Is(err, AuthService.ErrTOTPAlreadyEnabled) {
+			newErrorResponse(ctx, nameHandler, http.StatusConflict, err.Error())
+			return
+		}
+		newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, enrollment)
+}
+
+func (h *AuthHandler) ConfirmTOTP(ctx *gin.Context) {
+	nameHandler := "ConfirmTOTP"
+	var req AuthService.TOTPCodeRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "invalid input body")
+		return
+	}
+
+	userID := ctx.MustGet(userIDCtx).(int)
+
+	codes, err := h.service.ConfirmTOTP(userID, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, AuthService.ErrTOTPAlreadyEnabled):
+			newErrorResponse(ctx, nameHandler, http.StatusConflict, err.Error())
+		case errors.Is(err, AuthService.ErrTOTPNotEnrolled):
+			newErrorResponse(ctx, nameHandler, http.StatusNotFound, err.Error())
+		case errors.Is(err, AuthService.ErrInvalidMFACode):
+			newErrorResponse(ctx, nameHandler, http.StatusBadRequest, err.Error())
+		default:
+			newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"recovery codes": codes,
+	})
+}
+
+func (h *AuthHandler) VerifyEmail(ctx *gin.Context) {
+	nameHandler := "VerifyEmail"
+	token := ctx.Query("token")
+	if token == "" {
+		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "token is empty")
+		return
+	}
+
+	if err := h.service.VerifyEmail(token); err != nil {
+		if errors.Is(err, AuthService.ErrVerificationTokenInvalid) {
+			newErrorResponse(ctx, nameHandler, http.StatusBadRequest, err.Error())
+			return
+		}
+		newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"verified": true,
+	})
+}
+
+func (h *AuthHandler) ResendEmailVerification(ctx *gin.Context) {
+	nameHandler := "ResendEmailVerification"
+	var req AuthService.EmailRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "invalid input body")
+		return
+	}
+
+	if err := h.service.ResendEmailVerification(req.Email); err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{})
+}
+
+func (h *AuthHandler) ForgotPassword(ctx *gin.Context) {
+	nameHandler := "ForgotPassword"
+	var req AuthService.EmailRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "invalid input body")
+		return
+	}
+
+	if err := h.service.ForgotPassword(req.Email); err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{})
+}
+
+func (h *AuthHandler) ResetPassword(ctx *gin.Context) {
+	nameHandler := "ResetPassword"
+	var req AuthService.ResetPasswordRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "invalid input body")
+		return
+	}
+
+	if err := h.service.ResetPassword(req.Token, req.Password, clientInfo(ctx)); err != nil {
+		if errors.Is(err, AuthService.ErrResetTokenInvalid) || errors.Is(err, AuthService.ErrWeakPassword) {
+			newErrorResponse(ctx, nameHandler, http.StatusBadRequest, err.Error())
+			return
+		}
+		newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, gin.H{})
+}
+
+func (h *AuthHandler) ChangePassword(ctx *gin.Context) {
+	nameHandler := "ChangePassword"
+	var req AuthService.ChangePasswordRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "invalid input body")
+		return
+	}
+
+	userID := ctx.MustGet(userIDCtx).(int)
+
+	if err := h.service.ChangePassword(userID, req.OldPassword, req.NewPassword, req.ReauthToken, clientInfo(ctx)); err != nil {
+		if errors.Is(err, AuthService.ErrWrongPassword) || errors.Is(err, AuthService.ErrReauthenticationNeeded) {
+			newErrorResponse(ctx, nameHandler, http.StatusForbidden, err.Error())
+			return
+		}
+		if errors.Is(err, AuthService.ErrWeakPassword) {
+			newErrorResponse(ctx, nameHandler, http.StatusBadRequest, err.Error())
+			return
+		}
+		newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, gin.H{})
+}
+
 func (h *AuthHandler) Refresh(ctx *gin.Context) {
 	nameHandler := "Refresh"
 	var refreshToken AuthService.RefreshTokenRequest
@@ -62,40 +273,420 @@ func (h *AuthHandler) Refresh(ctx *gin.Context) {
 		return
 	}
 
-	newAccessToken, err := h.service.RefreshTokens(refreshToken.RefreshToken)
+	tokens, err := h.service.RefreshTokens(refreshToken.RefreshToken, clientInfo(ctx))
+	if err != nil {
+		if errors.Is(err, AuthService.ErrRefreshTokenNotFound) ||
+			errors.Is(err, AuthService.ErrRefreshTokenExpired) ||
+			errors.Is(err, AuthService.ErrRefreshTokenReused) ||
+			errors.Is(err, AuthService.ErrUserDeactivated) {
+			newErrorResponse(ctx, nameHandler, http.StatusUnauthorized, err.Error())
+			return
+		}
+		newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"access token":  tokens.AccessToken,
+		"refresh token": tokens.RefreshToken,
+	})
+}
+
+func (h *AuthHandler) Logout(ctx *gin.Context) {
+	nameHandler := "Logout"
+	var refreshToken AuthService.RefreshTokenRequest
+	if err := ctx.ShouldBind(&refreshToken); err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "invalid input body")
+		return
+	}
+
+	if err := h.service.Logout(refreshToken.RefreshToken, clientInfo(ctx)); err != nil {
+		if errors.Is(err, AuthService.ErrRefreshTokenNotFound) {
+			newErrorResponse(ctx, nameHandler, http.StatusUnauthorized, err.Error())
+			return
+		}
+		newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, gin.H{})
+}
+
+func (h *AuthHandler) LogoutAll(ctx *gin.Context) {
+	nameHandler := "LogoutAll"
+	userID := ctx.MustGet(userIDCtx).(int)
+
+	if err := h.service.LogoutAll(userID, clientInfo(ctx)); err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, gin.H{})
+}
+
+func (h *AuthHandler) ListSessions(ctx *gin.Context) {
+	nameHandler := "ListSessions"
+	userID := ctx.MustGet(userIDCtx).(int)
+
+	sessions, err := h.service.ListSessions(userID)
 	if err != nil {
 		newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{
-		"access token": newAccessToken,
+		"sessions": sessions,
 	})
 }
 
+func (h *AuthHandler) RevokeSession(ctx *gin.Context) {
+	nameHandler := "RevokeSession"
+	userID := ctx.MustGet(userIDCtx).(int)
+
+	if err := h.service.RevokeSession(userID, ctx.Param("id"), clientInfo(ctx)); err != nil {
+		if errors.Is(err, AuthService.ErrSessionNotFound) {
+			newErrorResponse(ctx, nameHandler, http.StatusNotFound, err.Error())
+			return
+		}
+		newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, gin.H{})
+}
+
+func (h *AuthHandler) CreateAPIKey(ctx *gin.Context) {
+	nameHandler := "CreateAPIKey"
+	var req AuthService.CreateAPIKeyRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "invalid input body")
+		return
+	}
+
+	userID := ctx.MustGet(userIDCtx).(int)
+
+	key, err := h.service.CreateAPIKey(userID, req)
+	if err != nil {
+		if errors.Is(err, AuthService.ErrInvalidScope) {
+			newErrorResponse(ctx, nameHandler, http.StatusBadRequest, err.Error())
+			return
+		}
+		newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, key)
+}
+
+func (h *AuthHandler) ListAPIKeys(ctx *gin.Context) {
+	nameHandler := "ListAPIKeys"
+	userID := ctx.MustGet(userIDCtx).(int)
+
+	keys, err := h.service.ListAPIKeys(userID)
+	if err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"api_keys": keys,
+	})
+}
+
+func (h *AuthHandler) RevokeAPIKey(ctx *gin.Context) {
+	nameHandler := "RevokeAPIKey"
+	keyID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "invalid api key id")
+		return
+	}
+
+	userID := ctx.MustGet(userIDCtx).(int)
+
+	if errRevoke := h.service.RevokeAPIKey(userID, keyID, clientInfo(ctx)); errRevoke != nil {
+		if errors.Is(errRevoke, AuthService.ErrAPIKeyNotFound) {
+			newErrorResponse(ctx, nameHandler, http.StatusNotFound, errRevoke.Error())
+			return
+		}
+		newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, errRevoke.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, gin.H{})
+}
+
+func (h *AuthHandler) JWKS(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, gin.H{
+		"keys": h.service.JWKS(),
+	})
+}
+
+func (h *AuthHandler) UnlockAccount(ctx *gin.Context) {
+	nameHandler := "UnlockAccount"
+	var req AuthService.EmailRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "invalid input body")
+		return
+	}
+
+	if err := h.service.UnlockAccount(req.Email); err != nil {
+		if errors.Is(err, AuthService.ErrUserNotFound) {
+			newErrorResponse(ctx, nameHandler, http.StatusNotFound, err.Error())
+			return
+		}
+		newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, gin.H{})
+}
+
 func (h *AuthHandler) ChangeRole(ctx *gin.Context) {
 	nameHandler := "ChangeRole"
-	type data struct {
-		User    AuthService.UserSignIn `json:"user"`
-		NewRole string                 `json:"newRole"`
-		Id      int                    `json:"id"`
-	}
-	var d data
-	if err := ctx.ShouldBind(&d); err != nil {
-		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, err.Error())
+	var req AuthService.ChangeRoleRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "invalid input body")
 		return
 	}
 
-	accessToken, err := h.service.ChangeRole(d.User, d.Id, d.NewRole)
+	actorID := ctx.MustGet(userIDCtx).(int)
+
+	user, err := h.service.ChangeRole(actorID, req.UserID, req.NewRole, clientInfo(ctx))
 	if err != nil {
-		if errors.Is(err, errors.New("not enough rights")) {
+		switch {
+		case errors.Is(err, AuthService.ErrInvalidRole), errors.Is(err, AuthService.ErrSelfRoleChange):
 			newErrorResponse(ctx, nameHandler, http.StatusBadRequest, err.Error())
+		case errors.Is(err, AuthService.ErrUserNotFound):
+			newErrorResponse(ctx, nameHandler, http.StatusNotFound, err.Error())
+		default:
+			newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"id":   user.ID,
+		"role": user.Role,
+	})
+}
+
+func (h *AuthHandler) GetProfile(ctx *gin.Context) {
+	nameHandler := "GetProfile"
+	userID := ctx.MustGet(userIDCtx).(int)
+
+	profile, err := h.service.GetProfile(userID)
+	if err != nil {
+		if errors.Is(err, AuthService.ErrUserNotFound) {
+			newErrorResponse(ctx, nameHandler, http.StatusNotFound, err.Error())
 			return
 		}
 		newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, profile)
+}
+
+func (h *AuthHandler) UpdateProfile(ctx *gin.Context) {
+	nameHandler := "UpdateProfile"
+	var req AuthService.UpdateProfileRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "invalid input body")
+		return
+	}
+
+	userID := ctx.MustGet(userIDCtx).(int)
+
+	profile, err := h.service.UpdateProfile(userID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, AuthService.ErrInvalidUserName):
+			newErrorResponse(ctx, nameHandler, http.StatusBadRequest, err.Error())
+		case errors.Is(err, AuthService.ErrEmailTaken):
+			newErrorResponse(ctx, nameHandler, http.StatusConflict, err.Error())
+		case errors.Is(err, AuthService.ErrUserNotFound):
+			newErrorResponse(ctx, nameHandler, http.StatusNotFound, err.Error())
+		default:
+			newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, profile)
+}
+
+func (h *AuthHandler) ExportAccount(ctx *gin.Context) {
+	nameHandler := "ExportAccount"
+	userID := ctx.MustGet(userIDCtx).(int)
+
+	export, err := h.service.ExportAccount(ctx.Request.Context(), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, AuthService.ErrUserNotFound):
+			newErrorResponse(ctx, nameHandler, http.StatusNotFound, err.Error())
+		case errors.Is(err, AuthService.ErrUserDataUnavailable):
+			newErrorResponse(ctx, nameHandler, http.StatusServiceUnavailable, err.Error())
+		default:
+			newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	ctx.Header("Content-Disposition", `attachment; filename="account-export.json"`)
+	ctx.JSON(http.StatusOK, export)
+}
+
+func (h *AuthHandler) DeleteAccount(ctx *gin.Context) {
+	nameHandler := "DeleteAccount"
+	var req AuthService.DeleteAccountRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "invalid input body")
+		return
+	}
+
+	userID := ctx.MustGet(userIDCtx).(int)
+
+	if err := h.service.DeleteAccount(ctx.Request.Context(), userID, req.Password, req.ReauthToken, clientInfo(ctx)); err != nil {
+		switch {
+		case errors.Is(err, AuthService.ErrWrongPassword), errors.Is(err, AuthService.ErrReauthenticationNeeded):
+			newErrorResponse(ctx, nameHandler, http.StatusForbidden, err.Error())
+		case errors.Is(err, AuthService.ErrUserNotFound):
+			newErrorResponse(ctx, nameHandler, http.StatusNotFound, err.Error())
+		case errors.Is(err, AuthService.ErrUserDataUnavailable):
+			newErrorResponse(ctx, nameHandler, http.StatusServiceUnavailable, err.Error())
+		default:
+			newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, gin.H{})
+}
+
+func (h *AuthHandler) ConfirmEmailChange(ctx *gin.Context) {
+	nameHandler := "ConfirmEmailChange"
+	token := ctx.Query("token")
+	if token == "" {
+		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "token is empty")
+		return
+	}
+
+	if err := h.service.ConfirmEmailChange(token); err != nil {
+		switch {
+		case errors.Is(err, AuthService.ErrVerificationTokenInvalid):
+			newErrorResponse(ctx, nameHandler, http.StatusBadRequest, err.Error())
+		case errors.Is(err, AuthService.ErrEmailTaken):
+			newErrorResponse(ctx, nameHandler, http.StatusConflict, err.Error())
+		default:
+			newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"changed": true,
+	})
+}
+
+func (h *AuthHandler) ListUsers(ctx *gin.Context) {
+	nameHandler := "ListUsers"
+	var req AuthService.ListUsersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "invalid query")
+		return
+	}
+
+	users, total, err := h.service.ListUsers(req)
+	if err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{
-		"accessToken": accessToken,
+		"users": users,
+		"total": total,
 	})
 }
+
+func (h *AuthHandler) DeactivateUser(ctx *gin.Context) {
+	nameHandler := "DeactivateUser"
+	userID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "invalid user id")
+		return
+	}
+
+	actorID := ctx.MustGet(userIDCtx).(int)
+
+	if errDeactivate := h.service.DeactivateUser(actorID, userID, clientInfo(ctx)); errDeactivate != nil {
+		switch {
+		case errors.Is(errDeactivate, AuthService.ErrSelfDeactivation):
+			newErrorResponse(ctx, nameHandler, http.StatusBadRequest, errDeactivate.Error())
+		case errors.Is(errDeactivate, AuthService.ErrUserNotFound):
+			newErrorResponse(ctx, nameHandler, http.StatusNotFound, errDeactivate.Error())
+		default:
+			newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, errDeactivate.Error())
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, gin.H{})
+}
+
+func (h *AuthHandler) ReactivateUser(ctx *gin.Context) {
+	nameHandler := "ReactivateUser"
+	userID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "invalid user id")
+		return
+	}
+
+	if errReactivate := h.service.ReactivateUser(userID); errReactivate != nil {
+		if errors.Is(errReactivate, AuthService.ErrUserNotFound) {
+			newErrorResponse(ctx, nameHandler, http.StatusNotFound, errReactivate.Error())
+			return
+		}
+		newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, errReactivate.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, gin.H{})
+}
+
+func (h *AuthHandler) Impersonate(ctx *gin.Context) {
+	nameHandler := "Impersonate"
+	userID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "invalid user id")
+		return
+	}
+
+	actorID := ctx.MustGet(userIDCtx).(int)
+
+	token, errImpersonate := h.service.Impersonate(actorID, userID, clientInfo(ctx))
+	if errImpersonate != nil {
+		switch {
+		case errors.Is(errImpersonate, AuthService.ErrSelfImpersonation):
+			newErrorResponse(ctx, nameHandler, http.StatusBadRequest, errImpersonate.Error())
+		case errors.Is(errImpersonate, AuthService.ErrImpersonationForbidden),
+			errors.Is(errImpersonate, AuthService.ErrUserDeactivated):
+			newErrorResponse(ctx, nameHandler, http.StatusForbidden, errImpersonate.Error())
+		case errors.Is(errImpersonate, AuthService.ErrUserNotFound):
+			newErrorResponse(ctx, nameHandler, http.StatusNotFound, errImpersonate.Error())
+		default:
+			newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, errImpersonate.Error())
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, token)
+}
+
+func clientInfo(ctx *gin.Context) AuthService.ClientInfo {
+	return AuthService.ClientInfo{
+		IP:            ctx.ClientIP(),
+		UserAgent:     ctx.Request.UserAgent(),
+		CorrelationID: ctx.GetString(correlationIDCtx),
+	}
+}
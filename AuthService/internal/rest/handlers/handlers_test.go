@@ -3,13 +3,16 @@ package handlers
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/assert/v2"
 	"github.com/golang/mock/gomock"
 	"github.com/jst-Frenzy/ControlSystem/AuthService/internal/AuthService"
 	mock_AuthService "github.com/jst-Frenzy/ControlSystem/AuthService/internal/mocks"
+	"github.com/jst-Frenzy/ControlSystem/protobuf/permissions"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestHandler_signUp(t *testing.T) {
@@ -32,7 +35,7 @@ func TestHandler_signUp(t *testing.T) {
 				Password: "qwerty",
 			},
 			mockBehavior: func(s *mock_AuthService.MockAuthService, u AuthService.UserSignUp) {
-				s.EXPECT().SignUp(u).Return(1, nil)
+				s.EXPECT().SignUp(u, gomock.Any()).Return(1, nil)
 			},
 			expectedStatusCode:   201,
 			expectedResponseBody: `{"id":1}`,
@@ -53,11 +56,25 @@ func TestHandler_signUp(t *testing.T) {
 				Password: "qwerty",
 			},
 			mockBehavior: func(s *mock_AuthService.MockAuthService, u AuthService.UserSignUp) {
-				s.EXPECT().SignUp(u).Return(0, errors.New("ServiceFailure"))
+				s.EXPECT().SignUp(u, gomock.Any()).Return(0, errors.New("ServiceFailure"))
 			},
 			expectedStatusCode:   500,
 			expectedResponseBody: `{"Message":"ServiceFailure"}`,
 		},
+		{
+			name:      "Weak Password",
+			inputBody: `{"user_name": "test", "email": "test@test.com", "password":"qwerty"}`,
+			inputUser: AuthService.UserSignUp{
+				UserName: "test",
+				Email:    "test@test.com",
+				Password: "qwerty",
+			},
+			mockBehavior: func(s *mock_AuthService.MockAuthService, u AuthService.UserSignUp) {
+				s.EXPECT().SignUp(u, gomock.Any()).Return(0, fmt.Errorf("%w: it must be at least 8 characters long", AuthService.ErrWeakPassword))
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"Message":"password is too weak: it must be at least 8 characters long"}`,
+		},
 	}
 
 	for _, testCase := range testTable {
@@ -100,6 +117,7 @@ func TestHandler_SignIn(t *testing.T) {
 		mockBehavior         mockBehavior
 		expectedStatusCode   int
 		expectedResponseBody string
+		expectedRetryAfter   string
 	}{
 		{
 			name:      "OK",
@@ -109,7 +127,7 @@ func TestHandler_SignIn(t *testing.T) {
 				Password: "qwerty",
 			},
 			mockBehavior: func(s *mock_AuthService.MockAuthService, u AuthService.UserSignIn) {
-				s.EXPECT().SignIn(u).Return(AuthService.Tokens{
+				s.EXPECT().SignIn(u, AuthService.ClientInfo{IP: "192.0.2.1", UserAgent: "test-agent"}).Return(AuthService.Tokens{
 					AccessToken:  "abc",
 					RefreshToken: "def",
 				}, nil)
@@ -117,6 +135,19 @@ func TestHandler_SignIn(t *testing.T) {
 			expectedStatusCode:   200,
 			expectedResponseBody: `{"access token":"abc","refresh token":"def"}`,
 		},
+		{
+			name:      "MFA Required",
+			inputBody: `{"email":"test@test.com", "password":"qwerty"}`,
+			inputUser: AuthService.UserSignIn{
+				Email:    "test@test.com",
+				Password: "qwerty",
+			},
+			mockBehavior: func(s *mock_AuthService.MockAuthService, u AuthService.UserSignIn) {
+				s.EXPECT().SignIn(u, gomock.Any()).Return(AuthService.Tokens{MFAToken: "mfa"}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"mfa required":true,"mfa token":"mfa"}`,
+		},
 		{
 			name:                 "Empty Fields",
 			inputBody:            `"email":"test@test.com", "password":"qwerty"}`,
@@ -132,11 +163,58 @@ func TestHandler_SignIn(t *testing.T) {
 				Password: "qwerty",
 			},
 			mockBehavior: func(s *mock_AuthService.MockAuthService, u AuthService.UserSignIn) {
-				s.EXPECT().SignIn(u).Return(AuthService.Tokens{}, errors.New("ServiceFailure"))
+				s.EXPECT().SignIn(u, AuthService.ClientInfo{IP: "192.0.2.1", UserAgent: "test-agent"}).Return(AuthService.Tokens{}, errors.New("ServiceFailure"))
 			},
 			expectedStatusCode:   500,
 			expectedResponseBody: `{"Message":"ServiceFailure"}`,
 		},
+		{
+			name:      "Wrong Password",
+			inputBody: `{"email":"test@test.com", "password":"wrong"}`,
+			inputUser: AuthService.UserSignIn{
+				Email:    "test@test.com",
+				Password: "wrong",
+			},
+			mockBehavior: func(s *mock_AuthService.MockAuthService, u AuthService.UserSignIn) {
+				s.EXPECT().SignIn(u, gomock.Any()).Return(AuthService.Tokens{}, AuthService.ErrWrongPassword)
+			},
+			expectedStatusCode:   401,
+			expectedResponseBody: `{"Message":"password is wrong"}`,
+		},
+		{
+			name:      "Account Locked",
+			inputBody: `{"email":"test@test.com", "password":"qwerty"}`,
+			inputUser: AuthService.UserSignIn{
+				Email:    "test@test.com",
+				Password: "qwerty",
+			},
+			mockBehavior: func(s *mock_AuthService.MockAuthService, u AuthService.UserSignIn) {
+				s.EXPECT().SignIn(u, gomock.Any()).Return(AuthService.Tokens{}, &AuthService.LockoutError{
+					Err:        AuthService.ErrAccountLocked,
+					RetryAfter: 90*time.Second + time.Millisecond,
+				})
+			},
+			expectedStatusCode:   423,
+			expectedResponseBody: `{"Message":"account is temporarily locked, retry after 1m30s"}`,
+			expectedRetryAfter:   "91",
+		},
+		{
+			name:      "Too Many Attempts",
+			inputBody: `{"email":"test@test.com", "password":"qwerty"}`,
+			inputUser: AuthService.UserSignIn{
+				Email:    "test@test.com",
+				Password: "qwerty",
+			},
+			mockBehavior: func(s *mock_AuthService.MockAuthService, u AuthService.UserSignIn) {
+				s.EXPECT().SignIn(u, gomock.Any()).Return(AuthService.Tokens{}, &AuthService.LockoutError{
+					Err:        AuthService.ErrTooManyAttempts,
+					RetryAfter: time.Minute,
+				})
+			},
+			expectedStatusCode:   429,
+			expectedResponseBody: `{"Message":"too many sign in attempts, retry after 1m0s"}`,
+			expectedRetryAfter:   "60",
+		},
 	}
 
 	for _, testCase := range testTable {
@@ -155,6 +233,531 @@ func TestHandler_SignIn(t *testing.T) {
 			w := httptest.NewRecorder()
 			req := httptest.NewRequest("POST", "/sign-in", bytes.NewBufferString(testCase.inputBody))
 			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("User-Agent", "test-agent")
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, w.Body.String())
+			assert.Equal(t, testCase.expectedRetryAfter, w.Header().Get("Retry-After"))
+		})
+	}
+}
+
+func TestHandler_SignInMFA(t *testing.T) {
+	type mockBehavior func(s *mock_AuthService.MockAuthService)
+
+	testTable := []struct {
+		name                 string
+		inputBody            string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:      "OK",
+			inputBody: `{"mfa_token":"mfa", "code":"123456"}`,
+			mockBehavior: func(s *mock_AuthService.MockAuthService) {
+				s.EXPECT().SignInMFA("mfa", "123456", gomock.Any()).Return(AuthService.Tokens{
+					AccessToken:  "abc",
+					RefreshToken: "def",
+				}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"access token":"abc","refresh token":"def"}`,
+		},
+		{
+			name:                 "Empty Code",
+			inputBody:            `{"mfa_token":"mfa"}`,
+			mockBehavior:         func(s *mock_AuthService.MockAuthService) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"Message":"invalid input body"}`,
+		},
+		{
+			name:      "Wrong Code",
+			inputBody: `{"mfa_token":"mfa", "code":"000000"}`,
+			mockBehavior: func(s *mock_AuthService.MockAuthService) {
+				s.EXPECT().SignInMFA("mfa", "000000", gomock.Any()).Return(AuthService.Tokens{}, AuthService.ErrInvalidMFACode)
+			},
+			expectedStatusCode:   401,
+			expectedResponseBody: `{"Message":"authentication code is wrong"}`,
+		},
+		{
+			name:      "Expired MFA Token",
+			inputBody: `{"mfa_token":"mfa", "code":"123456"}`,
+			mockBehavior: func(s *mock_AuthService.MockAuthService) {
+				s.EXPECT().SignInMFA("mfa", "123456", gomock.Any()).Return(AuthService.Tokens{}, AuthService.ErrMFATokenInvalid)
+			},
+			expectedStatusCode:   401,
+			expectedResponseBody: `{"Message":"mfa token is invalid or expired"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authServ := mock_AuthService.NewMockAuthService(c)
+			testCase.mockBehavior(authServ)
+
+			handler := NewAuthHandler(authServ)
+
+			r := gin.New()
+			r.POST("/sign-in/2fa", handler.SignInMFA)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/sign-in/2fa", bytes.NewBufferString(testCase.inputBody))
+			req.Header.Set("Content-Type", "application/json")
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_Logout(t *testing.T) {
+	type mockBehavior func(s *mock_AuthService.MockAuthService, refreshToken string)
+
+	testTable := []struct {
+		name                 string
+		inputBody            string
+		inputRefreshToken    string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:              "OK",
+			inputBody:         `{"RefreshToken":"refresh_token"}`,
+			inputRefreshToken: "refresh_token",
+			mockBehavior: func(s *mock_AuthService.MockAuthService, refreshToken string) {
+				s.EXPECT().Logout(refreshToken, gomock.Any()).Return(nil)
+			},
+			expectedStatusCode:   204,
+			expectedResponseBody: ``,
+		},
+		{
+			name:                 "Empty Fields",
+			inputBody:            `{}`,
+			mockBehavior:         func(s *mock_AuthService.MockAuthService, refreshToken string) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"Message":"invalid input body"}`,
+		},
+		{
+			name:              "Unknown Refresh Token",
+			inputBody:         `{"RefreshToken":"refresh_token"}`,
+			inputRefreshToken: "refresh_token",
+			mockBehavior: func(s *mock_AuthService.MockAuthService, refreshToken string) {
+				s.EXPECT().Logout(refreshToken, gomock.Any()).Return(AuthService.ErrRefreshTokenNotFound)
+			},
+			expectedStatusCode:   401,
+			expectedResponseBody: `{"Message":"refresh token not found"}`,
+		},
+		{
+			name:              "Service Error",
+			inputBody:         `{"RefreshToken":"refresh_token"}`,
+			inputRefreshToken: "refresh_token",
+			mockBehavior: func(s *mock_AuthService.MockAuthService, refreshToken string) {
+				s.EXPECT().Logout(refreshToken, gomock.Any()).Return(errors.New("ServiceFailure"))
+			},
+			expectedStatusCode:   500,
+			expectedResponseBody: `{"Message":"ServiceFailure"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authServ := mock_AuthService.NewMockAuthService(c)
+			testCase.mockBehavior(authServ, testCase.inputRefreshToken)
+
+			handler := NewAuthHandler(authServ)
+
+			r := gin.New()
+			r.POST("/logout", handler.Logout)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/logout", bytes.NewBufferString(testCase.inputBody))
+			req.Header.Set("Content-Type", "application/json")
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_ChangeRole(t *testing.T) {
+	type mockBehavior func(s *mock_AuthService.MockAuthService, actorID, userID int, newRole string)
+
+	testTable := []struct {
+		name                 string
+		inputBody            string
+		inputUserID          int
+		inputRole            string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:        "OK",
+			inputBody:   `{"id":2,"newRole":"seller"}`,
+			inputUserID: 2,
+			inputRole:   "seller",
+			mockBehavior: func(s *mock_AuthService.MockAuthService, actorID, userID int, newRole string) {
+				s.EXPECT().ChangeRole(actorID, userID, newRole, gomock.Any()).Return(AuthService.User{ID: userID, Role: newRole}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"id":2,"role":"seller"}`,
+		},
+		{
+			name:                 "Empty Fields",
+			inputBody:            `{"id":2}`,
+			mockBehavior:         func(s *mock_AuthService.MockAuthService, actorID, userID int, newRole string) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"Message":"invalid input body"}`,
+		},
+		{
+			name:        "Invalid Role",
+			inputBody:   `{"id":2,"newRole":"superuser"}`,
+			inputUserID: 2,
+			inputRole:   "superuser",
+			mockBehavior: func(s *mock_AuthService.MockAuthService, actorID, userID int, newRole string) {
+				s.EXPECT().ChangeRole(actorID, userID, newRole, gomock.Any()).Return(AuthService.User{}, AuthService.ErrInvalidRole)
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"Message":"invalid role"}`,
+		},
+		{
+			name:        "User Not Found",
+			inputBody:   `{"id":3,"newRole":"seller"}`,
+			inputUserID: 3,
+			inputRole:   "seller",
+			mockBehavior: func(s *mock_AuthService.MockAuthService, actorID, userID int, newRole string) {
+				s.EXPECT().ChangeRole(actorID, userID, newRole, gomock.Any()).Return(AuthService.User{}, AuthService.ErrUserNotFound)
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"Message":"user not found"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authServ := mock_AuthService.NewMockAuthService(c)
+			testCase.mockBehavior(authServ, 1, testCase.inputUserID, testCase.inputRole)
+
+			handler := NewAuthHandler(authServ)
+
+			r := gin.New()
+			r.POST("/changeRole", func(ctx *gin.Context) {
+				ctx.Set(userIDCtx, 1)
+				ctx.Set(userPermissionsCtx, AuthService.PermissionsForRole(AuthService.RoleAdmin))
+			}, handler.RequirePermission(permissions.UsersManage), handler.ChangeRole)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/changeRole", bytes.NewBufferString(testCase.inputBody))
+			req.Header.Set("Content-Type", "application/json")
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_VerifyEmail(t *testing.T) {
+	type mockBehavior func(s *mock_AuthService.MockAuthService, token string)
+
+	testTable := []struct {
+		name                 string
+		inputToken           string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:       "OK",
+			inputToken: "token",
+			mockBehavior: func(s *mock_AuthService.MockAuthService, token string) {
+				s.EXPECT().VerifyEmail(token).Return(nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"verified":true}`,
+		},
+		{
+			name:                 "Empty Token",
+			mockBehavior:         func(s *mock_AuthService.MockAuthService, token string) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"Message":"token is empty"}`,
+		},
+		{
+			name:       "Invalid Token",
+			inputToken: "token",
+			mockBehavior: func(s *mock_AuthService.MockAuthService, token string) {
+				s.EXPECT().VerifyEmail(token).Return(AuthService.ErrVerificationTokenInvalid)
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"Message":"verification token is invalid or expired"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authServ := mock_AuthService.NewMockAuthService(c)
+			testCase.mockBehavior(authServ, testCase.inputToken)
+
+			handler := NewAuthHandler(authServ)
+
+			r := gin.New()
+			r.GET("/verify", handler.VerifyEmail)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/verify?token="+testCase.inputToken, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_ChangePassword(t *testing.T) {
+	type mockBehavior func(s *mock_AuthService.MockAuthService, userID int)
+
+	testTable := []struct {
+		name                 string
+		inputBody            string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:      "OK",
+			inputBody: `{"old_password":"qwerty","new_password":"new_password"}`,
+			mockBehavior: func(s *mock_AuthService.MockAuthService, userID int) {
+				s.EXPECT().ChangePassword(userID, "qwerty", "new_password", "", gomock.Any()).Return(nil)
+			},
+			expectedStatusCode:   204,
+			expectedResponseBody: ``,
+		},
+		{
+			name:                 "Empty Fields",
+			inputBody:            `{"old_password":"qwerty"}`,
+			mockBehavior:         func(s *mock_AuthService.MockAuthService, userID int) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"Message":"invalid input body"}`,
+		},
+		{
+			name:      "Wrong Password",
+			inputBody: `{"old_password":"wrong","new_password":"new_password"}`,
+			mockBehavior: func(s *mock_AuthService.MockAuthService, userID int) {
+				s.EXPECT().ChangePassword(userID, "wrong", "new_password", "", gomock.Any()).Return(AuthService.ErrWrongPassword)
+			},
+			expectedStatusCode:   403,
+			expectedResponseBody: `{"Message":"password is wrong"}`,
+		},
+		{
+			name:      "Reauth Token",
+			inputBody: `{"reauth_token":"token","new_password":"new_password"}`,
+			mockBehavior: func(s *mock_AuthService.MockAuthService, userID int) {
+				s.EXPECT().ChangePassword(userID, "", "new_password", "token", gomock.Any()).Return(nil)
+			},
+			expectedStatusCode:   204,
+			expectedResponseBody: ``,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authServ := mock_AuthService.NewMockAuthService(c)
+			testCase.mockBehavior(authServ, 1)
+
+			handler := NewAuthHandler(authServ)
+
+			r := gin.New()
+			r.POST("/password/change", func(ctx *gin.Context) {
+				ctx.Set(userIDCtx, 1)
+			}, handler.ChangePassword)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/password/change", bytes.NewBufferString(testCase.inputBody))
+			req.Header.Set("Content-Type", "application/json")
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_UpdateProfile(t *testing.T) {
+	type mockBehavior func(s *mock_AuthService.MockAuthService, userID int)
+
+	newEmail := "new@test.com"
+	createdAt := time.Date(2026, 2, 4, 10, 0, 0, 0, time.UTC)
+
+	testTable := []struct {
+		name                 string
+		inputBody            string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:      "OK",
+			inputBody: `{"email":"new@test.com"}`,
+			mockBehavior: func(s *mock_AuthService.MockAuthService, userID int) {
+				s.EXPECT().UpdateProfile(userID, AuthService.UpdateProfileRequest{Email: &newEmail}).Return(AuthService.UserProfile{
+					ID:            1,
+					UserName:      "test",
+					Email:         "test@test.com",
+					PendingEmail:  newEmail,
+					Role:          "user",
+					Permissions:   []string{"cart:manage"},
+					EmailVerified: true,
+					CreatedAt:     createdAt,
+				}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: `{"id":1,"user_name":"test","email":"test@test.com","pending_email":"new@test.com","role":"user",` +
+				`"permissions":["cart:manage"],"email_verified":true,"totp_enabled":false,"created_at":"2026-02-04T10:00:00Z"}`,
+		},
+		{
+			name:                 "Invalid Email",
+			inputBody:            `{"email":"not an email"}`,
+			mockBehavior:         func(s *mock_AuthService.MockAuthService, userID int) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"Message":"invalid input body"}`,
+		},
+		{
+			name:      "Email Taken",
+			inputBody: `{"email":"new@test.com"}`,
+			mockBehavior: func(s *mock_AuthService.MockAuthService, userID int) {
+				s.EXPECT().UpdateProfile(userID, AuthService.UpdateProfileRequest{Email: &newEmail}).Return(AuthService.UserProfile{}, AuthService.ErrEmailTaken)
+			},
+			expectedStatusCode:   409,
+			expectedResponseBody: `{"Message":"email is already taken"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authServ := mock_AuthService.NewMockAuthService(c)
+			testCase.mockBehavior(authServ, 1)
+
+			handler := NewAuthHandler(authServ)
+
+			r := gin.New()
+			r.PATCH("/me", func(ctx *gin.Context) {
+				ctx.Set(userIDCtx, 1)
+			}, handler.UpdateProfile)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("PATCH", "/me", bytes.NewBufferString(testCase.inputBody))
+			req.Header.Set("Content-Type", "application/json")
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_DeleteAccount(t *testing.T) {
+	type mockBehavior func(s *mock_AuthService.MockAuthService, userID int)
+
+	testTable := []struct {
+		name                 string
+		inputBody            string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:      "OK",
+			inputBody: `{"password":"qwerty"}`,
+			mockBehavior: func(s *mock_AuthService.MockAuthService, userID int) {
+				s.EXPECT().DeleteAccount(gomock.Any(), userID, "qwerty", "", gomock.Any()).Return(nil)
+			},
+			expectedStatusCode:   204,
+			expectedResponseBody: ``,
+		},
+		{
+			name:                 "Empty Password",
+			inputBody:            `{}`,
+			mockBehavior:         func(s *mock_AuthService.MockAuthService, userID int) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"Message":"invalid input body"}`,
+		},
+		{
+			name:      "Wrong Password",
+			inputBody: `{"password":"wrong"}`,
+			mockBehavior: func(s *mock_AuthService.MockAuthService, userID int) {
+				s.EXPECT().DeleteAccount(gomock.Any(), userID, "wrong", "", gomock.Any()).Return(AuthService.ErrWrongPassword)
+			},
+			expectedStatusCode:   403,
+			expectedResponseBody: `{"Message":"password is wrong"}`,
+		},
+		{
+			name:      "Expired Reauth Token",
+			inputBody: `{"reauth_token":"expired"}`,
+			mockBehavior: func(s *mock_AuthService.MockAuthService, userID int) {
+				s.EXPECT().DeleteAccount(gomock.Any(), userID, "", "expired", gomock.Any()).Return(AuthService.ErrReauthenticationNeeded)
+			},
+			expectedStatusCode:   403,
+			expectedResponseBody: `{"Message":"user has no password, reauthenticate at the identity provider"}`,
+		},
+		{
+			name:      "Services Unavailable",
+			inputBody: `{"password":"qwerty"}`,
+			mockBehavior: func(s *mock_AuthService.MockAuthService, userID int) {
+				s.EXPECT().DeleteAccount(gomock.Any(), userID, "qwerty", "", gomock.Any()).Return(AuthService.ErrUserDataUnavailable)
+			},
+			expectedStatusCode:   503,
+			expectedResponseBody: `{"Message":"services holding user data are unavailable"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authServ := mock_AuthService.NewMockAuthService(c)
+			testCase.mockBehavior(authServ, 1)
+
+			handler := NewAuthHandler(authServ)
+
+			r := gin.New()
+			r.DELETE("/me", func(ctx *gin.Context) {
+				ctx.Set(userIDCtx, 1)
+			}, handler.DeleteAccount)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("DELETE", "/me", bytes.NewBufferString(testCase.inputBody))
+			req.Header.Set("Content-Type", "application/json")
 
 			r.ServeHTTP(w, req)
 
@@ -1,17 +1,54 @@
 package handlers
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 	"net/http"
 	"strings"
 )
 
 const (
 	authorizationHeader = "Authorization"
+	requestIDHeader     = "X-Request-ID"
+	correlationIDCtx    = "correlationID"
+	maxRequestIDLength  = 64
 	userIDCtx           = "userID"
 	userRoleCtx         = "userRole"
+	userPermissionsCtx  = "userPermissions"
+	actorIDCtx          = "actorID"
 )
 
+// CorrelationID takes the request ID set by the reverse proxy, or makes one up, so audit events can be
+// matched with the proxy logs. The ID is echoed in the response.
+func (h *AuthHandler) CorrelationID(ctx *gin.Context) {
+	requestID := ctx.GetHeader(requestIDHeader)
+	if !isValidRequestID(requestID) {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			logrus.WithError(err).Error("can't generate request id")
+			return
+		}
+		requestID = hex.EncodeToString(b)
+	}
+
+	ctx.Set(correlationIDCtx, requestID)
+	ctx.Header(requestIDHeader, requestID)
+}
+
+func isValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range requestID {
+		if r <= ' ' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
 func (h *AuthHandler) UserIdentity(ctx *gin.Context) {
 	nameHandler := "UserIdentity"
 	header := ctx.GetHeader(authorizationHeader)
@@ -40,6 +77,45 @@ func (h *AuthHandler) UserIdentity(ctx *gin.Context) {
 		newErrorResponse(ctx, nameHandler, http.StatusUnauthorized, err.Error())
 		return
 	}
+	// tokens issued to OAuth clients are only good for the userinfo endpoint
+	if info.ClientID != "" {
+		newErrorResponse(ctx, nameHandler, http.StatusUnauthorized, "token is issued to an oauth client")
+		return
+	}
 	ctx.Set(userIDCtx, info.ID)
 	ctx.Set(userRoleCtx, info.Role)
+	ctx.Set(userPermissionsCtx, info.Permissions)
+
+	if info.ActorID != 0 {
+		ctx.Set(actorIDCtx, info.ActorID)
+		logrus.WithFields(logrus.Fields{
+			"event":    "impersonated_request",
+			"actor_id": info.ActorID,
+			"user_id":  info.ID,
+			"method":   ctx.Request.Method,
+			"path":     ctx.Request.URL.Path,
+		}).Info("request made while impersonating user")
+	}
+}
+
+// DenyImpersonation must be mounted after UserIdentity, it keeps admins impersonating a user away
+// from the credentials and the role of the user.
+func (h *AuthHandler) DenyImpersonation(ctx *gin.Context) {
+	nameHandler := "DenyImpersonation"
+	if _, ok := ctx.Get(actorIDCtx); ok {
+		newErrorResponse(ctx, nameHandler, http.StatusForbidden, "not allowed while impersonating")
+	}
+}
+
+// RequirePermission must be mounted after UserIdentity.
+func (h *AuthHandler) RequirePermission(permission string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		nameHandler := "RequirePermission"
+		for _, p := range ctx.GetStringSlice(userPermissionsCtx) {
+			if p == permission {
+				return
+			}
+		}
+		newErrorResponse(ctx, nameHandler, http.StatusForbidden, "not enough rights")
+	}
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/jst-Frenzy/ControlSystem/AuthService/internal/AuthService"
 	mock_AuthService "github.com/jst-Frenzy/ControlSystem/AuthService/internal/mocks"
+	"github.com/jst-Frenzy/ControlSystem/protobuf/permissions"
 	"net/http/httptest"
 	"testing"
 )
@@ -61,6 +62,21 @@ func TestHandler_userIdentity(t *testing.T) {
 			expectedStatusCode:   401,
 			expectedResponseBody: `{"Message":"token is empty"}`,
 		},
+		{
+			name:        "OAuth Client Token",
+			headerName:  "Authorization",
+			headerValue: "Bearer token",
+			token:       "token",
+			mockBehavior: func(s *mock_AuthService.MockAuthService, token string) {
+				s.EXPECT().ParseToken(token).Return(AuthService.InfoFromToken{
+					ID:       1,
+					ClientID: "client",
+					Scope:    "openid",
+				}, nil)
+			},
+			expectedStatusCode:   401,
+			expectedResponseBody: `{"Message":"token is issued to an oauth client"}`,
+		},
 		{
 			name:        "Service Failure",
 			headerName:  "Authorization",
@@ -107,3 +123,163 @@ func TestHandler_userIdentity(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_requirePermission(t *testing.T) {
+	testTable := []struct {
+		name                 string
+		permissions          []string
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:                 "OK",
+			permissions:          []string{permissions.CartManage, permissions.UsersManage},
+			expectedStatusCode:   200,
+			expectedResponseBody: `ok`,
+		},
+		{
+			name:                 "Missing Permission",
+			permissions:          []string{permissions.CartManage, permissions.GoodsWrite},
+			expectedStatusCode:   403,
+			expectedResponseBody: `{"Message":"not enough rights"}`,
+		},
+		{
+			name:                 "No Permissions",
+			expectedStatusCode:   403,
+			expectedResponseBody: `{"Message":"not enough rights"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			handler := NewAuthHandler(mock_AuthService.NewMockAuthService(c))
+
+			r := gin.New()
+			r.POST("/protected", func(ctx *gin.Context) {
+				if testCase.permissions != nil {
+					ctx.Set(userPermissionsCtx, testCase.permissions)
+				}
+			}, handler.RequirePermission(permissions.UsersManage), func(ctx *gin.Context) {
+				ctx.String(200, "ok")
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/protected", nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, testCase.expectedStatusCode)
+			assert.Equal(t, w.Body.String(), testCase.expectedResponseBody)
+		})
+	}
+}
+
+func TestHandler_denyImpersonation(t *testing.T) {
+	type mockBehavior func(s *mock_AuthService.MockAuthService)
+
+	testTable := []struct {
+		name                 string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name: "OK",
+			mockBehavior: func(s *mock_AuthService.MockAuthService) {
+				s.EXPECT().ParseToken("token").Return(AuthService.InfoFromToken{ID: 2, Role: "user"}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `ok`,
+		},
+		{
+			name: "Impersonated",
+			mockBehavior: func(s *mock_AuthService.MockAuthService) {
+				s.EXPECT().ParseToken("token").Return(AuthService.InfoFromToken{ID: 2, Role: "user", ActorID: 1}, nil)
+			},
+			expectedStatusCode:   403,
+			expectedResponseBody: `{"Message":"not allowed while impersonating"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			serv := mock_AuthService.NewMockAuthService(c)
+			testCase.mockBehavior(serv)
+
+			handler := NewAuthHandler(serv)
+
+			r := gin.New()
+			r.POST("/password/change", handler.UserIdentity, handler.DenyImpersonation, func(ctx *gin.Context) {
+				ctx.String(200, "ok")
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/password/change", nil)
+			req.Header.Set("Authorization", "Bearer token")
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, testCase.expectedStatusCode)
+			assert.Equal(t, w.Body.String(), testCase.expectedResponseBody)
+		})
+	}
+}
+
+func TestHandler_correlationID(t *testing.T) {
+	testTable := []struct {
+		name            string
+		inputRequestID  string
+		expectGenerated bool
+	}{
+		{
+			name:           "From Proxy",
+			inputRequestID: "5f0c1a2b3c4d5e6f",
+		},
+		{
+			name:            "Missing",
+			expectGenerated: true,
+		},
+		{
+			name:            "Invalid",
+			inputRequestID:  "bad id\n",
+			expectGenerated: true,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			handler := NewAuthHandler(mock_AuthService.NewMockAuthService(c))
+
+			r := gin.New()
+			r.Use(handler.CorrelationID)
+			r.GET("/ping", func(ctx *gin.Context) {
+				ctx.String(200, ctx.GetString(correlationIDCtx))
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/ping", nil)
+			if testCase.inputRequestID != "" {
+				req.Header.Set("X-Request-ID", testCase.inputRequestID)
+			}
+
+			r.ServeHTTP(w, req)
+
+			requestID := w.Header().Get("X-Request-ID")
+			assert.Equal(t, w.Body.String(), requestID)
+			if testCase.expectGenerated {
+				assert.Equal(t, len(requestID), 32)
+			} else {
+				assert.Equal(t, requestID, testCase.inputRequestID)
+			}
+		})
+	}
+}
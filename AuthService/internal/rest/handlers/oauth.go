@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/jst-Frenzy/ControlSystem/AuthService/internal/AuthService"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type OAuthHandler struct {
+	service AuthService.OAuthService
+}
+
+func NewOAuthHandler(service AuthService.OAuthService) *OAuthHandler {
+	return &OAuthHandler{service: service}
+}
+
+type oauthErrorBody struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// newOAuthErrorResponse answers in the error format of RFC 6749 rather than errorResponse,
+// since OAuth clients parse it.
+func newOAuthErrorResponse(ctx *gin.Context, handlerName string, err error) {
+	statusCode := http.StatusInternalServerError
+	body := oauthErrorBody{Error: "server_error"}
+
+	var oauthErr *AuthService.OAuthError
+	if errors.As(err, &oauthErr) {
+		body = oauthErrorBody{Error: oauthErr.Code, ErrorDescription: oauthErr.Description}
+		switch oauthErr.Code {
+		case AuthService.OAuthInvalidClient:
+			statusCode = http.StatusUnauthorized
+		case AuthService.OAuthInvalidToken:
+			statusCode = http.StatusUnauthorized
+			ctx.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		case AuthService.OAuthInsufficientScope:
+			statusCode = http.StatusForbidden
+			ctx.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="openid"`)
+		default:
+			statusCode = http.StatusBadRequest
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"error":   err.Error(),
+		"handler": handlerName,
+		"path":    ctx.Request.URL.Path,
+		"method":  ctx.Request.Method,
+	}).Warn("handler error")
+	ctx.AbortWithStatusJSON(statusCode, body)
+}
+
+func (h *OAuthHandler) Discovery(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, h.service.Discovery())
+}
+
+// Authorize redirects a GET request to the client right away, a POST gets the redirect_uri back
+// so a frontend holding the access token can navigate there itself.
+func (h *OAuthHandler) Authorize(ctx *gin.Context) {
+	nameHandler := "Authorize"
+	var req AuthService.AuthorizeRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		newOAuthErrorResponse(ctx, nameHandler, &AuthService.OAuthError{Code: AuthService.OAuthInvalidRequest, Description: "invalid request"})
+		return
+	}
+
+	userID := ctx.MustGet(userIDCtx).(int)
+
+	redirectURI, err := h.service.Authorize(userID, req)
+	if err != nil {
+		newOAuthErrorResponse(ctx, nameHandler, err)
+		return
+	}
+
+	if ctx.Request.Method == http.MethodGet {
+		ctx.Redirect(http.StatusFound, redirectURI)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"redirect_uri": redirectURI,
+	})
+}
+
+// Token accepts client credentials both in the Authorization header and in the form.
+func (h *OAuthHandler) Token(ctx *gin.Context) {
+	nameHandler := "Token"
+	var req AuthService.TokenRequest
+	if err := ctx.ShouldBindWith(&req, binding.Form); err != nil {
+		newOAuthErrorResponse(ctx, nameHandler, &AuthService.OAuthError{Code: AuthService.OAuthInvalidRequest, Description: "invalid request"})
+		return
+	}
+
+	if clientID, secret, ok := ctx.Request.BasicAuth(); ok {
+		// client_secret_basic form-encodes the credentials before putting them in the header
+		req.ClientID, _ = url.QueryUnescape(clientID)
+		req.ClientSecret, _ = url.QueryUnescape(secret)
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Pragma", "no-cache")
+
+	tokens, err := h.service.Token(req, clientInfo(ctx))
+	if err != nil {
+		newOAuthErrorResponse(ctx, nameHandler, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, tokens)
+}
+
+func (h *OAuthHandler) UserInfo(ctx *gin.Context) {
+	nameHandler := "UserInfo"
+	token, found := strings.CutPrefix(ctx.GetHeader(authorizationHeader), "Bearer ")
+	if !found || token == "" {
+		newOAuthErrorResponse(ctx, nameHandler, &AuthService.OAuthError{Code: AuthService.OAuthInvalidToken, Description: "bearer token is required"})
+		return
+	}
+
+	info, err := h.service.UserInfo(token)
+	if err != nil {
+		newOAuthErrorResponse(ctx, nameHandler, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, info)
+}
+
+func (h *OAuthHandler) RegisterClient(ctx *gin.Context) {
+	nameHandler := "RegisterClient"
+	var req AuthService.RegisterOAuthClientRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "invalid input body")
+		return
+	}
+
+	client, err := h.service.RegisterClient(req)
+	if err != nil {
+		if errors.Is(err, AuthService.ErrInvalidRedirectURI) {
+			newErrorResponse(ctx, nameHandler, http.StatusBadRequest, err.Error())
+			return
+		}
+		newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, client)
+}
+
+func (h *OAuthHandler) ListClients(ctx *gin.Context) {
+	nameHandler := "ListClients"
+
+	clients, err := h.service.ListClients()
+	if err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"clients": clients,
+	})
+}
+
+func (h *OAuthHandler) DeleteClient(ctx *gin.Context) {
+	nameHandler := "DeleteClient"
+
+	if err := h.service.DeleteClient(ctx.Param("id")); err != nil {
+		if errors.Is(err, AuthService.ErrOAuthClientNotFound) {
+			newErrorResponse(ctx, nameHandler, http.StatusNotFound, err.Error())
+			return
+		}
+		newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, gin.H{})
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/jst-Frenzy/ControlSystem/AuthService/internal/AuthService"
+	mock_AuthService "github.com/jst-Frenzy/ControlSystem/AuthService/internal/mocks"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOAuthHandler_Token(t *testing.T) {
+	type mockBehavior func(s *mock_AuthService.MockOAuthService)
+
+	testTable := []struct {
+		name                 string
+		inputBody            string
+		basicAuth            bool
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:      "OK",
+			inputBody: "grant_type=authorization_code&code=code&redirect_uri=https%3A%2F%2Fshop.test%2Fcb&code_verifier=verifier",
+			basicAuth: true,
+			mockBehavior: func(s *mock_AuthService.MockOAuthService) {
+				s.EXPECT().Token(AuthService.TokenRequest{
+					GrantType:    "authorization_code",
+					Code:         "code",
+					RedirectURI:  "https://shop.test/cb",
+					CodeVerifier: "verifier",
+					ClientID:     "client",
+					ClientSecret: "se:cret",
+				}, gomock.Any()).Return(AuthService.OAuthTokens{
+					AccessToken:  "access",
+					TokenType:    "Bearer",
+					ExpiresIn:    7200,
+					RefreshToken: "refresh",
+					Scope:        "openid",
+				}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"access_token":"access","token_type":"Bearer","expires_in":7200,"refresh_token":"refresh","scope":"openid"}`,
+		},
+		{
+			name:      "Invalid Client",
+			inputBody: "grant_type=authorization_code&code=code&client_id=client&client_secret=wrong",
+			mockBehavior: func(s *mock_AuthService.MockOAuthService) {
+				s.EXPECT().Token(gomock.Any(), gomock.Any()).Return(AuthService.OAuthTokens{},
+					&AuthService.OAuthError{Code: AuthService.OAuthInvalidClient, Description: "client authentication failed"})
+			},
+			expectedStatusCode:   401,
+			expectedResponseBody: `{"error":"invalid_client","error_description":"client authentication failed"}`,
+		},
+		{
+			name:      "Invalid Grant",
+			inputBody: "grant_type=refresh_token&refresh_token=old&client_id=client",
+			mockBehavior: func(s *mock_AuthService.MockOAuthService) {
+				s.EXPECT().Token(gomock.Any(), gomock.Any()).Return(AuthService.OAuthTokens{},
+					&AuthService.OAuthError{Code: AuthService.OAuthInvalidGrant, Description: "refresh token is invalid"})
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"error":"invalid_grant","error_description":"refresh token is invalid"}`,
+		},
+		{
+			name:      "Service Error",
+			inputBody: "grant_type=refresh_token&refresh_token=old&client_id=client",
+			mockBehavior: func(s *mock_AuthService.MockOAuthService) {
+				s.EXPECT().Token(gomock.Any(), gomock.Any()).Return(AuthService.OAuthTokens{}, errors.New("ServiceFailure"))
+			},
+			expectedStatusCode:   500,
+			expectedResponseBody: `{"error":"server_error"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			//Init Deps
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			oauthServ := mock_AuthService.NewMockOAuthService(c)
+			testCase.mockBehavior(oauthServ)
+
+			handler := NewOAuthHandler(oauthServ)
+
+			//Test Server
+			r := gin.New()
+			r.POST("/oauth/token", handler.Token)
+
+			//Test Request
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/oauth/token", bytes.NewBufferString(testCase.inputBody))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if testCase.basicAuth {
+				req.SetBasicAuth("client", "se%3Acret")
+			}
+
+			//Perform Request
+			r.ServeHTTP(w, req)
+
+			//Assert
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, w.Body.String())
+			assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+		})
+	}
+}
@@ -0,0 +1,5 @@
+drop index if exists refresh_tokens_family_id_idx;
+drop index if exists refresh_tokens_token_hash_idx;
+
+alter table refresh_tokens drop column if exists revoked_at;
+alter table refresh_tokens drop column if exists family_id;
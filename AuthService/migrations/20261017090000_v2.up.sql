@@ -0,0 +1,7 @@
+alter table refresh_tokens add column family_id varchar(64) not null default '';
+alter table refresh_tokens add column revoked_at timestamp default null;
+
+update refresh_tokens set family_id = md5(id::text || token_hash);
+
+create unique index refresh_tokens_token_hash_idx on refresh_tokens(token_hash);
+create index refresh_tokens_family_id_idx on refresh_tokens(family_id);
@@ -0,0 +1,4 @@
+drop table if exists role_changes;
+
+alter table users drop constraint if exists users_role_check;
+alter table users alter column role drop not null;
@@ -0,0 +1,15 @@
+update users set role = 'user' where role is null or role not in ('user', 'seller', 'admin');
+
+alter table users alter column role set not null;
+alter table users add constraint users_role_check check (role in ('user', 'seller', 'admin'));
+
+create table role_changes(
+    id serial primary key,
+    actor_id integer default null references users(id) on delete set null,
+    target_id integer not null references users(id) on delete cascade,
+    old_role varchar(20) not null,
+    new_role varchar(20) not null,
+    created_at timestamp default now()
+);
+
+create index role_changes_target_id_idx on role_changes(target_id);
@@ -0,0 +1,15 @@
+alter table users add column email_verified_at timestamp default null;
+
+update users set email_verified_at = now();
+
+create table email_verifications(
+    id serial primary key,
+    user_id integer not null references users(id) on delete cascade,
+    email varchar(255) not null,
+    token_hash varchar(255) unique not null,
+    expires_at timestamp not null,
+    used_at timestamp default null,
+    created_at timestamp default now()
+);
+
+create index email_verifications_user_id_idx on email_verifications(user_id);
@@ -0,0 +1,10 @@
+create table password_resets(
+    id serial primary key,
+    user_id integer not null references users(id) on delete cascade,
+    token_hash varchar(255) unique not null,
+    expires_at timestamp not null,
+    used_at timestamp default null,
+    created_at timestamp default now()
+);
+
+create index password_resets_user_id_idx on password_resets(user_id);
@@ -0,0 +1,4 @@
+drop table if exists recovery_codes;
+drop table if exists totp_credentials;
+
+alter table users drop column if exists totp_enabled;
@@ -0,0 +1,19 @@
+alter table users add column totp_enabled boolean not null default false;
+
+create table totp_credentials(
+    user_id integer primary key references users(id) on delete cascade,
+    secret varchar(64) not null,
+    last_counter bigint not null default 0,
+    confirmed_at timestamp default null,
+    created_at timestamp default now()
+);
+
+create table recovery_codes(
+    id serial primary key,
+    user_id integer not null references users(id) on delete cascade,
+    code_hash varchar(255) not null,
+    used_at timestamp default null,
+    created_at timestamp default now()
+);
+
+create index recovery_codes_user_id_idx on recovery_codes(user_id);
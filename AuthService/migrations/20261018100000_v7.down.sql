@@ -0,0 +1,6 @@
+drop index if exists refresh_tokens_user_id_idx;
+
+alter table refresh_tokens drop column if exists last_used_at;
+alter table refresh_tokens drop column if exists device_label;
+alter table refresh_tokens drop column if exists ip;
+alter table refresh_tokens drop column if exists user_agent;
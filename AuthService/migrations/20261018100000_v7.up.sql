@@ -0,0 +1,8 @@
+alter table refresh_tokens add column user_agent varchar(512) not null default '';
+alter table refresh_tokens add column ip varchar(64) not null default '';
+alter table refresh_tokens add column device_label varchar(255) not null default '';
+alter table refresh_tokens add column last_used_at timestamp default now();
+
+update refresh_tokens set last_used_at = created_at;
+
+create index refresh_tokens_user_id_idx on refresh_tokens(user_id);
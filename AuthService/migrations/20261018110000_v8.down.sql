@@ -0,0 +1,3 @@
+drop table if exists email_changes;
+
+alter table users drop column if exists deactivated_at;
@@ -0,0 +1,13 @@
+alter table users add column deactivated_at timestamp default null;
+
+create table email_changes(
+    id serial primary key,
+    user_id integer not null references users(id) on delete cascade,
+    new_email varchar(255) not null,
+    token_hash varchar(255) unique not null,
+    expires_at timestamp not null,
+    used_at timestamp default null,
+    created_at timestamp default now()
+);
+
+create index email_changes_user_id_idx on email_changes(user_id);
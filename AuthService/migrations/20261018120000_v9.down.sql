@@ -0,0 +1,4 @@
+alter table refresh_tokens drop column if exists scope;
+alter table refresh_tokens drop column if exists client_id;
+
+drop table if exists oauth_clients;
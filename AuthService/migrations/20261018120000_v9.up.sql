@@ -0,0 +1,10 @@
+create table oauth_clients(
+    id varchar(64) primary key,
+    name varchar(255) not null,
+    secret_hash varchar(255) not null default '',
+    redirect_uris text not null,
+    created_at timestamp default now()
+);
+
+alter table refresh_tokens add column client_id varchar(64) default null references oauth_clients(id) on delete cascade;
+alter table refresh_tokens add column scope varchar(255) not null default '';
@@ -0,0 +1,10 @@
+create table identities(
+    id serial primary key,
+    user_id integer not null references users(id) on delete cascade,
+    provider varchar(64) not null,
+    subject varchar(255) not null,
+    email varchar(255) not null default '',
+    created_at timestamp default now(),
+    unique (provider, subject),
+    unique (user_id, provider)
+);
@@ -0,0 +1,14 @@
+create table api_keys(
+    id serial primary key,
+    user_id integer not null references users(id) on delete cascade,
+    name varchar(255) not null,
+    prefix varchar(16) not null,
+    key_hash varchar(255) unique not null,
+    scopes varchar(255) not null,
+    created_at timestamp default now(),
+    expires_at timestamp default null,
+    last_used_at timestamp default null,
+    revoked_at timestamp default null
+);
+
+create index api_keys_user_id_idx on api_keys(user_id);
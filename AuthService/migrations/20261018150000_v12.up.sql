@@ -0,0 +1,29 @@
+create table audit_log(
+    id bigserial primary key,
+    occurred_at timestamp not null default now(),
+    event varchar(64) not null,
+    outcome varchar(16) not null,
+    reason text not null default '',
+    user_id integer default null,
+    actor_id integer default null,
+    ip varchar(45) not null default '',
+    user_agent text not null default '',
+    correlation_id varchar(64) not null default ''
+);
+
+create index audit_log_occurred_at_idx on audit_log(occurred_at);
+create index audit_log_user_id_idx on audit_log(user_id);
+create index audit_log_event_idx on audit_log(event);
+
+-- the audit log outlives the users it mentions and can only be appended to
+create function audit_log_append_only() returns trigger as $$
+begin
+    raise exception 'audit_log is append-only';
+end;
+$$ language plpgsql;
+
+create trigger audit_log_no_update before update or delete on audit_log
+    for each row execute function audit_log_append_only();
+
+create trigger audit_log_no_truncate before truncate on audit_log
+    for each statement execute function audit_log_append_only();
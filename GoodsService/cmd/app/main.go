@@ -7,6 +7,7 @@ import (
 	"github.com/jst-Frenzy/ControlSystem/GoodsService/internal/gRPC/client"
 	"github.com/jst-Frenzy/ControlSystem/GoodsService/internal/gRPC/server"
 	"github.com/jst-Frenzy/ControlSystem/GoodsService/internal/rest/handlers"
+	"github.com/jst-Frenzy/ControlSystem/protobuf/permissions"
 	"github.com/sirupsen/logrus"
 	"os"
 	"os/signal"
@@ -20,7 +21,33 @@ func main() {
 	dataBase.InitMongo()
 
 	goodsMongoRepo := GoodService.NewGoodsMongoRepo(dataBase.MongoDB)
-	goodsService := GoodService.NewGoodService(goodsMongoRepo)
+	if err := goodsMongoRepo.EnsureIndexes(); err != nil {
+		logger.WithError(err).Fatal("can't create goods indexes")
+	}
+	if backfilled, err := goodsMongoRepo.BackfillSearchKeys(); err != nil {
+		logger.WithError(err).Error("can't backfill search keys")
+	} else if backfilled > 0 {
+		logger.WithField("items", backfilled).Info("backfilled search keys")
+	}
+
+	var blobStore GoodService.BlobStore
+	if os.Getenv("BLOB_STORE") == "s3" {
+		blobStore = GoodService.NewS3BlobStore(GoodService.S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+		})
+	} else {
+		var errBlob error
+		blobStore, errBlob = GoodService.NewLocalBlobStore(os.Getenv("BLOB_DIR"))
+		if errBlob != nil {
+			logger.WithError(errBlob).Fatal("can't open blob directory")
+		}
+	}
+
+	goodsService := GoodService.NewGoodService(goodsMongoRepo, GoodService.NewMongoSearcher(goodsMongoRepo), blobStore)
 	authClientGRPC, err := client.NewAuthClient(os.Getenv("ADDRESS_GRPC_AUTH_SERVER"))
 	if err != nil {
 		logrus.Fatal("Cant start grpc client")
@@ -42,6 +69,24 @@ func main() {
 		}
 	}()
 
+	stopExpiry := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(30 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopExpiry:
+				return
+			case <-ticker.C:
+				if expired, errExpire := goodsService.ExpireReservations(); errExpire != nil {
+					logger.WithError(errExpire).Error("can't expire stock reservations")
+				} else if expired > 0 {
+					logger.WithField("reservations", expired).Info("expired stock reservations")
+				}
+			}
+		}
+	}()
+
 	goodsHandler := handlers.NewGoodsHandlers(goodsService, authClientGRPC)
 
 	router := gin.Default()
@@ -49,13 +94,30 @@ func main() {
 	api := router.Group("/api/goods")
 	{
 		api.GET("/catalog", goodsHandler.GetGoods)
+		api.GET("/search", goodsHandler.Search)
+		api.GET("/images/*key", goodsHandler.GetImage)
+
+		categoryGroup := api.Group("/categories")
+		{
+			categoryGroup.GET("", goodsHandler.GetCategories)
+			categoryGroup.GET("/:id/items", goodsHandler.GetCategoryItems)
+			categoryGroup.POST("", goodsHandler.UserIdentity, goodsHandler.RequirePermission(permissions.CatalogManage), goodsHandler.CreateCategory)
+			categoryGroup.PUT("/:id", goodsHandler.UserIdentity, goodsHandler.RequirePermission(permissions.CatalogManage), goodsHandler.UpdateCategory)
+			categoryGroup.DELETE("/:id", goodsHandler.UserIdentity, goodsHandler.RequirePermission(permissions.CatalogManage), goodsHandler.DeleteCategory)
+		}
 
 		itemGroup := api.Group("/item")
-		itemGroup.Use(goodsHandler.UserIdentity)
+		itemGroup.Use(goodsHandler.UserIdentity, goodsHandler.RequirePermission(permissions.GoodsWrite))
 		{
 			itemGroup.POST("/", goodsHandler.AddItem)
 			itemGroup.DELETE("/:id", goodsHandler.DeleteItem)
 			itemGroup.PUT("/", goodsHandler.UpdateItem)
+			itemGroup.POST("/:id/images", goodsHandler.AddItemImage)
+			itemGroup.PUT("/:id/images/order", goodsHandler.ReorderItemImages)
+			itemGroup.DELETE("/:id/images/:imageID", goodsHandler.DeleteItemImage)
+			itemGroup.POST("/:id/variants", goodsHandler.AddVariant)
+			itemGroup.PUT("/:id/variants/:sku", goodsHandler.UpdateVariant)
+			itemGroup.DELETE("/:id/variants/:sku", goodsHandler.DeleteVariant)
 		}
 	}
 
@@ -69,6 +131,7 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
+	close(stopExpiry)
 	grpcServer.Stop()
 
 	time.Sleep(2 * time.Second)
@@ -59,3 +59,5 @@ require (
 	google.golang.org/protobuf v1.36.11 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace github.com/jst-Frenzy/ControlSystem/protobuf => ../protobuf
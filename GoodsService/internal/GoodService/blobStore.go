@@ -0,0 +1,113 @@
+package GoodService
+
+import (
+	"errors"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+//go:generate mockgen -source=blobStore.go -destination=../mocks/mockBlobStore.go -package=mocks
+
+// BlobStore keeps the uploaded media, keys are slash separated paths.
+type BlobStore interface {
+	Put(key string, data []byte, contentType string) error
+	Get(key string) (Blob, error)
+	// Delete of a missing key is not an error
+	Delete(key string) error
+}
+
+type Blob struct {
+	Data        []byte
+	ContentType string
+}
+
+var (
+	ErrBlobNotFound   = errors.New("blob not found")
+	ErrInvalidBlobKey = errors.New("invalid blob key")
+)
+
+// validBlobKey rejects keys that could escape the store, like absolute paths or ones with "..".
+func validBlobKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// localBlobStore keeps blobs as files under a directory, the content type comes from the extension.
+type localBlobStore struct {
+	dir string
+}
+
+func NewLocalBlobStore(dir string) (BlobStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &localBlobStore{dir: dir}, nil
+}
+
+// Put writes to a temporary file first, so a reader never sees half of a blob.
+func (s *localBlobStore) Put(key string, data []byte, contentType string) error {
+	if !validBlobKey(key) {
+		return ErrInvalidBlobKey
+	}
+
+	path := filepath.Join(s.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *localBlobStore) Get(key string) (Blob, error) {
+	if !validBlobKey(key) {
+		return Blob{}, ErrBlobNotFound
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.dir, filepath.FromSlash(key)))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Blob{}, ErrBlobNotFound
+		}
+		return Blob{}, err
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return Blob{Data: data, ContentType: contentType}, nil
+}
+
+func (s *localBlobStore) Delete(key string) error {
+	if !validBlobKey(key) {
+		return ErrInvalidBlobKey
+	}
+
+	err := os.Remove(filepath.Join(s.dir, filepath.FromSlash(key)))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package GoodService
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+)
+
+// The orders the catalog can be sorted in, items created at the same time or with the same price or name
+// follow their IDs.
+const (
+	SortNewest    = "newest"
+	SortOldest    = "oldest"
+	SortPriceAsc  = "price_asc"
+	SortPriceDesc = "price_desc"
+	SortNameAsc   = "name_asc"
+	SortNameDesc  = "name_desc"
+)
+
+var catalogSorts = []string{SortNewest, SortOldest, SortPriceAsc, SortPriceDesc, SortNameAsc, SortNameDesc}
+
+var (
+	ErrInvalidSort       = errors.New("invalid sort")
+	ErrInvalidCursor     = errors.New("invalid cursor")
+	ErrInvalidPriceRange = errors.New("invalid price range")
+)
+
+// GetGoods returns a page of the catalog, the next page is read with the NextCursor of this one
+// and the same query.
+func (s *goodService) GetGoods(q GoodsQuery) (GoodsPage, error) {
+	if q.Sort == "" {
+		q.Sort = SortNewest
+	}
+	if !slices.Contains(catalogSorts, q.Sort) {
+		return GoodsPage{}, ErrInvalidSort
+	}
+	if (q.MinPrice != nil && *q.MinPrice < 0) || (q.MaxPrice != nil && *q.MaxPrice < 0) ||
+		(q.MinPrice != nil && q.MaxPrice != nil && *q.MinPrice > *q.MaxPrice) {
+		return GoodsPage{}, ErrInvalidPriceRange
+	}
+
+	var after *GoodsCursor
+	if q.Cursor != "" {
+		cursor, err := decodeGoodsCursor(q.Cursor)
+		if err != nil || cursor.Sort != q.Sort {
+			return GoodsPage{}, ErrInvalidCursor
+		}
+		after = &cursor
+	}
+	limit, _ := pageBounds(q.Limit, 0)
+
+	// one item more than the page tells whether there is a next one
+	items, err := s.repo.GetGoods(q, after, limit+1)
+	if err != nil {
+		return GoodsPage{}, err
+	}
+
+	page := GoodsPage{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		last := page.Items[limit-1]
+		page.NextCursor = encodeGoodsCursor(GoodsCursor{Sort: q.Sort, ID: last.ID, Price: last.Price, Name: last.Name})
+	}
+	if page.Items == nil {
+		page.Items = []Item{}
+	}
+
+	if err = s.addBreadcrumbs(page.Items); err != nil {
+		return GoodsPage{}, err
+	}
+	return page, nil
+}
+
+func encodeGoodsCursor(c GoodsCursor) string {
+	// GoodsCursor has nothing json can fail on
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeGoodsCursor(s string) (GoodsCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return GoodsCursor{}, err
+	}
+
+	var c GoodsCursor
+	if err = json.Unmarshal(data, &c); err != nil {
+		return GoodsCursor{}, err
+	}
+	if c.ID == "" {
+		return GoodsCursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
@@ -0,0 +1,95 @@
+package GoodService
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetGoods returns up to limit items of the catalog matching the filters of the query, in its sort order
+// and after the cursor when it is set.
+func (r *goodsMongoRepo) GetGoods(q GoodsQuery, after *GoodsCursor, limit int) ([]Item, error) {
+	field, direction := catalogSortKey(q.Sort)
+
+	filter := bson.D{}
+	price := bson.D{}
+	if q.MinPrice != nil {
+		price = append(price, bson.E{Key: "$gte", Value: *q.MinPrice})
+	}
+	if q.MaxPrice != nil {
+		price = append(price, bson.E{Key: "$lte", Value: *q.MaxPrice})
+	}
+	if len(price) > 0 {
+		filter = append(filter, bson.E{Key: "price", Value: price})
+	}
+	if q.SellerID != "" {
+		filter = append(filter, bson.E{Key: "seller_id", Value: q.SellerID})
+	}
+	if q.InStock {
+		filter = append(filter, bson.E{Key: "quantity", Value: bson.D{{Key: "$gt", Value: 0}}})
+	}
+
+	if after != nil {
+		objectID, err := primitive.ObjectIDFromHex(after.ID)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		op := "$gt"
+		if direction < 0 {
+			op = "$lt"
+		}
+
+		afterID := bson.D{{Key: "_id", Value: bson.D{{Key: op, Value: objectID}}}}
+		switch field {
+		case "_id":
+			filter = append(filter, afterID...)
+		default:
+			var value interface{} = after.Price
+			if field == "name" {
+				value = after.Name
+			}
+			// items past the value of the cursor or with the same value and past its ID
+			filter = append(filter, bson.E{Key: "$or", Value: bson.A{
+				bson.D{{Key: field, Value: bson.D{{Key: op, Value: value}}}},
+				append(bson.D{{Key: field, Value: value}}, afterID...),
+			}})
+		}
+	}
+
+	sort := bson.D{{Key: field, Value: direction}}
+	if field != "_id" {
+		sort = append(sort, bson.E{Key: "_id", Value: direction})
+	}
+	opts := options.Find().SetSort(sort).SetLimit(int64(limit))
+
+	resp, errFind := r.itemCollection.Find(r.ctx, filter, opts)
+	if errFind != nil {
+		return nil, errFind
+	}
+
+	var items []Item
+	if err := resp.All(r.ctx, &items); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// catalogSortKey returns the field the sort orders the items by and its direction, the IDs of
+// the items grow with their creation time.
+func catalogSortKey(sort string) (string, int) {
+	switch sort {
+	case SortOldest:
+		return "_id", 1
+	case SortPriceAsc:
+		return "price", 1
+	case SortPriceDesc:
+		return "price", -1
+	case SortNameAsc:
+		return "name", 1
+	case SortNameDesc:
+		return "name", -1
+	default:
+		return "_id", -1
+	}
+}
@@ -0,0 +1,274 @@
+package GoodService
+
+import (
+	"sort"
+	"strings"
+)
+
+// categoryTree indexes the categories by id and by parent, the tree is always read whole.
+type categoryTree struct {
+	byID     map[string]Category
+	children map[string][]Category
+}
+
+func newCategoryTree(categories []Category) categoryTree {
+	t := categoryTree{
+		byID:     make(map[string]Category, len(categories)),
+		children: make(map[string][]Category),
+	}
+	for _, c := range categories {
+		t.byID[c.ID] = c
+		t.children[c.ParentID] = append(t.children[c.ParentID], c)
+	}
+	for _, children := range t.children {
+		sort.Slice(children, func(a, b int) bool { return children[a].Name < children[b].Name })
+	}
+	return t
+}
+
+// breadcrumb returns the path from the root to the category, the category included.
+func (t categoryTree) breadcrumb(id string) []CategoryRef {
+	var path []CategoryRef
+	for c, ok := t.byID[id]; ok && len(path) <= len(t.byID); c, ok = t.byID[c.ParentID] {
+		path = append(path, CategoryRef{ID: c.ID, Name: c.Name, Slug: c.Slug})
+	}
+	for a, b := 0, len(path)-1; a < b; a, b = a+1, b-1 {
+		path[a], path[b] = path[b], path[a]
+	}
+	return path
+}
+
+// isAncestor reports whether ancestorID is id or lies on the path from the root to it.
+func (t categoryTree) isAncestor(ancestorID, id string) bool {
+	for _, c := range t.breadcrumb(id) {
+		if c.ID == ancestorID {
+			return true
+		}
+	}
+	return false
+}
+
+// subtreeIDs returns the id of the category and of all of its descendants.
+func (t categoryTree) subtreeIDs(id string) []string {
+	ids := []string{id}
+	for n := 0; n < len(ids); n++ {
+		for _, c := range t.children[ids[n]] {
+			ids = append(ids, c.ID)
+		}
+	}
+	return ids
+}
+
+func (t categoryTree) nodes(parentID string) []CategoryNode {
+	nodes := make([]CategoryNode, 0, len(t.children[parentID]))
+	for _, c := range t.children[parentID] {
+		nodes = append(nodes, CategoryNode{Category: c, Children: t.nodes(c.ID)})
+	}
+	return nodes
+}
+
+// slugify lower cases the text and joins its words with dashes.
+func slugify(text string) string {
+	return strings.Join(searchWords(text), "-")
+}
+
+func (s *goodService) categoryTree() (categoryTree, error) {
+	categories, err := s.repo.GetCategories()
+	if err != nil {
+		return categoryTree{}, err
+	}
+	return newCategoryTree(categories), nil
+}
+
+func (s *goodService) GetCategories() ([]CategoryNode, error) {
+	tree, err := s.categoryTree()
+	if err != nil {
+		return nil, err
+	}
+	return tree.nodes(""), nil
+}
+
+func (s *goodService) CreateCategory(in CategoryInput) (Category, error) {
+	c, err := newCategory(in)
+	if err != nil {
+		return Category{}, err
+	}
+
+	if c.ParentID != "" {
+		tree, errTree := s.categoryTree()
+		if errTree != nil {
+			return Category{}, errTree
+		}
+		if _, ok := tree.byID[c.ParentID]; !ok {
+			return Category{}, ErrParentCategoryNotFound
+		}
+	}
+
+	c.ID, err = s.repo.CreateCategory(c)
+	if err != nil {
+		return Category{}, err
+	}
+	return c, nil
+}
+
+// UpdateCategory renames the category or moves it with its subtree under another parent.
+func (s *goodService) UpdateCategory(id string, in CategoryInput) (Category, error) {
+	c, err := newCategory(in)
+	if err != nil {
+		return Category{}, err
+	}
+	c.ID = id
+
+	tree, err := s.categoryTree()
+	if err != nil {
+		return Category{}, err
+	}
+	if _, ok := tree.byID[id]; !ok {
+		return Category{}, ErrCategoryNotFound
+	}
+	if c.ParentID != "" {
+		if _, ok := tree.byID[c.ParentID]; !ok {
+			return Category{}, ErrParentCategoryNotFound
+		}
+		if tree.isAncestor(id, c.ParentID) {
+			return Category{}, ErrCategoryCycle
+		}
+	}
+
+	if err = s.repo.UpdateCategory(c); err != nil {
+		return Category{}, err
+	}
+	return c, nil
+}
+
+// DeleteCategory removes a category without subcategories and unassigns it from its items.
+// The items go first, so a failed call leaves the category in place and can be retried.
+func (s *goodService) DeleteCategory(id string) error {
+	tree, err := s.categoryTree()
+	if err != nil {
+		return err
+	}
+	if _, ok := tree.byID[id]; !ok {
+		return ErrCategoryNotFound
+	}
+	if len(tree.children[id]) > 0 {
+		return ErrCategoryHasChildren
+	}
+
+	if _, err = s.repo.RemoveCategoryFromItems(id); err != nil {
+		return err
+	}
+	return s.repo.DeleteCategory(id)
+}
+
+// GetCategoryItems returns a page of the items in the category or any of its descendants.
+func (s *goodService) GetCategoryItems(id string, q CatalogQuery) (CategoryPage, error) {
+	tree, err := s.categoryTree()
+	if err != nil {
+		return CategoryPage{}, err
+	}
+	category, ok := tree.byID[id]
+	if !ok {
+		return CategoryPage{}, ErrCategoryNotFound
+	}
+
+	ids := tree.subtreeIDs(id)
+	limit, offset := pageBounds(q.Limit, q.Offset)
+
+	items, err := s.repo.GetItemsByCategoryIDs(ids, limit, offset)
+	if err != nil {
+		return CategoryPage{}, err
+	}
+	total, err := s.repo.CountItemsByCategoryIDs(ids)
+	if err != nil {
+		return CategoryPage{}, err
+	}
+
+	for n := range items {
+		items[n].Breadcrumbs = breadcrumbs(tree, items[n].CategoryIDs)
+	}
+	if items == nil {
+		items = []Item{}
+	}
+
+	return CategoryPage{
+		Category:   category,
+		Breadcrumb: tree.breadcrumb(id),
+		Items:      items,
+		Total:      total,
+	}, nil
+}
+
+func newCategory(in CategoryInput) (Category, error) {
+	name := strings.TrimSpace(in.Name)
+	if name == "" {
+		return Category{}, ErrInvalidCategoryName
+	}
+
+	slug := in.Slug
+	if slug == "" {
+		slug = name
+	}
+	slug = slugify(slug)
+	if slug == "" {
+		return Category{}, ErrInvalidCategorySlug
+	}
+
+	return Category{Name: name, Slug: slug, ParentID: strings.TrimSpace(in.ParentID)}, nil
+}
+
+// checkCategories drops repeated ids and fails with ErrCategoryNotFound on an unknown one.
+func (s *goodService) checkCategories(ids []string) ([]string, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	tree, err := s.categoryTree()
+	if err != nil {
+		return nil, err
+	}
+
+	var checked []string
+	seen := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		if _, ok := tree.byID[id]; !ok {
+			return nil, ErrCategoryNotFound
+		}
+		seen[id] = struct{}{}
+		checked = append(checked, id)
+	}
+	return checked, nil
+}
+
+// addBreadcrumbs fills the breadcrumbs of the items, reading the tree only when one has categories.
+func (s *goodService) addBreadcrumbs(items []Item) error {
+	var tree *categoryTree
+	for n := range items {
+		if len(items[n].CategoryIDs) == 0 {
+			continue
+		}
+		if tree == nil {
+			t, err := s.categoryTree()
+			if err != nil {
+				return err
+			}
+			tree = &t
+		}
+		items[n].Breadcrumbs = breadcrumbs(*tree, items[n].CategoryIDs)
+	}
+	return nil
+}
+
+// breadcrumbs skips categories deleted since the item was assigned to them.
+func breadcrumbs(tree categoryTree, ids []string) [][]CategoryRef {
+	var paths [][]CategoryRef
+	for _, id := range ids {
+		if path := tree.breadcrumb(id); len(path) > 0 {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
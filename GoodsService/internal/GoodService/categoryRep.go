@@ -0,0 +1,132 @@
+package GoodService
+
+import (
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetCategories returns the whole category tree, it is small enough to be read at once.
+func (r *goodsMongoRepo) GetCategories() ([]Category, error) {
+	resp, errFind := r.categoryCollection.Find(r.ctx, bson.M{})
+	if errFind != nil {
+		return nil, errFind
+	}
+
+	var categories []Category
+	if err := resp.All(r.ctx, &categories); err != nil {
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+func (r *goodsMongoRepo) CreateCategory(c Category) (string, error) {
+	res, err := r.categoryCollection.InsertOne(r.ctx, c)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return "", ErrCategorySlugTaken
+		}
+		return "", err
+	}
+
+	if id, ok := res.InsertedID.(primitive.ObjectID); ok {
+		return id.Hex(), nil
+	}
+
+	if id, ok := res.InsertedID.(string); ok {
+		return id, nil
+	}
+
+	return "", errors.New("cant convert id to ObjectID or str")
+}
+
+func (r *goodsMongoRepo) UpdateCategory(c Category) error {
+	objectID, err := primitive.ObjectIDFromHex(c.ID)
+	if err != nil {
+		return ErrCategoryNotFound
+	}
+
+	set := bson.D{
+		{Key: "name", Value: c.Name},
+		{Key: "slug", Value: c.Slug},
+	}
+	update := bson.D{{Key: "$unset", Value: bson.D{{Key: "parent_id", Value: ""}}}}
+	if c.ParentID != "" {
+		set = append(set, bson.E{Key: "parent_id", Value: c.ParentID})
+		update = nil
+	}
+	update = append(update, bson.E{Key: "$set", Value: set})
+	res, err := r.categoryCollection.UpdateOne(r.ctx, bson.D{{Key: "_id", Value: objectID}}, update)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrCategorySlugTaken
+		}
+		return err
+	}
+
+	if res.MatchedCount == 0 {
+		return ErrCategoryNotFound
+	}
+
+	return nil
+}
+
+func (r *goodsMongoRepo) DeleteCategory(id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrCategoryNotFound
+	}
+
+	res, err := r.categoryCollection.DeleteOne(r.ctx, bson.D{{Key: "_id", Value: objectID}})
+	if err != nil {
+		return err
+	}
+
+	if res.DeletedCount == 0 {
+		return ErrCategoryNotFound
+	}
+
+	return nil
+}
+
+// RemoveCategoryFromItems unassigns the category from every item and returns the number of changed items.
+func (r *goodsMongoRepo) RemoveCategoryFromItems(id string) (int64, error) {
+	res, err := r.itemCollection.UpdateMany(r.ctx,
+		bson.D{{Key: "category_ids", Value: id}},
+		bson.D{{Key: "$pull", Value: bson.D{{Key: "category_ids", Value: id}}}})
+	if err != nil {
+		return 0, err
+	}
+
+	return res.ModifiedCount, nil
+}
+
+// GetItemsByCategoryIDs returns a page of the items assigned to any of the categories, oldest first.
+func (r *goodsMongoRepo) GetItemsByCategoryIDs(ids []string, limit, offset int) ([]Item, error) {
+	filter := bson.D{{Key: "category_ids", Value: bson.D{{Key: "$in", Value: ids}}}}
+	opts := options.Find().
+		SetProjection(bson.D{{Key: "search_keys", Value: 0}}).
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit))
+
+	resp, errFind := r.itemCollection.Find(r.ctx, filter, opts)
+	if errFind != nil {
+		return nil, errFind
+	}
+
+	var items []Item
+	if err := resp.All(r.ctx, &items); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func (r *goodsMongoRepo) CountItemsByCategoryIDs(ids []string) (int64, error) {
+	filter := bson.D{{Key: "category_ids", Value: bson.D{{Key: "$in", Value: ids}}}}
+	return r.itemCollection.CountDocuments(r.ctx, filter)
+}
@@ -4,3 +4,101 @@ type UserCtx struct {
 	ID   int
 	Name string
 }
+
+type SearchQuery struct {
+	Query  string `form:"q"`
+	Limit  int    `form:"limit"`
+	Offset int    `form:"offset"`
+}
+
+type SearchResult struct {
+	Hits   []SearchHit  `json:"hits"`
+	Total  int          `json:"total"`
+	Facets SearchFacets `json:"facets"`
+}
+
+type SearchHit struct {
+	Item       Item            `json:"item"`
+	Score      float64         `json:"score"`
+	Highlights SearchHighlight `json:"highlights"`
+}
+
+// SearchHighlight holds HTML escaped text with the matching words wrapped in <em>.
+type SearchHighlight struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type SearchFacets struct {
+	Sellers      []SellerFacet      `json:"sellers"`
+	PriceBuckets []PriceBucketFacet `json:"priceBuckets"`
+}
+
+type SellerFacet struct {
+	SellerID string `json:"sellerID"`
+	Count    int    `json:"count"`
+}
+
+// PriceBucketFacet counts the hits priced from From up to but not including To, To is nil for the last bucket.
+type PriceBucketFacet struct {
+	From  float64  `json:"from"`
+	To    *float64 `json:"to"`
+	Count int      `json:"count"`
+}
+
+type CategoryInput struct {
+	Name string `json:"name" binding:"required"`
+	// Slug is made from the name when empty
+	Slug     string `json:"slug"`
+	ParentID string `json:"parentID"`
+}
+
+type CategoryRef struct {
+	ID   string `json:"_id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+type CategoryNode struct {
+	Category
+	Children []CategoryNode `json:"children"`
+}
+
+type CatalogQuery struct {
+	Limit  int `form:"limit"`
+	Offset int `form:"offset"`
+}
+
+// CategoryPage is a page of the items of a category and its descendants.
+type CategoryPage struct {
+	Category   Category      `json:"category"`
+	Breadcrumb []CategoryRef `json:"breadcrumb"`
+	Items      []Item        `json:"items"`
+	Total      int64         `json:"total"`
+}
+
+// GoodsQuery selects a page of the catalog. MinPrice and MaxPrice bound the price when set, Sort is
+// one of the Sort values and newest first when empty, Cursor is the NextCursor of the previous page.
+type GoodsQuery struct {
+	Cursor   string   `form:"cursor"`
+	Limit    int      `form:"limit"`
+	MinPrice *float64 `form:"minPrice"`
+	MaxPrice *float64 `form:"maxPrice"`
+	SellerID string   `form:"sellerID"`
+	InStock  bool     `form:"inStock"`
+	Sort     string   `form:"sort"`
+}
+
+// GoodsPage is a page of the catalog, NextCursor is empty on the last page.
+type GoodsPage struct {
+	Items      []Item `json:"items"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// GoodsCursor is the position of the last item of a page in the sort order the page was read in.
+type GoodsCursor struct {
+	Sort  string  `json:"s"`
+	ID    string  `json:"id"`
+	Price float64 `json:"p,omitempty"`
+	Name  string  `json:"n,omitempty"`
+}
@@ -0,0 +1,265 @@
+package GoodService
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+	"slices"
+)
+
+const (
+	MaxImageSize     = 5 << 20
+	maxImagePixels   = 20_000_000
+	maxImagesPerItem = 10
+
+	thumbnailSize    = 400
+	thumbnailQuality = 80
+)
+
+var (
+	ErrImageTooLarge        = errors.New("image is too large")
+	ErrUnsupportedImageType = errors.New("unsupported image type, use jpeg, png or gif")
+	ErrInvalidImage         = errors.New("invalid image")
+	ErrItemImageLimit       = errors.New("item has too many images")
+	ErrItemImageNotFound    = errors.New("image not found")
+	ErrInvalidImageOrder    = errors.New("image order must list every image of the item once")
+)
+
+// imageExtensions are the image types accepted, keyed by the sniffed content type.
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+type processedImage struct {
+	contentType string
+	width       int
+	height      int
+	thumbnail   []byte
+}
+
+// processImage checks the upload by its content rather than the declared type and makes the thumbnail.
+// The dimensions are read before decoding, so a small file can't claim a huge bitmap.
+func processImage(data []byte) (processedImage, error) {
+	if len(data) > MaxImageSize {
+		return processedImage{}, ErrImageTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	if _, ok := imageExtensions[contentType]; !ok {
+		return processedImage{}, ErrUnsupportedImageType
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return processedImage{}, ErrInvalidImage
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxImagePixels {
+		return processedImage{}, ErrInvalidImage
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return processedImage{}, ErrInvalidImage
+	}
+
+	var thumb bytes.Buffer
+	if err = jpeg.Encode(&thumb, thumbnail(img, thumbnailSize), &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return processedImage{}, err
+	}
+
+	return processedImage{
+		contentType: contentType,
+		width:       cfg.Width,
+		height:      cfg.Height,
+		thumbnail:   thumb.Bytes(),
+	}, nil
+}
+
+// thumbnail scales the image to fit in a size by size square averaging the covered pixels,
+// transparent parts become white as the thumbnail is a jpeg.
+func thumbnail(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Over)
+
+	w, h := bounds.Dx(), bounds.Dy()
+	if w <= size && h <= size {
+		return src
+	}
+	dw, dh := size, h*size/w
+	if h > w {
+		dw, dh = w*size/h, size
+	}
+	dw, dh = max(dw, 1), max(dh, 1)
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*h/dh, max((y+1)*h/dh, y*h/dh+1)
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*w/dw, max((x+1)*w/dw, x*w/dw+1)
+
+			var r, g, b, n int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					r += int(row[sx*4])
+					g += int(row[sx*4+1])
+					b += int(row[sx*4+2])
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: 255})
+		}
+	}
+	return dst
+}
+
+func newImageID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// ownItem returns the item if the user is the seller of it.
+func (s *goodService) ownItem(itemID string, userID int) (Item, error) {
+	sellerID, err := s.repo.GetSellerIDByUserID(userID)
+	if err != nil {
+		return Item{}, err
+	}
+
+	item, err := s.repo.GetItemByID(itemID)
+	if err != nil {
+		return Item{}, err
+	}
+	if item.SellerID != sellerID {
+		return Item{}, ErrNotItemOwner
+	}
+	return item, nil
+}
+
+// AddItemImage stores the image with its thumbnail and appends it to the images of the item.
+// The blobs are removed again when the item can't take the image.
+func (s *goodService) AddItemImage(itemID string, userID int, data []byte) (ItemImage, error) {
+	item, err := s.ownItem(itemID, userID)
+	if err != nil {
+		return ItemImage{}, err
+	}
+	if len(item.Images) >= maxImagesPerItem {
+		return ItemImage{}, ErrItemImageLimit
+	}
+
+	processed, err := processImage(data)
+	if err != nil {
+		return ItemImage{}, err
+	}
+
+	id, err := newImageID()
+	if err != nil {
+		return ItemImage{}, err
+	}
+	img := ItemImage{
+		ID:           id,
+		Key:          "items/" + item.ID + "/" + id + imageExtensions[processed.contentType],
+		ThumbnailKey: "items/" + item.ID + "/" + id + "_thumb.jpg",
+		ContentType:  processed.contentType,
+		Width:        processed.width,
+		Height:       processed.height,
+		Size:         len(data),
+	}
+
+	if err = s.blobs.Put(img.Key, data, img.ContentType); err != nil {
+		return ItemImage{}, err
+	}
+	if err = s.blobs.Put(img.ThumbnailKey, processed.thumbnail, "image/jpeg"); err != nil {
+		s.deleteImageBlobs([]ItemImage{img})
+		return ItemImage{}, err
+	}
+
+	if err = s.repo.AddItemImage(item.ID, item.SellerID, img, maxImagesPerItem); err != nil {
+		s.deleteImageBlobs([]ItemImage{img})
+		return ItemImage{}, err
+	}
+
+	return img, nil
+}
+
+func (s *goodService) DeleteItemImage(itemID, imageID string, userID int) error {
+	item, err := s.ownItem(itemID, userID)
+	if err != nil {
+		return err
+	}
+
+	i := slices.IndexFunc(item.Images, func(img ItemImage) bool { return img.ID == imageID })
+	if i < 0 {
+		return ErrItemImageNotFound
+	}
+
+	if err = s.repo.RemoveItemImage(item.ID, imageID); err != nil {
+		return err
+	}
+	s.deleteImageBlobs(item.Images[i : i+1])
+	return nil
+}
+
+// ReorderItemImages puts the images of the item in the order of the ids, which must name each of them once.
+func (s *goodService) ReorderItemImages(itemID string, userID int, imageIDs []string) ([]ItemImage, error) {
+	item, err := s.ownItem(itemID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(imageIDs) != len(item.Images) {
+		return nil, ErrInvalidImageOrder
+	}
+
+	byID := make(map[string]ItemImage, len(item.Images))
+	for _, img := range item.Images {
+		byID[img.ID] = img
+	}
+
+	images := make([]ItemImage, 0, len(imageIDs))
+	for _, id := range imageIDs {
+		img, ok := byID[id]
+		if !ok {
+			return nil, ErrInvalidImageOrder
+		}
+		delete(byID, id)
+		images = append(images, img)
+	}
+
+	if err = s.repo.SetItemImages(item.ID, images); err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+func (s *goodService) GetImage(key string) (Blob, error) {
+	return s.blobs.Get(key)
+}
+
+// deleteImageBlobs is best effort, a blob left behind only costs storage, so failures are logged.
+func (s *goodService) deleteImageBlobs(images []ItemImage) {
+	for _, img := range images {
+		for _, key := range []string{img.Key, img.ThumbnailKey} {
+			if err := s.blobs.Delete(key); err != nil {
+				logrus.WithFields(logrus.Fields{
+					"event": "blob_delete_failed",
+					"key":   key,
+				}).WithError(err).Warn("can't delete image blob")
+			}
+		}
+	}
+}
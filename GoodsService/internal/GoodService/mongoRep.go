@@ -3,16 +3,18 @@ package GoodService
 import (
 	"context"
 	"errors"
+	"fmt"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
 )
 
 //go:generate mockgen -source=mongoRep.go -destination=../mocks/mockMongo.go -package=mocks
 
 type GoodsMongoRepo interface {
-	GetGoods() ([]Item, error)
+	GetGoods(GoodsQuery, *GoodsCursor, int) ([]Item, error)
 
 	GetQuantity(string) (int, error)
 
@@ -23,43 +25,76 @@ type GoodsMongoRepo interface {
 	GetSellerIDByUserID(int) (string, error)
 	GetItemByID(string) (Item, error)
 	CreateSeller(int, string) (string, error)
+	GetSellerByUserID(int) (Seller, error)
+	GetItemsBySellerID(string) ([]Item, error)
+	DeleteSeller(string) (int64, error)
 
 	GetItemInfoForCart(string) (ItemInfoForCart, error)
+
+	SearchItemsByText(string, int) ([]ScoredItem, error)
+	FindItemsBySearchKeys([]string, int) ([]Item, error)
+	EnsureIndexes() error
+	BackfillSearchKeys() (int64, error)
+
+	GetCategories() ([]Category, error)
+	CreateCategory(Category) (string, error)
+	UpdateCategory(Category) error
+	DeleteCategory(string) error
+	RemoveCategoryFromItems(string) (int64, error)
+	GetItemsByCategoryIDs([]string, int, int) ([]Item, error)
+	CountItemsByCategoryIDs([]string) (int64, error)
+
+	AddItemImage(string, string, ItemImage, int) error
+	RemoveItemImage(string, string) error
+	SetItemImages(string, []ItemImage) error
+
+	SetItemVariants(string, int, []Variant, int) error
+
+	CreateReservation(Reservation) (string, error)
+	GetReservation(string) (Reservation, error)
+	GetReservationByKey(string) (Reservation, error)
+	DeleteReservation(string) error
+	FinishReservation(string, string, time.Time) (bool, error)
+	GetExpiredReservations(time.Time, int) ([]Reservation, error)
+	ReserveItemStock(string, ReservationLine) (bool, error)
+	ReleaseItemStock(string, ReservationLine) error
+	CommitItemStock(string, string) error
 }
 
+var (
+	ErrItemNotFound      = errors.New("item not found")
+	ErrSellerNotFound    = errors.New("seller not found")
+	ErrCategoryNotFound  = errors.New("category not found")
+	ErrCategorySlugTaken = errors.New("category slug is taken")
+)
+
 type goodsMongoRepo struct {
-	itemCollection   *mongo.Collection
-	sellerCollection *mongo.Collection
-	ctx              context.Context
+	itemCollection        *mongo.Collection
+	sellerCollection      *mongo.Collection
+	categoryCollection    *mongo.Collection
+	reservationCollection *mongo.Collection
+	ctx                   context.Context
 }
 
 func NewGoodsMongoRepo(client *mongo.Client) GoodsMongoRepo {
 	db := client.Database("GoodsInfo")
 	return &goodsMongoRepo{
-		itemCollection:   db.Collection("goods"),
-		sellerCollection: db.Collection("sellers"),
-		ctx:              context.Background(),
+		itemCollection:        db.Collection("goods"),
+		sellerCollection:      db.Collection("sellers"),
+		categoryCollection:    db.Collection("categories"),
+		reservationCollection: db.Collection("reservations"),
+		ctx:                   context.Background(),
 	}
 }
 
-func (r *goodsMongoRepo) GetGoods() ([]Item, error) {
-	resp, errFind := r.itemCollection.Find(r.ctx, bson.M{})
-	if errFind != nil {
-		return nil, errFind
-	}
-
-	var allItems []Item
-	if err := resp.All(r.ctx, &allItems); err != nil {
-		return nil, err
-	}
-
-	return allItems, nil
-}
-
 func (r *goodsMongoRepo) CreateItem(item Item) (string, error) {
+	item.SearchKeys = searchKeys(item.Name, item.Description)
 	res, err := r.itemCollection.InsertOne(r.ctx, item)
 
 	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return "", ErrSKUTaken
+		}
 		return "", errors.New("cant insert item")
 	}
 
@@ -101,29 +136,54 @@ func (r *goodsMongoRepo) UpdateItem(item Item) (Item, error) {
 		return Item{}, errors.New("can't parse itemId to objectId")
 	}
 
-	filter := bson.D{{Key: "_id", Value: objectID}}
-	update := bson.D{
-		{Key: "$set", Value: bson.D{
-			{Key: "name", Value: item.Name},
-			{Key: "description", Value: item.Description},
-			{Key: "quantity", Value: item.Quantity},
-			{Key: "seller_id", Value: item.SellerID},
+	// the stock of an item with variants is theirs and changes through the variants and reservations only,
+	// the update keeps it. Reservations take the stock of a plain item from its quantity, so the filter
+	// refuses to change the quantity while they hold some, it was read before them
+	hasVariants := bson.D{{Key: "$gt", Value: bson.A{bson.D{{Key: "$size", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$variants", bson.A{}}}}}}, 0}}}
+	filter := bson.D{
+		{Key: "_id", Value: objectID},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "variants.0", Value: bson.D{{Key: "$exists", Value: true}}}},
+			bson.D{{Key: "quantity", Value: item.Quantity}},
+			bson.D{{Key: "reservations.0", Value: bson.D{{Key: "$exists", Value: false}}}},
 		}},
 	}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.D{
+			{Key: "name", Value: literal(item.Name)},
+			{Key: "description", Value: literal(item.Description)},
+			{Key: "quantity", Value: bson.D{{Key: "$cond", Value: bson.A{hasVariants, "$quantity", item.Quantity}}}},
+			{Key: "seller_id", Value: literal(item.SellerID)},
+			{Key: "search_keys", Value: literal(searchKeys(item.Name, item.Description))},
+			{Key: "category_ids", Value: literal(item.CategoryIDs)},
+		}}},
+	}
 	res := r.itemCollection.FindOneAndUpdate(r.ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After))
 
 	var newItem Item
-	errDecode := res.Decode(&newItem)
-	if errDecode != nil {
-		if errors.Is(errDecode, mongo.ErrNoDocuments) {
-			return Item{}, errors.New("item not found")
+	if err = res.Decode(&newItem); err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return Item{}, err
 		}
-		return Item{}, err
+		count, errCount := r.itemCollection.CountDocuments(r.ctx, bson.D{{Key: "_id", Value: objectID}})
+		if errCount != nil {
+			return Item{}, errCount
+		}
+		if count > 0 {
+			return Item{}, ErrStockReserved
+		}
+		return Item{}, ErrItemNotFound
 	}
 
 	return newItem, nil
 }
 
+// literal keeps a value of an update pipeline from being read as an expression,
+// a string starting with $ would be a field path there.
+func literal(v interface{}) bson.D {
+	return bson.D{{Key: "$literal", Value: v}}
+}
+
 func (r *goodsMongoRepo) GetQuantity(itemID string) (int, error) {
 	objectID, err := primitive.ObjectIDFromHex(itemID)
 	if err != nil {
@@ -192,7 +252,7 @@ func (r *goodsMongoRepo) GetItemByID(id string) (Item, error) {
 	var i Item
 	if errDecode := res.Decode(&i); errDecode != nil {
 		if errors.Is(errDecode, mongo.ErrNoDocuments) {
-			return Item{}, errors.New("item not found")
+			return Item{}, ErrItemNotFound
 		}
 		return Item{}, errDecode
 	}
@@ -211,10 +271,268 @@ func (r *goodsMongoRepo) GetItemInfoForCart(id string) (ItemInfoForCart, error)
 	var i ItemInfoForCart
 	if errDecode := res.Decode(&i); errDecode != nil {
 		if errors.Is(errDecode, mongo.ErrNoDocuments) {
-			return ItemInfoForCart{}, errors.New("item not found")
+			return ItemInfoForCart{}, ErrItemNotFound
 		}
 		return ItemInfoForCart{}, errDecode
 	}
 
 	return i, nil
 }
+
+func (r *goodsMongoRepo) GetSellerByUserID(userID int) (Seller, error) {
+	filter := bson.D{{Key: "user_id", Value: userID}}
+	res := r.sellerCollection.FindOne(r.ctx, filter)
+
+	var s Seller
+	if err := res.Decode(&s); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return Seller{}, ErrSellerNotFound
+		}
+		return Seller{}, err
+	}
+	return s, nil
+}
+
+func (r *goodsMongoRepo) GetItemsBySellerID(sellerID string) ([]Item, error) {
+	filter := bson.D{{Key: "seller_id", Value: sellerID}}
+	resp, errFind := r.itemCollection.Find(r.ctx, filter)
+	if errFind != nil {
+		return nil, errFind
+	}
+
+	var items []Item
+	if err := resp.All(r.ctx, &items); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// DeleteSeller removes the seller with all of its items and returns the number of deleted items.
+// Items go first, so a failed call leaves the seller in place and can be retried.
+func (r *goodsMongoRepo) DeleteSeller(sellerID string) (int64, error) {
+	items, err := r.itemCollection.DeleteMany(r.ctx, bson.D{{Key: "seller_id", Value: sellerID}})
+	if err != nil {
+		return 0, err
+	}
+
+	var id interface{} = sellerID
+	if objectID, errParse := primitive.ObjectIDFromHex(sellerID); errParse == nil {
+		id = objectID
+	}
+
+	if _, errDelete := r.sellerCollection.DeleteOne(r.ctx, bson.D{{Key: "_id", Value: id}}); errDelete != nil {
+		return items.DeletedCount, errDelete
+	}
+
+	return items.DeletedCount, nil
+}
+
+// SearchItemsByText runs the query against the text index and returns the best matches first.
+func (r *goodsMongoRepo) SearchItemsByText(query string, limit int) ([]ScoredItem, error) {
+	filter := bson.D{{Key: "$text", Value: bson.D{{Key: "$search", Value: query}}}}
+	score := bson.D{{Key: "score", Value: bson.D{{Key: "$meta", Value: "textScore"}}}}
+	opts := options.Find().
+		SetProjection(append(score, bson.E{Key: "search_keys", Value: 0})).
+		SetSort(score).
+		SetLimit(int64(limit))
+
+	resp, errFind := r.itemCollection.Find(r.ctx, filter, opts)
+	if errFind != nil {
+		return nil, errFind
+	}
+
+	var items []ScoredItem
+	if err := resp.All(r.ctx, &items); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// FindItemsBySearchKeys returns items sharing at least one of the keys.
+func (r *goodsMongoRepo) FindItemsBySearchKeys(keys []string, limit int) ([]Item, error) {
+	filter := bson.D{{Key: "search_keys", Value: bson.D{{Key: "$in", Value: keys}}}}
+	opts := options.Find().
+		SetProjection(bson.D{{Key: "search_keys", Value: 0}}).
+		SetLimit(int64(limit))
+
+	resp, errFind := r.itemCollection.Find(r.ctx, filter, opts)
+	if errFind != nil {
+		return nil, errFind
+	}
+
+	var items []Item
+	if err := resp.All(r.ctx, &items); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// EnsureIndexes creates the indexes the search, the catalog, the categories and the reservations need,
+// creating an existing index is a no-op.
+func (r *goodsMongoRepo) EnsureIndexes() error {
+	_, err := r.itemCollection.Indexes().CreateMany(r.ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "name", Value: "text"}, {Key: "description", Value: "text"}},
+			Options: options.Index().
+				SetName("item_text").
+				SetWeights(bson.D{{Key: "name", Value: 10}, {Key: "description", Value: 2}}),
+		},
+		{Keys: bson.D{{Key: "search_keys", Value: 1}}},
+		{Keys: bson.D{{Key: "seller_id", Value: 1}}},
+		{Keys: bson.D{{Key: "category_ids", Value: 1}}},
+		// the catalog sorts, the ones sorted by creation use the _id index
+		{Keys: bson.D{{Key: "price", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}}},
+		{
+			Keys: bson.D{{Key: "variants.sku", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.D{{Key: "variants.sku", Value: bson.D{{Key: "$exists", Value: true}}}}),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = r.categoryCollection.Indexes().CreateMany(r.ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "slug", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "parent_id", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = r.reservationCollection.Indexes().CreateMany(r.ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "idempotency_key", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "expires_at", Value: 1}}},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(reservationRetention / time.Second)),
+		},
+	})
+	return err
+}
+
+// BackfillSearchKeys fills the search keys of items created before the search and returns how many were updated.
+func (r *goodsMongoRepo) BackfillSearchKeys() (int64, error) {
+	filter := bson.D{{Key: "search_keys", Value: bson.D{{Key: "$exists", Value: false}}}}
+	resp, errFind := r.itemCollection.Find(r.ctx, filter)
+	if errFind != nil {
+		return 0, errFind
+	}
+	defer resp.Close(r.ctx)
+
+	var updated int64
+	for resp.Next(r.ctx) {
+		var i Item
+		if err := resp.Decode(&i); err != nil {
+			return updated, err
+		}
+
+		objectID, err := primitive.ObjectIDFromHex(i.ID)
+		if err != nil {
+			return updated, errors.New("can't parse itemId to objectId")
+		}
+
+		update := bson.D{{Key: "$set", Value: bson.D{{Key: "search_keys", Value: searchKeys(i.Name, i.Description)}}}}
+		if _, errUpdate := r.itemCollection.UpdateOne(r.ctx, bson.D{{Key: "_id", Value: objectID}}, update); errUpdate != nil {
+			return updated, errUpdate
+		}
+		updated++
+	}
+
+	return updated, resp.Err()
+}
+
+// AddItemImage appends the image to the item of the seller unless the item already has maxImages of them.
+func (r *goodsMongoRepo) AddItemImage(itemID, sellerID string, img ItemImage, maxImages int) error {
+	objectID, err := primitive.ObjectIDFromHex(itemID)
+	if err != nil {
+		return errors.New("can't parse itemId to objectId")
+	}
+
+	filter := bson.D{
+		{Key: "_id", Value: objectID},
+		{Key: "seller_id", Value: sellerID},
+		{Key: fmt.Sprintf("images.%d", maxImages-1), Value: bson.D{{Key: "$exists", Value: false}}},
+	}
+	update := bson.D{{Key: "$push", Value: bson.D{{Key: "images", Value: img}}}}
+	res, err := r.itemCollection.UpdateOne(r.ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount == 0 {
+		return ErrItemImageLimit
+	}
+	return nil
+}
+
+func (r *goodsMongoRepo) RemoveItemImage(itemID, imageID string) error {
+	objectID, err := primitive.ObjectIDFromHex(itemID)
+	if err != nil {
+		return errors.New("can't parse itemId to objectId")
+	}
+
+	update := bson.D{{Key: "$pull", Value: bson.D{{Key: "images", Value: bson.D{{Key: "id", Value: imageID}}}}}}
+	res, err := r.itemCollection.UpdateOne(r.ctx, bson.D{{Key: "_id", Value: objectID}}, update)
+	if err != nil {
+		return err
+	}
+
+	if res.ModifiedCount == 0 {
+		return ErrItemImageNotFound
+	}
+	return nil
+}
+
+func (r *goodsMongoRepo) SetItemImages(itemID string, images []ItemImage) error {
+	objectID, err := primitive.ObjectIDFromHex(itemID)
+	if err != nil {
+		return errors.New("can't parse itemId to objectId")
+	}
+
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "images", Value: images}}}}
+	res, err := r.itemCollection.UpdateOne(r.ctx, bson.D{{Key: "_id", Value: objectID}}, update)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount == 0 {
+		return errors.New("item not found")
+	}
+	return nil
+}
+
+// SetItemVariants replaces the variants and the quantity of the item if its variants are still at the version.
+func (r *goodsMongoRepo) SetItemVariants(itemID string, version int, variants []Variant, quantity int) error {
+	objectID, err := primitive.ObjectIDFromHex(itemID)
+	if err != nil {
+		return errors.New("can't parse itemId to objectId")
+	}
+
+	filter := bson.D{{Key: "_id", Value: objectID}, {Key: "variants_version", Value: version}}
+	if version == 0 {
+		filter[1].Value = bson.D{{Key: "$exists", Value: false}}
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "variants", Value: variants},
+		{Key: "quantity", Value: quantity},
+		{Key: "variants_version", Value: version + 1},
+	}}}
+	res, err := r.itemCollection.UpdateOne(r.ctx, filter, update)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrSKUTaken
+		}
+		return err
+	}
+
+	if res.MatchedCount == 0 {
+		return ErrVariantsChanged
+	}
+	return nil
+}
@@ -1,12 +1,33 @@
 package GoodService
 
+import "time"
+
 type Item struct {
-	ID          string  `json:"_id" bson:"_id,omitempty"`
-	Name        string  `json:"name" bson:"name" binding:"required"`
-	Description string  `json:"description" bson:"description" binding:"required"`
-	Quantity    int     `json:"quantity" bson:"quantity" binding:"required"`
-	Price       float64 `json:"price" bson:"price" binding:"required"`
-	SellerID    string  `json:"sellerID" bson:"seller_id"`
+	ID          string   `json:"_id" bson:"_id,omitempty"`
+	Name        string   `json:"name" bson:"name" binding:"required"`
+	Description string   `json:"description" bson:"description" binding:"required"`
+	Quantity    int      `json:"quantity" bson:"quantity" binding:"required"`
+	Price       float64  `json:"price" bson:"price" binding:"required"`
+	SellerID    string   `json:"sellerID" bson:"seller_id"`
+	CategoryIDs []string `json:"categoryIDs,omitempty" bson:"category_ids,omitempty"`
+	// Images are in display order, the first one is the cover
+	Images []ItemImage `json:"images,omitempty" bson:"images,omitempty"`
+	// Variants are the sizes, colours and the like the item is sold in, Quantity is their total stock then
+	Variants []Variant `json:"variants,omitempty" bson:"variants,omitempty"`
+	// VariantsVersion grows with every change of the variants, so concurrent changes don't overwrite each other
+	VariantsVersion int `json:"-" bson:"variants_version,omitempty"`
+	// Reservations hold the stock taken from the item by pending reservations, see ReserveStock
+	Reservations []StockHold `json:"-" bson:"reservations,omitempty"`
+	// Breadcrumbs hold the path from the root to every category of the item, filled for responses
+	Breadcrumbs [][]CategoryRef `json:"breadcrumbs,omitempty" bson:"-"`
+	// SearchKeys back the typo tolerant prefix search, see searchKeys
+	SearchKeys []string `json:"-" bson:"search_keys,omitempty"`
+}
+
+// ScoredItem is an item found by the text index with its relevance.
+type ScoredItem struct {
+	Item  `bson:",inline"`
+	Score float64 `bson:"score"`
 }
 
 type Seller struct {
@@ -16,6 +37,57 @@ type Seller struct {
 }
 
 type ItemInfoForCart struct {
-	Quantity int     `json:"quantity" bson:"quantity"`
-	Price    float64 `json:"price" bson:"price"`
+	Quantity int       `json:"quantity" bson:"quantity"`
+	Price    float64   `json:"price" bson:"price"`
+	Variants []Variant `json:"-" bson:"variants,omitempty"`
+}
+
+// Category is a node of the category tree, a category without a parent is a root.
+type Category struct {
+	ID       string `json:"_id" bson:"_id,omitempty"`
+	Name     string `json:"name" bson:"name"`
+	Slug     string `json:"slug" bson:"slug"`
+	ParentID string `json:"parentID,omitempty" bson:"parent_id,omitempty"`
+}
+
+// ItemImage references the blobs of an uploaded image and of its thumbnail.
+type ItemImage struct {
+	ID           string `json:"id" bson:"id"`
+	Key          string `json:"key" bson:"key"`
+	ThumbnailKey string `json:"thumbnailKey" bson:"thumbnail_key"`
+	ContentType  string `json:"contentType" bson:"content_type"`
+	Width        int    `json:"width" bson:"width"`
+	Height       int    `json:"height" bson:"height"`
+	Size         int    `json:"size" bson:"size"`
+}
+
+// Variant is one combination of attributes of an item, like size M in red, sold under its own SKU.
+type Variant struct {
+	SKU        string            `json:"sku" bson:"sku" binding:"required"`
+	Attributes map[string]string `json:"attributes" bson:"attributes" binding:"required"`
+	Quantity   int               `json:"quantity" bson:"quantity"`
+	// Price overrides the price of the item when set
+	Price *float64 `json:"price,omitempty" bson:"price,omitempty"`
+}
+
+// Reservation holds stock of items for a buyer until it is committed, released or expires.
+type Reservation struct {
+	ID             string            `bson:"_id,omitempty"`
+	IdempotencyKey string            `bson:"idempotency_key"`
+	Lines          []ReservationLine `bson:"lines"`
+	Status         string            `bson:"status"`
+	ExpiresAt      time.Time         `bson:"expires_at"`
+}
+
+type ReservationLine struct {
+	ItemID   string `bson:"item_id"`
+	SKU      string `bson:"sku"`
+	Quantity int    `bson:"quantity"`
+}
+
+// StockHold marks an item with the stock a reservation took from it, so retries never take it twice.
+type StockHold struct {
+	ID       string `bson:"id"`
+	SKU      string `bson:"sku"`
+	Quantity int    `bson:"quantity"`
 }
@@ -0,0 +1,203 @@
+package GoodService
+
+import (
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+func (r *goodsMongoRepo) CreateReservation(res Reservation) (string, error) {
+	inserted, err := r.reservationCollection.InsertOne(r.ctx, res)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return "", ErrDuplicateReservation
+		}
+		return "", err
+	}
+
+	if id, ok := inserted.InsertedID.(primitive.ObjectID); ok {
+		return id.Hex(), nil
+	}
+
+	if id, ok := inserted.InsertedID.(string); ok {
+		return id, nil
+	}
+
+	return "", errors.New("cant convert id to ObjectID or str")
+}
+
+func (r *goodsMongoRepo) GetReservation(id string) (Reservation, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return Reservation{}, ErrReservationNotFound
+	}
+
+	return r.findReservation(bson.D{{Key: "_id", Value: objectID}})
+}
+
+func (r *goodsMongoRepo) GetReservationByKey(key string) (Reservation, error) {
+	return r.findReservation(bson.D{{Key: "idempotency_key", Value: key}})
+}
+
+func (r *goodsMongoRepo) findReservation(filter bson.D) (Reservation, error) {
+	var res Reservation
+	if err := r.reservationCollection.FindOne(r.ctx, filter).Decode(&res); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return Reservation{}, ErrReservationNotFound
+		}
+		return Reservation{}, err
+	}
+	return res, nil
+}
+
+func (r *goodsMongoRepo) DeleteReservation(id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrReservationNotFound
+	}
+
+	_, err = r.reservationCollection.DeleteOne(r.ctx, bson.D{{Key: "_id", Value: objectID}})
+	return err
+}
+
+// FinishReservation moves a pending reservation to the status and reports whether it was pending.
+// Only a reservation that hasn't expired at now can be committed and only one that has can expire.
+func (r *goodsMongoRepo) FinishReservation(id string, status string, now time.Time) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, ErrReservationNotFound
+	}
+
+	filter := bson.D{
+		{Key: "_id", Value: objectID},
+		{Key: "status", Value: ReservationPending},
+	}
+	switch status {
+	case ReservationCommitted:
+		filter = append(filter, bson.E{Key: "expires_at", Value: bson.D{{Key: "$gt", Value: now}}})
+	case ReservationExpired:
+		filter = append(filter, bson.E{Key: "expires_at", Value: bson.D{{Key: "$lte", Value: now}}})
+	}
+
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "status", Value: status}}}}
+	res, err := r.reservationCollection.UpdateOne(r.ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+// GetExpiredReservations returns up to limit reservations still pending after they expired.
+func (r *goodsMongoRepo) GetExpiredReservations(now time.Time, limit int) ([]Reservation, error) {
+	filter := bson.D{
+		{Key: "status", Value: ReservationPending},
+		{Key: "expires_at", Value: bson.D{{Key: "$lte", Value: now}}},
+	}
+	resp, errFind := r.reservationCollection.Find(r.ctx, filter, options.Find().SetLimit(int64(limit)))
+	if errFind != nil {
+		return nil, errFind
+	}
+
+	var reservations []Reservation
+	if err := resp.All(r.ctx, &reservations); err != nil {
+		return nil, err
+	}
+
+	return reservations, nil
+}
+
+// ReserveItemStock takes the quantity of the line from the stock of the item, or of its variant, and holds it
+// for the reservation. It reports false when the stock is short or the item already holds the line.
+func (r *goodsMongoRepo) ReserveItemStock(reservationID string, line ReservationLine) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(line.ItemID)
+	if err != nil {
+		return false, ErrItemNotFound
+	}
+
+	hold := bson.D{{Key: "id", Value: reservationID}, {Key: "sku", Value: line.SKU}}
+	filter := bson.D{
+		{Key: "_id", Value: objectID},
+		{Key: "reservations", Value: bson.D{{Key: "$not", Value: bson.D{{Key: "$elemMatch", Value: hold}}}}},
+	}
+	inc := bson.D{{Key: "quantity", Value: -line.Quantity}}
+	opts := options.Update()
+
+	if line.SKU == "" {
+		filter = append(filter,
+			bson.E{Key: "quantity", Value: bson.D{{Key: "$gte", Value: line.Quantity}}},
+			bson.E{Key: "variants.0", Value: bson.D{{Key: "$exists", Value: false}}},
+		)
+	} else {
+		filter = append(filter, bson.E{Key: "variants", Value: bson.D{{Key: "$elemMatch", Value: bson.D{
+			{Key: "sku", Value: line.SKU},
+			{Key: "quantity", Value: bson.D{{Key: "$gte", Value: line.Quantity}}},
+		}}}})
+		inc = append(inc,
+			bson.E{Key: "variants.$[v].quantity", Value: -line.Quantity},
+			bson.E{Key: "variants_version", Value: 1},
+		)
+		opts.SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.D{{Key: "v.sku", Value: line.SKU}}}})
+	}
+
+	update := bson.D{
+		{Key: "$inc", Value: inc},
+		{Key: "$push", Value: bson.D{{Key: "reservations", Value: StockHold{ID: reservationID, SKU: line.SKU, Quantity: line.Quantity}}}},
+	}
+	res, err := r.itemCollection.UpdateOne(r.ctx, filter, update, opts)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+// ReleaseItemStock gives the stock held for the line back to the item, releasing a line twice is a no-op.
+// The stock of a variant deleted meanwhile is gone with it.
+func (r *goodsMongoRepo) ReleaseItemStock(reservationID string, line ReservationLine) error {
+	objectID, err := primitive.ObjectIDFromHex(line.ItemID)
+	if err != nil {
+		return ErrItemNotFound
+	}
+
+	hold := bson.D{{Key: "id", Value: reservationID}, {Key: "sku", Value: line.SKU}}
+	filter := bson.D{
+		{Key: "_id", Value: objectID},
+		{Key: "reservations", Value: bson.D{{Key: "$elemMatch", Value: hold}}},
+	}
+	pull := bson.D{{Key: "$pull", Value: bson.D{{Key: "reservations", Value: hold}}}}
+	inc := bson.D{{Key: "quantity", Value: line.Quantity}}
+	opts := options.Update()
+
+	if line.SKU != "" {
+		filter = append(filter, bson.E{Key: "variants.sku", Value: line.SKU})
+		inc = append(inc,
+			bson.E{Key: "variants.$[v].quantity", Value: line.Quantity},
+			bson.E{Key: "variants_version", Value: 1},
+		)
+		opts.SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.D{{Key: "v.sku", Value: line.SKU}}}})
+	}
+
+	res, err := r.itemCollection.UpdateOne(r.ctx, filter, append(pull, bson.E{Key: "$inc", Value: inc}), opts)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount == 0 && line.SKU != "" {
+		_, err = r.itemCollection.UpdateOne(r.ctx, bson.D{{Key: "_id", Value: objectID}}, pull)
+	}
+	return err
+}
+
+// CommitItemStock drops the holds of the reservation from the item, the stock they took stays sold.
+func (r *goodsMongoRepo) CommitItemStock(reservationID string, itemID string) error {
+	objectID, err := primitive.ObjectIDFromHex(itemID)
+	if err != nil {
+		return ErrItemNotFound
+	}
+
+	update := bson.D{{Key: "$pull", Value: bson.D{{Key: "reservations", Value: bson.D{{Key: "id", Value: reservationID}}}}}}
+	_, err = r.itemCollection.UpdateOne(r.ctx, bson.D{{Key: "_id", Value: objectID}}, update)
+	return err
+}
@@ -0,0 +1,242 @@
+package GoodService
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	ReservationPending   = "pending"
+	ReservationCommitted = "committed"
+	ReservationReleased  = "released"
+	ReservationExpired   = "expired"
+)
+
+const (
+	defaultReservationTTL = 10 * time.Minute
+	maxReservationTTL     = time.Hour
+
+	maxReservationLines  = 100
+	maxIdempotencyKeyLen = 128
+	// reservationRetention keeps reservations, and so their idempotency keys, this long after they expire
+	reservationRetention = 7 * 24 * time.Hour
+	// expireBatch caps the reservations expired by one ExpireReservations call
+	expireBatch = 100
+)
+
+var (
+	ErrReservationNotFound  = errors.New("reservation not found")
+	ErrInvalidReservation   = errors.New("invalid reservation")
+	ErrInsufficientStock    = errors.New("not enough stock")
+	ErrDuplicateReservation = errors.New("reservation with this idempotency key exists")
+	ErrIdempotencyKeyReused = errors.New("idempotency key was used for another reservation")
+	ErrReservationExpired   = errors.New("reservation expired")
+	ErrReservationReleased  = errors.New("reservation was released")
+	ErrReservationCommitted = errors.New("reservation is committed")
+	ErrStockReserved        = errors.New("stock of the item is held by reservations, its quantity can't be changed now")
+)
+
+// checkReservationLines merges the lines of one item and SKU, keeping the order they first appear in.
+func checkReservationLines(lines []ReservationLine) ([]ReservationLine, error) {
+	if len(lines) == 0 || len(lines) > maxReservationLines {
+		return nil, ErrInvalidReservation
+	}
+
+	var merged []ReservationLine
+	for _, line := range lines {
+		line.ItemID, line.SKU = strings.TrimSpace(line.ItemID), strings.TrimSpace(line.SKU)
+		if line.ItemID == "" || line.Quantity <= 0 {
+			return nil, ErrInvalidReservation
+		}
+
+		n := slices.IndexFunc(merged, func(m ReservationLine) bool { return m.ItemID == line.ItemID && m.SKU == line.SKU })
+		if n < 0 {
+			merged = append(merged, line)
+			continue
+		}
+		merged[n].Quantity += line.Quantity
+	}
+	return merged, nil
+}
+
+// ReserveStock takes the stock of the lines and holds it until the reservation is committed, released or
+// expires after ttl, a ttl of 0 takes defaultReservationTTL. Either all the lines are reserved or none.
+// Calling it again with the same key returns the reservation made by the first call.
+func (s *goodService) ReserveStock(key string, lines []ReservationLine, ttl time.Duration) (Reservation, error) {
+	key = strings.TrimSpace(key)
+	if key == "" || len(key) > maxIdempotencyKeyLen || ttl < 0 {
+		return Reservation{}, ErrInvalidReservation
+	}
+	lines, err := checkReservationLines(lines)
+	if err != nil {
+		return Reservation{}, err
+	}
+
+	if ttl == 0 {
+		ttl = defaultReservationTTL
+	}
+	ttl = min(ttl, maxReservationTTL)
+
+	res := Reservation{
+		IdempotencyKey: key,
+		Lines:          lines,
+		Status:         ReservationPending,
+		ExpiresAt:      time.Now().Add(ttl).UTC().Truncate(time.Millisecond),
+	}
+	res.ID, err = s.repo.CreateReservation(res)
+	if errors.Is(err, ErrDuplicateReservation) {
+		return s.retryReservation(key, lines)
+	}
+	if err != nil {
+		return Reservation{}, err
+	}
+
+	return s.applyReservation(res)
+}
+
+// retryReservation finishes the reservation of a retried call, which the first call might have left half done.
+func (s *goodService) retryReservation(key string, lines []ReservationLine) (Reservation, error) {
+	res, err := s.repo.GetReservationByKey(key)
+	if err != nil {
+		return Reservation{}, err
+	}
+	if !slices.Equal(res.Lines, lines) {
+		return Reservation{}, ErrIdempotencyKeyReused
+	}
+
+	if res.Status != ReservationPending || !time.Now().Before(res.ExpiresAt) {
+		return res, nil
+	}
+	return s.applyReservation(res)
+}
+
+// applyReservation reserves the lines one by one, giving back the ones already reserved when one fails.
+// The failed reservation is deleted, so its key can be retried once there is stock.
+func (s *goodService) applyReservation(res Reservation) (Reservation, error) {
+	for _, line := range res.Lines {
+		if err := s.reserveLine(res.ID, line); err != nil {
+			// a reservation left pending gives its stock back once it expires
+			if errRelease := s.releaseLines(res.ID, res.Lines); errRelease != nil {
+				logrus.WithError(errRelease).WithField("reservation", res.ID).Error("can't release failed reservation")
+				return Reservation{}, err
+			}
+			if errDelete := s.repo.DeleteReservation(res.ID); errDelete != nil {
+				logrus.WithError(errDelete).WithField("reservation", res.ID).Error("can't delete failed reservation")
+			}
+			return Reservation{}, err
+		}
+	}
+	return res, nil
+}
+
+func (s *goodService) reserveLine(reservationID string, line ReservationLine) error {
+	reserved, err := s.repo.ReserveItemStock(reservationID, line)
+	if err != nil || reserved {
+		return err
+	}
+
+	item, err := s.repo.GetItemByID(line.ItemID)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(item.Reservations, StockHold{ID: reservationID, SKU: line.SKU, Quantity: line.Quantity}) {
+		return nil
+	}
+	if line.SKU == "" {
+		if len(item.Variants) > 0 {
+			return ErrSKURequired
+		}
+		return ErrInsufficientStock
+	}
+	if !slices.ContainsFunc(item.Variants, func(v Variant) bool { return v.SKU == line.SKU }) {
+		return ErrVariantNotFound
+	}
+	return ErrInsufficientStock
+}
+
+func (s *goodService) releaseLines(reservationID string, lines []ReservationLine) error {
+	for _, line := range lines {
+		if err := s.repo.ReleaseItemStock(reservationID, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CommitReservation turns the held stock into sold stock. Committing twice is a no-op.
+func (s *goodService) CommitReservation(id string) (Reservation, error) {
+	committed, err := s.repo.FinishReservation(id, ReservationCommitted, time.Now())
+	if err != nil {
+		return Reservation{}, err
+	}
+
+	res, err := s.repo.GetReservation(id)
+	if err != nil {
+		return Reservation{}, err
+	}
+	if !committed {
+		switch res.Status {
+		case ReservationReleased:
+			return Reservation{}, ErrReservationReleased
+		case ReservationPending, ReservationExpired:
+			return Reservation{}, ErrReservationExpired
+		}
+	}
+
+	// a retried commit drops the holds a failed one left behind
+	for _, line := range res.Lines {
+		if err = s.repo.CommitItemStock(id, line.ItemID); err != nil {
+			return Reservation{}, err
+		}
+	}
+	return res, nil
+}
+
+// ReleaseReservation gives the held stock back to the items. Releasing twice is a no-op.
+func (s *goodService) ReleaseReservation(id string) (Reservation, error) {
+	if _, err := s.repo.FinishReservation(id, ReservationReleased, time.Now()); err != nil {
+		return Reservation{}, err
+	}
+
+	res, err := s.repo.GetReservation(id)
+	if err != nil {
+		return Reservation{}, err
+	}
+	if res.Status == ReservationCommitted {
+		return Reservation{}, ErrReservationCommitted
+	}
+
+	if err = s.releaseLines(id, res.Lines); err != nil {
+		return Reservation{}, err
+	}
+	return res, nil
+}
+
+// ExpireReservations gives back the stock of the pending reservations past their expiry and returns how many
+// expired. The stock goes back first, an expired reservation can't be committed anyway, so a failed call
+// leaves the reservations pending for the next one.
+func (s *goodService) ExpireReservations() (int, error) {
+	now := time.Now()
+	reservations, err := s.repo.GetExpiredReservations(now, expireBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, res := range reservations {
+		if err = s.releaseLines(res.ID, res.Lines); err != nil {
+			return expired, err
+		}
+		ok, errFinish := s.repo.FinishReservation(res.ID, ReservationExpired, now)
+		if errFinish != nil {
+			return expired, errFinish
+		}
+		if ok {
+			expired++
+		}
+	}
+	return expired, nil
+}
@@ -0,0 +1,134 @@
+package GoodService
+
+import (
+	"sort"
+)
+
+//go:generate mockgen -source=search.go -destination=../mocks/mockSearch.go -package=mocks
+
+// Searcher finds items matching a query. The mongo one is built in, an external search engine can take its place.
+type Searcher interface {
+	Search(SearchQuery) (SearchResult, error)
+}
+
+// maxSearchCandidates caps the items read from each of the indexes for one query,
+// the ranking and the facets only cover these.
+const maxSearchCandidates = 1000
+
+// priceBucketEdges split the prices into the buckets of the price facet, the last bucket is open ended.
+var priceBucketEdges = []float64{0, 10, 50, 100, 500}
+
+type mongoSearcher struct {
+	repo GoodsMongoRepo
+}
+
+// NewMongoSearcher searches with the text index of the items for stemmed whole words and with their
+// search keys for prefixes and typos, the candidates of both are ranked together.
+func NewMongoSearcher(repo GoodsMongoRepo) Searcher {
+	return &mongoSearcher{repo: repo}
+}
+
+type searchCandidate struct {
+	item  Item
+	score float64
+}
+
+func (s *mongoSearcher) Search(q SearchQuery) (SearchResult, error) {
+	terms := searchTerms(q.Query)
+	if len(terms) == 0 {
+		return SearchResult{Hits: []SearchHit{}, Facets: facets(nil)}, nil
+	}
+
+	textItems, err := s.repo.SearchItemsByText(q.Query, maxSearchCandidates)
+	if err != nil {
+		return SearchResult{}, err
+	}
+	keyItems, err := s.repo.FindItemsBySearchKeys(queryKeys(terms), maxSearchCandidates)
+	if err != nil {
+		return SearchResult{}, err
+	}
+
+	textScores := make(map[string]float64, len(textItems))
+	candidates := make(map[string]Item, len(textItems)+len(keyItems))
+	for _, i := range textItems {
+		textScores[i.ID] = i.Score
+		candidates[i.ID] = i.Item
+	}
+	for _, i := range keyItems {
+		candidates[i.ID] = i
+	}
+
+	// two words two typos apart can share a deletion variant, such candidates match no term and score nothing
+	var matches []searchCandidate
+	for id, i := range candidates {
+		score := textScores[id] + termScore(terms, i)
+		if score == 0 {
+			continue
+		}
+		matches = append(matches, searchCandidate{item: i, score: score})
+	}
+
+	sort.Slice(matches, func(a, b int) bool {
+		if matches[a].score != matches[b].score {
+			return matches[a].score > matches[b].score
+		}
+		return matches[a].item.ID < matches[b].item.ID
+	})
+
+	// the offset is capped before the limit is added to it, a huge offset would overflow the sum
+	from := min(q.Offset, len(matches))
+	page := matches[from : from+min(q.Limit, len(matches)-from)]
+	hits := make([]SearchHit, 0, len(page))
+	for _, m := range page {
+		hits = append(hits, SearchHit{
+			Item:  m.item,
+			Score: m.score,
+			Highlights: SearchHighlight{
+				Name:        highlight(m.item.Name, terms, true),
+				Description: snippet(m.item.Description, terms),
+			},
+		})
+	}
+
+	return SearchResult{
+		Hits:   hits,
+		Total:  len(matches),
+		Facets: facets(matches),
+	}, nil
+}
+
+// facets counts the matches per seller, most first, and per price bucket, empty buckets included.
+func facets(matches []searchCandidate) SearchFacets {
+	sellerCounts := make(map[string]int)
+	buckets := make([]PriceBucketFacet, len(priceBucketEdges))
+	for n, from := range priceBucketEdges {
+		buckets[n].From = from
+		if n+1 < len(priceBucketEdges) {
+			to := priceBucketEdges[n+1]
+			buckets[n].To = &to
+		}
+	}
+
+	for _, m := range matches {
+		sellerCounts[m.item.SellerID]++
+		for n := len(buckets) - 1; n >= 0; n-- {
+			if m.item.Price >= buckets[n].From || n == 0 {
+				buckets[n].Count++
+				break
+			}
+		}
+	}
+
+	sellers := make([]SellerFacet, 0, len(sellerCounts))
+	for id, count := range sellerCounts {
+		sellers = append(sellers, SellerFacet{SellerID: id, Count: count})
+	}
+	sort.Slice(sellers, func(a, b int) bool {
+		if sellers[a].Count != sellers[b].Count {
+			return sellers[a].Count > sellers[b].Count
+		}
+		return sellers[a].SellerID < sellers[b].SellerID
+	})
+
+	return SearchFacets{Sellers: sellers, PriceBuckets: buckets}
+}
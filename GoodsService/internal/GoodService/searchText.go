@@ -0,0 +1,310 @@
+package GoodService
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	minSearchTermLength = 2
+	maxSearchTerms      = 10
+	// prefixes longer than this match like the whole word, which keeps the number of keys per item down
+	maxSearchPrefixLength = 10
+	// typos are only forgiven in terms of at least this length, shorter ones match too much
+	minFuzzyTermLength   = 4
+	maxFuzzyPrefixLength = 8
+
+	prefixKey = "p:"
+	fuzzyKey  = "f:"
+
+	snippetLength  = 160
+	highlightOpen  = "<em>"
+	highlightClose = "</em>"
+)
+
+// Weights of a term matching a word of the name or the description, the best match of every term counts.
+const (
+	nameExactWeight         = 3
+	namePrefixWeight        = 2
+	nameFuzzyWeight         = 1
+	descriptionExactWeight  = 1.5
+	descriptionPrefixWeight = 1
+)
+
+type termMatch int
+
+const (
+	noMatch termMatch = iota
+	fuzzyMatch
+	prefixMatch
+	exactMatch
+)
+
+// searchTerms splits the query into lower case words, dropping one letter words and repeats.
+func searchTerms(query string) []string {
+	var terms []string
+	seen := make(map[string]struct{})
+	for _, word := range searchWords(query) {
+		if utf8.RuneCountInString(word) < minSearchTermLength {
+			continue
+		}
+		if _, ok := seen[word]; ok {
+			continue
+		}
+		seen[word] = struct{}{}
+		terms = append(terms, word)
+		if len(terms) == maxSearchTerms {
+			break
+		}
+	}
+	return terms
+}
+
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), isNotWordRune)
+}
+
+func isNotWordRune(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// searchKeys are stored with the item for the typo tolerant prefix matching the text index can't do.
+// Every word gets its prefixes, and the prefixes of the words of the name also get every variant with
+// one letter deleted, so a term and a word one typo apart share a key.
+func searchKeys(name, description string) []string {
+	keys := make(map[string]struct{})
+	for _, word := range searchWords(name) {
+		addPrefixKeys(keys, word)
+		for _, prefix := range prefixes(word, minFuzzyTermLength, maxFuzzyPrefixLength) {
+			for _, variant := range deletionVariants(prefix) {
+				keys[fuzzyKey+variant] = struct{}{}
+			}
+		}
+	}
+	for _, word := range searchWords(description) {
+		addPrefixKeys(keys, word)
+	}
+
+	result := make([]string, 0, len(keys))
+	for key := range keys {
+		result = append(result, key)
+	}
+	return result
+}
+
+func addPrefixKeys(keys map[string]struct{}, word string) {
+	for _, prefix := range prefixes(word, minSearchTermLength, maxSearchPrefixLength) {
+		keys[prefixKey+prefix] = struct{}{}
+	}
+}
+
+// queryKeys are the search keys an item has to share with the query to be a candidate.
+func queryKeys(terms []string) []string {
+	var keys []string
+	for _, term := range terms {
+		runes := []rune(term)
+		keys = append(keys, prefixKey+string(runes[:min(len(runes), maxSearchPrefixLength)]))
+
+		if len(runes) < minFuzzyTermLength {
+			continue
+		}
+		for _, variant := range deletionVariants(string(runes[:min(len(runes), maxFuzzyPrefixLength)])) {
+			keys = append(keys, fuzzyKey+variant)
+		}
+	}
+	return keys
+}
+
+func prefixes(word string, minLength, maxLength int) []string {
+	runes := []rune(word)
+	var result []string
+	for n := minLength; n <= min(len(runes), maxLength); n++ {
+		result = append(result, string(runes[:n]))
+	}
+	return result
+}
+
+// deletionVariants returns the word itself and the word with each of its letters deleted.
+func deletionVariants(word string) []string {
+	runes := []rune(word)
+	variants := []string{word}
+	for i := range runes {
+		variants = append(variants, string(runes[:i])+string(runes[i+1:]))
+	}
+	return variants
+}
+
+// matchWord tells how the term matches the word. A fuzzy match is a term at most one typo away from
+// the start of the word, which the search keys only point at, so it is checked here.
+func matchWord(term, word string, fuzzy bool) termMatch {
+	switch {
+	case term == word:
+		return exactMatch
+	case strings.HasPrefix(word, term):
+		return prefixMatch
+	case fuzzy && isFuzzyPrefix([]rune(term), []rune(word)):
+		return fuzzyMatch
+	}
+	return noMatch
+}
+
+// isFuzzyPrefix reports whether a prefix of word is one insertion, deletion, substitution or
+// transposition of adjacent letters away from term.
+func isFuzzyPrefix(term, word []rune) bool {
+	if len(term) < minFuzzyTermLength {
+		return false
+	}
+	for n := len(term) - 1; n <= len(term)+1; n++ {
+		if n <= len(word) && editDistance(term, word[:n]) <= 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// editDistance is the optimal string alignment distance between a and b.
+func editDistance(a, b []rune) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+// termScore adds up the best match of every term in the name and in the description.
+func termScore(terms []string, item Item) float64 {
+	nameWords := searchWords(item.Name)
+	descriptionWords := searchWords(item.Description)
+
+	var score float64
+	for _, term := range terms {
+		switch bestMatch(term, nameWords, true) {
+		case exactMatch:
+			score += nameExactWeight
+		case prefixMatch:
+			score += namePrefixWeight
+		case fuzzyMatch:
+			score += nameFuzzyWeight
+		}
+
+		switch bestMatch(term, descriptionWords, false) {
+		case exactMatch:
+			score += descriptionExactWeight
+		case prefixMatch:
+			score += descriptionPrefixWeight
+		}
+	}
+	return score
+}
+
+func bestMatch(term string, words []string, fuzzy bool) termMatch {
+	best := noMatch
+	for _, word := range words {
+		best = max(best, matchWord(term, word, fuzzy))
+		if best == exactMatch {
+			break
+		}
+	}
+	return best
+}
+
+// highlight escapes text for HTML and wraps every word matching a term in <em>.
+func highlight(text string, terms []string, fuzzy bool) string {
+	var b strings.Builder
+	forEachWord(text, func(segment string, isWord bool) {
+		if isWord && matchesAny(strings.ToLower(segment), terms, fuzzy) {
+			b.WriteString(highlightOpen + html.EscapeString(segment) + highlightClose)
+			return
+		}
+		b.WriteString(html.EscapeString(segment))
+	})
+	return b.String()
+}
+
+// snippet highlights the part of the description around the first matching word, cut at word
+// boundaries with an ellipsis where the description goes on.
+func snippet(description string, terms []string) string {
+	runes := []rune(description)
+	if len(runes) <= snippetLength {
+		return highlight(description, terms, false)
+	}
+
+	first := 0
+	position := 0
+	found := false
+	forEachWord(description, func(segment string, isWord bool) {
+		if !found && isWord && matchesAny(strings.ToLower(segment), terms, false) {
+			first = position
+			found = true
+		}
+		position += utf8.RuneCountInString(segment)
+	})
+
+	start := max(0, first-snippetLength/4)
+	end := min(len(runes), start+snippetLength)
+	start = max(0, end-snippetLength)
+	for start > 0 && !isNotWordRune(runes[start-1]) && !isNotWordRune(runes[start]) {
+		start++
+	}
+	for end < len(runes) && end > start && !isNotWordRune(runes[end-1]) && !isNotWordRune(runes[end]) {
+		end--
+	}
+	if end == start {
+		// a single word longer than the snippet
+		end = min(len(runes), start+snippetLength)
+	}
+
+	result := highlight(strings.TrimSpace(string(runes[start:end])), terms, false)
+	if start > 0 {
+		result = "…" + result
+	}
+	if end < len(runes) {
+		result += "…"
+	}
+	return result
+}
+
+func matchesAny(word string, terms []string, fuzzy bool) bool {
+	for _, term := range terms {
+		if matchWord(term, word, fuzzy) != noMatch {
+			return true
+		}
+	}
+	return false
+}
+
+// forEachWord calls fn with the words of text and the runs of characters between them, in order.
+func forEachWord(text string, fn func(segment string, isWord bool)) {
+	start := 0
+	inWord := false
+	for i, r := range text {
+		isWord := !isNotWordRune(r)
+		if i > 0 && isWord != inWord {
+			fn(text[start:i], inWord)
+			start = i
+		}
+		inWord = isWord
+	}
+	if start < len(text) {
+		fn(text[start:], inWord)
+	}
+}
@@ -2,30 +2,70 @@ package GoodService
 
 import (
 	"errors"
+	"strings"
+	"time"
 )
 
 //go:generate mockgen -source=service.go -destination=../mocks/mockServ.go -package=mocks
 
 type GoodService interface {
-	GetGoods() ([]Item, error)
+	GetGoods(GoodsQuery) (GoodsPage, error)
 
 	AddItem(Item, UserCtx) (string, error)
 	DeleteItem(string, int) error
 	UpdateItem(Item, int) (Item, error)
 
-	GetItemInfoForCart(string) (ItemInfoForCart, error)
-}
+	GetItemInfoForCart(string, string) (ItemInfoForCart, error)
 
-type goodService struct {
-	repo GoodsMongoRepo
+	ExportUserData(int) (Seller, []Item, error)
+	DeleteUserData(int) (int64, error)
+
+	Search(SearchQuery) (SearchResult, error)
+
+	GetCategories() ([]CategoryNode, error)
+	CreateCategory(CategoryInput) (Category, error)
+	UpdateCategory(string, CategoryInput) (Category, error)
+	DeleteCategory(string) error
+	GetCategoryItems(string, CatalogQuery) (CategoryPage, error)
+
+	AddItemImage(string, int, []byte) (ItemImage, error)
+	DeleteItemImage(string, string, int) error
+	ReorderItemImages(string, int, []string) ([]ItemImage, error)
+	GetImage(string) (Blob, error)
+
+	AddVariant(string, int, Variant) (Item, error)
+	UpdateVariant(string, int, Variant) (Item, error)
+	DeleteVariant(string, int, string) (Item, error)
+
+	ReserveStock(string, []ReservationLine, time.Duration) (Reservation, error)
+	CommitReservation(string) (Reservation, error)
+	ReleaseReservation(string) (Reservation, error)
+	ExpireReservations() (int, error)
 }
 
-func NewGoodService(repo GoodsMongoRepo) GoodService {
-	return &goodService{repo: repo}
+var (
+	ErrNotItemOwner           = errors.New("it's not your item")
+	ErrEmptySearchQuery       = errors.New("search query is empty")
+	ErrInvalidCategoryName    = errors.New("invalid category name")
+	ErrInvalidCategorySlug    = errors.New("invalid category slug")
+	ErrParentCategoryNotFound = errors.New("parent category not found")
+	ErrCategoryCycle          = errors.New("category can't be moved under itself")
+	ErrCategoryHasChildren    = errors.New("category has subcategories")
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+type goodService struct {
+	repo     GoodsMongoRepo
+	searcher Searcher
+	blobs    BlobStore
 }
 
-func (s *goodService) GetGoods() ([]Item, error) {
-	return s.repo.GetGoods()
+func NewGoodService(repo GoodsMongoRepo, searcher Searcher, blobs BlobStore) GoodService {
+	return &goodService{repo: repo, searcher: searcher, blobs: blobs}
 }
 
 func (s *goodService) AddItem(i Item, seller UserCtx) (string, error) {
@@ -43,16 +83,39 @@ func (s *goodService) AddItem(i Item, seller UserCtx) (string, error) {
 		}
 	}
 	i.SellerID = sellerID
+	// images are only added through AddItemImage, so their blobs exist
+	i.Images = nil
+	if len(i.Variants) > 0 {
+		if i.Variants, err = checkVariants(i.Variants); err != nil {
+			return "", err
+		}
+		i.Quantity = variantStock(i.Variants)
+		i.VariantsVersion = 1
+	}
+	if i.CategoryIDs, err = s.checkCategories(i.CategoryIDs); err != nil {
+		return "", err
+	}
 	return s.repo.CreateItem(i)
 }
 
+// DeleteItem removes the item of the user and then the blobs of its images.
 func (s *goodService) DeleteItem(itemID string, userID int) error {
 	sellerID, err := s.repo.GetSellerIDByUserID(userID)
 
 	if err != nil {
 		return err
 	}
-	return s.repo.DeleteItem(itemID, sellerID)
+
+	item, err := s.repo.GetItemByID(itemID)
+	if err != nil {
+		return err
+	}
+
+	if err = s.repo.DeleteItem(itemID, sellerID); err != nil {
+		return err
+	}
+	s.deleteImageBlobs(item.Images)
+	return nil
 }
 
 func (s *goodService) UpdateItem(i Item, userID int) (Item, error) {
@@ -67,12 +130,110 @@ func (s *goodService) UpdateItem(i Item, userID int) (Item, error) {
 	}
 
 	if oldItem.SellerID != sellerID {
-		return Item{}, errors.New("it's not your item")
+		return Item{}, ErrNotItemOwner
 	}
 	i.SellerID = sellerID
-	return s.repo.UpdateItem(i)
+	if i.CategoryIDs, err = s.checkCategories(i.CategoryIDs); err != nil {
+		return Item{}, err
+	}
+
+	newItem, err := s.repo.UpdateItem(i)
+	if err != nil {
+		return Item{}, err
+	}
+
+	items := []Item{newItem}
+	if err = s.addBreadcrumbs(items); err != nil {
+		return Item{}, err
+	}
+	return items[0], nil
 }
 
-func (s *goodService) GetItemInfoForCart(id string) (ItemInfoForCart, error) {
-	return s.repo.GetItemInfoForCart(id)
+// GetItemInfoForCart returns the stock and the price of the variant with the SKU, or of the item
+// itself when it has no variants.
+func (s *goodService) GetItemInfoForCart(id, sku string) (ItemInfoForCart, error) {
+	info, err := s.repo.GetItemInfoForCart(id)
+	if err != nil {
+		return ItemInfoForCart{}, err
+	}
+	return resolveVariant(info, sku)
+}
+
+// ExportUserData returns the seller record of the user with its items, an empty seller if the user sells nothing.
+func (s *goodService) ExportUserData(userID int) (Seller, []Item, error) {
+	seller, err := s.repo.GetSellerByUserID(userID)
+	if err != nil {
+		if errors.Is(err, ErrSellerNotFound) {
+			return Seller{}, nil, nil
+		}
+		return Seller{}, nil, err
+	}
+
+	items, err := s.repo.GetItemsBySellerID(seller.ID)
+	if err != nil {
+		return Seller{}, nil, err
+	}
+
+	return seller, items, nil
+}
+
+// DeleteUserData removes the seller record of the user and its items with their images,
+// deleting a user without one is a no-op.
+func (s *goodService) DeleteUserData(userID int) (int64, error) {
+	seller, err := s.repo.GetSellerByUserID(userID)
+	if err != nil {
+		if errors.Is(err, ErrSellerNotFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	items, err := s.repo.GetItemsBySellerID(seller.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := s.repo.DeleteSeller(seller.ID)
+	if err != nil {
+		return deleted, err
+	}
+	for _, i := range items {
+		s.deleteImageBlobs(i.Images)
+	}
+	return deleted, nil
+}
+
+// Search returns a page of the items matching the query.
+func (s *goodService) Search(q SearchQuery) (SearchResult, error) {
+	q.Query = strings.TrimSpace(q.Query)
+	if q.Query == "" {
+		return SearchResult{}, ErrEmptySearchQuery
+	}
+	q.Limit, q.Offset = pageBounds(q.Limit, q.Offset)
+
+	result, err := s.searcher.Search(q)
+	if err != nil {
+		return SearchResult{}, err
+	}
+
+	items := make([]Item, len(result.Hits))
+	for n, hit := range result.Hits {
+		items[n] = hit.Item
+	}
+	if err = s.addBreadcrumbs(items); err != nil {
+		return SearchResult{}, err
+	}
+	for n := range result.Hits {
+		result.Hits[n].Item = items[n]
+	}
+
+	return result, nil
+}
+
+// pageBounds defaults the limit to 20, caps it at 100 and clamps a negative offset to 0.
+func pageBounds(limit, offset int) (int, int) {
+	if limit <= 0 {
+		limit = defaultPageLimit
+	}
+	return min(limit, maxPageLimit), max(offset, 0)
 }
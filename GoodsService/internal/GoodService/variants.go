@@ -0,0 +1,203 @@
+package GoodService
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const (
+	maxVariantsPerItem = 100
+	maxVariantAttrs    = 5
+)
+
+var (
+	ErrSKURequired       = errors.New("item has variants, a sku is required")
+	ErrVariantNotFound   = errors.New("variant not found")
+	ErrSKUTaken          = errors.New("sku is taken")
+	ErrInvalidVariant    = errors.New("invalid variant")
+	ErrVariantAttributes = errors.New("variants of an item must have the same attributes")
+	ErrDuplicateVariant  = errors.New("item already has a variant with these attributes")
+	ErrItemVariantLimit  = errors.New("item has too many variants")
+	ErrVariantsChanged   = errors.New("variants of the item were changed meanwhile, try again")
+)
+
+var skuPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// normalizeVariant trims the attributes, lower casing their names so "Size" and "size" are one attribute.
+func normalizeVariant(v Variant) (Variant, error) {
+	v.SKU = strings.TrimSpace(v.SKU)
+	if !skuPattern.MatchString(v.SKU) || v.Quantity < 0 || (v.Price != nil && *v.Price < 0) {
+		return Variant{}, ErrInvalidVariant
+	}
+	if len(v.Attributes) == 0 || len(v.Attributes) > maxVariantAttrs {
+		return Variant{}, ErrInvalidVariant
+	}
+
+	attrs := make(map[string]string, len(v.Attributes))
+	for name, value := range v.Attributes {
+		name, value = strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(value)
+		if name == "" || value == "" {
+			return Variant{}, ErrInvalidVariant
+		}
+		if _, ok := attrs[name]; ok {
+			return Variant{}, ErrInvalidVariant
+		}
+		attrs[name] = value
+	}
+	v.Attributes = attrs
+	return v, nil
+}
+
+// checkVariants normalizes the variants and makes sure they all have the same attribute names,
+// differ in their values and don't repeat a SKU.
+func checkVariants(variants []Variant) ([]Variant, error) {
+	if len(variants) > maxVariantsPerItem {
+		return nil, ErrItemVariantLimit
+	}
+
+	checked := make([]Variant, 0, len(variants))
+	skus := make(map[string]struct{}, len(variants))
+	combinations := make(map[string]struct{}, len(variants))
+	var names []string
+	for _, v := range variants {
+		v, err := normalizeVariant(v)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := skus[strings.ToLower(v.SKU)]; ok {
+			return nil, ErrSKUTaken
+		}
+		skus[strings.ToLower(v.SKU)] = struct{}{}
+
+		variantNames := attributeNames(v)
+		if names == nil {
+			names = variantNames
+		} else if !slices.Equal(names, variantNames) {
+			return nil, ErrVariantAttributes
+		}
+
+		combination := attributeCombination(v, names)
+		if _, ok := combinations[combination]; ok {
+			return nil, ErrDuplicateVariant
+		}
+		combinations[combination] = struct{}{}
+
+		checked = append(checked, v)
+	}
+	return checked, nil
+}
+
+func attributeNames(v Variant) []string {
+	names := make([]string, 0, len(v.Attributes))
+	for name := range v.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func attributeCombination(v Variant, names []string) string {
+	values := make([]string, len(names))
+	for n, name := range names {
+		values[n] = strings.ToLower(v.Attributes[name])
+	}
+	return strings.Join(values, "\x00")
+}
+
+func variantStock(variants []Variant) int {
+	total := 0
+	for _, v := range variants {
+		total += v.Quantity
+	}
+	return total
+}
+
+// AddVariant adds a variant to the item of the user.
+func (s *goodService) AddVariant(itemID string, userID int, v Variant) (Item, error) {
+	item, err := s.ownItem(itemID, userID)
+	if err != nil {
+		return Item{}, err
+	}
+
+	return s.saveVariants(item, append(slices.Clone(item.Variants), v))
+}
+
+// UpdateVariant replaces the attributes, stock and price of the variant with the SKU of v.
+func (s *goodService) UpdateVariant(itemID string, userID int, v Variant) (Item, error) {
+	item, err := s.ownItem(itemID, userID)
+	if err != nil {
+		return Item{}, err
+	}
+
+	i := slices.IndexFunc(item.Variants, func(old Variant) bool { return old.SKU == v.SKU })
+	if i < 0 {
+		return Item{}, ErrVariantNotFound
+	}
+
+	variants := slices.Clone(item.Variants)
+	variants[i] = v
+	return s.saveVariants(item, variants)
+}
+
+func (s *goodService) DeleteVariant(itemID string, userID int, sku string) (Item, error) {
+	item, err := s.ownItem(itemID, userID)
+	if err != nil {
+		return Item{}, err
+	}
+
+	i := slices.IndexFunc(item.Variants, func(v Variant) bool { return v.SKU == sku })
+	if i < 0 {
+		return Item{}, ErrVariantNotFound
+	}
+
+	return s.saveVariants(item, slices.Delete(slices.Clone(item.Variants), i, i+1))
+}
+
+// saveVariants stores the variants of the item with their total stock as the quantity of the item.
+// The write only applies if the variants weren't changed since the item was read.
+func (s *goodService) saveVariants(item Item, variants []Variant) (Item, error) {
+	variants, err := checkVariants(variants)
+	if err != nil {
+		return Item{}, err
+	}
+
+	quantity := item.Quantity
+	if len(variants) > 0 || len(item.Variants) > 0 {
+		quantity = variantStock(variants)
+	}
+
+	if err = s.repo.SetItemVariants(item.ID, item.VariantsVersion, variants, quantity); err != nil {
+		return Item{}, err
+	}
+
+	item.Variants = variants
+	item.VariantsVersion++
+	item.Quantity = quantity
+	return item, nil
+}
+
+// resolveVariant picks the stock and the price of the SKU from the item info.
+func resolveVariant(info ItemInfoForCart, sku string) (ItemInfoForCart, error) {
+	if sku == "" {
+		if len(info.Variants) > 0 {
+			return ItemInfoForCart{}, ErrSKURequired
+		}
+		return ItemInfoForCart{Quantity: info.Quantity, Price: info.Price}, nil
+	}
+
+	for _, v := range info.Variants {
+		if v.SKU != sku {
+			continue
+		}
+		price := info.Price
+		if v.Price != nil {
+			price = *v.Price
+		}
+		return ItemInfoForCart{Quantity: v.Quantity, Price: price}, nil
+	}
+	return ItemInfoForCart{}, ErrVariantNotFound
+}
@@ -0,0 +1,106 @@
+package GoodService_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/jst-Frenzy/ControlSystem/GoodsService/internal/GoodService"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func testBlobStore(t *testing.T, store GoodService.BlobStore) {
+	assert.NoError(t, store.Put("items/1/a.png", []byte("png data"), "image/png"))
+
+	blob, err := store.Get("items/1/a.png")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("png data"), blob.Data)
+	assert.Equal(t, "image/png", blob.ContentType)
+
+	assert.NoError(t, store.Delete("items/1/a.png"))
+	_, err = store.Get("items/1/a.png")
+	assert.ErrorIs(t, err, GoodService.ErrBlobNotFound)
+
+	assert.NoError(t, store.Delete("items/1/a.png"))
+
+	assert.ErrorIs(t, store.Put("../escape.png", []byte("x"), "image/png"), GoodService.ErrInvalidBlobKey)
+	assert.ErrorIs(t, store.Put("/abs.png", []byte("x"), "image/png"), GoodService.ErrInvalidBlobKey)
+	_, err = store.Get("items/../../etc/passwd")
+	assert.ErrorIs(t, err, GoodService.ErrBlobNotFound)
+}
+
+func TestLocalBlobStore(t *testing.T) {
+	store, err := GoodService.NewLocalBlobStore(t.TempDir())
+	assert.NoError(t, err)
+
+	testBlobStore(t, store)
+}
+
+// fakeS3 stands in for an S3 compatible service, keeping the objects of one bucket in memory.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]GoodService.Blob
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=access/") ||
+		!strings.Contains(auth, "/eu-central-1/s3/aws4_request, SignedHeaders=") ||
+		!strings.Contains(auth, "host;") || r.Header.Get("X-Amz-Date") == "" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	hash := sha256.Sum256(body)
+	if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(hash[:]) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	key, ok := strings.CutPrefix(r.URL.Path, "/media/")
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		s.objects[key] = GoodService.Blob{Data: body, ContentType: r.Header.Get("Content-Type")}
+	case http.MethodGet:
+		obj, found := s.objects[key]
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", obj.ContentType)
+		w.Write(obj.Data)
+	case http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestS3BlobStore(t *testing.T) {
+	server := httptest.NewServer(&fakeS3{objects: make(map[string]GoodService.Blob)})
+	defer server.Close()
+
+	store := GoodService.NewS3BlobStore(GoodService.S3Config{
+		Endpoint:  server.URL + "/",
+		Region:    "eu-central-1",
+		Bucket:    "media",
+		AccessKey: "access",
+		SecretKey: "secret",
+	})
+
+	testBlobStore(t, store)
+
+	denied := GoodService.NewS3BlobStore(GoodService.S3Config{Endpoint: server.URL, Region: "us-east-1", Bucket: "media"})
+	assert.Error(t, denied.Put("items/1/a.png", []byte("png data"), "image/png"))
+}
@@ -0,0 +1,283 @@
+package GoodService
+
+import (
+	"errors"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	GoodService "github.com/jst-Frenzy/ControlSystem/GoodsService/internal/GoodService"
+	mock "github.com/jst-Frenzy/ControlSystem/GoodsService/internal/mocks"
+	"testing"
+)
+
+var testCategories = []GoodService.Category{
+	{ID: "shoes", Name: "Shoes", Slug: "shoes", ParentID: "clothes"},
+	{ID: "clothes", Name: "Clothes", Slug: "clothes"},
+	{ID: "boots", Name: "Boots", Slug: "boots", ParentID: "shoes"},
+	{ID: "coats", Name: "Coats", Slug: "coats", ParentID: "clothes"},
+	{ID: "food", Name: "Food", Slug: "food"},
+}
+
+func TestService_getCategories(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mongoRep := mock.NewMockGoodsMongoRepo(c)
+	mongoRep.EXPECT().GetCategories().Return(testCategories, nil)
+
+	serv := GoodService.NewGoodService(mongoRep, nil, nil)
+
+	tree, err := serv.GetCategories()
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, tree, []GoodService.CategoryNode{
+		{
+			Category: testCategories[1],
+			Children: []GoodService.CategoryNode{
+				{Category: testCategories[3], Children: []GoodService.CategoryNode{}},
+				{Category: testCategories[0], Children: []GoodService.CategoryNode{
+					{Category: testCategories[2], Children: []GoodService.CategoryNode{}},
+				}},
+			},
+		},
+		{Category: testCategories[4], Children: []GoodService.CategoryNode{}},
+	})
+}
+
+func TestService_createCategory(t *testing.T) {
+	type mockBehavior func(r *mock.MockGoodsMongoRepo)
+
+	testTable := []struct {
+		name             string
+		inputCategory    GoodService.CategoryInput
+		mockBehavior     mockBehavior
+		expectedCategory GoodService.Category
+		expectedError    error
+	}{
+		{
+			name:          "OK",
+			inputCategory: GoodService.CategoryInput{Name: " Winter Boots ", ParentID: "shoes"},
+			mockBehavior: func(r *mock.MockGoodsMongoRepo) {
+				r.EXPECT().GetCategories().Return(testCategories, nil)
+				r.EXPECT().CreateCategory(GoodService.Category{Name: "Winter Boots", Slug: "winter-boots", ParentID: "shoes"}).
+					Return("winter", nil)
+			},
+			expectedCategory: GoodService.Category{ID: "winter", Name: "Winter Boots", Slug: "winter-boots", ParentID: "shoes"},
+		},
+		{
+			name:          "Root With Slug",
+			inputCategory: GoodService.CategoryInput{Name: "Toys", Slug: "Kids Toys!"},
+			mockBehavior: func(r *mock.MockGoodsMongoRepo) {
+				r.EXPECT().CreateCategory(GoodService.Category{Name: "Toys", Slug: "kids-toys"}).Return("toys", nil)
+			},
+			expectedCategory: GoodService.Category{ID: "toys", Name: "Toys", Slug: "kids-toys"},
+		},
+		{
+			name:          "Parent Not Found",
+			inputCategory: GoodService.CategoryInput{Name: "Sandals", ParentID: "summer"},
+			mockBehavior: func(r *mock.MockGoodsMongoRepo) {
+				r.EXPECT().GetCategories().Return(testCategories, nil)
+			},
+			expectedError: GoodService.ErrParentCategoryNotFound,
+		},
+		{
+			name:          "Invalid Slug",
+			inputCategory: GoodService.CategoryInput{Name: "Toys", Slug: "!!"},
+			mockBehavior:  func(r *mock.MockGoodsMongoRepo) {},
+			expectedError: GoodService.ErrInvalidCategorySlug,
+		},
+		{
+			name:          "Slug Taken",
+			inputCategory: GoodService.CategoryInput{Name: "Food"},
+			mockBehavior: func(r *mock.MockGoodsMongoRepo) {
+				r.EXPECT().CreateCategory(GoodService.Category{Name: "Food", Slug: "food"}).Return("", GoodService.ErrCategorySlugTaken)
+			},
+			expectedError: GoodService.ErrCategorySlugTaken,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mongoRep := mock.NewMockGoodsMongoRepo(c)
+			testCase.mockBehavior(mongoRep)
+
+			serv := GoodService.NewGoodService(mongoRep, nil, nil)
+
+			category, err := serv.CreateCategory(testCase.inputCategory)
+
+			assert.Equal(t, err, testCase.expectedError)
+			assert.Equal(t, category, testCase.expectedCategory)
+		})
+	}
+}
+
+func TestService_updateCategory(t *testing.T) {
+	type mockBehavior func(r *mock.MockGoodsMongoRepo)
+
+	testTable := []struct {
+		name          string
+		inputID       string
+		inputCategory GoodService.CategoryInput
+		mockBehavior  mockBehavior
+		expectedError error
+	}{
+		{
+			name:          "Move Subtree",
+			inputID:       "shoes",
+			inputCategory: GoodService.CategoryInput{Name: "Shoes", ParentID: "food"},
+			mockBehavior: func(r *mock.MockGoodsMongoRepo) {
+				r.EXPECT().GetCategories().Return(testCategories, nil)
+				r.EXPECT().UpdateCategory(GoodService.Category{ID: "shoes", Name: "Shoes", Slug: "shoes", ParentID: "food"}).Return(nil)
+			},
+		},
+		{
+			name:          "Under Own Descendant",
+			inputID:       "clothes",
+			inputCategory: GoodService.CategoryInput{Name: "Clothes", ParentID: "boots"},
+			mockBehavior: func(r *mock.MockGoodsMongoRepo) {
+				r.EXPECT().GetCategories().Return(testCategories, nil)
+			},
+			expectedError: GoodService.ErrCategoryCycle,
+		},
+		{
+			name:          "Under Itself",
+			inputID:       "coats",
+			inputCategory: GoodService.CategoryInput{Name: "Coats", ParentID: "coats"},
+			mockBehavior: func(r *mock.MockGoodsMongoRepo) {
+				r.EXPECT().GetCategories().Return(testCategories, nil)
+			},
+			expectedError: GoodService.ErrCategoryCycle,
+		},
+		{
+			name:          "Not Found",
+			inputID:       "toys",
+			inputCategory: GoodService.CategoryInput{Name: "Toys"},
+			mockBehavior: func(r *mock.MockGoodsMongoRepo) {
+				r.EXPECT().GetCategories().Return(testCategories, nil)
+			},
+			expectedError: GoodService.ErrCategoryNotFound,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mongoRep := mock.NewMockGoodsMongoRepo(c)
+			testCase.mockBehavior(mongoRep)
+
+			serv := GoodService.NewGoodService(mongoRep, nil, nil)
+
+			_, err := serv.UpdateCategory(testCase.inputID, testCase.inputCategory)
+
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
+
+func TestService_deleteCategory(t *testing.T) {
+	type mockBehavior func(r *mock.MockGoodsMongoRepo)
+
+	testTable := []struct {
+		name          string
+		inputID       string
+		mockBehavior  mockBehavior
+		expectedError error
+	}{
+		{
+			name:    "OK",
+			inputID: "boots",
+			mockBehavior: func(r *mock.MockGoodsMongoRepo) {
+				r.EXPECT().GetCategories().Return(testCategories, nil)
+				gomock.InOrder(
+					r.EXPECT().RemoveCategoryFromItems("boots").Return(int64(3), nil),
+					r.EXPECT().DeleteCategory("boots").Return(nil),
+				)
+			},
+		},
+		{
+			name:    "Has Children",
+			inputID: "shoes",
+			mockBehavior: func(r *mock.MockGoodsMongoRepo) {
+				r.EXPECT().GetCategories().Return(testCategories, nil)
+			},
+			expectedError: GoodService.ErrCategoryHasChildren,
+		},
+		{
+			name:    "Unassign Failure",
+			inputID: "food",
+			mockBehavior: func(r *mock.MockGoodsMongoRepo) {
+				r.EXPECT().GetCategories().Return(testCategories, nil)
+				r.EXPECT().RemoveCategoryFromItems("food").Return(int64(0), errors.New("DB Failure"))
+			},
+			expectedError: errors.New("DB Failure"),
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mongoRep := mock.NewMockGoodsMongoRepo(c)
+			testCase.mockBehavior(mongoRep)
+
+			serv := GoodService.NewGoodService(mongoRep, nil, nil)
+
+			err := serv.DeleteCategory(testCase.inputID)
+
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
+
+func TestService_getCategoryItems(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mongoRep := mock.NewMockGoodsMongoRepo(c)
+	mongoRep.EXPECT().GetCategories().Return(testCategories, nil)
+	mongoRep.EXPECT().GetItemsByCategoryIDs([]string{"shoes", "boots"}, 20, 0).Return([]GoodService.Item{
+		{ID: "1", Name: "Hiking boots", CategoryIDs: []string{"boots", "gone"}},
+	}, nil)
+	mongoRep.EXPECT().CountItemsByCategoryIDs([]string{"shoes", "boots"}).Return(int64(1), nil)
+
+	serv := GoodService.NewGoodService(mongoRep, nil, nil)
+
+	page, err := serv.GetCategoryItems("shoes", GoodService.CatalogQuery{Offset: -1})
+
+	clothes := GoodService.CategoryRef{ID: "clothes", Name: "Clothes", Slug: "clothes"}
+	shoes := GoodService.CategoryRef{ID: "shoes", Name: "Shoes", Slug: "shoes"}
+	boots := GoodService.CategoryRef{ID: "boots", Name: "Boots", Slug: "boots"}
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, page, GoodService.CategoryPage{
+		Category:   testCategories[0],
+		Breadcrumb: []GoodService.CategoryRef{clothes, shoes},
+		Items: []GoodService.Item{{
+			ID:          "1",
+			Name:        "Hiking boots",
+			CategoryIDs: []string{"boots", "gone"},
+			Breadcrumbs: [][]GoodService.CategoryRef{{clothes, shoes, boots}},
+		}},
+		Total: 1,
+	})
+}
+
+func TestService_addItemUnknownCategory(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mongoRep := mock.NewMockGoodsMongoRepo(c)
+	mongoRep.EXPECT().GetSellerIDByUserID(1).Return("100", nil)
+	mongoRep.EXPECT().GetCategories().Return(testCategories, nil)
+
+	serv := GoodService.NewGoodService(mongoRep, nil, nil)
+
+	_, err := serv.AddItem(GoodService.Item{Name: "apple", CategoryIDs: []string{"food", "fruit"}}, GoodService.UserCtx{ID: 1})
+
+	assert.Equal(t, err, GoodService.ErrCategoryNotFound)
+}
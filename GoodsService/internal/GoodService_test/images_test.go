@@ -0,0 +1,229 @@
+package GoodService
+
+import (
+	"bytes"
+	"errors"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	GoodService "github.com/jst-Frenzy/ControlSystem/GoodsService/internal/GoodService"
+	mock "github.com/jst-Frenzy/ControlSystem/GoodsService/internal/mocks"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testPNG(t *testing.T, width, height int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 200, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestService_addItemImage(t *testing.T) {
+	type mockBehavior func(r *mock.MockGoodsMongoRepo, b *mock.MockBlobStore)
+
+	pngData := testPNG(t, 800, 200)
+	item := GoodService.Item{ID: "507f1f77bcf86cd799439011", SellerID: "100"}
+
+	testTable := []struct {
+		name          string
+		inputData     []byte
+		mockBehavior  mockBehavior
+		expectedError error
+	}{
+		{
+			name:      "OK",
+			inputData: pngData,
+			mockBehavior: func(r *mock.MockGoodsMongoRepo, b *mock.MockBlobStore) {
+				r.EXPECT().GetSellerIDByUserID(1).Return("100", nil)
+				r.EXPECT().GetItemByID(item.ID).Return(item, nil)
+				b.EXPECT().Put(gomock.Any(), pngData, "image/png").Return(nil)
+				b.EXPECT().Put(gomock.Any(), gomock.Any(), "image/jpeg").
+					DoAndReturn(func(key string, data []byte, contentType string) error {
+						thumb, err := jpeg.Decode(bytes.NewReader(data))
+						assert.Equal(t, err, nil)
+						assert.Equal(t, thumb.Bounds().Dx(), 400)
+						assert.Equal(t, thumb.Bounds().Dy(), 100)
+						return nil
+					})
+				r.EXPECT().AddItemImage(item.ID, "100", gomock.Any(), 10).Return(nil)
+			},
+		},
+		{
+			name:      "Not An Image",
+			inputData: []byte("<html><body>hello</body></html>"),
+			mockBehavior: func(r *mock.MockGoodsMongoRepo, b *mock.MockBlobStore) {
+				r.EXPECT().GetSellerIDByUserID(1).Return("100", nil)
+				r.EXPECT().GetItemByID(item.ID).Return(item, nil)
+			},
+			expectedError: GoodService.ErrUnsupportedImageType,
+		},
+		{
+			name:      "Broken Image",
+			inputData: pngData[:100],
+			mockBehavior: func(r *mock.MockGoodsMongoRepo, b *mock.MockBlobStore) {
+				r.EXPECT().GetSellerIDByUserID(1).Return("100", nil)
+				r.EXPECT().GetItemByID(item.ID).Return(item, nil)
+			},
+			expectedError: GoodService.ErrInvalidImage,
+		},
+		{
+			name:      "Too Large",
+			inputData: make([]byte, GoodService.MaxImageSize+1),
+			mockBehavior: func(r *mock.MockGoodsMongoRepo, b *mock.MockBlobStore) {
+				r.EXPECT().GetSellerIDByUserID(1).Return("100", nil)
+				r.EXPECT().GetItemByID(item.ID).Return(item, nil)
+			},
+			expectedError: GoodService.ErrImageTooLarge,
+		},
+		{
+			name:      "Not Owner",
+			inputData: pngData,
+			mockBehavior: func(r *mock.MockGoodsMongoRepo, b *mock.MockBlobStore) {
+				r.EXPECT().GetSellerIDByUserID(1).Return("200", nil)
+				r.EXPECT().GetItemByID(item.ID).Return(item, nil)
+			},
+			expectedError: GoodService.ErrNotItemOwner,
+		},
+		{
+			name:      "Limit Reached",
+			inputData: pngData,
+			mockBehavior: func(r *mock.MockGoodsMongoRepo, b *mock.MockBlobStore) {
+				full := item
+				full.Images = make([]GoodService.ItemImage, 10)
+				r.EXPECT().GetSellerIDByUserID(1).Return("100", nil)
+				r.EXPECT().GetItemByID(item.ID).Return(full, nil)
+			},
+			expectedError: GoodService.ErrItemImageLimit,
+		},
+		{
+			name:      "Blobs Removed When Item Refuses",
+			inputData: pngData,
+			mockBehavior: func(r *mock.MockGoodsMongoRepo, b *mock.MockBlobStore) {
+				r.EXPECT().GetSellerIDByUserID(1).Return("100", nil)
+				r.EXPECT().GetItemByID(item.ID).Return(item, nil)
+				b.EXPECT().Put(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(2)
+				r.EXPECT().AddItemImage(item.ID, "100", gomock.Any(), 10).Return(GoodService.ErrItemImageLimit)
+				b.EXPECT().Delete(gomock.Any()).Return(nil).Times(2)
+			},
+			expectedError: GoodService.ErrItemImageLimit,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mongoRep := mock.NewMockGoodsMongoRepo(c)
+			blobs := mock.NewMockBlobStore(c)
+			testCase.mockBehavior(mongoRep, blobs)
+
+			serv := GoodService.NewGoodService(mongoRep, nil, blobs)
+
+			img, err := serv.AddItemImage(item.ID, 1, testCase.inputData)
+
+			assert.Equal(t, err, testCase.expectedError)
+			if err == nil {
+				assert.Equal(t, img.Key, "items/"+item.ID+"/"+img.ID+".png")
+				assert.Equal(t, img.ThumbnailKey, "items/"+item.ID+"/"+img.ID+"_thumb.jpg")
+				assert.Equal(t, img.Width, 800)
+				assert.Equal(t, img.Height, 200)
+				assert.Equal(t, img.Size, len(pngData))
+			}
+		})
+	}
+}
+
+func TestService_reorderItemImages(t *testing.T) {
+	images := []GoodService.ItemImage{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	item := GoodService.Item{ID: "1", SellerID: "100", Images: images}
+
+	testTable := []struct {
+		name           string
+		inputIDs       []string
+		expectedImages []GoodService.ItemImage
+		expectedError  error
+	}{
+		{
+			name:           "OK",
+			inputIDs:       []string{"c", "a", "b"},
+			expectedImages: []GoodService.ItemImage{{ID: "c"}, {ID: "a"}, {ID: "b"}},
+		},
+		{
+			name:          "Missing Image",
+			inputIDs:      []string{"c", "a"},
+			expectedError: GoodService.ErrInvalidImageOrder,
+		},
+		{
+			name:          "Repeated Image",
+			inputIDs:      []string{"c", "a", "a"},
+			expectedError: GoodService.ErrInvalidImageOrder,
+		},
+		{
+			name:          "Unknown Image",
+			inputIDs:      []string{"c", "a", "d"},
+			expectedError: GoodService.ErrInvalidImageOrder,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mongoRep := mock.NewMockGoodsMongoRepo(c)
+			mongoRep.EXPECT().GetSellerIDByUserID(1).Return("100", nil)
+			mongoRep.EXPECT().GetItemByID("1").Return(item, nil)
+			if testCase.expectedError == nil {
+				mongoRep.EXPECT().SetItemImages("1", testCase.expectedImages).Return(nil)
+			}
+
+			serv := GoodService.NewGoodService(mongoRep, nil, nil)
+
+			got, err := serv.ReorderItemImages("1", 1, testCase.inputIDs)
+
+			assert.Equal(t, err, testCase.expectedError)
+			assert.Equal(t, got, testCase.expectedImages)
+		})
+	}
+}
+
+func TestService_deleteItemRemovesImages(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mongoRep := mock.NewMockGoodsMongoRepo(c)
+	blobs := mock.NewMockBlobStore(c)
+
+	item := GoodService.Item{ID: "1", SellerID: "100", Images: []GoodService.ItemImage{
+		{ID: "a", Key: "items/1/a.png", ThumbnailKey: "items/1/a_thumb.jpg"},
+		{ID: "b", Key: "items/1/b.jpg", ThumbnailKey: "items/1/b_thumb.jpg"},
+	}}
+
+	mongoRep.EXPECT().GetSellerIDByUserID(1).Return("100", nil)
+	mongoRep.EXPECT().GetItemByID("1").Return(item, nil)
+	gomock.InOrder(
+		mongoRep.EXPECT().DeleteItem("1", "100").Return(nil),
+		blobs.EXPECT().Delete("items/1/a.png").Return(nil),
+		blobs.EXPECT().Delete("items/1/a_thumb.jpg").Return(errors.New("storage down")),
+		blobs.EXPECT().Delete("items/1/b.jpg").Return(nil),
+		blobs.EXPECT().Delete("items/1/b_thumb.jpg").Return(nil),
+	)
+
+	serv := GoodService.NewGoodService(mongoRep, nil, blobs)
+
+	err := serv.DeleteItem("1", 1)
+
+	assert.Equal(t, err, nil)
+}
@@ -162,11 +162,12 @@ func TestMongoRep_updateItem(t *testing.T) {
 	type mockBehavior func(m *mtest.T)
 
 	testTable := []struct {
-		name         string
-		inputItem    GoodService.Item
-		mockBehavior mockBehavior
-		expectedItem GoodService.Item
-		wantErr      bool
+		name          string
+		inputItem     GoodService.Item
+		mockBehavior  mockBehavior
+		expectedItem  GoodService.Item
+		expectedError error
+		wantErr       bool
 	}{
 		{
 			name: "OK",
@@ -200,6 +201,58 @@ func TestMongoRep_updateItem(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			// a reservation took 2 of the 5 the seller saw, the update must not give them back
+			name: "Item With Variants",
+			inputItem: GoodService.Item{
+				ID:          "507f1f77bcf86cd799439011",
+				Name:        "T-shirt",
+				Description: "cotton",
+				Quantity:    5,
+				SellerID:    "100",
+			},
+			mockBehavior: func(m *mtest.T) {
+				objectID, _ := primitive.ObjectIDFromHex("507f1f77bcf86cd799439011")
+				m.AddMockResponses(bson.D{
+					{Key: "ok", Value: 1},
+					{Key: "value", Value: bson.D{
+						{Key: "_id", Value: objectID},
+						{Key: "name", Value: "T-shirt"},
+						{Key: "description", Value: "cotton"},
+						{Key: "quantity", Value: 3},
+						{Key: "seller_id", Value: "100"},
+						{Key: "variants", Value: bson.A{bson.D{{Key: "sku", Value: "TS-M"}, {Key: "quantity", Value: 3}}}},
+					}},
+				})
+			},
+			expectedItem: GoodService.Item{
+				ID:          "507f1f77bcf86cd799439011",
+				Name:        "T-shirt",
+				Description: "cotton",
+				Quantity:    3,
+				SellerID:    "100",
+				Variants:    []GoodService.Variant{{SKU: "TS-M", Quantity: 3}},
+			},
+			wantErr: false,
+		},
+		{
+			// a reservation holds some of the stock, the quantity the seller saw is stale
+			name: "Stock Reserved",
+			inputItem: GoodService.Item{
+				ID:          "507f1f77bcf86cd799439011",
+				Name:        "apple",
+				Description: "tasty apple",
+				Quantity:    5,
+				SellerID:    "100",
+			},
+			mockBehavior: func(m *mtest.T) {
+				m.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: nil}})
+				m.AddMockResponses(mtest.CreateCursorResponse(0, "test.items", mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}))
+			},
+			expectedItem:  GoodService.Item{},
+			expectedError: GoodService.ErrStockReserved,
+			wantErr:       true,
+		},
 		{
 			name: "Cant parse itemId",
 			inputItem: GoodService.Item{
@@ -228,9 +281,11 @@ func TestMongoRep_updateItem(t *testing.T) {
 					{Key: "value", Value: nil},
 				}
 				m.AddMockResponses(response)
+				m.AddMockResponses(mtest.CreateCursorResponse(0, "test.items", mtest.FirstBatch))
 			},
-			expectedItem: GoodService.Item{},
-			wantErr:      true,
+			expectedItem:  GoodService.Item{},
+			expectedError: GoodService.ErrItemNotFound,
+			wantErr:       true,
 		},
 		{
 			name: "Decode error",
@@ -267,7 +322,23 @@ func TestMongoRep_updateItem(t *testing.T) {
 			} else {
 				assert.NoError(t, err)
 			}
+			if testCase.expectedError != nil {
+				assert.ErrorIs(t, err, testCase.expectedError)
+			}
 			assert.Equal(t, testCase.expectedItem, newItem)
+
+			if !testCase.wantErr {
+				// the stock and the rest of the item are written by one update
+				events := mt.GetAllStartedEvents()
+				assert.Len(t, events, 1)
+				or := events[0].Command.Lookup("query", "$or").Array()
+				assert.False(t, or.Index(2).Value().Document().Lookup("reservations.0", "$exists").Boolean())
+				quantity := events[0].Command.Lookup("update").Array().Index(0).Value().Document().Lookup("$set", "quantity", "$cond").Array()
+				assert.Equal(t, "$quantity", quantity.Index(1).Value().StringValue())
+				assert.Equal(t, int32(testCase.inputItem.Quantity), quantity.Index(2).Value().Int32())
+				name := events[0].Command.Lookup("update").Array().Index(0).Value().Document().Lookup("$set", "name", "$literal")
+				assert.Equal(t, testCase.inputItem.Name, name.StringValue())
+			}
 		})
 	}
 }
@@ -366,7 +437,7 @@ func TestMongoRep_getGoods(t *testing.T) {
 
 			testCase.mockBehavior(mt)
 
-			items, err := mongoRep.GetGoods()
+			items, err := mongoRep.GetGoods(GoodService.GoodsQuery{}, nil, 21)
 
 			if testCase.wantErr {
 				assert.Error(t, err)
@@ -378,6 +449,46 @@ func TestMongoRep_getGoods(t *testing.T) {
 	}
 }
 
+func TestMongoRep_getGoodsFilters(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	mt.Run("OK", func(mt *mtest.T) {
+		mongoRep := GoodService.NewGoodsMongoRepo(mt.Client)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test.items", mtest.FirstBatch))
+
+		minPrice := 5.0
+		q := GoodService.GoodsQuery{MinPrice: &minPrice, SellerID: "100", InStock: true, Sort: GoodService.SortPriceDesc}
+		after := &GoodService.GoodsCursor{Sort: GoodService.SortPriceDesc, ID: "507f1f77bcf86cd799439011", Price: 20}
+
+		_, err := mongoRep.GetGoods(q, after, 21)
+		assert.NoError(t, err)
+
+		command := mt.GetStartedEvent().Command
+		filter := command.Lookup("filter").Document()
+		assert.Equal(t, 5.0, filter.Lookup("price", "$gte").Double())
+		assert.Equal(t, "100", filter.Lookup("seller_id").StringValue())
+		assert.Equal(t, int32(0), filter.Lookup("quantity", "$gt").Int32())
+
+		or := filter.Lookup("$or").Array()
+		assert.Equal(t, 20.0, or.Index(0).Value().Document().Lookup("price", "$lt").Double())
+		tie := or.Index(1).Value().Document()
+		assert.Equal(t, 20.0, tie.Lookup("price").Double())
+		assert.Equal(t, "507f1f77bcf86cd799439011", tie.Lookup("_id", "$lt").ObjectID().Hex())
+
+		sort := command.Lookup("sort").Document()
+		assert.Equal(t, int32(-1), sort.Lookup("price").Int32())
+		assert.Equal(t, int32(-1), sort.Lookup("_id").Int32())
+		assert.Equal(t, int64(21), command.Lookup("limit").Int64())
+	})
+
+	mt.Run("Invalid Cursor", func(mt *mtest.T) {
+		mongoRep := GoodService.NewGoodsMongoRepo(mt.Client)
+
+		_, err := mongoRep.GetGoods(GoodService.GoodsQuery{Sort: GoodService.SortNewest},
+			&GoodService.GoodsCursor{Sort: GoodService.SortNewest, ID: "1"}, 21)
+		assert.Equal(t, GoodService.ErrInvalidCursor, err)
+	})
+}
+
 func TestMongoRep_getQuantity(t *testing.T) {
 	type mockBehavior func(m *mtest.T)
 
@@ -795,3 +906,175 @@ func TestMongoRep_getItemInfoForCart(t *testing.T) {
 		})
 	}
 }
+
+// The gRPC server tells a removed item from a failing database by the sentinel.
+func TestMongoRep_getItemInfoForCartNotFound(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	mt.Run("Item not found", func(mt *mtest.T) {
+		mongoRepo := GoodService.NewGoodsMongoRepo(mt.Client)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "item.test", mtest.FirstBatch))
+
+		_, err := mongoRepo.GetItemInfoForCart("507f1f77bcf86cd799439011")
+		assert.ErrorIs(t, err, GoodService.ErrItemNotFound)
+	})
+}
+
+func TestMongoRep_deleteSeller(t *testing.T) {
+	type mockBehavior func(m *mtest.T)
+
+	testTable := []struct {
+		name            string
+		inputSellerID   string
+		mockBehavior    mockBehavior
+		expectedDeleted int64
+		wantErr         bool
+	}{
+		{
+			name:          "OK",
+			inputSellerID: "507f1f77bcf86cd799439011",
+			mockBehavior: func(m *mtest.T) {
+				m.AddMockResponses(
+					bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 2}},
+					bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}},
+				)
+			},
+			expectedDeleted: 2,
+			wantErr:         false,
+		},
+		{
+			name:          "Error delete items",
+			inputSellerID: "507f1f77bcf86cd799439011",
+			mockBehavior: func(m *mtest.T) {
+				m.AddMockResponses(bson.D{{Key: "ok", Value: 0}})
+			},
+			expectedDeleted: 0,
+			wantErr:         true,
+		},
+		{
+			name:          "Error delete seller",
+			inputSellerID: "15",
+			mockBehavior: func(m *mtest.T) {
+				m.AddMockResponses(
+					bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}},
+					bson.D{{Key: "ok", Value: 0}},
+				)
+			},
+			expectedDeleted: 1,
+			wantErr:         true,
+		},
+	}
+
+	for _, testCase := range testTable {
+		mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+		mt.Run(testCase.name, func(mt *mtest.T) {
+			mongoRep := GoodService.NewGoodsMongoRepo(mt.Client)
+
+			testCase.mockBehavior(mt)
+
+			deleted, err := mongoRep.DeleteSeller(testCase.inputSellerID)
+			if testCase.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, testCase.expectedDeleted, deleted)
+		})
+	}
+}
+
+func TestMongoRep_searchItemsByText(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	mt.Run("OK", func(mt *mtest.T) {
+		mongoRep := GoodService.NewGoodsMongoRepo(mt.Client)
+
+		objectID, _ := primitive.ObjectIDFromHex("507f1f77bcf86cd799439011")
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(1, "test.items", mtest.FirstBatch,
+				bson.D{
+					{Key: "_id", Value: objectID},
+					{Key: "name", Value: "apple"},
+					{Key: "description", Value: "tasty apple"},
+					{Key: "seller_id", Value: "100"},
+					{Key: "score", Value: 1.5},
+				}),
+			mtest.CreateCursorResponse(0, "test.items", mtest.NextBatch),
+		)
+
+		items, err := mongoRep.SearchItemsByText("apple", 10)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []GoodService.ScoredItem{{
+			Item: GoodService.Item{
+				ID:          "507f1f77bcf86cd799439011",
+				Name:        "apple",
+				Description: "tasty apple",
+				SellerID:    "100",
+			},
+			Score: 1.5,
+		}}, items)
+
+		filter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+		assert.Equal(t, "apple", filter.Lookup("$text", "$search").StringValue())
+	})
+}
+
+func TestMongoRep_reserveItemStock(t *testing.T) {
+	type mockBehavior func(m *mtest.T)
+
+	testTable := []struct {
+		name             string
+		inputLine        GoodService.ReservationLine
+		mockBehavior     mockBehavior
+		expectedReserved bool
+		expectedError    error
+	}{
+		{
+			name:      "OK",
+			inputLine: GoodService.ReservationLine{ItemID: "507f1f77bcf86cd799439011", SKU: "TS-M", Quantity: 2},
+			mockBehavior: func(m *mtest.T) {
+				m.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}})
+			},
+			expectedReserved: true,
+		},
+		{
+			name:      "Short Stock",
+			inputLine: GoodService.ReservationLine{ItemID: "507f1f77bcf86cd799439011", Quantity: 2},
+			mockBehavior: func(m *mtest.T) {
+				m.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}, {Key: "nModified", Value: 0}})
+			},
+			expectedReserved: false,
+		},
+		{
+			name:          "Cant parse itemId",
+			inputLine:     GoodService.ReservationLine{ItemID: "1", Quantity: 2},
+			mockBehavior:  func(m *mtest.T) {},
+			expectedError: GoodService.ErrItemNotFound,
+		},
+	}
+
+	for _, testCase := range testTable {
+		mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+		mt.Run(testCase.name, func(mt *mtest.T) {
+			mongoRep := GoodService.NewGoodsMongoRepo(mt.Client)
+
+			testCase.mockBehavior(mt)
+
+			reserved, err := mongoRep.ReserveItemStock("r1", testCase.inputLine)
+
+			assert.Equal(t, testCase.expectedError, err)
+			assert.Equal(t, testCase.expectedReserved, reserved)
+
+			if testCase.expectedError == nil {
+				update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+				filter := update.Lookup("q").Document()
+				if testCase.inputLine.SKU == "" {
+					assert.Equal(t, int32(2), filter.Lookup("quantity", "$gte").Int32())
+					assert.False(t, filter.Lookup("variants.0", "$exists").Boolean())
+				} else {
+					assert.Equal(t, "TS-M", filter.Lookup("variants", "$elemMatch", "sku").StringValue())
+					assert.Equal(t, "TS-M", update.Lookup("arrayFilters").Array().Index(0).Value().Document().Lookup("v.sku").StringValue())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package GoodService
+
+import (
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	GoodService "github.com/jst-Frenzy/ControlSystem/GoodsService/internal/GoodService"
+	mock "github.com/jst-Frenzy/ControlSystem/GoodsService/internal/mocks"
+	"testing"
+	"time"
+)
+
+func TestService_reserveStock(t *testing.T) {
+	type mockBehavior func(r *mock.MockGoodsMongoRepo)
+
+	shirt := GoodService.ReservationLine{ItemID: "1", SKU: "TS-M", Quantity: 3}
+	mug := GoodService.ReservationLine{ItemID: "2", Quantity: 1}
+	pending := GoodService.Reservation{
+		ID:             "r1",
+		IdempotencyKey: "order-7",
+		Lines:          []GoodService.ReservationLine{shirt, mug},
+		Status:         GoodService.ReservationPending,
+		ExpiresAt:      time.Now().Add(time.Minute),
+	}
+
+	testTable := []struct {
+		name          string
+		inputKey      string
+		inputLines    []GoodService.ReservationLine
+		mockBehavior  mockBehavior
+		expectedID    string
+		expectedError error
+	}{
+		{
+			name:     "OK",
+			inputKey: "order-7",
+			inputLines: []GoodService.ReservationLine{
+				{ItemID: "1", SKU: "TS-M", Quantity: 2}, mug, {ItemID: "1", SKU: "TS-M", Quantity: 1},
+			},
+			mockBehavior: func(r *mock.MockGoodsMongoRepo) {
+				r.EXPECT().CreateReservation(gomock.Any()).DoAndReturn(func(res GoodService.Reservation) (string, error) {
+					assert.Equal(t, res.Lines, []GoodService.ReservationLine{shirt, mug})
+					assert.Equal(t, res.Status, GoodService.ReservationPending)
+					return "r1", nil
+				})
+				r.EXPECT().ReserveItemStock("r1", shirt).Return(true, nil)
+				r.EXPECT().ReserveItemStock("r1", mug).Return(true, nil)
+			},
+			expectedID: "r1",
+		},
+		{
+			name:       "Not Enough Stock",
+			inputKey:   "order-7",
+			inputLines: []GoodService.ReservationLine{shirt, mug},
+			mockBehavior: func(r *mock.MockGoodsMongoRepo) {
+				r.EXPECT().CreateReservation(gomock.Any()).Return("r1", nil)
+				r.EXPECT().ReserveItemStock("r1", shirt).Return(true, nil)
+				r.EXPECT().ReserveItemStock("r1", mug).Return(false, nil)
+				r.EXPECT().GetItemByID("2").Return(GoodService.Item{ID: "2", Quantity: 0}, nil)
+				r.EXPECT().ReleaseItemStock("r1", shirt).Return(nil)
+				r.EXPECT().ReleaseItemStock("r1", mug).Return(nil)
+				r.EXPECT().DeleteReservation("r1").Return(nil)
+			},
+			expectedError: GoodService.ErrInsufficientStock,
+		},
+		{
+			name:       "SKU Required",
+			inputKey:   "order-7",
+			inputLines: []GoodService.ReservationLine{{ItemID: "1", Quantity: 1}},
+			mockBehavior: func(r *mock.MockGoodsMongoRepo) {
+				line := GoodService.ReservationLine{ItemID: "1", Quantity: 1}
+				r.EXPECT().CreateReservation(gomock.Any()).Return("r1", nil)
+				r.EXPECT().ReserveItemStock("r1", line).Return(false, nil)
+				r.EXPECT().GetItemByID("1").Return(GoodService.Item{ID: "1", Variants: []GoodService.Variant{{SKU: "TS-M"}}}, nil)
+				r.EXPECT().ReleaseItemStock("r1", line).Return(nil)
+				r.EXPECT().DeleteReservation("r1").Return(nil)
+			},
+			expectedError: GoodService.ErrSKURequired,
+		},
+		{
+			name:       "Retry Finishes Reservation",
+			inputKey:   "order-7",
+			inputLines: []GoodService.ReservationLine{shirt, mug},
+			mockBehavior: func(r *mock.MockGoodsMongoRepo) {
+				r.EXPECT().CreateReservation(gomock.Any()).Return("", GoodService.ErrDuplicateReservation)
+				r.EXPECT().GetReservationByKey("order-7").Return(pending, nil)
+				r.EXPECT().ReserveItemStock("r1", shirt).Return(false, nil)
+				r.EXPECT().GetItemByID("1").Return(GoodService.Item{
+					ID:           "1",
+					Reservations: []GoodService.StockHold{{ID: "r1", SKU: "TS-M", Quantity: 3}},
+				}, nil)
+				r.EXPECT().ReserveItemStock("r1", mug).Return(true, nil)
+			},
+			expectedID: "r1",
+		},
+		{
+			name:       "Retry Of Committed Reservation",
+			inputKey:   "order-7",
+			inputLines: []GoodService.ReservationLine{shirt, mug},
+			mockBehavior: func(r *mock.MockGoodsMongoRepo) {
+				committed := pending
+				committed.Status = GoodService.ReservationCommitted
+				r.EXPECT().CreateReservation(gomock.Any()).Return("", GoodService.ErrDuplicateReservation)
+				r.EXPECT().GetReservationByKey("order-7").Return(committed, nil)
+			},
+			expectedID: "r1",
+		},
+		{
+			name:       "Key Of Another Reservation",
+			inputKey:   "order-7",
+			inputLines: []GoodService.ReservationLine{mug},
+			mockBehavior: func(r *mock.MockGoodsMongoRepo) {
+				r.EXPECT().CreateReservation(gomock.Any()).Return("", GoodService.ErrDuplicateReservation)
+				r.EXPECT().GetReservationByKey("order-7").Return(pending, nil)
+			},
+			expectedError: GoodService.ErrIdempotencyKeyReused,
+		},
+		{
+			name:          "No Key",
+			inputLines:    []GoodService.ReservationLine{mug},
+			mockBehavior:  func(r *mock.MockGoodsMongoRepo) {},
+			expectedError: GoodService.ErrInvalidReservation,
+		},
+		{
+			name:          "Zero Quantity",
+			inputKey:      "order-7",
+			inputLines:    []GoodService.ReservationLine{{ItemID: "2"}},
+			mockBehavior:  func(r *mock.MockGoodsMongoRepo) {},
+			expectedError: GoodService.ErrInvalidReservation,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mongoRep := mock.NewMockGoodsMongoRepo(c)
+			testCase.mockBehavior(mongoRep)
+
+			serv := GoodService.NewGoodService(mongoRep, nil, nil)
+
+			res, err := serv.ReserveStock(testCase.inputKey, testCase.inputLines, 0)
+
+			assert.Equal(t, err, testCase.expectedError)
+			assert.Equal(t, res.ID, testCase.expectedID)
+		})
+	}
+}
+
+func TestService_commitReservation(t *testing.T) {
+	lines := []GoodService.ReservationLine{{ItemID: "1", SKU: "TS-M", Quantity: 3}, {ItemID: "2", Quantity: 1}}
+
+	testTable := []struct {
+		name          string
+		committed     bool
+		status        string
+		expectedError error
+	}{
+		{
+			name:      "OK",
+			committed: true,
+			status:    GoodService.ReservationCommitted,
+		},
+		{
+			name:   "Committed Before",
+			status: GoodService.ReservationCommitted,
+		},
+		{
+			name:          "Expired",
+			status:        GoodService.ReservationPending,
+			expectedError: GoodService.ErrReservationExpired,
+		},
+		{
+			name:          "Released",
+			status:        GoodService.ReservationReleased,
+			expectedError: GoodService.ErrReservationReleased,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mongoRep := mock.NewMockGoodsMongoRepo(c)
+			mongoRep.EXPECT().FinishReservation("r1", GoodService.ReservationCommitted, gomock.Any()).Return(testCase.committed, nil)
+			mongoRep.EXPECT().GetReservation("r1").Return(GoodService.Reservation{ID: "r1", Lines: lines, Status: testCase.status}, nil)
+			if testCase.expectedError == nil {
+				mongoRep.EXPECT().CommitItemStock("r1", "1").Return(nil)
+				mongoRep.EXPECT().CommitItemStock("r1", "2").Return(nil)
+			}
+
+			serv := GoodService.NewGoodService(mongoRep, nil, nil)
+
+			_, err := serv.CommitReservation("r1")
+
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
+
+func TestService_releaseReservation(t *testing.T) {
+	lines := []GoodService.ReservationLine{{ItemID: "1", SKU: "TS-M", Quantity: 3}}
+
+	testTable := []struct {
+		name          string
+		status        string
+		expectedError error
+	}{
+		{
+			name:   "OK",
+			status: GoodService.ReservationReleased,
+		},
+		{
+			name:   "Expired",
+			status: GoodService.ReservationExpired,
+		},
+		{
+			name:          "Committed",
+			status:        GoodService.ReservationCommitted,
+			expectedError: GoodService.ErrReservationCommitted,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mongoRep := mock.NewMockGoodsMongoRepo(c)
+			mongoRep.EXPECT().FinishReservation("r1", GoodService.ReservationReleased, gomock.Any()).
+				Return(testCase.status == GoodService.ReservationReleased, nil)
+			mongoRep.EXPECT().GetReservation("r1").Return(GoodService.Reservation{ID: "r1", Lines: lines, Status: testCase.status}, nil)
+			if testCase.expectedError == nil {
+				mongoRep.EXPECT().ReleaseItemStock("r1", lines[0]).Return(nil)
+			}
+
+			serv := GoodService.NewGoodService(mongoRep, nil, nil)
+
+			res, err := serv.ReleaseReservation("r1")
+
+			assert.Equal(t, err, testCase.expectedError)
+			if err == nil {
+				assert.Equal(t, res.Status, testCase.status)
+			}
+		})
+	}
+}
+
+func TestService_expireReservations(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	first := GoodService.Reservation{ID: "r1", Lines: []GoodService.ReservationLine{{ItemID: "1", Quantity: 1}}}
+	second := GoodService.Reservation{ID: "r2", Lines: []GoodService.ReservationLine{{ItemID: "2", Quantity: 2}}}
+
+	mongoRep := mock.NewMockGoodsMongoRepo(c)
+	gomock.InOrder(
+		mongoRep.EXPECT().GetExpiredReservations(gomock.Any(), 100).Return([]GoodService.Reservation{first, second}, nil),
+		mongoRep.EXPECT().ReleaseItemStock("r1", first.Lines[0]).Return(nil),
+		mongoRep.EXPECT().FinishReservation("r1", GoodService.ReservationExpired, gomock.Any()).Return(true, nil),
+		// released by the buyer meanwhile, giving the stock back again is a no-op
+		mongoRep.EXPECT().ReleaseItemStock("r2", second.Lines[0]).Return(nil),
+		mongoRep.EXPECT().FinishReservation("r2", GoodService.ReservationExpired, gomock.Any()).Return(false, nil),
+	)
+
+	serv := GoodService.NewGoodService(mongoRep, nil, nil)
+
+	expired, err := serv.ExpireReservations()
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, expired, 1)
+}
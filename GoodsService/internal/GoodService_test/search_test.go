@@ -0,0 +1,222 @@
+package GoodService
+
+import (
+	"errors"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	GoodService "github.com/jst-Frenzy/ControlSystem/GoodsService/internal/GoodService"
+	mock "github.com/jst-Frenzy/ControlSystem/GoodsService/internal/mocks"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestSearcher_search(t *testing.T) {
+	runningShoes := GoodService.Item{
+		ID:          "a1",
+		Name:        "Running Shoes",
+		Description: "Light shoes for running on the road",
+		Price:       80,
+		SellerID:    "s1",
+	}
+	trailRunner := GoodService.Item{
+		ID:          "b2",
+		Name:        "Trail runner",
+		Description: "Shoes made for the trail",
+		Price:       120,
+		SellerID:    "s2",
+	}
+	rainJacket := GoodService.Item{
+		ID:          "c3",
+		Name:        "Rain jacket",
+		Description: "Keeps you dry",
+		Price:       45,
+		SellerID:    "s1",
+	}
+
+	fifty, hundred := 50.0, 100.0
+	ten, fiveHundred := 10.0, 500.0
+	priceBuckets := func(counts ...int) []GoodService.PriceBucketFacet {
+		return []GoodService.PriceBucketFacet{
+			{From: 0, To: &ten, Count: counts[0]},
+			{From: 10, To: &fifty, Count: counts[1]},
+			{From: 50, To: &hundred, Count: counts[2]},
+			{From: 100, To: &fiveHundred, Count: counts[3]},
+			{From: 500, Count: counts[4]},
+		}
+	}
+
+	testTable := []struct {
+		name           string
+		inputQuery     GoodService.SearchQuery
+		expectedResult GoodService.SearchResult
+	}{
+		{
+			name:       "Ranked With Typo",
+			inputQuery: GoodService.SearchQuery{Query: "runing shoes", Limit: 20},
+			expectedResult: GoodService.SearchResult{
+				Hits: []GoodService.SearchHit{
+					{
+						Item:  runningShoes,
+						Score: 6.75,
+						Highlights: GoodService.SearchHighlight{
+							Name:        "<em>Running</em> <em>Shoes</em>",
+							Description: "Light <em>shoes</em> for running on the road",
+						},
+					},
+					{
+						Item:  trailRunner,
+						Score: 2.25,
+						Highlights: GoodService.SearchHighlight{
+							Name:        "Trail runner",
+							Description: "<em>Shoes</em> made for the trail",
+						},
+					},
+				},
+				Total: 2,
+				Facets: GoodService.SearchFacets{
+					Sellers:      []GoodService.SellerFacet{{SellerID: "s1", Count: 1}, {SellerID: "s2", Count: 1}},
+					PriceBuckets: priceBuckets(0, 0, 1, 1, 0),
+				},
+			},
+		},
+		{
+			name:       "Second Page",
+			inputQuery: GoodService.SearchQuery{Query: "runing shoes", Limit: 1, Offset: 1},
+			expectedResult: GoodService.SearchResult{
+				Hits: []GoodService.SearchHit{
+					{
+						Item:  trailRunner,
+						Score: 2.25,
+						Highlights: GoodService.SearchHighlight{
+							Name:        "Trail runner",
+							Description: "<em>Shoes</em> made for the trail",
+						},
+					},
+				},
+				Total: 2,
+				Facets: GoodService.SearchFacets{
+					Sellers:      []GoodService.SellerFacet{{SellerID: "s1", Count: 1}, {SellerID: "s2", Count: 1}},
+					PriceBuckets: priceBuckets(0, 0, 1, 1, 0),
+				},
+			},
+		},
+		{
+			name:       "Offset Past The End",
+			inputQuery: GoodService.SearchQuery{Query: "runing shoes", Limit: 20, Offset: math.MaxInt},
+			expectedResult: GoodService.SearchResult{
+				Hits:  []GoodService.SearchHit{},
+				Total: 2,
+				Facets: GoodService.SearchFacets{
+					Sellers:      []GoodService.SellerFacet{{SellerID: "s1", Count: 1}, {SellerID: "s2", Count: 1}},
+					PriceBuckets: priceBuckets(0, 0, 1, 1, 0),
+				},
+			},
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mongoRep := mock.NewMockGoodsMongoRepo(c)
+			mongoRep.EXPECT().SearchItemsByText("runing shoes", 1000).Return([]GoodService.ScoredItem{
+				{Item: runningShoes, Score: 1.25},
+				{Item: trailRunner, Score: 0.75},
+			}, nil)
+			mongoRep.EXPECT().FindItemsBySearchKeys(gomock.Any(), 1000).Return([]GoodService.Item{runningShoes, rainJacket}, nil)
+
+			searcher := GoodService.NewMongoSearcher(mongoRep)
+
+			result, err := searcher.Search(testCase.inputQuery)
+
+			assert.Equal(t, err, nil)
+			assert.Equal(t, result, testCase.expectedResult)
+		})
+	}
+}
+
+func TestSearcher_searchSnippet(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	kettle := GoodService.Item{
+		ID:          "k1",
+		Name:        "Kettle & Co",
+		Description: strings.Repeat("filler ", 40) + "steel kettle" + strings.Repeat(" filler", 10),
+		Price:       600,
+		SellerID:    "s1",
+	}
+
+	mongoRep := mock.NewMockGoodsMongoRepo(c)
+	mongoRep.EXPECT().SearchItemsByText("kettle", 1000).Return(nil, nil)
+	mongoRep.EXPECT().FindItemsBySearchKeys(gomock.Any(), 1000).Return([]GoodService.Item{kettle}, nil)
+
+	result, err := GoodService.NewMongoSearcher(mongoRep).Search(GoodService.SearchQuery{Query: "kettle", Limit: 20})
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(result.Hits), 1)
+	assert.Equal(t, result.Hits[0].Highlights.Name, "<em>Kettle</em> &amp; Co")
+	assert.Equal(t, result.Hits[0].Highlights.Description,
+		"…"+strings.Repeat("filler ", 11)+"steel <em>kettle</em>"+strings.Repeat(" filler", 10))
+	assert.Equal(t, result.Facets.PriceBuckets[4].Count, 1)
+}
+
+func TestService_search(t *testing.T) {
+	type mockBehavior func(s *mock.MockSearcher)
+
+	testTable := []struct {
+		name          string
+		inputQuery    GoodService.SearchQuery
+		mockBehavior  mockBehavior
+		expectedError error
+	}{
+		{
+			name:       "Defaults",
+			inputQuery: GoodService.SearchQuery{Query: "  apple ", Offset: -3},
+			mockBehavior: func(s *mock.MockSearcher) {
+				s.EXPECT().Search(GoodService.SearchQuery{Query: "apple", Limit: 20}).Return(GoodService.SearchResult{}, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "Limit Capped",
+			inputQuery: GoodService.SearchQuery{Query: "apple", Limit: 500, Offset: 40},
+			mockBehavior: func(s *mock.MockSearcher) {
+				s.EXPECT().Search(GoodService.SearchQuery{Query: "apple", Limit: 100, Offset: 40}).Return(GoodService.SearchResult{}, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:          "Empty Query",
+			inputQuery:    GoodService.SearchQuery{Query: "   "},
+			mockBehavior:  func(s *mock.MockSearcher) {},
+			expectedError: GoodService.ErrEmptySearchQuery,
+		},
+		{
+			name:       "Searcher Failure",
+			inputQuery: GoodService.SearchQuery{Query: "apple", Limit: 5},
+			mockBehavior: func(s *mock.MockSearcher) {
+				s.EXPECT().Search(GoodService.SearchQuery{Query: "apple", Limit: 5}).Return(GoodService.SearchResult{}, errors.New("DB Failure"))
+			},
+			expectedError: errors.New("DB Failure"),
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			searcher := mock.NewMockSearcher(c)
+			testCase.mockBehavior(searcher)
+
+			serv := GoodService.NewGoodService(nil, searcher, nil)
+
+			_, err := serv.Search(testCase.inputQuery)
+
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
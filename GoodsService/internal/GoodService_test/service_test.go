@@ -96,7 +96,7 @@ func TestService_addItem(t *testing.T) {
 			mongoRep := mock.NewMockGoodsMongoRepo(c)
 			testCase.mockBehavior(mongoRep, testCase.inputItem, testCase.inputUser)
 
-			serv := GoodService.NewGoodService(mongoRep)
+			serv := GoodService.NewGoodService(mongoRep, nil, nil)
 			id, err := serv.AddItem(testCase.inputItem, testCase.inputUser)
 
 			assert.Equal(t, id, testCase.expectedId)
@@ -121,6 +121,7 @@ func TestService_deleteItem(t *testing.T) {
 			userID: 1,
 			mockBehavior: func(r *mock.MockGoodsMongoRepo, itemID string, userID int) {
 				r.EXPECT().GetSellerIDByUserID(userID).Return("100", nil)
+				r.EXPECT().GetItemByID(itemID).Return(GoodService.Item{ID: itemID, SellerID: "100"}, nil)
 				r.EXPECT().DeleteItem(itemID, "100").Return(nil)
 			},
 			expectedError: nil,
@@ -143,7 +144,7 @@ func TestService_deleteItem(t *testing.T) {
 			mongoRep := mock.NewMockGoodsMongoRepo(c)
 			testCase.mockBehavior(mongoRep, testCase.itemID, testCase.userID)
 
-			serv := GoodService.NewGoodService(mongoRep)
+			serv := GoodService.NewGoodService(mongoRep, nil, nil)
 
 			err := serv.DeleteItem(testCase.itemID, testCase.userID)
 
@@ -257,7 +258,7 @@ func TestService_updateItem(t *testing.T) {
 			mongoRep := mock.NewMockGoodsMongoRepo(c)
 			testCase.mockBehavior(mongoRep, testCase.inputItem, testCase.inputUserID)
 
-			serv := GoodService.NewGoodService(mongoRep)
+			serv := GoodService.NewGoodService(mongoRep, nil, nil)
 
 			i, err := serv.UpdateItem(testCase.inputItem, testCase.inputUserID)
 
@@ -269,63 +270,69 @@ func TestService_updateItem(t *testing.T) {
 
 func TestService_getGoods(t *testing.T) {
 	type mockBehavior func(r *mock.MockGoodsMongoRepo)
+
+	apple := GoodService.Item{ID: "1", Name: "apple", Price: 10, Quantity: 1, SellerID: "1"}
+	pear := GoodService.Item{ID: "2", Name: "pear", Price: 20, Quantity: 2, SellerID: "1"}
+	plum := GoodService.Item{ID: "3", Name: "plum", Price: 30, Quantity: 3, SellerID: "2"}
+	minPrice, maxPrice := 30.0, 10.0
+
 	testTable := []struct {
-		name          string
-		mockBehavior  mockBehavior
-		expectedSlice []GoodService.Item
-		expectedError error
+		name           string
+		inputQuery     GoodService.GoodsQuery
+		mockBehavior   mockBehavior
+		expectedItems  []GoodService.Item
+		expectedCursor bool
+		expectedError  error
 	}{
 		{
-			name: "OK",
+			name:       "Next Page",
+			inputQuery: GoodService.GoodsQuery{Limit: 2, Sort: GoodService.SortPriceAsc},
 			mockBehavior: func(r *mock.MockGoodsMongoRepo) {
-				r.EXPECT().GetGoods().Return([]GoodService.Item{
-					{
-						ID:          "1",
-						Name:        "test name 1",
-						Description: "test description 1",
-						Quantity:    1,
-						SellerID:    "1",
-					},
-					{
-						ID:          "2",
-						Name:        "test name 2",
-						Description: "test description 2",
-						Quantity:    2,
-						SellerID:    "2",
-					},
-					{
-						ID:          "3",
-						Name:        "test name 3",
-						Description: "test description 3",
-						Quantity:    3,
-						SellerID:    "3",
-					},
-				}, nil)
+				q := GoodService.GoodsQuery{Limit: 2, Sort: GoodService.SortPriceAsc}
+				r.EXPECT().GetGoods(q, nil, 3).Return([]GoodService.Item{apple, pear, plum}, nil)
 			},
-			expectedSlice: []GoodService.Item{
-				{
-					ID:          "1",
-					Name:        "test name 1",
-					Description: "test description 1",
-					Quantity:    1,
-					SellerID:    "1",
-				},
-				{
-					ID:          "2",
-					Name:        "test name 2",
-					Description: "test description 2",
-					Quantity:    2,
-					SellerID:    "2",
-				},
-				{
-					ID:          "3",
-					Name:        "test name 3",
-					Description: "test description 3",
-					Quantity:    3,
-					SellerID:    "3",
-				},
+			expectedItems:  []GoodService.Item{apple, pear},
+			expectedCursor: true,
+		},
+		{
+			name: "Last Page",
+			mockBehavior: func(r *mock.MockGoodsMongoRepo) {
+				q := GoodService.GoodsQuery{Sort: GoodService.SortNewest}
+				r.EXPECT().GetGoods(q, nil, 21).Return([]GoodService.Item{plum}, nil)
 			},
-			expectedError: nil,
+			expectedItems: []GoodService.Item{plum},
+		},
+		{
+			name: "Empty",
+			mockBehavior: func(r *mock.MockGoodsMongoRepo) {
+				r.EXPECT().GetGoods(gomock.Any(), nil, 21).Return(nil, nil)
+			},
+			expectedItems: []GoodService.Item{},
+		},
+		{
+			name:          "Unknown Sort",
+			inputQuery:    GoodService.GoodsQuery{Sort: "popular"},
+			mockBehavior:  func(r *mock.MockGoodsMongoRepo) {},
+			expectedError: GoodService.ErrInvalidSort,
+		},
+		{
+			name:          "Min Price Above Max",
+			inputQuery:    GoodService.GoodsQuery{MinPrice: &minPrice, MaxPrice: &maxPrice},
+			mockBehavior:  func(r *mock.MockGoodsMongoRepo) {},
+			expectedError: GoodService.ErrInvalidPriceRange,
+		},
+		{
+			name:          "Invalid Cursor",
+			inputQuery:    GoodService.GoodsQuery{Cursor: "not a cursor"},
+			mockBehavior:  func(r *mock.MockGoodsMongoRepo) {},
+			expectedError: GoodService.ErrInvalidCursor,
+		},
+		{
+			name: "Repo Error",
+			mockBehavior: func(r *mock.MockGoodsMongoRepo) {
+				r.EXPECT().GetGoods(gomock.Any(), nil, 21).Return(nil, errors.New("repo failure"))
+			},
+			expectedError: errors.New("repo failure"),
 		},
 	}
 
@@ -337,16 +344,48 @@ func TestService_getGoods(t *testing.T) {
 			mongoRep := mock.NewMockGoodsMongoRepo(c)
 			testCase.mockBehavior(mongoRep)
 
-			serv := GoodService.NewGoodService(mongoRep)
+			serv := GoodService.NewGoodService(mongoRep, nil, nil)
 
-			items, err := serv.GetGoods()
+			page, err := serv.GetGoods(testCase.inputQuery)
 
-			assert.Equal(t, testCase.expectedSlice, items)
 			assert.Equal(t, testCase.expectedError, err)
+			assert.Equal(t, testCase.expectedItems, page.Items)
+			assert.Equal(t, testCase.expectedCursor, page.NextCursor != "")
 		})
 	}
 }
 
+func TestService_getGoodsCursor(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	apple := GoodService.Item{ID: "1", Name: "apple", Price: 10}
+	pear := GoodService.Item{ID: "2", Name: "pear", Price: 20}
+
+	mongoRep := mock.NewMockGoodsMongoRepo(c)
+	serv := GoodService.NewGoodService(mongoRep, nil, nil)
+
+	q := GoodService.GoodsQuery{Limit: 1, Sort: GoodService.SortPriceDesc}
+	mongoRep.EXPECT().GetGoods(q, nil, 2).Return([]GoodService.Item{pear, apple}, nil)
+
+	first, err := serv.GetGoods(q)
+	assert.Equal(t, nil, err)
+
+	q.Cursor = first.NextCursor
+	after := &GoodService.GoodsCursor{Sort: GoodService.SortPriceDesc, ID: "2", Name: "pear", Price: 20}
+	mongoRep.EXPECT().GetGoods(q, after, 2).Return([]GoodService.Item{apple}, nil)
+
+	second, err := serv.GetGoods(q)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []GoodService.Item{apple}, second.Items)
+	assert.Equal(t, "", second.NextCursor)
+
+	// a cursor only continues the sort it was made in
+	q.Sort = GoodService.SortPriceAsc
+	_, err = serv.GetGoods(q)
+	assert.Equal(t, GoodService.ErrInvalidCursor, err)
+}
+
 func TestService_getItemInfoForCart(t *testing.T) {
 	type mockBehavior func(r *mock.MockGoodsMongoRepo, id string)
 	testTable := []struct {
@@ -380,12 +419,135 @@ func TestService_getItemInfoForCart(t *testing.T) {
 			mongoRep := mock.NewMockGoodsMongoRepo(c)
 			testCase.mockBehavior(mongoRep, testCase.inputID)
 
-			serv := GoodService.NewGoodService(mongoRep)
+			serv := GoodService.NewGoodService(mongoRep, nil, nil)
 
-			inf, err := serv.GetItemInfoForCart(testCase.inputID)
+			inf, err := serv.GetItemInfoForCart(testCase.inputID, "")
 
 			assert.Equal(t, inf, testCase.expectedInfo)
 			assert.Equal(t, err, testCase.expectedError)
 		})
 	}
 }
+
+func TestService_exportUserData(t *testing.T) {
+	type mockBehavior func(r *mock.MockGoodsMongoRepo, userID int)
+
+	testTable := []struct {
+		name           string
+		userID         int
+		mockBehavior   mockBehavior
+		expectedSeller GoodService.Seller
+		expectedItems  []GoodService.Item
+		expectedError  error
+	}{
+		{
+			name:   "OK",
+			userID: 1,
+			mockBehavior: func(r *mock.MockGoodsMongoRepo, userID int) {
+				r.EXPECT().GetSellerByUserID(userID).Return(GoodService.Seller{ID: "100", UserID: userID, Name: "test name"}, nil)
+				r.EXPECT().GetItemsBySellerID("100").Return([]GoodService.Item{{ID: "1", Name: "apple", SellerID: "100"}}, nil)
+			},
+			expectedSeller: GoodService.Seller{ID: "100", UserID: 1, Name: "test name"},
+			expectedItems:  []GoodService.Item{{ID: "1", Name: "apple", SellerID: "100"}},
+			expectedError:  nil,
+		},
+		{
+			name:   "Not a seller",
+			userID: 1,
+			mockBehavior: func(r *mock.MockGoodsMongoRepo, userID int) {
+				r.EXPECT().GetSellerByUserID(userID).Return(GoodService.Seller{}, GoodService.ErrSellerNotFound)
+			},
+			expectedSeller: GoodService.Seller{},
+			expectedItems:  nil,
+			expectedError:  nil,
+		},
+		{
+			name:   "Error get items",
+			userID: 1,
+			mockBehavior: func(r *mock.MockGoodsMongoRepo, userID int) {
+				r.EXPECT().GetSellerByUserID(userID).Return(GoodService.Seller{ID: "100"}, nil)
+				r.EXPECT().GetItemsBySellerID("100").Return(nil, errors.New("db error"))
+			},
+			expectedSeller: GoodService.Seller{},
+			expectedItems:  nil,
+			expectedError:  errors.New("db error"),
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mongoRep := mock.NewMockGoodsMongoRepo(c)
+			testCase.mockBehavior(mongoRep, testCase.userID)
+
+			serv := GoodService.NewGoodService(mongoRep, nil, nil)
+
+			seller, items, err := serv.ExportUserData(testCase.userID)
+
+			assert.Equal(t, seller, testCase.expectedSeller)
+			assert.Equal(t, items, testCase.expectedItems)
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
+
+func TestService_deleteUserData(t *testing.T) {
+	type mockBehavior func(r *mock.MockGoodsMongoRepo, userID int)
+
+	testTable := []struct {
+		name            string
+		userID          int
+		mockBehavior    mockBehavior
+		expectedDeleted int64
+		expectedError   error
+	}{
+		{
+			name:   "OK",
+			userID: 1,
+			mockBehavior: func(r *mock.MockGoodsMongoRepo, userID int) {
+				r.EXPECT().GetSellerByUserID(userID).Return(GoodService.Seller{ID: "100", UserID: userID}, nil)
+				r.EXPECT().GetItemsBySellerID("100").Return([]GoodService.Item{{ID: "1"}, {ID: "2"}, {ID: "3"}}, nil)
+				r.EXPECT().DeleteSeller("100").Return(int64(3), nil)
+			},
+			expectedDeleted: 3,
+			expectedError:   nil,
+		},
+		{
+			name:   "Not a seller",
+			userID: 1,
+			mockBehavior: func(r *mock.MockGoodsMongoRepo, userID int) {
+				r.EXPECT().GetSellerByUserID(userID).Return(GoodService.Seller{}, GoodService.ErrSellerNotFound)
+			},
+			expectedDeleted: 0,
+			expectedError:   nil,
+		},
+		{
+			name:   "Error looking seller",
+			userID: 1,
+			mockBehavior: func(r *mock.MockGoodsMongoRepo, userID int) {
+				r.EXPECT().GetSellerByUserID(userID).Return(GoodService.Seller{}, errors.New("db error"))
+			},
+			expectedDeleted: 0,
+			expectedError:   errors.New("db error"),
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mongoRep := mock.NewMockGoodsMongoRepo(c)
+			testCase.mockBehavior(mongoRep, testCase.userID)
+
+			serv := GoodService.NewGoodService(mongoRep, nil, nil)
+
+			deleted, err := serv.DeleteUserData(testCase.userID)
+
+			assert.Equal(t, deleted, testCase.expectedDeleted)
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package GoodService
+
+import (
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	GoodService "github.com/jst-Frenzy/ControlSystem/GoodsService/internal/GoodService"
+	mock "github.com/jst-Frenzy/ControlSystem/GoodsService/internal/mocks"
+	"testing"
+)
+
+func TestService_addVariant(t *testing.T) {
+	item := GoodService.Item{
+		ID:       "1",
+		SellerID: "100",
+		Quantity: 3,
+		Variants: []GoodService.Variant{
+			{SKU: "TS-M-RED", Attributes: map[string]string{"size": "M", "colour": "red"}, Quantity: 3},
+		},
+		VariantsVersion: 4,
+	}
+
+	testTable := []struct {
+		name             string
+		inputVariant     GoodService.Variant
+		expectedVariants []GoodService.Variant
+		expectedQuantity int
+		expectedError    error
+	}{
+		{
+			name:         "OK",
+			inputVariant: GoodService.Variant{SKU: " TS-L-RED ", Attributes: map[string]string{" Size": "L ", "Colour": "red"}, Quantity: 5},
+			expectedVariants: []GoodService.Variant{
+				{SKU: "TS-M-RED", Attributes: map[string]string{"size": "M", "colour": "red"}, Quantity: 3},
+				{SKU: "TS-L-RED", Attributes: map[string]string{"size": "L", "colour": "red"}, Quantity: 5},
+			},
+			expectedQuantity: 8,
+		},
+		{
+			name:          "Other Attributes",
+			inputVariant:  GoodService.Variant{SKU: "TS-L", Attributes: map[string]string{"size": "L"}, Quantity: 5},
+			expectedError: GoodService.ErrVariantAttributes,
+		},
+		{
+			name:          "Same Attributes",
+			inputVariant:  GoodService.Variant{SKU: "TS-M-RED-2", Attributes: map[string]string{"size": "m", "colour": "Red"}, Quantity: 5},
+			expectedError: GoodService.ErrDuplicateVariant,
+		},
+		{
+			name:          "SKU Of Another Variant",
+			inputVariant:  GoodService.Variant{SKU: "ts-m-red", Attributes: map[string]string{"size": "L", "colour": "red"}, Quantity: 5},
+			expectedError: GoodService.ErrSKUTaken,
+		},
+		{
+			name:          "Invalid SKU",
+			inputVariant:  GoodService.Variant{SKU: "TS L", Attributes: map[string]string{"size": "L", "colour": "red"}, Quantity: 5},
+			expectedError: GoodService.ErrInvalidVariant,
+		},
+		{
+			name:          "Negative Quantity",
+			inputVariant:  GoodService.Variant{SKU: "TS-L-RED", Attributes: map[string]string{"size": "L", "colour": "red"}, Quantity: -1},
+			expectedError: GoodService.ErrInvalidVariant,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mongoRep := mock.NewMockGoodsMongoRepo(c)
+			mongoRep.EXPECT().GetSellerIDByUserID(1).Return("100", nil)
+			mongoRep.EXPECT().GetItemByID("1").Return(item, nil)
+			if testCase.expectedError == nil {
+				mongoRep.EXPECT().SetItemVariants("1", 4, testCase.expectedVariants, testCase.expectedQuantity).Return(nil)
+			}
+
+			serv := GoodService.NewGoodService(mongoRep, nil, nil)
+
+			got, err := serv.AddVariant("1", 1, testCase.inputVariant)
+
+			assert.Equal(t, err, testCase.expectedError)
+			if err == nil {
+				assert.Equal(t, got.Variants, testCase.expectedVariants)
+				assert.Equal(t, got.Quantity, testCase.expectedQuantity)
+				assert.Equal(t, got.VariantsVersion, 5)
+			}
+		})
+	}
+}
+
+func TestService_updateVariant(t *testing.T) {
+	price := 25.0
+	item := GoodService.Item{
+		ID:       "1",
+		SellerID: "100",
+		Quantity: 8,
+		Variants: []GoodService.Variant{
+			{SKU: "TS-M", Attributes: map[string]string{"size": "M"}, Quantity: 3},
+			{SKU: "TS-L", Attributes: map[string]string{"size": "L"}, Quantity: 5},
+		},
+		VariantsVersion: 2,
+	}
+
+	testTable := []struct {
+		name          string
+		inputVariant  GoodService.Variant
+		mockBehavior  func(r *mock.MockGoodsMongoRepo)
+		expectedError error
+	}{
+		{
+			name:         "OK",
+			inputVariant: GoodService.Variant{SKU: "TS-L", Attributes: map[string]string{"size": "L"}, Quantity: 1, Price: &price},
+			mockBehavior: func(r *mock.MockGoodsMongoRepo) {
+				r.EXPECT().SetItemVariants("1", 2, []GoodService.Variant{
+					{SKU: "TS-M", Attributes: map[string]string{"size": "M"}, Quantity: 3},
+					{SKU: "TS-L", Attributes: map[string]string{"size": "L"}, Quantity: 1, Price: &price},
+				}, 4).Return(nil)
+			},
+		},
+		{
+			name:          "Unknown SKU",
+			inputVariant:  GoodService.Variant{SKU: "TS-XL", Attributes: map[string]string{"size": "XL"}, Quantity: 1},
+			mockBehavior:  func(r *mock.MockGoodsMongoRepo) {},
+			expectedError: GoodService.ErrVariantNotFound,
+		},
+		{
+			name:          "Attributes Of Another Variant",
+			inputVariant:  GoodService.Variant{SKU: "TS-L", Attributes: map[string]string{"size": "M"}, Quantity: 1},
+			mockBehavior:  func(r *mock.MockGoodsMongoRepo) {},
+			expectedError: GoodService.ErrDuplicateVariant,
+		},
+		{
+			name:         "Changed Meanwhile",
+			inputVariant: GoodService.Variant{SKU: "TS-L", Attributes: map[string]string{"size": "L"}, Quantity: 1},
+			mockBehavior: func(r *mock.MockGoodsMongoRepo) {
+				r.EXPECT().SetItemVariants("1", 2, gomock.Any(), 4).Return(GoodService.ErrVariantsChanged)
+			},
+			expectedError: GoodService.ErrVariantsChanged,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mongoRep := mock.NewMockGoodsMongoRepo(c)
+			mongoRep.EXPECT().GetSellerIDByUserID(1).Return("100", nil)
+			mongoRep.EXPECT().GetItemByID("1").Return(item, nil)
+			testCase.mockBehavior(mongoRep)
+
+			serv := GoodService.NewGoodService(mongoRep, nil, nil)
+
+			_, err := serv.UpdateVariant("1", 1, testCase.inputVariant)
+
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
+
+func TestService_deleteVariant(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	item := GoodService.Item{
+		ID:       "1",
+		SellerID: "100",
+		Quantity: 3,
+		Variants: []GoodService.Variant{
+			{SKU: "TS-M", Attributes: map[string]string{"size": "M"}, Quantity: 3},
+		},
+		VariantsVersion: 1,
+	}
+
+	mongoRep := mock.NewMockGoodsMongoRepo(c)
+	mongoRep.EXPECT().GetSellerIDByUserID(1).Return("100", nil)
+	mongoRep.EXPECT().GetItemByID("1").Return(item, nil)
+	mongoRep.EXPECT().SetItemVariants("1", 1, []GoodService.Variant{}, 0).Return(nil)
+
+	serv := GoodService.NewGoodService(mongoRep, nil, nil)
+
+	got, err := serv.DeleteVariant("1", 1, "TS-M")
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(got.Variants), 0)
+	assert.Equal(t, got.Quantity, 0)
+}
+
+func TestService_getItemInfoForCartVariant(t *testing.T) {
+	price := 12.5
+	info := GoodService.ItemInfoForCart{
+		Quantity: 7,
+		Price:    10,
+		Variants: []GoodService.Variant{
+			{SKU: "TS-M", Attributes: map[string]string{"size": "M"}, Quantity: 3},
+			{SKU: "TS-L", Attributes: map[string]string{"size": "L"}, Quantity: 4, Price: &price},
+		},
+	}
+
+	testTable := []struct {
+		name          string
+		inputSKU      string
+		expectedInfo  GoodService.ItemInfoForCart
+		expectedError error
+	}{
+		{
+			name:         "Item Price",
+			inputSKU:     "TS-M",
+			expectedInfo: GoodService.ItemInfoForCart{Quantity: 3, Price: 10},
+		},
+		{
+			name:         "Variant Price",
+			inputSKU:     "TS-L",
+			expectedInfo: GoodService.ItemInfoForCart{Quantity: 4, Price: 12.5},
+		},
+		{
+			name:          "No SKU",
+			expectedError: GoodService.ErrSKURequired,
+		},
+		{
+			name:          "Unknown SKU",
+			inputSKU:      "TS-XL",
+			expectedError: GoodService.ErrVariantNotFound,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mongoRep := mock.NewMockGoodsMongoRepo(c)
+			mongoRep.EXPECT().GetItemInfoForCart("1").Return(info, nil)
+
+			serv := GoodService.NewGoodService(mongoRep, nil, nil)
+
+			inf, err := serv.GetItemInfoForCart("1", testCase.inputSKU)
+
+			assert.Equal(t, inf, testCase.expectedInfo)
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
@@ -13,6 +13,7 @@ import (
 
 type AuthClient interface {
 	ValidateToken(ctx context.Context, token string) (*gen.ValidateTokenResponse, error)
+	ValidateAPIKey(ctx context.Context, apiKey string) (*gen.ValidateTokenResponse, error)
 	Close() error
 }
 
@@ -42,6 +43,15 @@ func (c *authClient) ValidateToken(ctx context.Context, token string) (*gen.Vali
 	return c.client.ValidateToken(ctx, req)
 }
 
+func (c *authClient) ValidateAPIKey(ctx context.Context, apiKey string) (*gen.ValidateTokenResponse, error) {
+	req := &gen.ValidateAPIKeyRequest{ApiKey: apiKey}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	return c.client.ValidateAPIKey(ctx, req)
+}
+
 func (c *authClient) Close() error {
 	if c.conn != nil {
 		return c.conn.Close()
@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	grpc_logrus "github.com/grpc-ecosystem/go-grpc-middleware/logging/logrus"
@@ -14,6 +15,7 @@ import (
 	"google.golang.org/grpc/status"
 	"net"
 	"strconv"
+	"time"
 )
 
 type Deps struct {
@@ -71,8 +73,15 @@ func (s *Server) GetItemQuantityAndPrice(ctx context.Context, req *gen.ItemQuant
 		return nil, status.Errorf(codes.InvalidArgument, "item id is required")
 	}
 
-	info, err := s.goodsService.GetItemInfoForCart(req.GetItemId())
+	info, err := s.goodsService.GetItemInfoForCart(req.GetItemId(), req.GetSku())
 	if err != nil {
+		// carts tell lines that can't be priced any more from GoodsService being unavailable by these codes
+		switch {
+		case errors.Is(err, GoodService.ErrSKURequired):
+			return &gen.ItemQuantityAndPriceResponse{Valid: false}, status.Error(codes.InvalidArgument, err.Error())
+		case errors.Is(err, GoodService.ErrItemNotFound), errors.Is(err, GoodService.ErrVariantNotFound):
+			return &gen.ItemQuantityAndPriceResponse{Valid: false}, status.Error(codes.NotFound, err.Error())
+		}
 		return &gen.ItemQuantityAndPriceResponse{Valid: false}, err
 	}
 
@@ -82,3 +91,112 @@ func (s *Server) GetItemQuantityAndPrice(ctx context.Context, req *gen.ItemQuant
 		Price:    strconv.FormatFloat(info.Price, 'f', 2, 64),
 	}, nil
 }
+
+func (s *Server) ExportUserData(ctx context.Context, req *gen.UserDataRequest) (*gen.ExportUserDataResponse, error) {
+	if req.GetUserId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "user id is required")
+	}
+
+	seller, items, err := s.goodsService.ExportUserData(int(req.GetUserId()))
+	if err != nil {
+		return nil, status.Error(codes.Internal, "can't export user data")
+	}
+
+	resp := &gen.ExportUserDataResponse{
+		SellerId:   seller.ID,
+		SellerName: seller.Name,
+		Items:      make([]*gen.SellerItem, 0, len(items)),
+	}
+	for _, i := range items {
+		resp.Items = append(resp.Items, &gen.SellerItem{
+			Id:          i.ID,
+			Name:        i.Name,
+			Description: i.Description,
+			Quantity:    int64(i.Quantity),
+			Price:       i.Price,
+		})
+	}
+
+	return resp, nil
+}
+
+func (s *Server) DeleteUserData(ctx context.Context, req *gen.UserDataRequest) (*gen.DeleteUserDataResponse, error) {
+	if req.GetUserId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "user id is required")
+	}
+
+	deleted, err := s.goodsService.DeleteUserData(int(req.GetUserId()))
+	if err != nil {
+		return nil, status.Error(codes.Internal, "can't delete user data")
+	}
+
+	return &gen.DeleteUserDataResponse{DeletedItems: deleted}, nil
+}
+
+func (s *Server) ReserveStock(ctx context.Context, req *gen.ReserveStockRequest) (*gen.ReservationResponse, error) {
+	if req.GetIdempotencyKey() == "" || len(req.GetLines()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "idempotency key and lines are required")
+	}
+
+	lines := make([]GoodService.ReservationLine, 0, len(req.GetLines()))
+	for _, l := range req.GetLines() {
+		lines = append(lines, GoodService.ReservationLine{ItemID: l.GetItemId(), SKU: l.GetSku(), Quantity: int(l.GetQuantity())})
+	}
+
+	res, err := s.goodsService.ReserveStock(req.GetIdempotencyKey(), lines, time.Duration(req.GetTtlSeconds())*time.Second)
+	if err != nil {
+		return nil, reservationError(err)
+	}
+
+	return reservationResponse(res), nil
+}
+
+func (s *Server) CommitReservation(ctx context.Context, req *gen.ReservationRequest) (*gen.ReservationResponse, error) {
+	if req.GetReservationId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "reservation id is required")
+	}
+
+	res, err := s.goodsService.CommitReservation(req.GetReservationId())
+	if err != nil {
+		return nil, reservationError(err)
+	}
+
+	return reservationResponse(res), nil
+}
+
+func (s *Server) ReleaseReservation(ctx context.Context, req *gen.ReservationRequest) (*gen.ReservationResponse, error) {
+	if req.GetReservationId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "reservation id is required")
+	}
+
+	res, err := s.goodsService.ReleaseReservation(req.GetReservationId())
+	if err != nil {
+		return nil, reservationError(err)
+	}
+
+	return reservationResponse(res), nil
+}
+
+func reservationResponse(res GoodService.Reservation) *gen.ReservationResponse {
+	return &gen.ReservationResponse{
+		ReservationId: res.ID,
+		Status:        res.Status,
+		ExpiresAt:     res.ExpiresAt.Unix(),
+	}
+}
+
+func reservationError(err error) error {
+	switch {
+	case errors.Is(err, GoodService.ErrInvalidReservation), errors.Is(err, GoodService.ErrSKURequired):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, GoodService.ErrReservationNotFound), errors.Is(err, GoodService.ErrItemNotFound),
+		errors.Is(err, GoodService.ErrVariantNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, GoodService.ErrInsufficientStock), errors.Is(err, GoodService.ErrReservationExpired),
+		errors.Is(err, GoodService.ErrReservationReleased), errors.Is(err, GoodService.ErrReservationCommitted):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, GoodService.ErrIdempotencyKeyReused):
+		return status.Error(codes.AlreadyExists, err.Error())
+	}
+	return status.Error(codes.Internal, "can't process reservation")
+}
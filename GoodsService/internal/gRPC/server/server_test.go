@@ -11,6 +11,7 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"testing"
+	"time"
 )
 
 func TestServer_GetItemQuantityAndPrice(t *testing.T) {
@@ -29,7 +30,7 @@ func TestServer_GetItemQuantityAndPrice(t *testing.T) {
 			inputItemIdRequest: &gen.ItemQuantityAndPriceRequest{ItemId: "Object id"},
 			inputItemId:        "Object id",
 			mockBehavior: func(s *mock.MockGoodService, itemID string) {
-				s.EXPECT().GetItemInfoForCart(itemID).Return(GoodService.ItemInfoForCart{
+				s.EXPECT().GetItemInfoForCart(itemID, "").Return(GoodService.ItemInfoForCart{
 					Quantity: 6,
 					Price:    15,
 				}, nil)
@@ -54,11 +55,31 @@ func TestServer_GetItemQuantityAndPrice(t *testing.T) {
 			inputItemIdRequest: &gen.ItemQuantityAndPriceRequest{ItemId: "Object id"},
 			inputItemId:        "Object id",
 			mockBehavior: func(s *mock.MockGoodService, itemID string) {
-				s.EXPECT().GetItemInfoForCart(itemID).Return(GoodService.ItemInfoForCart{}, errors.New("can't get info"))
+				s.EXPECT().GetItemInfoForCart(itemID, "").Return(GoodService.ItemInfoForCart{}, errors.New("can't get info"))
 			},
 			expectedItemInfoResponse: &gen.ItemQuantityAndPriceResponse{},
 			expectedError:            errors.New("can't get info"),
 		},
+		{
+			name:               "No SKU For Item With Variants",
+			inputItemIdRequest: &gen.ItemQuantityAndPriceRequest{ItemId: "Object id"},
+			inputItemId:        "Object id",
+			mockBehavior: func(s *mock.MockGoodService, itemID string) {
+				s.EXPECT().GetItemInfoForCart(itemID, "").Return(GoodService.ItemInfoForCart{}, GoodService.ErrSKURequired)
+			},
+			expectedItemInfoResponse: &gen.ItemQuantityAndPriceResponse{},
+			expectedError:            status.Error(codes.InvalidArgument, GoodService.ErrSKURequired.Error()),
+		},
+		{
+			name:               "Item Not Found",
+			inputItemIdRequest: &gen.ItemQuantityAndPriceRequest{ItemId: "Object id"},
+			inputItemId:        "Object id",
+			mockBehavior: func(s *mock.MockGoodService, itemID string) {
+				s.EXPECT().GetItemInfoForCart(itemID, "").Return(GoodService.ItemInfoForCart{}, GoodService.ErrItemNotFound)
+			},
+			expectedItemInfoResponse: &gen.ItemQuantityAndPriceResponse{},
+			expectedError:            status.Error(codes.NotFound, GoodService.ErrItemNotFound.Error()),
+		},
 	}
 
 	for _, testCase := range testTable {
@@ -81,3 +102,164 @@ func TestServer_GetItemQuantityAndPrice(t *testing.T) {
 		})
 	}
 }
+
+func TestServer_DeleteUserData(t *testing.T) {
+	type mockBehavior func(s *mock.MockGoodService, userID int)
+
+	testTable := []struct {
+		name             string
+		inputUserID      int64
+		mockBehavior     mockBehavior
+		expectedResponse *gen.DeleteUserDataResponse
+		expectedError    error
+	}{
+		{
+			name:        "OK",
+			inputUserID: 1,
+			mockBehavior: func(s *mock.MockGoodService, userID int) {
+				s.EXPECT().DeleteUserData(userID).Return(int64(2), nil)
+			},
+			expectedResponse: &gen.DeleteUserDataResponse{DeletedItems: 2},
+			expectedError:    nil,
+		},
+		{
+			name:             "Empty user id",
+			inputUserID:      0,
+			mockBehavior:     func(s *mock.MockGoodService, userID int) {},
+			expectedResponse: nil,
+			expectedError:    status.Error(codes.InvalidArgument, "user id is required"),
+		},
+		{
+			name:        "Fail delete",
+			inputUserID: 1,
+			mockBehavior: func(s *mock.MockGoodService, userID int) {
+				s.EXPECT().DeleteUserData(userID).Return(int64(0), errors.New("db error"))
+			},
+			expectedResponse: nil,
+			expectedError:    status.Error(codes.Internal, "can't delete user data"),
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			servMock := mock.NewMockGoodService(c)
+			testCase.mockBehavior(servMock, int(testCase.inputUserID))
+
+			gRPCServ := NewGRPCServer(Deps{
+				GoodsService: servMock,
+				Logger:       nil,
+			})
+
+			resp, err := gRPCServ.DeleteUserData(context.Background(), &gen.UserDataRequest{UserId: testCase.inputUserID})
+
+			assert.Equal(t, resp, testCase.expectedResponse)
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
+
+func TestServer_ReserveStock(t *testing.T) {
+	type mockBehavior func(s *mock.MockGoodService)
+
+	expiresAt := time.Unix(1760000000, 0)
+	request := &gen.ReserveStockRequest{
+		IdempotencyKey: "order-7",
+		Lines:          []*gen.ReservationLine{{ItemId: "1", Sku: "TS-M", Quantity: 2}},
+		TtlSeconds:     60,
+	}
+	lines := []GoodService.ReservationLine{{ItemID: "1", SKU: "TS-M", Quantity: 2}}
+
+	testTable := []struct {
+		name             string
+		inputRequest     *gen.ReserveStockRequest
+		mockBehavior     mockBehavior
+		expectedResponse *gen.ReservationResponse
+		expectedError    error
+	}{
+		{
+			name:         "OK",
+			inputRequest: request,
+			mockBehavior: func(s *mock.MockGoodService) {
+				s.EXPECT().ReserveStock("order-7", lines, time.Minute).Return(GoodService.Reservation{
+					ID: "r1", Lines: lines, Status: GoodService.ReservationPending, ExpiresAt: expiresAt,
+				}, nil)
+			},
+			expectedResponse: &gen.ReservationResponse{ReservationId: "r1", Status: "pending", ExpiresAt: 1760000000},
+		},
+		{
+			name:             "No Idempotency Key",
+			inputRequest:     &gen.ReserveStockRequest{Lines: request.Lines},
+			mockBehavior:     func(s *mock.MockGoodService) {},
+			expectedResponse: nil,
+			expectedError:    status.Error(codes.InvalidArgument, "idempotency key and lines are required"),
+		},
+		{
+			name:         "Not Enough Stock",
+			inputRequest: request,
+			mockBehavior: func(s *mock.MockGoodService) {
+				s.EXPECT().ReserveStock("order-7", lines, time.Minute).Return(GoodService.Reservation{}, GoodService.ErrInsufficientStock)
+			},
+			expectedResponse: nil,
+			expectedError:    status.Error(codes.FailedPrecondition, "not enough stock"),
+		},
+		{
+			name:         "Key Reused",
+			inputRequest: request,
+			mockBehavior: func(s *mock.MockGoodService) {
+				s.EXPECT().ReserveStock("order-7", lines, time.Minute).Return(GoodService.Reservation{}, GoodService.ErrIdempotencyKeyReused)
+			},
+			expectedResponse: nil,
+			expectedError:    status.Error(codes.AlreadyExists, "idempotency key was used for another reservation"),
+		},
+		{
+			name:         "Fail reserve",
+			inputRequest: request,
+			mockBehavior: func(s *mock.MockGoodService) {
+				s.EXPECT().ReserveStock("order-7", lines, time.Minute).Return(GoodService.Reservation{}, errors.New("db error"))
+			},
+			expectedResponse: nil,
+			expectedError:    status.Error(codes.Internal, "can't process reservation"),
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			servMock := mock.NewMockGoodService(c)
+			testCase.mockBehavior(servMock)
+
+			gRPCServ := NewGRPCServer(Deps{
+				GoodsService: servMock,
+				Logger:       nil,
+			})
+
+			resp, err := gRPCServ.ReserveStock(context.Background(), testCase.inputRequest)
+
+			assert.Equal(t, resp, testCase.expectedResponse)
+			assert.Equal(t, err, testCase.expectedError)
+		})
+	}
+}
+
+func TestServer_CommitReservation(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	servMock := mock.NewMockGoodService(c)
+	servMock.EXPECT().CommitReservation("r1").Return(GoodService.Reservation{}, GoodService.ErrReservationExpired)
+
+	gRPCServ := NewGRPCServer(Deps{
+		GoodsService: servMock,
+		Logger:       nil,
+	})
+
+	resp, err := gRPCServ.CommitReservation(context.Background(), &gen.ReservationRequest{ReservationId: "r1"})
+
+	assert.Equal(t, resp, (*gen.ReservationResponse)(nil))
+	assert.Equal(t, err, status.Error(codes.FailedPrecondition, "reservation expired"))
+}
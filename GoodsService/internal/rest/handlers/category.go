@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/jst-Frenzy/ControlSystem/GoodsService/internal/GoodService"
+	"net/http"
+)
+
+func (h *GoodsHandlers) GetCategories(ctx *gin.Context) {
+	nameHandler := "GetCategories"
+	tree, err := h.serv.GetCategories()
+	if err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"categories": tree})
+}
+
+func (h *GoodsHandlers) GetCategoryItems(ctx *gin.Context) {
+	nameHandler := "GetCategoryItems"
+	var q GoodService.CatalogQuery
+	if err := ctx.ShouldBindQuery(&q); err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "invalid query")
+		return
+	}
+
+	page, err := h.serv.GetCategoryItems(ctx.Param("id"), q)
+	if err != nil {
+		if errors.Is(err, GoodService.ErrCategoryNotFound) {
+			newErrorResponse(ctx, nameHandler, http.StatusNotFound, err.Error())
+			return
+		}
+		newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, page)
+}
+
+func (h *GoodsHandlers) CreateCategory(ctx *gin.Context) {
+	nameHandler := "CreateCategory"
+	var in GoodService.CategoryInput
+	if err := ctx.ShouldBindJSON(&in); err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "invalid input body")
+		return
+	}
+
+	category, err := h.serv.CreateCategory(in)
+	if err != nil {
+		newErrorResponse(ctx, nameHandler, categoryErrorStatus(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, category)
+}
+
+func (h *GoodsHandlers) UpdateCategory(ctx *gin.Context) {
+	nameHandler := "UpdateCategory"
+	var in GoodService.CategoryInput
+	if err := ctx.ShouldBindJSON(&in); err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "invalid input body")
+		return
+	}
+
+	category, err := h.serv.UpdateCategory(ctx.Param("id"), in)
+	if err != nil {
+		newErrorResponse(ctx, nameHandler, categoryErrorStatus(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, category)
+}
+
+func (h *GoodsHandlers) DeleteCategory(ctx *gin.Context) {
+	nameHandler := "DeleteCategory"
+	if err := h.serv.DeleteCategory(ctx.Param("id")); err != nil {
+		newErrorResponse(ctx, nameHandler, categoryErrorStatus(err), err.Error())
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func categoryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, GoodService.ErrCategoryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, GoodService.ErrInvalidCategoryName), errors.Is(err, GoodService.ErrInvalidCategorySlug),
+		errors.Is(err, GoodService.ErrParentCategoryNotFound), errors.Is(err, GoodService.ErrCategoryCycle):
+		return http.StatusBadRequest
+	case errors.Is(err, GoodService.ErrCategorySlugTaken), errors.Is(err, GoodService.ErrCategoryHasChildren):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/jst-Frenzy/ControlSystem/GoodsService/internal/GoodService"
+	mock "github.com/jst-Frenzy/ControlSystem/GoodsService/internal/mocks"
+	"github.com/jst-Frenzy/ControlSystem/protobuf/permissions"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler_createCategory(t *testing.T) {
+	type mockBehavior func(s *mock.MockGoodService)
+
+	testTable := []struct {
+		name                 string
+		inputBody            string
+		userPermissions      []string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:            "OK",
+			inputBody:       `{"name": "Boots", "parentID": "shoes"}`,
+			userPermissions: []string{permissions.CatalogManage},
+			mockBehavior: func(s *mock.MockGoodService) {
+				s.EXPECT().CreateCategory(GoodService.CategoryInput{Name: "Boots", ParentID: "shoes"}).
+					Return(GoodService.Category{ID: "boots", Name: "Boots", Slug: "boots", ParentID: "shoes"}, nil)
+			},
+			expectedStatusCode:   201,
+			expectedResponseBody: `{"_id":"boots","name":"Boots","slug":"boots","parentID":"shoes"}`,
+		},
+		{
+			name:                 "Not Enough Rights",
+			inputBody:            `{"name": "Boots"}`,
+			userPermissions:      []string{permissions.GoodsWrite},
+			mockBehavior:         func(s *mock.MockGoodService) {},
+			expectedStatusCode:   403,
+			expectedResponseBody: `{"Message":"not enough rights"}`,
+		},
+		{
+			name:                 "Invalid Body",
+			inputBody:            `{"slug": "boots"}`,
+			userPermissions:      []string{permissions.CatalogManage},
+			mockBehavior:         func(s *mock.MockGoodService) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"Message":"invalid input body"}`,
+		},
+		{
+			name:            "Parent Not Found",
+			inputBody:       `{"name": "Boots", "parentID": "summer"}`,
+			userPermissions: []string{permissions.CatalogManage},
+			mockBehavior: func(s *mock.MockGoodService) {
+				s.EXPECT().CreateCategory(GoodService.CategoryInput{Name: "Boots", ParentID: "summer"}).
+					Return(GoodService.Category{}, GoodService.ErrParentCategoryNotFound)
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"Message":"parent category not found"}`,
+		},
+		{
+			name:            "Slug Taken",
+			inputBody:       `{"name": "Boots"}`,
+			userPermissions: []string{permissions.CatalogManage},
+			mockBehavior: func(s *mock.MockGoodService) {
+				s.EXPECT().CreateCategory(GoodService.CategoryInput{Name: "Boots"}).
+					Return(GoodService.Category{}, GoodService.ErrCategorySlugTaken)
+			},
+			expectedStatusCode:   409,
+			expectedResponseBody: `{"Message":"category slug is taken"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			goodService := mock.NewMockGoodService(c)
+			testCase.mockBehavior(goodService)
+
+			authClient := mock.NewMockAuthClient(c)
+
+			handler := NewGoodsHandlers(goodService, authClient)
+
+			r := gin.New()
+			r.POST("/categories", func(ctx *gin.Context) {
+				ctx.Set("userPermissions", testCase.userPermissions)
+			}, handler.RequirePermission(permissions.CatalogManage), handler.CreateCategory)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/categories", bytes.NewBufferString(testCase.inputBody))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_getCategoryItems(t *testing.T) {
+	type mockBehavior func(s *mock.MockGoodService)
+
+	testTable := []struct {
+		name                 string
+		inputURL             string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:     "OK",
+			inputURL: "/categories/shoes/items?limit=10",
+			mockBehavior: func(s *mock.MockGoodService) {
+				shoes := GoodService.CategoryRef{ID: "shoes", Name: "Shoes", Slug: "shoes"}
+				s.EXPECT().GetCategoryItems("shoes", GoodService.CatalogQuery{Limit: 10}).Return(GoodService.CategoryPage{
+					Category:   GoodService.Category{ID: "shoes", Name: "Shoes", Slug: "shoes"},
+					Breadcrumb: []GoodService.CategoryRef{shoes},
+					Items: []GoodService.Item{{
+						ID:          "1",
+						Name:        "Sneakers",
+						Description: "white",
+						Price:       50,
+						CategoryIDs: []string{"shoes"},
+						Breadcrumbs: [][]GoodService.CategoryRef{{shoes}},
+					}},
+					Total: 1,
+				}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: `{"category":{"_id":"shoes","name":"Shoes","slug":"shoes"},` +
+				`"breadcrumb":[{"_id":"shoes","name":"Shoes","slug":"shoes"}],` +
+				`"items":[{"_id":"1","name":"Sneakers","description":"white","quantity":0,"price":50,"sellerID":"",` +
+				`"categoryIDs":["shoes"],"breadcrumbs":[[{"_id":"shoes","name":"Shoes","slug":"shoes"}]]}],"total":1}`,
+		},
+		{
+			name:     "Not Found",
+			inputURL: "/categories/toys/items",
+			mockBehavior: func(s *mock.MockGoodService) {
+				s.EXPECT().GetCategoryItems("toys", GoodService.CatalogQuery{}).Return(GoodService.CategoryPage{}, GoodService.ErrCategoryNotFound)
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"Message":"category not found"}`,
+		},
+		{
+			name:     "Service Failure",
+			inputURL: "/categories/shoes/items",
+			mockBehavior: func(s *mock.MockGoodService) {
+				s.EXPECT().GetCategoryItems("shoes", GoodService.CatalogQuery{}).Return(GoodService.CategoryPage{}, errors.New("DB Failure"))
+			},
+			expectedStatusCode:   500,
+			expectedResponseBody: `{"Message":"DB Failure"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			goodService := mock.NewMockGoodService(c)
+			testCase.mockBehavior(goodService)
+
+			authClient := mock.NewMockAuthClient(c)
+
+			handler := NewGoodsHandlers(goodService, authClient)
+
+			r := gin.New()
+			r.GET("/categories/:id/items", handler.GetCategoryItems)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", testCase.inputURL, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_deleteCategory(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	goodService := mock.NewMockGoodService(c)
+	goodService.EXPECT().DeleteCategory("shoes").Return(GoodService.ErrCategoryHasChildren)
+
+	handler := NewGoodsHandlers(goodService, mock.NewMockAuthClient(c))
+
+	r := gin.New()
+	r.DELETE("/categories/:id", handler.DeleteCategory)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("DELETE", "/categories/shoes", nil))
+
+	assert.Equal(t, 409, w.Code)
+	assert.Equal(t, `{"Message":"category has subcategories"}`, w.Body.String())
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/jst-Frenzy/ControlSystem/GoodsService/internal/GoodService"
 	"github.com/jst-Frenzy/ControlSystem/GoodsService/internal/gRPC/client"
@@ -21,30 +22,28 @@ func NewGoodsHandlers(serv GoodService.GoodService, authClient client.AuthClient
 
 func (h *GoodsHandlers) GetGoods(ctx *gin.Context) {
 	nameHandler := "GetGoods"
-	items, err := h.serv.GetGoods()
-	if err != nil {
-		newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+	var q GoodService.GoodsQuery
+	if err := ctx.ShouldBindQuery(&q); err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "invalid query")
 		return
 	}
 
-	resp := make(map[string]string)
-
-	for _, i := range items {
-		resp[i.Name] = i.Description
+	page, err := h.serv.GetGoods(q)
+	if err != nil {
+		if errors.Is(err, GoodService.ErrInvalidSort) || errors.Is(err, GoodService.ErrInvalidCursor) ||
+			errors.Is(err, GoodService.ErrInvalidPriceRange) {
+			newErrorResponse(ctx, nameHandler, http.StatusBadRequest, err.Error())
+			return
+		}
+		newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+		return
 	}
 
-	ctx.JSON(http.StatusOK, resp)
+	ctx.JSON(http.StatusOK, page)
 }
 
 func (h *GoodsHandlers) AddItem(ctx *gin.Context) {
 	nameHandler := "AddItem"
-	role := ctx.MustGet("userRole")
-
-	if role != "seller" {
-		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "not enough rights")
-		return
-	}
-
 	var i GoodService.Item
 	if err := ctx.ShouldBind(&i); err != nil {
 		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "invalid input body")
@@ -61,7 +60,11 @@ func (h *GoodsHandlers) AddItem(ctx *gin.Context) {
 
 	id, err := h.serv.AddItem(i, s)
 	if err != nil {
-		newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+		if errors.Is(err, GoodService.ErrCategoryNotFound) {
+			newErrorResponse(ctx, nameHandler, http.StatusBadRequest, err.Error())
+			return
+		}
+		newErrorResponse(ctx, nameHandler, variantErrorStatus(err), err.Error())
 		return
 	}
 
@@ -70,13 +73,6 @@ func (h *GoodsHandlers) AddItem(ctx *gin.Context) {
 
 func (h *GoodsHandlers) DeleteItem(ctx *gin.Context) {
 	nameHandler := "DeleteItem"
-	role := ctx.MustGet("userRole")
-
-	if role != "seller" {
-		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "not enough rights")
-		return
-	}
-
 	itemID := ctx.Param("id")
 
 	userID := ctx.MustGet("userID").(int)
@@ -92,13 +88,6 @@ func (h *GoodsHandlers) DeleteItem(ctx *gin.Context) {
 
 func (h *GoodsHandlers) UpdateItem(ctx *gin.Context) {
 	nameHandler := "UpdateItem"
-	role := ctx.MustGet("userRole")
-
-	if role != "seller" {
-		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "not enough rights")
-		return
-	}
-
 	var i GoodService.Item
 	if err := ctx.ShouldBind(&i); err != nil {
 		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "invalid input body")
@@ -109,9 +98,38 @@ func (h *GoodsHandlers) UpdateItem(ctx *gin.Context) {
 
 	respItem, err := h.serv.UpdateItem(i, userID)
 	if err != nil {
+		if errors.Is(err, GoodService.ErrCategoryNotFound) {
+			newErrorResponse(ctx, nameHandler, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, GoodService.ErrStockReserved) {
+			newErrorResponse(ctx, nameHandler, http.StatusConflict, err.Error())
+			return
+		}
 		newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
 		return
 	}
 
 	ctx.JSON(http.StatusOK, respItem)
 }
+
+func (h *GoodsHandlers) Search(ctx *gin.Context) {
+	nameHandler := "Search"
+	var q GoodService.SearchQuery
+	if err := ctx.ShouldBindQuery(&q); err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "invalid query")
+		return
+	}
+
+	result, err := h.serv.Search(q)
+	if err != nil {
+		if errors.Is(err, GoodService.ErrEmptySearchQuery) {
+			newErrorResponse(ctx, nameHandler, http.StatusBadRequest, err.Error())
+			return
+		}
+		newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
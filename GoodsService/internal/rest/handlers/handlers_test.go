@@ -8,6 +8,7 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/jst-Frenzy/ControlSystem/GoodsService/internal/GoodService"
 	mock "github.com/jst-Frenzy/ControlSystem/GoodsService/internal/mocks"
+	"github.com/jst-Frenzy/ControlSystem/protobuf/permissions"
 	"net/http/httptest"
 	"testing"
 )
@@ -21,7 +22,7 @@ func TestHandler_addItem(t *testing.T) {
 		inputItem            GoodService.Item
 		inputUserCtx         GoodService.UserCtx
 		mockBehavior         mockBehavior
-		userRole             string
+		userPermissions      []string
 		userName             string
 		userID               int
 		expectedStatusCode   int
@@ -43,24 +44,24 @@ func TestHandler_addItem(t *testing.T) {
 			mockBehavior: func(s *mock.MockGoodService, i GoodService.Item, u GoodService.UserCtx) {
 				s.EXPECT().AddItem(i, u).Return("itemID", nil)
 			},
-			userRole:             "seller",
+			userPermissions:      []string{permissions.GoodsWrite},
 			userName:             "testName",
 			userID:               1,
 			expectedStatusCode:   201,
 			expectedResponseBody: `{"id":"itemID"}`,
 		},
 		{
-			name:                 "Incorrect Role",
+			name:                 "Missing Permission",
 			mockBehavior:         func(s *mock.MockGoodService, i GoodService.Item, u GoodService.UserCtx) {},
-			userRole:             "user",
-			expectedStatusCode:   400,
+			userPermissions:      []string{"cart:manage"},
+			expectedStatusCode:   403,
 			expectedResponseBody: `{"Message":"not enough rights"}`,
 		},
 		{
 			name:                 "Empty Fields",
 			inputBody:            `{"name": "testName", "quantity": 1}`,
 			mockBehavior:         func(s *mock.MockGoodService, i GoodService.Item, u GoodService.UserCtx) {},
-			userRole:             "seller",
+			userPermissions:      []string{permissions.GoodsWrite},
 			expectedStatusCode:   400,
 			expectedResponseBody: `{"Message":"invalid input body"}`,
 		},
@@ -80,7 +81,7 @@ func TestHandler_addItem(t *testing.T) {
 			mockBehavior: func(s *mock.MockGoodService, i GoodService.Item, u GoodService.UserCtx) {
 				s.EXPECT().AddItem(i, u).Return("", errors.New("service failure"))
 			},
-			userRole:             "seller",
+			userPermissions:      []string{permissions.GoodsWrite},
 			userName:             "testName",
 			userID:               1,
 			expectedStatusCode:   500,
@@ -102,10 +103,9 @@ func TestHandler_addItem(t *testing.T) {
 			r := gin.New()
 			r.POST("/item", func(ctx *gin.Context) {
 				ctx.Set("userID", testCase.userID)
-				ctx.Set("userRole", testCase.userRole)
+				ctx.Set("userPermissions", testCase.userPermissions)
 				ctx.Set("userName", testCase.userName)
-				handler.AddItem(ctx)
-			})
+			}, handler.RequirePermission(permissions.GoodsWrite), handler.AddItem)
 
 			w := httptest.NewRecorder()
 			req := httptest.NewRequest("POST", "/item", bytes.NewBufferString(testCase.inputBody))
@@ -121,33 +121,69 @@ func TestHandler_addItem(t *testing.T) {
 
 func TestHandler_getGoods(t *testing.T) {
 	type mockBehavior func(s *mock.MockGoodService)
+
+	minPrice, maxPrice := 5.0, 50.0
+
 	testTable := []struct {
 		name                 string
+		query                string
 		mockBehavior         mockBehavior
 		expectedStatusCode   int
 		expectedResponseBody string
 	}{
 		{
-			name: "OK",
+			name:  "OK",
+			query: "?limit=1&minPrice=5&maxPrice=50&sellerID=100&inStock=true&sort=price_asc&cursor=abc",
 			mockBehavior: func(s *mock.MockGoodService) {
-				s.EXPECT().GetGoods().Return([]GoodService.Item{
-					{
-						Name:        "apple",
-						Description: "apple description",
-					},
-					{
-						Name:        "orange",
-						Description: "orange description",
-					},
+				s.EXPECT().GetGoods(GoodService.GoodsQuery{
+					Cursor:   "abc",
+					Limit:    1,
+					MinPrice: &minPrice,
+					MaxPrice: &maxPrice,
+					SellerID: "100",
+					InStock:  true,
+					Sort:     GoodService.SortPriceAsc,
+				}).Return(GoodService.GoodsPage{
+					Items: []GoodService.Item{{
+						ID: "1", Name: "apple", Description: "apple description", Quantity: 3, Price: 10, SellerID: "100",
+					}},
+					NextCursor: "next",
 				}, nil)
 			},
+			expectedStatusCode: 200,
+			expectedResponseBody: `{"items":[{"_id":"1","name":"apple","description":"apple description","quantity":3,` +
+				`"price":10,"sellerID":"100"}],"nextCursor":"next"}`,
+		},
+		{
+			name:  "Last Page",
+			query: "",
+			mockBehavior: func(s *mock.MockGoodService) {
+				s.EXPECT().GetGoods(GoodService.GoodsQuery{}).Return(GoodService.GoodsPage{Items: []GoodService.Item{}}, nil)
+			},
 			expectedStatusCode:   200,
-			expectedResponseBody: `{"apple":"apple description","orange":"orange description"}`,
+			expectedResponseBody: `{"items":[]}`,
+		},
+		{
+			name:                 "Invalid Price",
+			query:                "?minPrice=cheap",
+			mockBehavior:         func(s *mock.MockGoodService) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"Message":"invalid query"}`,
+		},
+		{
+			name:  "Invalid Cursor",
+			query: "?cursor=abc",
+			mockBehavior: func(s *mock.MockGoodService) {
+				s.EXPECT().GetGoods(GoodService.GoodsQuery{Cursor: "abc"}).Return(GoodService.GoodsPage{}, GoodService.ErrInvalidCursor)
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"Message":"invalid cursor"}`,
 		},
 		{
-			name: "Server Error",
+			name:  "Server Error",
+			query: "",
 			mockBehavior: func(s *mock.MockGoodService) {
-				s.EXPECT().GetGoods().Return([]GoodService.Item{}, errors.New("service failure"))
+				s.EXPECT().GetGoods(GoodService.GoodsQuery{}).Return(GoodService.GoodsPage{}, errors.New("service failure"))
 			},
 			expectedStatusCode:   500,
 			expectedResponseBody: `{"Message":"service failure"}`,
@@ -170,8 +206,7 @@ func TestHandler_getGoods(t *testing.T) {
 			r.GET("/catalog", handler.GetGoods)
 
 			w := httptest.NewRecorder()
-			req := httptest.NewRequest("GET", "/catalog", nil)
-			req.Header.Set("Content-Type", "application/json")
+			req := httptest.NewRequest("GET", "/catalog"+testCase.query, nil)
 
 			r.ServeHTTP(w, req)
 
@@ -186,7 +221,7 @@ func TestHandler_deleteItem(t *testing.T) {
 
 	testTable := []struct {
 		name                 string
-		userRole             string
+		userPermissions      []string
 		userID               int
 		userName             string
 		itemID               string
@@ -195,11 +230,11 @@ func TestHandler_deleteItem(t *testing.T) {
 		expectedResponseBody string
 	}{
 		{
-			name:     "OK",
-			userRole: "seller",
-			userID:   1,
-			userName: "testName",
-			itemID:   "123",
+			name:            "OK",
+			userPermissions: []string{permissions.GoodsWrite},
+			userID:          1,
+			userName:        "testName",
+			itemID:          "123",
 			mockBehavior: func(s *mock.MockGoodService, itemID string, userID int) {
 				s.EXPECT().DeleteItem(itemID, userID).Return(nil)
 			},
@@ -207,19 +242,19 @@ func TestHandler_deleteItem(t *testing.T) {
 			expectedResponseBody: ``,
 		},
 		{
-			name:                 "Incorrect Role",
-			userRole:             "user",
+			name:                 "Missing Permission",
+			userPermissions:      []string{"cart:manage"},
 			itemID:               "123",
 			mockBehavior:         func(s *mock.MockGoodService, itemID string, userID int) {},
-			expectedStatusCode:   400,
+			expectedStatusCode:   403,
 			expectedResponseBody: `{"Message":"not enough rights"}`,
 		},
 		{
-			name:     "Server error",
-			userRole: "seller",
-			userID:   1,
-			userName: "testName",
-			itemID:   "123",
+			name:            "Server error",
+			userPermissions: []string{permissions.GoodsWrite},
+			userID:          1,
+			userName:        "testName",
+			itemID:          "123",
 			mockBehavior: func(s *mock.MockGoodService, itemID string, userID int) {
 				s.EXPECT().DeleteItem(itemID, userID).Return(errors.New("service failure"))
 			},
@@ -242,10 +277,9 @@ func TestHandler_deleteItem(t *testing.T) {
 			r := gin.New()
 			r.DELETE("/item/:id", func(ctx *gin.Context) {
 				ctx.Set("userID", testCase.userID)
-				ctx.Set("userRole", testCase.userRole)
+				ctx.Set("userPermissions", testCase.userPermissions)
 				ctx.Set("userName", testCase.userName)
-				handler.DeleteItem(ctx)
-			})
+			}, handler.RequirePermission(permissions.GoodsWrite), handler.DeleteItem)
 
 			url := "/item/" + testCase.itemID
 			w := httptest.NewRecorder()
@@ -265,7 +299,7 @@ func TestHandler_updateItem(t *testing.T) {
 
 	testTable := []struct {
 		name                 string
-		userRole             string
+		userPermissions      []string
 		userName             string
 		userID               int
 		inputItem            GoodService.Item
@@ -275,10 +309,10 @@ func TestHandler_updateItem(t *testing.T) {
 		expectedResponseBody string
 	}{
 		{
-			name:     "OK",
-			userRole: "seller",
-			userName: "testName",
-			userID:   100,
+			name:            "OK",
+			userPermissions: []string{permissions.GoodsWrite},
+			userName:        "testName",
+			userID:          100,
 			inputItem: GoodService.Item{
 				ID:          "123",
 				Name:        "apple",
@@ -301,23 +335,40 @@ func TestHandler_updateItem(t *testing.T) {
 			expectedResponseBody: `{"_id":"123","name":"apple","description":"new description","quantity":10,"price":6,"sellerID":"1"}`,
 		},
 		{
-			name:                 "Incorrect role",
-			userRole:             "user",
+			name:                 "Missing Permission",
+			userPermissions:      []string{"cart:manage"},
 			mockBehavior:         func(s *mock.MockGoodService, i GoodService.Item, userID int) {},
-			expectedStatusCode:   400,
+			expectedStatusCode:   403,
 			expectedResponseBody: `{"Message":"not enough rights"}`,
 		},
 		{
 			name:                 "Empty Fields",
-			userRole:             "seller",
+			userPermissions:      []string{permissions.GoodsWrite},
 			inputBody:            `{"_id":"123","name":"apple","quantity": 10}`,
 			mockBehavior:         func(s *mock.MockGoodService, i GoodService.Item, userID int) {},
 			expectedStatusCode:   400,
 			expectedResponseBody: `{"Message":"invalid input body"}`,
 		},
 		{
-			name:     "Server Error",
-			userRole: "seller",
+			name:            "Stock Reserved",
+			userPermissions: []string{permissions.GoodsWrite},
+			inputItem: GoodService.Item{
+				ID:          "123",
+				Name:        "apple",
+				Description: "new description",
+				Quantity:    10,
+				Price:       6,
+			},
+			inputBody: `{"_id":"123","name":"apple","description":"new description","quantity": 10,"price": 6}`,
+			mockBehavior: func(s *mock.MockGoodService, i GoodService.Item, userID int) {
+				s.EXPECT().UpdateItem(i, userID).Return(GoodService.Item{}, GoodService.ErrStockReserved)
+			},
+			expectedStatusCode:   409,
+			expectedResponseBody: `{"Message":"stock of the item is held by reservations, its quantity can't be changed now"}`,
+		},
+		{
+			name:            "Server Error",
+			userPermissions: []string{permissions.GoodsWrite},
 			inputItem: GoodService.Item{
 				ID:          "123",
 				Name:        "apple",
@@ -349,10 +400,9 @@ func TestHandler_updateItem(t *testing.T) {
 			r := gin.New()
 			r.PUT("/item", func(ctx *gin.Context) {
 				ctx.Set("userID", testCase.userID)
-				ctx.Set("userRole", testCase.userRole)
+				ctx.Set("userPermissions", testCase.userPermissions)
 				ctx.Set("userName", testCase.userName)
-				handler.UpdateItem(ctx)
-			})
+			}, handler.RequirePermission(permissions.GoodsWrite), handler.UpdateItem)
 
 			w := httptest.NewRecorder()
 			req := httptest.NewRequest("PUT", "/item", bytes.NewBufferString(testCase.inputBody))
@@ -365,3 +415,88 @@ func TestHandler_updateItem(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_search(t *testing.T) {
+	type mockBehavior func(s *mock.MockGoodService)
+
+	testTable := []struct {
+		name                 string
+		inputQuery           string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:       "OK",
+			inputQuery: "?q=apple&limit=5",
+			mockBehavior: func(s *mock.MockGoodService) {
+				s.EXPECT().Search(GoodService.SearchQuery{Query: "apple", Limit: 5}).Return(GoodService.SearchResult{
+					Hits: []GoodService.SearchHit{{
+						Item:       GoodService.Item{ID: "1", Name: "apple", Description: "tasty apple", Price: 2, SellerID: "100"},
+						Score:      4.5,
+						Highlights: GoodService.SearchHighlight{Name: "<em>apple</em>", Description: "tasty <em>apple</em>"},
+					}},
+					Total: 1,
+					Facets: GoodService.SearchFacets{
+						Sellers:      []GoodService.SellerFacet{{SellerID: "100", Count: 1}},
+						PriceBuckets: []GoodService.PriceBucketFacet{{From: 0, Count: 1}},
+					},
+				}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: `{"hits":[{"item":{"_id":"1","name":"apple","description":"tasty apple","quantity":0,"price":2,"sellerID":"100"},` +
+				`"score":4.5,"highlights":{"name":"\u003cem\u003eapple\u003c/em\u003e","description":"tasty \u003cem\u003eapple\u003c/em\u003e"}}],` +
+				`"total":1,"facets":{"sellers":[{"sellerID":"100","count":1}],"priceBuckets":[{"from":0,"to":null,"count":1}]}}`,
+		},
+		{
+			name:       "Empty Query",
+			inputQuery: "?q=",
+			mockBehavior: func(s *mock.MockGoodService) {
+				s.EXPECT().Search(GoodService.SearchQuery{}).Return(GoodService.SearchResult{}, GoodService.ErrEmptySearchQuery)
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"Message":"search query is empty"}`,
+		},
+		{
+			name:                 "Invalid Limit",
+			inputQuery:           "?q=apple&limit=many",
+			mockBehavior:         func(s *mock.MockGoodService) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"Message":"invalid query"}`,
+		},
+		{
+			name:       "Service Failure",
+			inputQuery: "?q=apple",
+			mockBehavior: func(s *mock.MockGoodService) {
+				s.EXPECT().Search(GoodService.SearchQuery{Query: "apple"}).Return(GoodService.SearchResult{}, errors.New("DB Failure"))
+			},
+			expectedStatusCode:   500,
+			expectedResponseBody: `{"Message":"DB Failure"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			goodService := mock.NewMockGoodService(c)
+			testCase.mockBehavior(goodService)
+
+			authClient := mock.NewMockAuthClient(c)
+
+			handler := NewGoodsHandlers(goodService, authClient)
+
+			r := gin.New()
+			r.GET("/search", handler.Search)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/search"+testCase.inputQuery, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, w.Body.String())
+		})
+	}
+}
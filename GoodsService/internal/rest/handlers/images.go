@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/jst-Frenzy/ControlSystem/GoodsService/internal/GoodService"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// multipartOverhead is the room left in the request body for the multipart headers around the image.
+const multipartOverhead = 64 << 10
+
+type reorderImagesInput struct {
+	ImageIDs []string `json:"imageIDs" binding:"required"`
+}
+
+// AddItemImage takes the image from the "image" field of a multipart form.
+func (h *GoodsHandlers) AddItemImage(ctx *gin.Context) {
+	nameHandler := "AddItemImage"
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, GoodService.MaxImageSize+multipartOverhead)
+
+	file, err := ctx.FormFile("image")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			newErrorResponse(ctx, nameHandler, http.StatusRequestEntityTooLarge, GoodService.ErrImageTooLarge.Error())
+			return
+		}
+		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "invalid input body")
+		return
+	}
+	if file.Size > GoodService.MaxImageSize {
+		newErrorResponse(ctx, nameHandler, http.StatusRequestEntityTooLarge, GoodService.ErrImageTooLarge.Error())
+		return
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "invalid input body")
+		return
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, GoodService.MaxImageSize+1))
+	if err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "invalid input body")
+		return
+	}
+
+	userID := ctx.MustGet("userID").(int)
+
+	img, err := h.serv.AddItemImage(ctx.Param("id"), userID, data)
+	if err != nil {
+		newErrorResponse(ctx, nameHandler, imageErrorStatus(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, img)
+}
+
+func (h *GoodsHandlers) DeleteItemImage(ctx *gin.Context) {
+	nameHandler := "DeleteItemImage"
+	userID := ctx.MustGet("userID").(int)
+
+	if err := h.serv.DeleteItemImage(ctx.Param("id"), ctx.Param("imageID"), userID); err != nil {
+		newErrorResponse(ctx, nameHandler, imageErrorStatus(err), err.Error())
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (h *GoodsHandlers) ReorderItemImages(ctx *gin.Context) {
+	nameHandler := "ReorderItemImages"
+	var in reorderImagesInput
+	if err := ctx.ShouldBindJSON(&in); err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "invalid input body")
+		return
+	}
+
+	userID := ctx.MustGet("userID").(int)
+
+	images, err := h.serv.ReorderItemImages(ctx.Param("id"), userID, in.ImageIDs)
+	if err != nil {
+		newErrorResponse(ctx, nameHandler, imageErrorStatus(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"images": images})
+}
+
+// GetImage serves an image or a thumbnail, keys are never reused so they are cached for good.
+func (h *GoodsHandlers) GetImage(ctx *gin.Context) {
+	nameHandler := "GetImage"
+	blob, err := h.serv.GetImage(strings.TrimPrefix(ctx.Param("key"), "/"))
+	if err != nil {
+		if errors.Is(err, GoodService.ErrBlobNotFound) {
+			newErrorResponse(ctx, nameHandler, http.StatusNotFound, "image not found")
+			return
+		}
+		newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx.Header("Cache-Control", "public, max-age=31536000, immutable")
+	ctx.Header("X-Content-Type-Options", "nosniff")
+	ctx.Data(http.StatusOK, blob.ContentType, blob.Data)
+}
+
+func imageErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, GoodService.ErrImageTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, GoodService.ErrUnsupportedImageType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, GoodService.ErrInvalidImage), errors.Is(err, GoodService.ErrInvalidImageOrder):
+		return http.StatusBadRequest
+	case errors.Is(err, GoodService.ErrNotItemOwner):
+		return http.StatusForbidden
+	case errors.Is(err, GoodService.ErrItemImageNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, GoodService.ErrItemImageLimit):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/jst-Frenzy/ControlSystem/GoodsService/internal/GoodService"
+	mock "github.com/jst-Frenzy/ControlSystem/GoodsService/internal/mocks"
+	"mime/multipart"
+	"net/http/httptest"
+	"testing"
+)
+
+func multipartImage(t *testing.T, field string, data []byte) (*bytes.Buffer, string) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile(field, "photo.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	w.Close()
+	return &body, w.FormDataContentType()
+}
+
+func TestHandler_addItemImage(t *testing.T) {
+	type mockBehavior func(s *mock.MockGoodService)
+
+	testTable := []struct {
+		name                 string
+		field                string
+		data                 []byte
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:  "OK",
+			field: "image",
+			data:  []byte("png data"),
+			mockBehavior: func(s *mock.MockGoodService) {
+				s.EXPECT().AddItemImage("1", 7, []byte("png data")).Return(GoodService.ItemImage{
+					ID: "a", Key: "items/1/a.png", ThumbnailKey: "items/1/a_thumb.jpg", ContentType: "image/png", Width: 2, Height: 1, Size: 8,
+				}, nil)
+			},
+			expectedStatusCode: 201,
+			expectedResponseBody: `{"id":"a","key":"items/1/a.png","thumbnailKey":"items/1/a_thumb.jpg",` +
+				`"contentType":"image/png","width":2,"height":1,"size":8}`,
+		},
+		{
+			name:                 "No Image Field",
+			field:                "file",
+			data:                 []byte("png data"),
+			mockBehavior:         func(s *mock.MockGoodService) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"Message":"invalid input body"}`,
+		},
+		{
+			name:                 "Too Large",
+			field:                "image",
+			data:                 make([]byte, GoodService.MaxImageSize+1),
+			mockBehavior:         func(s *mock.MockGoodService) {},
+			expectedStatusCode:   413,
+			expectedResponseBody: `{"Message":"image is too large"}`,
+		},
+		{
+			name:  "Unsupported Type",
+			field: "image",
+			data:  []byte("GIF89a"),
+			mockBehavior: func(s *mock.MockGoodService) {
+				s.EXPECT().AddItemImage("1", 7, []byte("GIF89a")).Return(GoodService.ItemImage{}, GoodService.ErrUnsupportedImageType)
+			},
+			expectedStatusCode:   415,
+			expectedResponseBody: `{"Message":"unsupported image type, use jpeg, png or gif"}`,
+		},
+		{
+			name:  "Not Owner",
+			field: "image",
+			data:  []byte("png data"),
+			mockBehavior: func(s *mock.MockGoodService) {
+				s.EXPECT().AddItemImage("1", 7, []byte("png data")).Return(GoodService.ItemImage{}, GoodService.ErrNotItemOwner)
+			},
+			expectedStatusCode:   403,
+			expectedResponseBody: `{"Message":"it's not your item"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			goodService := mock.NewMockGoodService(c)
+			testCase.mockBehavior(goodService)
+
+			handler := NewGoodsHandlers(goodService, mock.NewMockAuthClient(c))
+
+			r := gin.New()
+			r.POST("/item/:id/images", func(ctx *gin.Context) {
+				ctx.Set("userID", 7)
+			}, handler.AddItemImage)
+
+			body, contentType := multipartImage(t, testCase.field, testCase.data)
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/item/1/images", body)
+			req.Header.Set("Content-Type", contentType)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_getImage(t *testing.T) {
+	type mockBehavior func(s *mock.MockGoodService)
+
+	testTable := []struct {
+		name                 string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedContentType  string
+		expectedResponseBody string
+	}{
+		{
+			name: "OK",
+			mockBehavior: func(s *mock.MockGoodService) {
+				s.EXPECT().GetImage("items/1/a_thumb.jpg").Return(GoodService.Blob{Data: []byte("jpeg data"), ContentType: "image/jpeg"}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedContentType:  "image/jpeg",
+			expectedResponseBody: "jpeg data",
+		},
+		{
+			name: "Not Found",
+			mockBehavior: func(s *mock.MockGoodService) {
+				s.EXPECT().GetImage("items/1/a_thumb.jpg").Return(GoodService.Blob{}, GoodService.ErrBlobNotFound)
+			},
+			expectedStatusCode:   404,
+			expectedContentType:  "application/json; charset=utf-8",
+			expectedResponseBody: `{"Message":"image not found"}`,
+		},
+		{
+			name: "Storage Failure",
+			mockBehavior: func(s *mock.MockGoodService) {
+				s.EXPECT().GetImage("items/1/a_thumb.jpg").Return(GoodService.Blob{}, errors.New("storage down"))
+			},
+			expectedStatusCode:   500,
+			expectedContentType:  "application/json; charset=utf-8",
+			expectedResponseBody: `{"Message":"storage down"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			goodService := mock.NewMockGoodService(c)
+			testCase.mockBehavior(goodService)
+
+			handler := NewGoodsHandlers(goodService, mock.NewMockAuthClient(c))
+
+			r := gin.New()
+			r.GET("/images/*key", handler.GetImage)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("GET", "/images/items/1/a_thumb.jpg", nil))
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, testCase.expectedResponseBody, w.Body.String())
+		})
+	}
+}
@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"github.com/gin-gonic/gin"
+	proto "github.com/jst-Frenzy/ControlSystem/protobuf/gen/auth"
+	"github.com/sirupsen/logrus"
 	"net/http"
 	"strconv"
 	"strings"
@@ -9,6 +11,8 @@ import (
 
 const (
 	authorizationHeader = "Authorization"
+	// scripts send their AuthService API key with this scheme instead of a Bearer token
+	apiKeyScheme = "ApiKey"
 )
 
 func (h *GoodsHandlers) UserIdentity(ctx *gin.Context) {
@@ -18,14 +22,14 @@ func (h *GoodsHandlers) UserIdentity(ctx *gin.Context) {
 		newErrorResponse(ctx, nameHandler, http.StatusUnauthorized, "empty auth header")
 		return
 	}
-	
+
 	headerParts := strings.Split(header, " ")
 	if len(headerParts) != 2 {
 		newErrorResponse(ctx, nameHandler, http.StatusUnauthorized, "invalid auth header")
 		return
 	}
 
-	if headerParts[0] != "Bearer" {
+	if headerParts[0] != "Bearer" && headerParts[0] != apiKeyScheme {
 		newErrorResponse(ctx, nameHandler, http.StatusUnauthorized, "invalid auth header")
 		return
 	}
@@ -37,7 +41,13 @@ func (h *GoodsHandlers) UserIdentity(ctx *gin.Context) {
 
 	token := headerParts[1]
 
-	response, err := h.authClient.ValidateToken(ctx, token)
+	var response *proto.ValidateTokenResponse
+	var err error
+	if headerParts[0] == apiKeyScheme {
+		response, err = h.authClient.ValidateAPIKey(ctx, token)
+	} else {
+		response, err = h.authClient.ValidateToken(ctx, token)
+	}
 	if err != nil {
 		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, err.Error())
 		return
@@ -56,4 +66,30 @@ func (h *GoodsHandlers) UserIdentity(ctx *gin.Context) {
 	ctx.Set("userID", id)
 	ctx.Set("userRole", response.Role)
 	ctx.Set("userName", response.UserName)
+	ctx.Set("userPermissions", response.Permissions)
+
+	// the token belongs to an admin acting as the user: keep the admin's ID and log every such request
+	if response.ActorId != "" {
+		ctx.Set("actorID", response.ActorId)
+		logrus.WithFields(logrus.Fields{
+			"event":    "impersonated_request",
+			"actor_id": response.ActorId,
+			"user_id":  response.UserId,
+			"method":   ctx.Request.Method,
+			"path":     ctx.Request.URL.Path,
+		}).Info("request made while impersonating user")
+	}
+}
+
+// RequirePermission must be mounted after UserIdentity.
+func (h *GoodsHandlers) RequirePermission(permission string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		nameHandler := "RequirePermission"
+		for _, p := range ctx.GetStringSlice("userPermissions") {
+			if p == permission {
+				return
+			}
+		}
+		newErrorResponse(ctx, nameHandler, http.StatusForbidden, "not enough rights")
+	}
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/golang/mock/gomock"
 	mock "github.com/jst-Frenzy/ControlSystem/GoodsService/internal/mocks"
 	proto "github.com/jst-Frenzy/ControlSystem/protobuf/gen/auth"
+	"github.com/jst-Frenzy/ControlSystem/protobuf/permissions"
 	"net/http/httptest"
 	"testing"
 )
@@ -40,6 +41,35 @@ func TestHandler_userIdentity(t *testing.T) {
 			expectedStatusCode:   200,
 			expectedResponseBody: `{"id":1,"role":"user","name":"testName"}`,
 		},
+		{
+			name:        "API Key",
+			headerName:  "Authorization",
+			headerValue: "ApiKey cs_key",
+			token:       "cs_key",
+			mockBehavior: func(authClient *mock.MockAuthClient, token string) {
+				authClient.EXPECT().ValidateAPIKey(gomock.Any(), token).Return(&proto.ValidateTokenResponse{
+					Valid:       true,
+					UserId:      "1",
+					Role:        "seller",
+					UserName:    "testName",
+					Permissions: []string{permissions.GoodsWrite},
+				}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"id":1,"role":"seller","name":"testName"}`,
+		},
+		{
+			name:        "Invalid API Key",
+			headerName:  "Authorization",
+			headerValue: "ApiKey cs_revoked",
+			token:       "cs_revoked",
+			mockBehavior: func(authClient *mock.MockAuthClient, token string) {
+				authClient.EXPECT().ValidateAPIKey(gomock.Any(), token).Return(&proto.ValidateTokenResponse{},
+					errors.New("can't validate api key"))
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"Message":"can't validate api key"}`,
+		},
 		{
 			name:                 "No header",
 			mockBehavior:         func(authClient *mock.MockAuthClient, token string) {},
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/jst-Frenzy/ControlSystem/GoodsService/internal/GoodService"
+	"net/http"
+)
+
+func (h *GoodsHandlers) AddVariant(ctx *gin.Context) {
+	nameHandler := "AddVariant"
+	var v GoodService.Variant
+	if err := ctx.ShouldBindJSON(&v); err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "invalid input body")
+		return
+	}
+
+	userID := ctx.MustGet("userID").(int)
+
+	item, err := h.serv.AddVariant(ctx.Param("id"), userID, v)
+	if err != nil {
+		newErrorResponse(ctx, nameHandler, variantErrorStatus(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, item)
+}
+
+// variantInput is the body of UpdateVariant, the sku comes from the path.
+type variantInput struct {
+	Attributes map[string]string `json:"attributes" binding:"required"`
+	Quantity   int               `json:"quantity"`
+	Price      *float64          `json:"price"`
+}
+
+func (h *GoodsHandlers) UpdateVariant(ctx *gin.Context) {
+	nameHandler := "UpdateVariant"
+	var in variantInput
+	if err := ctx.ShouldBindJSON(&in); err != nil {
+		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "invalid input body")
+		return
+	}
+
+	userID := ctx.MustGet("userID").(int)
+
+	v := GoodService.Variant{SKU: ctx.Param("sku"), Attributes: in.Attributes, Quantity: in.Quantity, Price: in.Price}
+	item, err := h.serv.UpdateVariant(ctx.Param("id"), userID, v)
+	if err != nil {
+		newErrorResponse(ctx, nameHandler, variantErrorStatus(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, item)
+}
+
+func (h *GoodsHandlers) DeleteVariant(ctx *gin.Context) {
+	nameHandler := "DeleteVariant"
+	userID := ctx.MustGet("userID").(int)
+
+	item, err := h.serv.DeleteVariant(ctx.Param("id"), userID, ctx.Param("sku"))
+	if err != nil {
+		newErrorResponse(ctx, nameHandler, variantErrorStatus(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, item)
+}
+
+func variantErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, GoodService.ErrInvalidVariant), errors.Is(err, GoodService.ErrVariantAttributes):
+		return http.StatusBadRequest
+	case errors.Is(err, GoodService.ErrNotItemOwner):
+		return http.StatusForbidden
+	case errors.Is(err, GoodService.ErrVariantNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, GoodService.ErrSKUTaken), errors.Is(err, GoodService.ErrDuplicateVariant),
+		errors.Is(err, GoodService.ErrItemVariantLimit), errors.Is(err, GoodService.ErrVariantsChanged):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
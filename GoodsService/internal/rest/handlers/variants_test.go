@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/jst-Frenzy/ControlSystem/GoodsService/internal/GoodService"
+	mock "github.com/jst-Frenzy/ControlSystem/GoodsService/internal/mocks"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler_addVariant(t *testing.T) {
+	type mockBehavior func(s *mock.MockGoodService)
+
+	variant := GoodService.Variant{SKU: "TS-M", Attributes: map[string]string{"size": "M"}, Quantity: 3}
+
+	testTable := []struct {
+		name                 string
+		inputBody            string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:      "OK",
+			inputBody: `{"sku":"TS-M","attributes":{"size":"M"},"quantity":3}`,
+			mockBehavior: func(s *mock.MockGoodService) {
+				s.EXPECT().AddVariant("1", 7, variant).Return(GoodService.Item{
+					ID: "1", Name: "T-shirt", Description: "cotton", Quantity: 3, Price: 10, SellerID: "100",
+					Variants: []GoodService.Variant{variant},
+				}, nil)
+			},
+			expectedStatusCode: 201,
+			expectedResponseBody: `{"_id":"1","name":"T-shirt","description":"cotton","quantity":3,"price":10,"sellerID":"100",` +
+				`"variants":[{"sku":"TS-M","attributes":{"size":"M"},"quantity":3}]}`,
+		},
+		{
+			name:                 "No SKU",
+			inputBody:            `{"attributes":{"size":"M"},"quantity":3}`,
+			mockBehavior:         func(s *mock.MockGoodService) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"Message":"invalid input body"}`,
+		},
+		{
+			name:      "Duplicate Variant",
+			inputBody: `{"sku":"TS-M","attributes":{"size":"M"},"quantity":3}`,
+			mockBehavior: func(s *mock.MockGoodService) {
+				s.EXPECT().AddVariant("1", 7, variant).Return(GoodService.Item{}, GoodService.ErrDuplicateVariant)
+			},
+			expectedStatusCode:   409,
+			expectedResponseBody: `{"Message":"item already has a variant with these attributes"}`,
+		},
+		{
+			name:      "Other Attributes",
+			inputBody: `{"sku":"TS-M","attributes":{"size":"M"},"quantity":3}`,
+			mockBehavior: func(s *mock.MockGoodService) {
+				s.EXPECT().AddVariant("1", 7, variant).Return(GoodService.Item{}, GoodService.ErrVariantAttributes)
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"Message":"variants of an item must have the same attributes"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			goodService := mock.NewMockGoodService(c)
+			testCase.mockBehavior(goodService)
+
+			handler := NewGoodsHandlers(goodService, mock.NewMockAuthClient(c))
+
+			r := gin.New()
+			r.POST("/item/:id/variants", func(ctx *gin.Context) {
+				ctx.Set("userID", 7)
+			}, handler.AddVariant)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/item/1/variants", bytes.NewBufferString(testCase.inputBody))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_updateVariant(t *testing.T) {
+	type mockBehavior func(s *mock.MockGoodService)
+
+	testTable := []struct {
+		name                 string
+		inputBody            string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:      "SKU From Path",
+			inputBody: `{"sku":"OTHER","attributes":{"size":"L"},"quantity":2}`,
+			mockBehavior: func(s *mock.MockGoodService) {
+				s.EXPECT().UpdateVariant("1", 7, GoodService.Variant{
+					SKU: "TS-L", Attributes: map[string]string{"size": "L"}, Quantity: 2,
+				}).Return(GoodService.Item{ID: "1"}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"_id":"1","name":"","description":"","quantity":0,"price":0,"sellerID":""}`,
+		},
+		{
+			name:      "Unknown SKU",
+			inputBody: `{"attributes":{"size":"L"},"quantity":2}`,
+			mockBehavior: func(s *mock.MockGoodService) {
+				s.EXPECT().UpdateVariant("1", 7, gomock.Any()).Return(GoodService.Item{}, GoodService.ErrVariantNotFound)
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"Message":"variant not found"}`,
+		},
+		{
+			name:      "Changed Meanwhile",
+			inputBody: `{"attributes":{"size":"L"},"quantity":2}`,
+			mockBehavior: func(s *mock.MockGoodService) {
+				s.EXPECT().UpdateVariant("1", 7, gomock.Any()).Return(GoodService.Item{}, GoodService.ErrVariantsChanged)
+			},
+			expectedStatusCode:   409,
+			expectedResponseBody: `{"Message":"variants of the item were changed meanwhile, try again"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			goodService := mock.NewMockGoodService(c)
+			testCase.mockBehavior(goodService)
+
+			handler := NewGoodsHandlers(goodService, mock.NewMockAuthClient(c))
+
+			r := gin.New()
+			r.PUT("/item/:id/variants/:sku", func(ctx *gin.Context) {
+				ctx.Set("userID", 7)
+			}, handler.UpdateVariant)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("PUT", "/item/1/variants/TS-L", bytes.NewBufferString(testCase.inputBody))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, w.Body.String())
+		})
+	}
+}
@@ -4,13 +4,17 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/jst-Frenzy/ControlSystem/OrderService/internals/dataBase"
 	"github.com/jst-Frenzy/ControlSystem/OrderService/internals/gRPC/client"
+	"github.com/jst-Frenzy/ControlSystem/OrderService/internals/gRPC/server"
 	"github.com/jst-Frenzy/ControlSystem/OrderService/internals/orderService"
 	"github.com/jst-Frenzy/ControlSystem/OrderService/internals/rest/handlers"
+	"github.com/jst-Frenzy/ControlSystem/protobuf/permissions"
 	"github.com/sirupsen/logrus"
 	"os"
+	"strconv"
 )
 
 func main() {
+	logger := logrus.New()
 	dataBase.InitPostgres()
 
 	orderPostgresRepo := orderService.NewOrderPostgresRep(dataBase.PostgresDB)
@@ -22,6 +26,22 @@ func main() {
 
 	orderServ := orderService.NewOrderService(orderPostgresRepo, goodsClient)
 
+	grpcServer := server.NewGRPCServer(server.Deps{
+		OrderService: orderServ,
+		Logger:       logger,
+	})
+
+	go func() {
+		port, errConv := strconv.Atoi(os.Getenv("GRPC_PORT_SERVER"))
+		if errConv != nil {
+			logger.Fatal("can't get grpc port from env")
+		}
+
+		if errStart := grpcServer.Start(port); errStart != nil {
+			logger.WithError(errStart).Fatal("can't start grpc order server")
+		}
+	}()
+
 	authClient, err := client.NewAuthClient(os.Getenv("ADDRESS_GRPC_AUTH"))
 	if err != nil {
 		logrus.Fatal("can't start auth client")
@@ -32,7 +52,7 @@ func main() {
 	router := gin.Default()
 
 	api := router.Group("/api/orders")
-	api.Use(orderHandler.UserIdentity)
+	api.Use(orderHandler.UserIdentity, orderHandler.RequirePermission(permissions.CartManage))
 	{
 		cartGroup := api.Group("/cart")
 		{
@@ -42,6 +62,8 @@ func main() {
 		}
 	}
 
+	router.GET("/api/orders/carts/:cartID", orderHandler.UserIdentity, orderHandler.RequirePermission(permissions.OrdersReadAny), orderHandler.GetUserCart)
+
 	if errRun := router.Run(":8082"); errRun != nil {
 		logrus.WithError(err).Fatalf("can't start order server")
 	}
@@ -10,7 +10,7 @@ import (
 )
 
 type GoodsClient interface {
-	GetItemQuantityAndPrice(ctx context.Context, itemID string) (*gen.ItemQuantityAndPriceResponse, error)
+	GetItemQuantityAndPrice(ctx context.Context, itemID, sku string) (*gen.ItemQuantityAndPriceResponse, error)
 	Close() error
 }
 
@@ -31,8 +31,8 @@ func NewGoodsClient(addr string) (GoodsClient, error) {
 	}, nil
 }
 
-func (c *goodsClient) GetItemQuantityAndPrice(ctx context.Context, itemID string) (*gen.ItemQuantityAndPriceResponse, error) {
-	req := &gen.ItemQuantityAndPriceRequest{ItemId: itemID}
+func (c *goodsClient) GetItemQuantityAndPrice(ctx context.Context, itemID, sku string) (*gen.ItemQuantityAndPriceResponse, error) {
+	req := &gen.ItemQuantityAndPriceRequest{ItemId: itemID, Sku: sku}
 
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
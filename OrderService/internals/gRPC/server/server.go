@@ -0,0 +1,93 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"github.com/jst-Frenzy/ControlSystem/OrderService/internals/orderService"
+	gen "github.com/jst-Frenzy/ControlSystem/protobuf/gen/order"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net"
+)
+
+type Deps struct {
+	OrderService orderService.OrderService
+	Logger       *logrus.Logger
+}
+
+type Server struct {
+	gen.UnimplementedOrderServiceServer
+	srv          *grpc.Server
+	orderService orderService.OrderService
+	logger       *logrus.Entry
+}
+
+func NewGRPCServer(d Deps) *Server {
+	return &Server{
+		srv:          grpc.NewServer(),
+		orderService: d.OrderService,
+		logger:       logrus.NewEntry(d.Logger),
+	}
+}
+
+func (s *Server) Start(port int) error {
+	addr := fmt.Sprintf(":%d", port)
+
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	gen.RegisterOrderServiceServer(s.srv, s)
+
+	return s.srv.Serve(lis)
+}
+
+func (s *Server) Stop() {
+	if s.srv != nil {
+		s.srv.GracefulStop()
+	}
+}
+
+// ExportUserData returns the cart of the user, the cart id of a user is its user id.
+func (s *Server) ExportUserData(ctx context.Context, req *gen.UserDataRequest) (*gen.ExportUserDataResponse, error) {
+	if req.GetUserId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "user id is required")
+	}
+
+	cart, err := s.orderService.ExportCart(int(req.GetUserId()))
+	if err != nil {
+		s.logger.WithError(err).WithField("user_id", req.GetUserId()).Error("can't export cart")
+		return nil, status.Error(codes.Internal, "can't export user data")
+	}
+
+	resp := &gen.ExportUserDataResponse{Items: make([]*gen.CartItem, 0, len(cart))}
+	for _, i := range cart {
+		resp.Items = append(resp.Items, &gen.CartItem{
+			Id:        int64(i.Id),
+			ProductId: i.ProductID,
+			Name:      i.Name,
+			Quantity:  int64(i.Quantity),
+			Price:     i.Price,
+			Sku:       i.SKU,
+		})
+	}
+
+	return resp, nil
+}
+
+func (s *Server) DeleteUserData(ctx context.Context, req *gen.UserDataRequest) (*gen.DeleteUserDataResponse, error) {
+	if req.GetUserId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "user id is required")
+	}
+
+	deleted, err := s.orderService.DeleteCart(int(req.GetUserId()))
+	if err != nil {
+		s.logger.WithError(err).WithField("user_id", req.GetUserId()).Error("can't delete cart")
+		return nil, status.Error(codes.Internal, "can't delete user data")
+	}
+
+	return &gen.DeleteUserDataResponse{DeletedItems: deleted}, nil
+}
@@ -5,6 +5,9 @@ type CartItem struct {
 	CartID    int     `json:"cart_id"`
 	Name      string  `json:"name"`
 	ProductID string  `json:"product_id"`
+	SKU       string  `json:"sku"` // empty for products without variants
 	Quantity  int     `json:"quantity"`
 	Price     float64 `json:"price"`
+	// Unavailable marks a line GoodsService can't price any more, e.g. one added without a sku before its item got variants
+	Unavailable bool `json:"unavailable,omitempty" gorm:"-"`
 }
@@ -7,8 +7,9 @@ import (
 
 type OrderPostgresRep interface {
 	AddToCart(CartItem) (int, error)
-	RemoveFromCart(int, string) error
+	RemoveFromCart(int, string, string) error
 	GetCart(int) ([]CartItem, error)
+	DeleteCart(int) (int64, error)
 }
 
 type orderPostgresRep struct {
@@ -26,15 +27,15 @@ func (r *orderPostgresRep) AddToCart(i CartItem) (int, error) {
 	return i.Id, nil
 }
 
-func (r *orderPostgresRep) RemoveFromCart(cartID int, productID string) error {
-	res := r.db.Table("carts").Delete(&CartItem{}, "cart_id = ? AND product_id = ?", cartID, productID)
+func (r *orderPostgresRep) RemoveFromCart(cartID int, productID string, sku string) error {
+	res := r.db.Table("carts").Delete(&CartItem{}, "cart_id = ? AND product_id = ? AND sku = ?", cartID, productID, sku)
 
 	if res.Error != nil {
 		return res.Error
 	}
 
 	if res.RowsAffected == 0 {
-		return fmt.Errorf("item with product id: %s in cart with id: %d don't exists", productID, cartID)
+		return fmt.Errorf("item with product id: %s and sku: %q in cart with id: %d don't exists", productID, sku, cartID)
 	}
 
 	return nil
@@ -47,3 +48,11 @@ func (r *orderPostgresRep) GetCart(cartID int) ([]CartItem, error) {
 	}
 	return items, nil
 }
+
+func (r *orderPostgresRep) DeleteCart(cartID int) (int64, error) {
+	res := r.db.Table("carts").Delete(&CartItem{}, "cart_id = ?", cartID)
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	return res.RowsAffected, nil
+}
@@ -4,13 +4,18 @@ import (
 	"context"
 	"errors"
 	"github.com/jst-Frenzy/ControlSystem/OrderService/internals/gRPC/client"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"strconv"
 )
 
 type OrderService interface {
 	AddToCart(CartItem) (int, error)
-	RemoveFromCart(int, string) error
+	RemoveFromCart(int, string, string) error
 	GetCart(int, context.Context) ([]CartItem, float64, error)
+
+	ExportCart(int) ([]CartItem, error)
+	DeleteCart(int) (int64, error)
 }
 
 type orderService struct {
@@ -29,8 +34,8 @@ func (s *orderService) AddToCart(i CartItem) (int, error) {
 	return s.repo.AddToCart(i)
 }
 
-func (s *orderService) RemoveFromCart(cartID int, itemID string) error {
-	return s.repo.RemoveFromCart(cartID, itemID)
+func (s *orderService) RemoveFromCart(cartID int, itemID string, sku string) error {
+	return s.repo.RemoveFromCart(cartID, itemID, sku)
 }
 
 func (s *orderService) GetCart(cartID int, ctx context.Context) ([]CartItem, float64, error) {
@@ -43,7 +48,12 @@ func (s *orderService) GetCart(cartID int, ctx context.Context) ([]CartItem, flo
 	var totalPrice float64
 
 	for i := range cart {
-		resp, errGet := s.goodsClient.GetItemQuantityAndPrice(ctx, cart[i].ProductID)
+		resp, errGet := s.goodsClient.GetItemQuantityAndPrice(ctx, cart[i].ProductID, cart[i].SKU)
+		if code := status.Code(errGet); code == codes.InvalidArgument || code == codes.NotFound {
+			// a stale line doesn't make the rest of the cart unreadable, it's left out of the total
+			cart[i].Unavailable = true
+			continue
+		}
 		if errGet != nil {
 			return nil, 0, errGet
 		}
@@ -63,3 +73,12 @@ func (s *orderService) GetCart(cartID int, ctx context.Context) ([]CartItem, flo
 
 	return cart, totalPrice, nil
 }
+
+// ExportCart returns the cart rows as stored, without refreshing prices from GoodsService.
+func (s *orderService) ExportCart(cartID int) ([]CartItem, error) {
+	return s.repo.GetCart(cartID)
+}
+
+func (s *orderService) DeleteCart(cartID int) (int64, error) {
+	return s.repo.DeleteCart(cartID)
+}
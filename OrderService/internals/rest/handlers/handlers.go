@@ -42,28 +42,54 @@ func (h *OrderHandler) AddToCart(ctx *gin.Context) {
 }
 
 func (h *OrderHandler) GetCart(ctx *gin.Context) {
-	nameHandler := "GetCart"
 	cartIDstr := ctx.MustGet("CartID").(string)
 	cartID, _ := strconv.Atoi(cartIDstr)
 
+	h.writeCart(ctx, "GetCart", cartID)
+}
+
+// GetUserCart lets staff read the cart of any user, it needs the orders:read:any permission.
+func (h *OrderHandler) GetUserCart(ctx *gin.Context) {
+	nameHandler := "GetUserCart"
+	cartID, err := strconv.Atoi(ctx.Param("cartID"))
+	if err != nil || cartID <= 0 {
+		newErrorResponse(ctx, nameHandler, http.StatusBadRequest, "invalid cart id")
+		return
+	}
+
+	h.writeCart(ctx, nameHandler, cartID)
+}
+
+func (h *OrderHandler) writeCart(ctx *gin.Context, nameHandler string, cartID int) {
 	cart, totalPrice, err := h.serv.GetCart(cartID, ctx)
 	if err != nil {
 		newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
+		return
 	}
 
 	type ItemStruct struct {
 		ProductID string
+		SKU       string
 		Quantity  int
 		Price     float64
+		// Unavailable lines are left out of the total price
+		Unavailable bool `json:",omitempty"`
 	}
 
 	resp := make(map[string]interface{})
 
 	for _, i := range cart {
-		resp[i.Name] = ItemStruct{
-			ProductID: i.ProductID,
-			Quantity:  i.Quantity,
-			Price:     i.Price,
+		// variants of one product share its name
+		key := i.Name
+		if i.SKU != "" {
+			key += " (" + i.SKU + ")"
+		}
+		resp[key] = ItemStruct{
+			ProductID:   i.ProductID,
+			SKU:         i.SKU,
+			Quantity:    i.Quantity,
+			Price:       i.Price,
+			Unavailable: i.Unavailable,
 		}
 	}
 
@@ -79,7 +105,7 @@ func (h *OrderHandler) DeleteFromCart(ctx *gin.Context) {
 
 	itemID := ctx.Param("id")
 
-	err := h.serv.RemoveFromCart(cartID, itemID)
+	err := h.serv.RemoveFromCart(cartID, itemID, ctx.Query("sku"))
 	if err != nil {
 		newErrorResponse(ctx, nameHandler, http.StatusInternalServerError, err.Error())
 		return
@@ -2,12 +2,16 @@ package handlers
 
 import (
 	"github.com/gin-gonic/gin"
+	proto "github.com/jst-Frenzy/ControlSystem/protobuf/gen/auth"
+	"github.com/sirupsen/logrus"
 	"net/http"
 	"strings"
 )
 
 const (
 	authorizationHeader = "Authorization"
+	// requests signed with an API key use this scheme, AuthService checks the key like a token
+	apiKeyScheme = "ApiKey"
 )
 
 func (h *OrderHandler) UserIdentity(ctx *gin.Context) {
@@ -24,7 +28,7 @@ func (h *OrderHandler) UserIdentity(ctx *gin.Context) {
 		return
 	}
 
-	if headerParts[0] != "Bearer" {
+	if headerParts[0] != "Bearer" && headerParts[0] != apiKeyScheme {
 		newErrorResponse(ctx, handlerName, http.StatusUnauthorized, "invalid auth header")
 		return
 	}
@@ -36,15 +40,47 @@ func (h *OrderHandler) UserIdentity(ctx *gin.Context) {
 
 	token := headerParts[1]
 
-	resp, err := h.authClient.ValidateToken(ctx, token)
+	var resp *proto.ValidateTokenResponse
+	var err error
+	if headerParts[0] == apiKeyScheme {
+		resp, err = h.authClient.ValidateAPIKey(ctx, token)
+	} else {
+		resp, err = h.authClient.ValidateToken(ctx, token)
+	}
 	if err != nil {
 		newErrorResponse(ctx, handlerName, http.StatusBadRequest, err.Error())
 		return
 	}
 	if !resp.Valid {
-		newErrorResponse(ctx, handlerName, http.StatusBadRequest, err.Error())
+		newErrorResponse(ctx, handlerName, http.StatusUnauthorized, "invalid token")
 		return
 	}
 
 	ctx.Set("CartID", resp.CartId)
+	ctx.Set("Permissions", resp.Permissions)
+
+	// an admin is signed in as this user, so the request is logged with the admin's ID
+	if resp.ActorId != "" {
+		ctx.Set("ActorID", resp.ActorId)
+		logrus.WithFields(logrus.Fields{
+			"event":    "impersonated_request",
+			"actor_id": resp.ActorId,
+			"user_id":  resp.UserId,
+			"method":   ctx.Request.Method,
+			"path":     ctx.Request.URL.Path,
+		}).Info("request made while impersonating user")
+	}
+}
+
+// RequirePermission must be mounted after UserIdentity.
+func (h *OrderHandler) RequirePermission(permission string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		handlerName := "RequirePermission"
+		for _, p := range ctx.GetStringSlice("Permissions") {
+			if p == permission {
+				return
+			}
+		}
+		newErrorResponse(ctx, handlerName, http.StatusForbidden, "not enough rights")
+	}
 }
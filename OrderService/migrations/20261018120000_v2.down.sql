@@ -0,0 +1 @@
+alter table carts drop column if exists sku;
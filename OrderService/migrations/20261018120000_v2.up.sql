@@ -0,0 +1 @@
+alter table carts add column sku varchar(255) not null default '';
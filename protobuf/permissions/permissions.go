@@ -0,0 +1,12 @@
+// Package permissions names the permissions carried in access tokens. AuthService grants them to roles,
+// the other services only check them, so every service reads the names from here.
+package permissions
+
+const (
+	CartManage    = "cart:manage"
+	GoodsWrite    = "goods:write"
+	UsersManage   = "users:manage"
+	CatalogManage = "catalog:manage"
+	// OrdersReadAny lets support staff read the cart of any user, not only their own
+	OrdersReadAny = "orders:read:any"
+)